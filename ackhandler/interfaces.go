@@ -15,6 +15,9 @@ type SentPacketHandler interface {
 	SetHandshakeComplete()
 
 	SendingAllowed() bool
+	// TimeUntilSend returns the time when the next packet should be sent, as determined by the pacer.
+	// It returns the zero value if the next packet can be sent immediately.
+	TimeUntilSend() time.Time
 	GetStopWaitingFrame(force bool) *wire.StopWaitingFrame
 	ShouldSendRetransmittablePacket() bool
 	DequeuePacketForRetransmission() (packet *Packet)
@@ -22,6 +25,19 @@ type SentPacketHandler interface {
 
 	GetAlarmTimeout() time.Time
 	OnAlarm()
+
+	GetStatistics() Statistics
+}
+
+// Statistics are statistics about the packets sent on a connection
+type Statistics struct {
+	PacketsSent      uint64
+	BytesSent        protocol.ByteCount
+	PacketsLost      uint64 // packets declared lost, either by loss detection or by an RTO
+	RTOCount         uint64
+	Retransmissions  uint64 // packets queued for retransmission
+	BytesInFlight    protocol.ByteCount
+	CongestionWindow protocol.ByteCount
 }
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
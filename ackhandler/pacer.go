@@ -0,0 +1,66 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+)
+
+const (
+	// The number of packets that may be sent without pacing when leaving quiescence.
+	initialUnpacedBurst = 10
+	// Packets that are due less than this duration in the future are sent immediately.
+	// This prevents the timer from firing for every single packet.
+	pacingGranularity = time.Millisecond
+)
+
+// The pacer spreads the packets of a congestion window over time, sending them at the pacing rate.
+// It is based on Chromium's PacingSender.
+type pacer struct {
+	// The number of packets that can still be sent without pacing.
+	burstTokens int
+	// The time when the next packet should be sent.
+	idealNextPacketSendTime time.Time
+}
+
+func newPacer() *pacer {
+	return &pacer{burstTokens: initialUnpacedBurst}
+}
+
+// SentPacket should be called for every retransmittable packet sent.
+// priorInFlight are the bytes in flight before this packet was sent.
+// A pacing rate of 0 means that the rate is unknown, and that packets are not paced.
+func (p *pacer) SentPacket(sentTime time.Time, priorInFlight, size, congestionWindow protocol.ByteCount, rate congestion.Bandwidth) {
+	if priorInFlight == 0 {
+		// Allow a burst anytime the connection is leaving quiescence,
+		// but don't send more than one congestion window at once.
+		p.burstTokens = utils.Min(initialUnpacedBurst, int(congestionWindow/protocol.DefaultTCPMSS))
+	}
+	if p.burstTokens > 0 {
+		p.burstTokens--
+		p.idealNextPacketSendTime = time.Time{}
+		return
+	}
+	delay := transferTime(size, rate)
+	// If the packet was sent too late, don't try to make up for the lost time.
+	p.idealNextPacketSendTime = utils.MaxTime(p.idealNextPacketSendTime.Add(delay), sentTime.Add(delay))
+}
+
+// TimeUntilSend returns when the next packet may be sent.
+// The zero value is returned if the next packet can be sent immediately.
+func (p *pacer) TimeUntilSend(now time.Time) time.Time {
+	if p.idealNextPacketSendTime.After(now.Add(pacingGranularity)) {
+		return p.idealNextPacketSendTime
+	}
+	return time.Time{}
+}
+
+// transferTime is the time it takes to send size bytes at the pacing rate.
+func transferTime(size protocol.ByteCount, rate congestion.Bandwidth) time.Duration {
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(congestion.Bandwidth(size) * congestion.BytesPerSecond * congestion.Bandwidth(time.Second) / rate)
+}
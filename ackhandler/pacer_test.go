@@ -0,0 +1,87 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pacer", func() {
+	const packetSize protocol.ByteCount = 1000
+	const congestionWindow = 20 * packetSize
+	// sending one packet takes 4ms at this rate
+	const rate = 250 * congestion.BytesPerSecond * congestion.Bandwidth(packetSize)
+
+	var p *pacer
+
+	BeforeEach(func() {
+		p = newPacer()
+	})
+
+	// sendBurst sends the packets that the pacer allows to send without pacing, after leaving quiescence
+	sendBurst := func(now time.Time) protocol.ByteCount {
+		var bytesInFlight protocol.ByteCount
+		for i := 0; i < initialUnpacedBurst; i++ {
+			Expect(p.TimeUntilSend(now)).To(BeZero())
+			p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, rate)
+			bytesInFlight += packetSize
+		}
+		return bytesInFlight
+	}
+
+	It("allows sending a burst when leaving quiescence", func() {
+		now := time.Now()
+		sendBurst(now)
+	})
+
+	It("limits the burst to the congestion window", func() {
+		now := time.Now()
+		p.SentPacket(now, 0, packetSize, 2*packetSize, rate)
+		p.SentPacket(now, packetSize, packetSize, 2*packetSize, rate)
+		Expect(p.TimeUntilSend(now)).ToNot(BeZero())
+	})
+
+	It("paces packets after the burst", func() {
+		now := time.Now()
+		bytesInFlight := sendBurst(now)
+		p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, rate)
+		Expect(p.TimeUntilSend(now)).To(Equal(now.Add(4 * time.Millisecond)))
+		p.SentPacket(now.Add(4*time.Millisecond), bytesInFlight+packetSize, packetSize, congestionWindow, rate)
+		Expect(p.TimeUntilSend(now)).To(Equal(now.Add(8 * time.Millisecond)))
+	})
+
+	It("doesn't pace packets that are due within the granularity", func() {
+		now := time.Now()
+		bytesInFlight := sendBurst(now)
+		p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, rate)
+		Expect(p.TimeUntilSend(now.Add(3 * time.Millisecond))).To(BeZero())
+	})
+
+	It("doesn't make up for time lost", func() {
+		now := time.Now()
+		bytesInFlight := sendBurst(now)
+		p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, rate)
+		// the packet is sent one second too late
+		p.SentPacket(now.Add(time.Second), bytesInFlight+packetSize, packetSize, congestionWindow, rate)
+		Expect(p.TimeUntilSend(now.Add(time.Second))).To(Equal(now.Add(time.Second + 4*time.Millisecond)))
+	})
+
+	It("allows another burst when leaving quiescence again", func() {
+		now := time.Now()
+		bytesInFlight := sendBurst(now)
+		p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, rate)
+		Expect(p.TimeUntilSend(now)).ToNot(BeZero())
+		sendBurst(now.Add(time.Second))
+	})
+
+	It("doesn't pace if the pacing rate is unknown", func() {
+		now := time.Now()
+		bytesInFlight := sendBurst(now)
+		p.SentPacket(now, bytesInFlight, packetSize, congestionWindow, 0)
+		Expect(p.TimeUntilSend(now)).To(BeZero())
+	})
+})
@@ -19,6 +19,7 @@ type Packet struct {
 }
 
 // GetFramesForRetransmission gets all the frames for retransmission
+// DATAGRAM frames are unreliable, so they are never retransmitted
 func (p *Packet) GetFramesForRetransmission() []wire.Frame {
 	var fs []wire.Frame
 	for _, frame := range p.Frames {
@@ -27,6 +28,8 @@ func (p *Packet) GetFramesForRetransmission() []wire.Frame {
 			continue
 		case *wire.StopWaitingFrame:
 			continue
+		case *wire.DatagramFrame:
+			continue
 		}
 		fs = append(fs, frame)
 	}
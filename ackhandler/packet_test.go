@@ -47,5 +47,13 @@ var _ = Describe("Packet", func() {
 			Expect(fs).ToNot(ContainElement(ackFrame))
 		})
 
+		It("doesn't retransmit DATAGRAM frames", func() {
+			datagramFrame := &wire.DatagramFrame{Data: []byte("foobar")}
+			packet := &Packet{
+				Frames: []wire.Frame{datagramFrame, streamFrame},
+			}
+			Expect(packet.GetFramesForRetransmission()).To(Equal([]wire.Frame{streamFrame}))
+		})
+
 	})
 })
@@ -10,6 +10,7 @@ import (
 	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
 )
 
 const (
@@ -27,6 +28,11 @@ const (
 	minRTOTimeout = 200 * time.Millisecond
 	// maxRTOTimeout is the maximum RTO time
 	maxRTOTimeout = 60 * time.Second
+	// If the congestion controller doesn't provide a pacing rate, the pacing rate is the bandwidth estimate
+	// (congestion window / smoothed RTT) multiplied by this factor.
+	// Pacing slightly faster than the bandwidth estimate makes sure that the congestion window is used up,
+	// even if the timer fires late.
+	pacingGain = 1.25
 )
 
 var (
@@ -60,6 +66,10 @@ type sentPacketHandler struct {
 
 	congestion congestion.SendAlgorithm
 	rttStats   *congestion.RTTStats
+	pacer      *pacer
+
+	tracer tracing.SessionTracer
+	logger utils.Logger
 
 	handshakeComplete bool
 	// The number of times the handshake packets have been retransmitted without receiving an ack.
@@ -73,23 +83,26 @@ type sentPacketHandler struct {
 
 	// The alarm timeout
 	alarm time.Time
-}
 
-// NewSentPacketHandler creates a new sentPacketHandler
-func NewSentPacketHandler(rttStats *congestion.RTTStats) SentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		false, /* don't use reno since chromium doesn't (why?) */
-		protocol.InitialCongestionWindow,
-		protocol.DefaultMaxCongestionWindow,
-	)
+	// counters for the Statistics
+	packetsSent     uint64
+	bytesSent       protocol.ByteCount
+	packetsLost     uint64
+	numRTOs         uint64
+	retransmissions uint64
+}
 
+// NewSentPacketHandler creates a new sentPacketHandler, using the given congestion controller.
+// The tracer may be nil.
+func NewSentPacketHandler(rttStats *congestion.RTTStats, congestion congestion.SendAlgorithm, tracer tracing.SessionTracer, logger utils.Logger) SentPacketHandler {
 	return &sentPacketHandler{
 		packetHistory:      NewPacketList(),
 		stopWaitingManager: stopWaitingManager{},
 		rttStats:           rttStats,
 		congestion:         congestion,
+		pacer:              newPacer(),
+		tracer:             tracer,
+		logger:             logger,
 	}
 }
 
@@ -126,12 +139,15 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 	}
 
 	h.lastSentPacketNumber = packet.PacketNumber
+	h.packetsSent++
+	h.bytesSent += packet.Length
 	now := time.Now()
 
 	packet.Frames = stripNonRetransmittableFrames(packet.Frames)
 	isRetransmittable := len(packet.Frames) != 0
 
 	if isRetransmittable {
+		h.pacer.SentPacket(now, h.bytesInFlight, packet.Length, h.congestion.GetCongestionWindow(), h.pacingRate())
 		packet.SendTime = now
 		h.bytesInFlight += packet.Length
 		h.packetHistory.PushBack(*packet)
@@ -243,6 +259,9 @@ func (h *sentPacketHandler) maybeUpdateRTT(largestAcked protocol.PacketNumber, a
 		packet := el.Value
 		if packet.PacketNumber == largestAcked {
 			h.rttStats.UpdateRTT(rcvTime.Sub(packet.SendTime), ackDelay, time.Now())
+			if h.tracer != nil {
+				h.tracer.UpdatedRTT(h.rttStats)
+			}
 			return true
 		}
 		// Packets are sorted by number, so we can stop searching
@@ -298,8 +317,12 @@ func (h *sentPacketHandler) detectLostPackets() {
 
 	if len(lostPackets) > 0 {
 		for _, p := range lostPackets {
+			h.packetsLost++
 			h.queuePacketForRetransmission(p)
 			h.congestion.OnPacketLost(p.Value.PacketNumber, p.Value.Length, h.bytesInFlight)
+			if h.tracer != nil {
+				h.tracer.LostPacket(p.Value.PacketNumber, tracing.PacketLossTimeThreshold)
+			}
 		}
 	}
 }
@@ -316,6 +339,7 @@ func (h *sentPacketHandler) OnAlarm() {
 		// RTO
 		h.retransmitOldestTwoPackets()
 		h.rtoCount++
+		h.numRTOs++
 	}
 
 	h.updateLossDetectionAlarm()
@@ -325,6 +349,18 @@ func (h *sentPacketHandler) GetAlarmTimeout() time.Time {
 	return h.alarm
 }
 
+func (h *sentPacketHandler) GetStatistics() Statistics {
+	return Statistics{
+		PacketsSent:      h.packetsSent,
+		BytesSent:        h.bytesSent,
+		PacketsLost:      h.packetsLost,
+		RTOCount:         h.numRTOs,
+		Retransmissions:  h.retransmissions,
+		BytesInFlight:    h.bytesInFlight,
+		CongestionWindow: h.congestion.GetCongestionWindow(),
+	}
+}
+
 func (h *sentPacketHandler) onPacketAcked(packetElement *PacketElement) {
 	h.bytesInFlight -= packetElement.Value.Length
 	h.rtoCount = 0
@@ -354,10 +390,10 @@ func (h *sentPacketHandler) GetStopWaitingFrame(force bool) *wire.StopWaitingFra
 }
 
 func (h *sentPacketHandler) SendingAllowed() bool {
-	congestionLimited := h.bytesInFlight > h.congestion.GetCongestionWindow()
+	congestionLimited := h.congestion.TimeUntilSend(time.Now(), h.bytesInFlight) != 0
 	maxTrackedLimited := protocol.PacketNumber(len(h.retransmissionQueue)+h.packetHistory.Len()) >= protocol.MaxTrackedSentPackets
 	if congestionLimited {
-		utils.Debugf("Congestion limited: bytes in flight %d, window %d",
+		h.logger.Debugf("Congestion limited: bytes in flight %d, window %d",
 			h.bytesInFlight,
 			h.congestion.GetCongestionWindow())
 	}
@@ -368,6 +404,25 @@ func (h *sentPacketHandler) SendingAllowed() bool {
 	return !maxTrackedLimited && (!congestionLimited || haveRetransmissions)
 }
 
+func (h *sentPacketHandler) TimeUntilSend() time.Time {
+	return h.pacer.TimeUntilSend(time.Now())
+}
+
+// pacingRate is the rate at which packets are paced.
+// If the congestion controller doesn't provide a pacing rate, the congestion window is spread over the smoothed RTT.
+func (h *sentPacketHandler) pacingRate() congestion.Bandwidth {
+	if c, ok := h.congestion.(congestion.PacingSendAlgorithm); ok {
+		return c.PacingRate(h.bytesInFlight)
+	}
+	srtt := h.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// Without an RTT sample, the bandwidth is unknown, and we can't pace.
+		return 0
+	}
+	bandwidth := congestion.BandwidthFromDelta(h.congestion.GetCongestionWindow(), srtt)
+	return congestion.Bandwidth(pacingGain * float64(bandwidth))
+}
+
 func (h *sentPacketHandler) retransmitOldestTwoPackets() {
 	if p := h.packetHistory.Front(); p != nil {
 		h.queueRTO(p)
@@ -379,14 +434,18 @@ func (h *sentPacketHandler) retransmitOldestTwoPackets() {
 
 func (h *sentPacketHandler) queueRTO(el *PacketElement) {
 	packet := &el.Value
-	utils.Debugf(
+	h.logger.Debugf(
 		"\tQueueing packet 0x%x for retransmission (RTO), %d outstanding",
 		packet.PacketNumber,
 		h.packetHistory.Len(),
 	)
+	h.packetsLost++
 	h.queuePacketForRetransmission(el)
 	h.congestion.OnPacketLost(packet.PacketNumber, packet.Length, h.bytesInFlight)
 	h.congestion.OnRetransmissionTimeout(true)
+	if h.tracer != nil {
+		h.tracer.LostPacket(packet.PacketNumber, tracing.PacketLossRetransmissionTimeout)
+	}
 }
 
 func (h *sentPacketHandler) queueHandshakePacketsForRetransmission() {
@@ -397,6 +456,9 @@ func (h *sentPacketHandler) queueHandshakePacketsForRetransmission() {
 		}
 	}
 	for _, el := range handshakePackets {
+		if h.tracer != nil {
+			h.tracer.LostPacket(el.Value.PacketNumber, tracing.PacketLossHandshakeTimeout)
+		}
 		h.queuePacketForRetransmission(el)
 	}
 }
@@ -404,6 +466,7 @@ func (h *sentPacketHandler) queueHandshakePacketsForRetransmission() {
 func (h *sentPacketHandler) queuePacketForRetransmission(packetElement *PacketElement) {
 	packet := &packetElement.Value
 	h.bytesInFlight -= packet.Length
+	h.retransmissions++
 	h.retransmissionQueue = append(h.retransmissionQueue, packet)
 	h.packetHistory.Remove(packetElement)
 	h.stopWaitingManager.QueuedRetransmissionForPacketNumber(packet.PacketNumber)
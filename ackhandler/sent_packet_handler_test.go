@@ -3,9 +3,13 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/mocks"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
+	"github.com/seong889/quic-go/tracing"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -19,8 +23,20 @@ type mockCongestion struct {
 	packetsLost             [][]interface{}
 }
 
+type mockPacingCongestion struct {
+	mockCongestion
+	rate congestion.Bandwidth
+}
+
+func (m *mockPacingCongestion) PacingRate(protocol.ByteCount) congestion.Bandwidth {
+	return m.rate
+}
+
 func (m *mockCongestion) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
-	panic("not implemented")
+	if m.GetCongestionWindow() > bytesInFlight {
+		return 0
+	}
+	return utils.InfDuration
 }
 
 func (m *mockCongestion) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
@@ -87,7 +103,14 @@ var _ = Describe("SentPacketHandler", func() {
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(rttStats).(*sentPacketHandler)
+		cong := congestion.NewCubicSender(
+			congestion.DefaultClock{},
+			rttStats,
+			false,
+			protocol.InitialCongestionWindow,
+			protocol.DefaultMaxCongestionWindow,
+		)
+		handler = NewSentPacketHandler(rttStats, cong, nil, utils.DefaultLogger).(*sentPacketHandler)
 		handler.SetHandshakeComplete()
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
@@ -95,6 +118,12 @@ var _ = Describe("SentPacketHandler", func() {
 		}
 	})
 
+	It("uses the congestion controller", func() {
+		cong := &mockCongestion{}
+		handler = NewSentPacketHandler(&congestion.RTTStats{}, cong, nil, utils.DefaultLogger).(*sentPacketHandler)
+		Expect(handler.congestion).To(Equal(cong))
+	})
+
 	getPacketElement := func(p protocol.PacketNumber) *PacketElement {
 		for el := handler.packetHistory.Front(); el != nil; el = el.Next() {
 			if el.Value.PacketNumber == p {
@@ -668,6 +697,39 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(0)))
 	})
 
+	Context("statistics", func() {
+		It("counts sent packets", func() {
+			err := handler.SentPacket(&Packet{PacketNumber: 1, Frames: []wire.Frame{&streamFrame}, Length: 10})
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(nonRetransmittablePacket(2))
+			Expect(err).NotTo(HaveOccurred())
+			stats := handler.GetStatistics()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(2))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(11)))
+			Expect(stats.BytesInFlight).To(Equal(protocol.ByteCount(10)))
+			Expect(stats.CongestionWindow).To(Equal(handler.congestion.GetCongestionWindow()))
+		})
+
+		It("counts lost packets and RTOs", func() {
+			err := handler.SentPacket(retransmittablePacket(1))
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(retransmittablePacket(2))
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(retransmittablePacket(3))
+			Expect(err).NotTo(HaveOccurred())
+			handler.OnAlarm() // RTO, meaning 2 lost packets
+			stats := handler.GetStatistics()
+			Expect(stats.RTOCount).To(BeEquivalentTo(1))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(2))
+			Expect(stats.Retransmissions).To(BeEquivalentTo(2))
+			// the rtoCount is reset when a packet is acked, the statistics are not
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 3, LowestAcked: 3}, 1, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handler.rtoCount).To(BeZero())
+			Expect(handler.GetStatistics().RTOCount).To(BeEquivalentTo(1))
+		})
+	})
+
 	Context("congestion", func() {
 		var (
 			cong *mockCongestion
@@ -748,6 +810,51 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("pacing", func() {
+		BeforeEach(func() {
+			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
+		})
+
+		It("paces retransmittable packets", func() {
+			sendPacket := func(pn protocol.PacketNumber) {
+				p := retransmittablePacket(pn)
+				p.Length = protocol.DefaultTCPMSS
+				err := handler.SentPacket(p)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			for i := 1; i <= initialUnpacedBurst; i++ {
+				Expect(handler.TimeUntilSend()).To(BeZero())
+				sendPacket(protocol.PacketNumber(i))
+			}
+			Expect(handler.TimeUntilSend()).To(BeZero())
+			sendPacket(initialUnpacedBurst + 1)
+			Expect(handler.TimeUntilSend()).ToNot(BeZero())
+		})
+
+		It("doesn't pace ACK-only packets", func() {
+			for i := 1; i <= 2*initialUnpacedBurst; i++ {
+				err := handler.SentPacket(nonRetransmittablePacket(protocol.PacketNumber(i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(handler.TimeUntilSend()).To(BeZero())
+		})
+
+		It("spreads the congestion window over the smoothed RTT", func() {
+			cwnd := handler.congestion.GetCongestionWindow()
+			Expect(handler.pacingRate()).To(Equal(congestion.Bandwidth(pacingGain * float64(congestion.BandwidthFromDelta(cwnd, time.Second)))))
+		})
+
+		It("doesn't pace without an RTT estimate", func() {
+			handler.rttStats = &congestion.RTTStats{}
+			Expect(handler.pacingRate()).To(BeZero())
+		})
+
+		It("uses the pacing rate of the congestion controller, if provided", func() {
+			handler.congestion = &mockPacingCongestion{rate: 1337}
+			Expect(handler.pacingRate()).To(Equal(congestion.Bandwidth(1337)))
+		})
+	})
+
 	Context("calculating RTO", func() {
 		It("uses default RTO", func() {
 			Expect(handler.computeRTOTimeout()).To(Equal(defaultRTOTimeout))
@@ -886,4 +993,64 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.rtoCount).To(BeEquivalentTo(1))
 		})
 	})
+
+	Context("tracing", func() {
+		var (
+			mockCtrl *gomock.Controller
+			tracer   *mocks.MockSessionTracer
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			tracer = mocks.NewMockSessionTracer(mockCtrl)
+			handler.tracer = tracer
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("traces RTT updates", func() {
+			err := handler.SentPacket(retransmittablePacket(1))
+			Expect(err).NotTo(HaveOccurred())
+			tracer.EXPECT().UpdatedRTT(handler.rttStats)
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("traces packets detected as lost", func() {
+			err := handler.SentPacket(retransmittablePacket(1))
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(retransmittablePacket(2))
+			Expect(err).NotTo(HaveOccurred())
+			tracer.EXPECT().UpdatedRTT(gomock.Any())
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now().Add(time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			handler.packetHistory.Front().Value.SendTime = time.Now().Add(-2 * time.Hour)
+			tracer.EXPECT().LostPacket(protocol.PacketNumber(1), tracing.PacketLossTimeThreshold)
+			handler.OnAlarm()
+		})
+
+		It("traces packets retransmitted because of an RTO", func() {
+			err := handler.SentPacket(retransmittablePacket(1))
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(retransmittablePacket(2))
+			Expect(err).NotTo(HaveOccurred())
+			gomock.InOrder(
+				tracer.EXPECT().LostPacket(protocol.PacketNumber(1), tracing.PacketLossRetransmissionTimeout),
+				tracer.EXPECT().LostPacket(protocol.PacketNumber(2), tracing.PacketLossRetransmissionTimeout),
+			)
+			handler.OnAlarm()
+		})
+
+		It("traces handshake packets retransmitted because of the handshake timeout", func() {
+			handler.handshakeComplete = false
+			err := handler.SentPacket(handshakePacket(1))
+			Expect(err).NotTo(HaveOccurred())
+			err = handler.SentPacket(retransmittablePacket(2))
+			Expect(err).NotTo(HaveOccurred())
+			tracer.EXPECT().LostPacket(protocol.PacketNumber(1), tracing.PacketLossHandshakeTimeout)
+			handler.OnAlarm()
+		})
+	})
 })
@@ -0,0 +1,107 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// The admissionController decides if the server accepts new connections.
+// It limits the number of concurrent handshakes, the number of sessions,
+// and the rate of new sessions per source IP address.
+type admissionController struct {
+	mutex sync.Mutex
+
+	maxHandshakes           int
+	maxSessions             int
+	maxNewSessionsPerSource int
+	policy                  func(net.Addr) AdmissionDecision
+
+	numHandshakes int
+	numSessions   int
+
+	// the number of new sessions per source, counted in intervals of one second
+	intervalStart     time.Time
+	sessionsPerSource map[string]int
+}
+
+func newAdmissionController(config *Config) *admissionController {
+	return &admissionController{
+		maxHandshakes:           config.MaxIncomingHandshakes,
+		maxSessions:             config.MaxIncomingSessions,
+		maxNewSessionsPerSource: config.MaxNewSessionsPerSourcePerSecond,
+		policy:                  config.AdmitSession,
+		sessionsPerSource:       make(map[string]int),
+	}
+}
+
+// Admit decides how a new connection from remoteAddr is handled.
+// If the connection is accepted (possibly depending on the cookie), SessionStarted must be called when the session is created.
+func (c *admissionController) Admit(remoteAddr net.Addr, now time.Time) AdmissionDecision {
+	decision := c.checkLimits(remoteAddr, now)
+	if c.policy != nil {
+		// use the stricter of the two decisions
+		if d := c.policy(remoteAddr); d > decision {
+			decision = d
+		}
+	}
+	return decision
+}
+
+func (c *admissionController) checkLimits(remoteAddr net.Addr, now time.Time) AdmissionDecision {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.maxHandshakes > 0 && c.numHandshakes >= c.maxHandshakes {
+		return AdmissionReject
+	}
+	if c.maxSessions > 0 && c.numSessions >= c.maxSessions {
+		return AdmissionReject
+	}
+	if c.maxNewSessionsPerSource > 0 {
+		if now.Sub(c.intervalStart) >= time.Second {
+			c.intervalStart = now
+			c.sessionsPerSource = make(map[string]int)
+		}
+		if c.sessionsPerSource[sourceOf(remoteAddr)] >= c.maxNewSessionsPerSource {
+			return AdmissionRequireCookie
+		}
+	}
+	return AdmissionAccept
+}
+
+// SessionStarted is called when a new session is created
+func (c *admissionController) SessionStarted(remoteAddr net.Addr) {
+	c.mutex.Lock()
+	c.numHandshakes++
+	c.numSessions++
+	if c.maxNewSessionsPerSource > 0 {
+		c.sessionsPerSource[sourceOf(remoteAddr)]++
+	}
+	c.mutex.Unlock()
+}
+
+// HandshakeFinished is called when the handshake of a session completes or fails
+func (c *admissionController) HandshakeFinished() {
+	c.mutex.Lock()
+	c.numHandshakes--
+	c.mutex.Unlock()
+}
+
+// SessionClosed is called when a session is closed
+func (c *admissionController) SessionClosed() {
+	c.mutex.Lock()
+	c.numSessions--
+	c.mutex.Unlock()
+}
+
+// sourceOf returns the IP address of UDP addresses, and the string representation of all other addresses
+func sourceOf(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
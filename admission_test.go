@@ -0,0 +1,107 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admission Controller", func() {
+	var (
+		addr1 *net.UDPAddr
+		addr2 *net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		addr1 = &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1234}
+		addr2 = &net.UDPAddr{IP: net.IPv4(192, 168, 13, 38), Port: 1234}
+	})
+
+	It("accepts all connections by default", func() {
+		c := newAdmissionController(&Config{})
+		for i := 0; i < 100; i++ {
+			Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionAccept))
+			c.SessionStarted(addr1)
+		}
+	})
+
+	It("limits the number of handshakes", func() {
+		c := newAdmissionController(&Config{MaxIncomingHandshakes: 2})
+		c.SessionStarted(addr1)
+		c.SessionStarted(addr2)
+		Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionReject))
+		c.HandshakeFinished()
+		Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionAccept))
+	})
+
+	It("limits the number of sessions", func() {
+		c := newAdmissionController(&Config{MaxIncomingSessions: 2})
+		c.SessionStarted(addr1)
+		c.SessionStarted(addr2)
+		c.HandshakeFinished()
+		c.HandshakeFinished()
+		Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionReject))
+		c.SessionClosed()
+		Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionAccept))
+	})
+
+	Context("limiting the rate of new sessions per source", func() {
+		var c *admissionController
+
+		BeforeEach(func() {
+			c = newAdmissionController(&Config{MaxNewSessionsPerSourcePerSecond: 2})
+		})
+
+		It("requires a cookie when the limit is reached", func() {
+			now := time.Now()
+			Expect(c.Admit(addr1, now)).To(Equal(AdmissionAccept))
+			c.SessionStarted(addr1)
+			c.SessionStarted(addr1)
+			Expect(c.Admit(addr1, now)).To(Equal(AdmissionRequireCookie))
+			Expect(c.Admit(addr2, now)).To(Equal(AdmissionAccept))
+		})
+
+		It("counts sessions per IP address", func() {
+			now := time.Now()
+			Expect(c.Admit(addr1, now)).To(Equal(AdmissionAccept))
+			c.SessionStarted(addr1)
+			c.SessionStarted(&net.UDPAddr{IP: addr1.IP, Port: 4321})
+			Expect(c.Admit(&net.UDPAddr{IP: addr1.IP, Port: 5678}, now)).To(Equal(AdmissionRequireCookie))
+		})
+
+		It("resets the counters after one second", func() {
+			now := time.Now()
+			Expect(c.Admit(addr1, now)).To(Equal(AdmissionAccept))
+			c.SessionStarted(addr1)
+			c.SessionStarted(addr1)
+			Expect(c.Admit(addr1, now.Add(999*time.Millisecond))).To(Equal(AdmissionRequireCookie))
+			Expect(c.Admit(addr1, now.Add(time.Second))).To(Equal(AdmissionAccept))
+		})
+	})
+
+	Context("using a policy", func() {
+		It("calls the policy", func() {
+			var policyAddr net.Addr
+			c := newAdmissionController(&Config{
+				AdmitSession: func(addr net.Addr) AdmissionDecision {
+					policyAddr = addr
+					return AdmissionRequireCookie
+				},
+			})
+			Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionRequireCookie))
+			Expect(policyAddr).To(Equal(addr1))
+		})
+
+		It("uses the stricter decision", func() {
+			c := newAdmissionController(&Config{
+				MaxIncomingSessions: 1,
+				AdmitSession:        func(net.Addr) AdmissionDecision { return AdmissionRequireCookie },
+			})
+			Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionRequireCookie))
+			c.SessionStarted(addr1)
+			Expect(c.Admit(addr1, time.Now())).To(Equal(AdmissionReject))
+		})
+	})
+})
@@ -6,30 +6,46 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
 	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
 )
 
 type client struct {
 	mutex sync.Mutex
 
-	conn     connection
-	hostname string
+	conn connection
+	// If the client created the packet conn, it has to close it after the session is closed.
+	createdPacketConn bool
+	packetHandlers    packetHandlerManager
+	hostname          string
 
 	handshakeChan <-chan handshakeEvent
 
 	versionNegotiationChan           chan struct{} // the versionNegotiationChan is closed as soon as the server accepted the suggested version
 	versionNegotiated                bool          // has version negotiation completed yet
 	receivedVersionNegotiationPacket bool
+	// if set, establishSecureConnection returns right away, and the session is closed if the server doesn't support its version
+	early bool
+
+	// needed for validation of the gQUIC version negotiation
+	initialVersion     protocol.VersionNumber
+	negotiatedVersions []protocol.VersionNumber
+	// the STK received in a stateless reject, presented in the CHLO of the new connection
+	stk []byte
+	// sessionRestarted receives a value when a new session was created after a stateless reject
+	sessionRestarted chan struct{}
 
 	tlsConf *tls.Config
 	config  *Config
+	logger  utils.Logger
 
 	connectionID protocol.ConnectionID
 	version      protocol.VersionNumber
@@ -37,6 +53,8 @@ type client struct {
 	session packetHandler
 }
 
+var _ rawPacketHandler = &client{}
+
 var (
 	// make it possible to mock connection ID generation in the tests
 	generateConnectionID         = utils.GenerateConnectionID
@@ -54,7 +72,11 @@ func DialAddr(addr string, tlsConf *tls.Config, config *Config) (Session, error)
 	if err != nil {
 		return nil, err
 	}
-	return Dial(udpConn, udpAddr, addr, tlsConf, config)
+	sess, err := dial(udpConn, udpAddr, addr, tlsConf, config, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return waitForHandshake(sess)
 }
 
 // DialAddrNonFWSecure establishes a new QUIC connection to a server.
@@ -72,10 +94,12 @@ func DialAddrNonFWSecure(
 	if err != nil {
 		return nil, err
 	}
-	return DialNonFWSecure(udpConn, udpAddr, addr, tlsConf, config)
+	return dial(udpConn, udpAddr, addr, tlsConf, config, true, false)
 }
 
 // DialNonFWSecure establishes a new non-forward-secure QUIC connection to a server using a net.PacketConn.
+// The net.PacketConn can be shared with other client sessions and with a Listener.
+// It is not closed when the session is closed.
 // The host parameter is used for SNI.
 func DialNonFWSecure(
 	pconn net.PacketConn,
@@ -83,6 +107,76 @@ func DialNonFWSecure(
 	host string,
 	tlsConf *tls.Config,
 	config *Config,
+) (NonFWSession, error) {
+	return dial(pconn, remoteAddr, host, tlsConf, config, false, false)
+}
+
+// DialAddrEarly establishes a new QUIC connection to a server, without waiting for the handshake to complete.
+// The hostname for SNI is taken from the given address.
+func DialAddrEarly(
+	addr string,
+	tlsConf *tls.Config,
+	config *Config,
+) (Session, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, err
+	}
+	return dial(udpConn, udpAddr, addr, tlsConf, config, true, true)
+}
+
+// DialEarly establishes a new QUIC connection to a server using a net.PacketConn, without waiting for the handshake to complete.
+// It returns right away, without waiting for a response from the server.
+// Streams can be opened right away, the data is sent as soon as the connection is secure.
+// Since the session might already be in use, it is not recreated with a different QUIC version.
+// If the server doesn't support the first version of Config.Versions, the session is closed with an InvalidVersion error.
+// Likewise, if the server sends a stateless reject, the session is closed instead of starting a new connection.
+// If the ClientSessionCache contains the state of a previous connection to the server, the handshake is performed in 0-RTT.
+// Session.HandshakeComplete can be used to wait for the handshake to complete.
+// The net.PacketConn can be shared with other client sessions and with a Listener.
+// It is not closed when the session is closed.
+// The host parameter is used for SNI.
+func DialEarly(
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+) (Session, error) {
+	return dial(pconn, remoteAddr, host, tlsConf, config, false, true)
+}
+
+func dial(
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+	createdPacketConn bool,
+	early bool,
+) (NonFWSession, error) {
+	sess, err := dialImpl(pconn, remoteAddr, host, tlsConf, config, createdPacketConn, early)
+	if err != nil && createdPacketConn {
+		// The session might not have been started, so nothing else closes the net.PacketConn.
+		// A net.PacketConn passed to Dial is not removed from the multiplexer, since it might be used by another Dial or a Listener.
+		getMultiplexer().RemoveConn(pconn)
+		pconn.Close()
+	}
+	return sess, err
+}
+
+func dialImpl(
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+	createdPacketConn bool,
+	early bool,
 ) (NonFWSession, error) {
 	connID, err := generateConnectionID()
 	if err != nil {
@@ -104,15 +198,20 @@ func DialNonFWSecure(
 	clientConfig := populateClientConfig(config)
 	c := &client{
 		conn:                   &conn{pconn: pconn, currentAddr: remoteAddr},
+		createdPacketConn:      createdPacketConn,
+		packetHandlers:         getMultiplexer().AddConn(pconn, clientConfig.Logger),
 		connectionID:           connID,
 		hostname:               hostname,
 		tlsConf:                tlsConf,
 		config:                 clientConfig,
+		logger:                 clientConfig.Logger,
 		version:                clientConfig.Versions[0],
 		versionNegotiationChan: make(chan struct{}),
+		early:                  early,
+		sessionRestarted:       make(chan struct{}, 1),
 	}
 
-	utils.Infof("Starting new connection to %s (%s -> %s), connectionID %x, version %s", hostname, c.conn.LocalAddr().String(), c.conn.RemoteAddr().String(), c.connectionID, c.version)
+	c.logger.Infof("Starting new connection to %s (%s -> %s), connectionID %x, version %s", hostname, c.conn.LocalAddr().String(), c.conn.RemoteAddr().String(), c.connectionID, c.version)
 
 	if err := c.establishSecureConnection(); err != nil {
 		return nil, err
@@ -121,6 +220,8 @@ func DialNonFWSecure(
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
+// The net.PacketConn can be shared with other client sessions and with a Listener.
+// It is not closed when the session is closed.
 // The host parameter is used for SNI.
 func Dial(
 	pconn net.PacketConn,
@@ -133,6 +234,10 @@ func Dial(
 	if err != nil {
 		return nil, err
 	}
+	return waitForHandshake(sess)
+}
+
+func waitForHandshake(sess NonFWSession) (Session, error) {
 	if err := sess.WaitUntilHandshakeComplete(); err != nil {
 		return nil, err
 	}
@@ -150,6 +255,11 @@ func populateClientConfig(config *Config) *Config {
 		versions = protocol.SupportedVersions
 	}
 
+	logger := config.Logger
+	if logger == nil {
+		logger = utils.DefaultLogger
+	}
+
 	handshakeTimeout := protocol.DefaultHandshakeTimeout
 	if config.HandshakeTimeout != 0 {
 		handshakeTimeout = config.HandshakeTimeout
@@ -168,38 +278,93 @@ func populateClientConfig(config *Config) *Config {
 		maxReceiveConnectionFlowControlWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindowClient
 	}
 
+	congestionControl := config.CongestionControl
+	if congestionControl == nil {
+		congestionControl = congestion.CubicSenderFactory
+	}
+	maxCongestionWindow := config.MaxCongestionWindow
+	if maxCongestionWindow == 0 {
+		maxCongestionWindow = protocol.DefaultMaxCongestionWindow
+	}
+	initialCongestionWindow := config.InitialCongestionWindow
+	if initialCongestionWindow == 0 {
+		initialCongestionWindow = protocol.InitialCongestionWindow
+	}
+	initialCongestionWindow = utils.MinUint64(initialCongestionWindow, maxCongestionWindow)
+
 	return &Config{
 		Versions:                              versions,
 		HandshakeTimeout:                      handshakeTimeout,
 		IdleTimeout:                           idleTimeout,
 		RequestConnectionIDOmission:           config.RequestConnectionIDOmission,
+		ClientSessionCache:                    config.ClientSessionCache,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
-		KeepAlive: config.KeepAlive,
+		CongestionControl:                     congestionControl,
+		InitialCongestionWindow:               initialCongestionWindow,
+		MaxCongestionWindow:                   maxCongestionWindow,
+		EnableDatagrams:                       config.EnableDatagrams,
+		KeepAlive:                             config.KeepAlive,
+		Logger:                                logger,
+		Tracer:                                config.Tracer,
+		KeyLogWriter:                          config.KeyLogWriter,
+		PacketCapture:                         config.PacketCapture,
 	}
 }
 
-// establishSecureConnection returns as soon as the connection is secure (as opposed to forward-secure)
+// NewLRUClientSessionCache returns a ClientSessionCache that holds the state of up to capacity servers, evicting the least recently used entries.
+func NewLRUClientSessionCache(capacity int) ClientSessionCache {
+	return handshake.NewLRUClientSessionCache(capacity)
+}
+
+// establishSecureConnection returns as soon as the connection is secure (as opposed to forward-secure).
+// For early connections, it returns as soon as the session is started.
 func (c *client) establishSecureConnection() error {
-	if err := c.createNewSession(c.version, nil); err != nil {
+	c.initialVersion = c.version
+	if err := c.createNewSession(); err != nil {
+		return err
+	}
+	if err := c.packetHandlers.Add(c.connectionID, c); err != nil {
 		return err
 	}
-	go c.listen()
 
 	var runErr error
 	errorChan := make(chan struct{})
 	go func() {
-		// session.run() returns as soon as the session is closed
-		runErr = c.session.run()
-		if runErr == errCloseSessionForNewVersion {
-			// run the new session
-			runErr = c.session.run()
+		for {
+			c.mutex.Lock()
+			sess := c.session
+			c.mutex.Unlock()
+			// session.run() returns as soon as the session is closed
+			runErr = sess.run()
+			if runErr == errCloseSessionForNewVersion {
+				// run the new session
+				continue
+			}
+			// the application might already be using an early session, so it can't be replaced
+			if e, ok := runErr.(*handshake.StatelessRejectError); ok && !c.early {
+				if runErr = c.handleStatelessReject(e); runErr == nil {
+					c.sessionRestarted <- struct{}{}
+					continue
+				}
+			}
+			break
 		}
 		close(errorChan)
-		utils.Infof("Connection %x closed.", c.connectionID)
-		c.conn.Close()
+		c.mutex.Lock()
+		connID := c.connectionID
+		c.mutex.Unlock()
+		c.logger.Infof("Connection %x closed.", connID)
+		c.packetHandlers.Remove(connID)
+		if c.createdPacketConn {
+			c.conn.Close()
+		}
 	}()
 
+	if c.early {
+		return nil
+	}
+
 	// wait until the server accepts the QUIC version (or an error occurs)
 	select {
 	case <-errorChan:
@@ -207,42 +372,45 @@ func (c *client) establishSecureConnection() error {
 	case <-c.versionNegotiationChan:
 	}
 
-	select {
-	case <-errorChan:
-		return runErr
-	case ev := <-c.handshakeChan:
-		if ev.err != nil {
-			return ev.err
-		}
-		if !c.version.UsesTLS() && ev.encLevel != protocol.EncryptionSecure {
-			return fmt.Errorf("Client BUG: Expected encryption level to be secure, was %s", ev.encLevel)
+	for {
+		c.mutex.Lock()
+		handshakeChan := c.handshakeChan
+		c.mutex.Unlock()
+
+		select {
+		case <-errorChan:
+			return runErr
+		case ev := <-handshakeChan:
+			if _, ok := ev.err.(*handshake.StatelessRejectError); ok {
+				// wait until the new session was created
+				select {
+				case <-errorChan:
+					return runErr
+				case <-c.sessionRestarted:
+				}
+				continue
+			}
+			if ev.err != nil {
+				return ev.err
+			}
+			if !c.version.UsesTLS() && ev.encLevel != protocol.EncryptionSecure {
+				return fmt.Errorf("Client BUG: Expected encryption level to be secure, was %s", ev.encLevel)
+			}
+			return nil
 		}
-		return nil
 	}
 }
 
-// Listen listens
-func (c *client) listen() {
-	var err error
-
-	for {
-		var n int
-		var addr net.Addr
-		data := getPacketBuffer()
-		data = data[:protocol.MaxReceivePacketSize]
-		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
-		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err = c.conn.Read(data)
-		if err != nil {
-			if !strings.HasSuffix(err.Error(), "use of closed network connection") {
-				c.session.Close(err)
-			}
-			break
-		}
-		data = data[:n]
+func (c *client) handleRawPacket(remoteAddr net.Addr, data []byte) {
+	c.handlePacket(remoteAddr, data)
+}
 
-		c.handlePacket(addr, data)
-	}
+// closeWithError is called when reading from the connection fails
+func (c *client) closeWithError(e error) {
+	c.mutex.Lock()
+	sess := c.session
+	c.mutex.Unlock()
+	sess.Close(e)
 }
 
 func (c *client) handlePacket(remoteAddr net.Addr, packet []byte) {
@@ -251,7 +419,7 @@ func (c *client) handlePacket(remoteAddr net.Addr, packet []byte) {
 	r := bytes.NewReader(packet)
 	hdr, err := wire.ParseHeader(r, protocol.PerspectiveServer, c.version)
 	if err != nil {
-		utils.Errorf("error parsing packet from %s: %s", remoteAddr.String(), err.Error())
+		c.logger.Errorf("error parsing packet from %s: %s", remoteAddr.String(), err.Error())
 		// drop this packet if we can't parse the header
 		return
 	}
@@ -261,6 +429,9 @@ func (c *client) handlePacket(remoteAddr net.Addr, packet []byte) {
 	}
 	// reject packets with the wrong connection ID
 	if !hdr.OmitConnectionID && hdr.ConnectionID != c.connectionID {
+		if c.config.Tracer != nil {
+			c.config.Tracer.DroppedPacket(remoteAddr, protocol.ByteCount(len(packet)), tracing.PacketDropUnknownConnectionID)
+		}
 		return
 	}
 	hdr.Raw = packet[:len(packet)-r.Len()]
@@ -273,15 +444,15 @@ func (c *client) handlePacket(remoteAddr net.Addr, packet []byte) {
 		// check if the remote address and the connection ID match
 		// otherwise this might be an attacker trying to inject a PUBLIC_RESET to kill the connection
 		if cr.Network() != remoteAddr.Network() || cr.String() != remoteAddr.String() || hdr.ConnectionID != c.connectionID {
-			utils.Infof("Received a spoofed Public Reset. Ignoring.")
+			c.logger.Infof("Received a spoofed Public Reset. Ignoring.")
 			return
 		}
 		pr, err := wire.ParsePublicReset(r)
 		if err != nil {
-			utils.Infof("Received a Public Reset. An error occurred parsing the packet: %s", err)
+			c.logger.Infof("Received a Public Reset. An error occurred parsing the packet: %s", err)
 			return
 		}
-		utils.Infof("Received Public Reset, rejected packet number: %#x.", pr.RejectedPacketNumber)
+		c.logger.Infof("Received Public Reset, rejected packet number: %#x.", pr.RejectedPacketNumber)
 		c.session.closeRemote(qerr.Error(qerr.PublicReset, fmt.Sprintf("Received a Public Reset for packet number %#x", pr.RejectedPacketNumber)))
 		return
 	}
@@ -329,31 +500,64 @@ func (c *client) handleVersionNegotiationPacket(hdr *wire.Header) error {
 
 	c.receivedVersionNegotiationPacket = true
 
+	// the application might already be using an early session, so it can't be replaced by a session using a different version
+	if c.early {
+		return qerr.Error(qerr.InvalidVersion, fmt.Sprintf("server doesn't support version %s of the early session", c.version))
+	}
+
 	newVersion, ok := protocol.ChooseSupportedVersion(c.config.Versions, hdr.SupportedVersions)
 	if !ok {
 		return qerr.InvalidVersion
 	}
 
 	// switch to negotiated version
-	initialVersion := c.version
+	oldConnID := c.connectionID
 	c.version = newVersion
+	c.negotiatedVersions = hdr.SupportedVersions
 	var err error
 	c.connectionID, err = utils.GenerateConnectionID()
 	if err != nil {
 		return err
 	}
-	utils.Infof("Switching to QUIC version %s. New connection ID: %x", newVersion, c.connectionID)
+	c.logger.Infof("Switching to QUIC version %s. New connection ID: %x", newVersion, c.connectionID)
+	// add the new connection ID first, so that the connection isn't closed in the meantime
+	if err := c.packetHandlers.Add(c.connectionID, c); err != nil {
+		return err
+	}
+	c.packetHandlers.Remove(oldConnID)
 
 	// create a new session and close the old one
 	// the new session must be created first to update client member variables
 	oldSession := c.session
 	defer oldSession.Close(errCloseSessionForNewVersion)
-	return c.createNewSession(initialVersion, hdr.SupportedVersions)
+	return c.createNewSession()
+}
+
+// handleStatelessReject starts a new connection using the connection ID designated by the server.
+// The CHLO of the new connection contains the STK received in the SREJ.
+func (c *client) handleStatelessReject(e *handshake.StatelessRejectError) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// the STK is valid, so the server must accept the new connection
+	if c.stk != nil {
+		return qerr.Error(qerr.CryptoTooManyRejects, "received a second stateless reject")
+	}
+	oldConnID := c.connectionID
+	c.connectionID = e.ConnectionID
+	c.stk = e.STK
+	c.logger.Infof("Received a stateless reject. New connection ID: %x", c.connectionID)
+	// add the new connection ID first, so that the connection isn't closed in the meantime
+	if err := c.packetHandlers.Add(c.connectionID, c); err != nil {
+		return err
+	}
+	c.packetHandlers.Remove(oldConnID)
+	return c.createNewSession()
 }
 
-func (c *client) createNewSession(initialVersion protocol.VersionNumber, negotiatedVersions []protocol.VersionNumber) error {
+func (c *client) createNewSession() error {
 	var err error
-	utils.Debugf("createNewSession with initial version %s", initialVersion)
+	c.logger.Debugf("createNewSession with initial version %s", c.initialVersion)
 	c.session, c.handshakeChan, err = newClientSession(
 		c.conn,
 		c.hostname,
@@ -361,8 +565,9 @@ func (c *client) createNewSession(initialVersion protocol.VersionNumber, negotia
 		c.connectionID,
 		c.tlsConf,
 		c.config,
-		initialVersion,
-		negotiatedVersions,
+		c.initialVersion,
+		c.negotiatedVersions,
+		c.stk,
 	)
 	return err
 }
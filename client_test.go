@@ -5,10 +5,14 @@ import (
 	"crypto/tls"
 	"errors"
 	"net"
+	"reflect"
 	"sync/atomic"
 	"time"
 
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
 
@@ -24,7 +28,7 @@ var _ = Describe("Client", func() {
 		packetConn *mockPacketConn
 		addr       net.Addr
 
-		originalClientSessConstructor func(conn connection, hostname string, v protocol.VersionNumber, connectionID protocol.ConnectionID, tlsConf *tls.Config, config *Config, initialVersion protocol.VersionNumber, negotiatedVersions []protocol.VersionNumber) (packetHandler, <-chan handshakeEvent, error)
+		originalClientSessConstructor func(conn connection, hostname string, v protocol.VersionNumber, connectionID protocol.ConnectionID, tlsConf *tls.Config, config *Config, initialVersion protocol.VersionNumber, negotiatedVersions []protocol.VersionNumber, stk []byte) (packetHandler, <-chan handshakeEvent, error)
 	)
 
 	// generate a packet sent by the server that accepts the QUIC version suggested by the client
@@ -51,14 +55,18 @@ var _ = Describe("Client", func() {
 		}
 		config = &Config{
 			Versions: []protocol.VersionNumber{protocol.SupportedVersions[0], 77, 78},
+			Logger:   utils.DefaultLogger,
 		}
 		cl = &client{
-			config:       config,
-			connectionID: 0x1337,
-			session:      sess,
-			version:      protocol.SupportedVersions[0],
-			conn:         &conn{pconn: packetConn, currentAddr: addr},
+			config:                 config,
+			logger:                 utils.DefaultLogger,
+			connectionID:           0x1337,
+			session:                sess,
+			version:                protocol.SupportedVersions[0],
+			conn:                   &conn{pconn: packetConn, currentAddr: addr},
+			packetHandlers:         newPacketHandlerMap(packetConn, utils.DefaultLogger),
 			versionNegotiationChan: make(chan struct{}),
+			sessionRestarted:       make(chan struct{}, 1),
 		}
 	})
 
@@ -86,6 +94,7 @@ var _ = Describe("Client", func() {
 				_ *Config,
 				_ protocol.VersionNumber,
 				_ []protocol.VersionNumber,
+				_ []byte,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				Expect(conn.Write([]byte("fake CHLO"))).To(Succeed())
 				return sess, sess.handshakeChan, nil
@@ -116,6 +125,37 @@ var _ = Describe("Client", func() {
 			close(done)
 		})
 
+		It("dials early", func(done Done) {
+			packetConn.dataToRead = acceptClientVersionPacket(cl.connectionID)
+			dialed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				s, err := DialEarly(packetConn, addr, "quic.clemente.io:1337", nil, config)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).ToNot(BeNil())
+				close(dialed)
+			}()
+			// the session is returned before the connection is secure
+			Eventually(dialed).Should(BeClosed())
+			close(done)
+		})
+
+		It("dials early, if the server doesn't respond", func(done Done) {
+			dialed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				s, err := DialEarly(packetConn, addr, "quic.clemente.io:1337", nil, config)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).To(Equal(sess))
+				close(dialed)
+			}()
+			Eventually(dialed).Should(BeClosed())
+			Expect(packetConn.dataWritten.Bytes()).To(Equal([]byte("fake CHLO")))
+			Expect(sess.closed).To(BeFalse())
+			sess.Close(nil)
+			close(done)
+		})
+
 		It("dials a non-forward-secure address", func(done Done) {
 			serverAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
 			Expect(err).ToNot(HaveOccurred())
@@ -176,6 +216,7 @@ var _ = Describe("Client", func() {
 				_ *Config,
 				_ protocol.VersionNumber,
 				_ []protocol.VersionNumber,
+				_ []byte,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				remoteAddrChan <- conn.RemoteAddr().String()
 				return sess, nil, nil
@@ -203,6 +244,7 @@ var _ = Describe("Client", func() {
 				_ *Config,
 				_ protocol.VersionNumber,
 				_ []protocol.VersionNumber,
+				_ []byte,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				hostnameChan <- h
 				return sess, nil, nil
@@ -230,6 +272,52 @@ var _ = Describe("Client", func() {
 			sess.Close(testErr)
 		})
 
+		It("doesn't close the net.PacketConn passed to Dial when the session is closed", func(done Done) {
+			testErr := errors.New("early handshake error")
+			dialed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := Dial(packetConn, addr, "quic.clemente.io:1337", nil, config)
+				Expect(err).To(MatchError(testErr))
+				close(dialed)
+			}()
+			sess.Close(testErr)
+			Eventually(dialed).Should(BeClosed())
+			Consistently(func() bool { return packetConn.closed }).Should(BeFalse())
+			close(done)
+		})
+
+		It("closes the net.PacketConn created by DialAddr when the session is closed", func(done Done) {
+			connChan := make(chan connection, 1)
+			newClientSession = func(
+				conn connection,
+				_ string,
+				_ protocol.VersionNumber,
+				_ protocol.ConnectionID,
+				_ *tls.Config,
+				_ *Config,
+				_ protocol.VersionNumber,
+				_ []protocol.VersionNumber,
+				_ []byte,
+			) (packetHandler, <-chan handshakeEvent, error) {
+				connChan <- conn
+				return sess, nil, nil
+			}
+			dialed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				DialAddr("localhost:17890", nil, nil)
+				close(dialed)
+			}()
+			var cconn connection
+			Eventually(connChan).Should(Receive(&cconn))
+			Expect(cconn.Write([]byte("foobar"))).To(Succeed())
+			sess.Close(errors.New("peer doesn't reply"))
+			Eventually(dialed).Should(BeClosed())
+			Eventually(func() error { return cconn.Write([]byte("foobar")) }).Should(HaveOccurred())
+			close(done)
+		})
+
 		It("returns an error that occurs while waiting for the connection to become secure", func(done Done) {
 			testErr := errors.New("early handshake error")
 			packetConn.dataToRead = acceptClientVersionPacket(cl.connectionID)
@@ -256,15 +344,29 @@ var _ = Describe("Client", func() {
 		})
 
 		It("setups with the right values", func() {
+			logger := utils.DefaultLogger.WithField("foo", "bar")
+			sessionCache := NewLRUClientSessionCache(10)
 			config := &Config{
 				HandshakeTimeout:            1337 * time.Minute,
 				IdleTimeout:                 42 * time.Hour,
 				RequestConnectionIDOmission: true,
+				ClientSessionCache:          sessionCache,
+				CongestionControl:           congestion.RenoSenderFactory,
+				InitialCongestionWindow:     10,
+				MaxCongestionWindow:         100,
+				EnableDatagrams:             true,
+				Logger:                      logger,
 			}
 			c := populateClientConfig(config)
 			Expect(c.HandshakeTimeout).To(Equal(1337 * time.Minute))
 			Expect(c.IdleTimeout).To(Equal(42 * time.Hour))
 			Expect(c.RequestConnectionIDOmission).To(BeTrue())
+			Expect(c.ClientSessionCache).To(Equal(sessionCache))
+			Expect(reflect.ValueOf(c.CongestionControl).Pointer()).To(Equal(reflect.ValueOf(congestion.RenoSenderFactory).Pointer()))
+			Expect(c.InitialCongestionWindow).To(BeEquivalentTo(10))
+			Expect(c.MaxCongestionWindow).To(BeEquivalentTo(100))
+			Expect(c.EnableDatagrams).To(BeTrue())
+			Expect(c.Logger).To(Equal(logger))
 		})
 
 		It("fills in default values if options are not set in the Config", func() {
@@ -273,6 +375,11 @@ var _ = Describe("Client", func() {
 			Expect(c.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 			Expect(c.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
 			Expect(c.RequestConnectionIDOmission).To(BeFalse())
+			Expect(reflect.ValueOf(c.CongestionControl).Pointer()).To(Equal(reflect.ValueOf(congestion.CubicSenderFactory).Pointer()))
+			Expect(c.InitialCongestionWindow).To(BeEquivalentTo(protocol.InitialCongestionWindow))
+			Expect(c.MaxCongestionWindow).To(BeEquivalentTo(protocol.DefaultMaxCongestionWindow))
+			Expect(c.EnableDatagrams).To(BeFalse())
+			Expect(c.Logger).To(Equal(utils.DefaultLogger))
 		})
 
 		It("errors when receiving an error from the connection", func(done Done) {
@@ -294,11 +401,59 @@ var _ = Describe("Client", func() {
 				_ *Config,
 				_ protocol.VersionNumber,
 				_ []protocol.VersionNumber,
+				_ []byte,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				return nil, nil, testErr
 			}
 			_, err := DialNonFWSecure(packetConn, addr, "quic.clemente.io:1337", nil, config)
 			Expect(err).To(MatchError(testErr))
+			Expect(packetConn.closed).To(BeFalse())
+		})
+
+		Context("cleaning up the net.PacketConn created by DialAddr", func() {
+			isRegistered := func(c net.PacketConn) bool {
+				getMultiplexer().mutex.Lock()
+				defer getMultiplexer().mutex.Unlock()
+				_, ok := getMultiplexer().conns[c]
+				return ok
+			}
+
+			It("closes it if the hostname can't be determined", func() {
+				_, err := dial(packetConn, addr, "quic.clemente.io", nil, config, true, false)
+				Expect(err).To(HaveOccurred())
+				Expect(packetConn.closed).To(BeTrue())
+				Expect(isRegistered(packetConn)).To(BeFalse())
+			})
+
+			It("closes it and removes it from the multiplexer if it can't create a session", func() {
+				testErr := errors.New("error creating session")
+				newClientSession = func(
+					_ connection,
+					_ string,
+					_ protocol.VersionNumber,
+					_ protocol.ConnectionID,
+					_ *tls.Config,
+					_ *Config,
+					_ protocol.VersionNumber,
+					_ []protocol.VersionNumber,
+					_ []byte,
+				) (packetHandler, <-chan handshakeEvent, error) {
+					Expect(isRegistered(packetConn)).To(BeTrue())
+					return nil, nil, testErr
+				}
+				_, err := dial(packetConn, addr, "quic.clemente.io:1337", nil, config, true, false)
+				Expect(err).To(MatchError(testErr))
+				Expect(packetConn.closed).To(BeTrue())
+				Expect(isRegistered(packetConn)).To(BeFalse())
+			})
+
+			It("closes it if the session can't be added to the multiplexer", func() {
+				getMultiplexer().AddConn(packetConn, utils.DefaultLogger).(*packetHandlerMap).closed = true
+				_, err := dial(packetConn, addr, "quic.clemente.io:1337", nil, config, true, false)
+				Expect(err).To(MatchError(errConnectionClosed))
+				Expect(packetConn.closed).To(BeTrue())
+				Expect(isRegistered(packetConn)).To(BeFalse())
+			})
 		})
 
 		Context("version negotiation", func() {
@@ -337,6 +492,7 @@ var _ = Describe("Client", func() {
 					_ *Config,
 					initialVersionP protocol.VersionNumber,
 					negotiatedVersionsP []protocol.VersionNumber,
+					_ []byte,
 				) (packetHandler, <-chan handshakeEvent, error) {
 					initialVersion = initialVersionP
 					negotiatedVersions = negotiatedVersionsP
@@ -387,6 +543,7 @@ var _ = Describe("Client", func() {
 					_ *Config,
 					_ protocol.VersionNumber,
 					_ []protocol.VersionNumber,
+					_ []byte,
 				) (packetHandler, <-chan handshakeEvent, error) {
 					atomic.AddUint32(&sessionCounter, 1)
 					return sess, nil, nil
@@ -420,6 +577,32 @@ var _ = Describe("Client", func() {
 				Expect(cl.session.(*mockSession).closeReason).To(MatchError(qerr.InvalidVersion))
 			})
 
+			It("closes early sessions instead of changing the version", func() {
+				var created bool
+				newClientSession = func(
+					_ connection,
+					_ string,
+					_ protocol.VersionNumber,
+					_ protocol.ConnectionID,
+					_ *tls.Config,
+					_ *Config,
+					_ protocol.VersionNumber,
+					_ []protocol.VersionNumber,
+					_ []byte,
+				) (packetHandler, <-chan handshakeEvent, error) {
+					created = true
+					return nil, nil, errors.New("unexpected session")
+				}
+				cl.early = true
+				ver := cl.version
+				cl.handlePacket(nil, wire.ComposeGQUICVersionNegotiation(0x1337, []protocol.VersionNumber{config.Versions[1]}))
+				Expect(created).To(BeFalse())
+				Expect(cl.version).To(Equal(ver))
+				Expect(cl.connectionID).To(BeEquivalentTo(0x1337))
+				Expect(sess.closed).To(BeTrue())
+				Expect(sess.closeReason.(*qerr.QuicError).ErrorCode).To(Equal(qerr.InvalidVersion))
+			})
+
 			It("changes to the version preferred by the quic.Config", func() {
 				cl.handlePacket(nil, wire.ComposeGQUICVersionNegotiation(0x1337, []protocol.VersionNumber{config.Versions[2], config.Versions[1]}))
 				Expect(cl.version).To(Equal(config.Versions[1]))
@@ -442,6 +625,106 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Context("stateless rejects", func() {
+		var (
+			sessionChan chan *mockSession
+			connIDs     chan protocol.ConnectionID
+			stks        chan []byte
+			srej        *handshake.StatelessRejectError
+		)
+
+		BeforeEach(func() {
+			sessionChan = make(chan *mockSession, 2)
+			connIDs = make(chan protocol.ConnectionID, 2)
+			stks = make(chan []byte, 2)
+			srej = &handshake.StatelessRejectError{ConnectionID: 0xdecafbad, STK: []byte("foobar")}
+			packetConn.dataToRead = acceptClientVersionPacket(cl.connectionID)
+			newClientSession = func(
+				_ connection,
+				_ string,
+				_ protocol.VersionNumber,
+				connectionID protocol.ConnectionID,
+				_ *tls.Config,
+				_ *Config,
+				_ protocol.VersionNumber,
+				_ []protocol.VersionNumber,
+				stk []byte,
+			) (packetHandler, <-chan handshakeEvent, error) {
+				sess := &mockSession{
+					connectionID:  connectionID,
+					stopRunLoop:   make(chan struct{}),
+					handshakeChan: make(chan handshakeEvent, 1),
+				}
+				connIDs <- connectionID
+				stks <- stk
+				sessionChan <- sess
+				return sess, sess.handshakeChan, nil
+			}
+		})
+
+		// rejectSession makes the session behave like a session that received a SREJ
+		rejectSession := func(sess *mockSession) {
+			sess.handshakeChan <- handshakeEvent{err: srej}
+			sess.Close(srej)
+		}
+
+		It("starts a new connection with the connection ID and the STK from the stateless reject", func() {
+			established := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := cl.establishSecureConnection()
+				Expect(err).ToNot(HaveOccurred())
+				close(established)
+			}()
+			var firstSession, secondSession *mockSession
+			Eventually(sessionChan).Should(Receive(&firstSession))
+			Expect(<-connIDs).To(BeEquivalentTo(0x1337))
+			Expect(<-stks).To(BeNil())
+			// the SREJ is the server's first packet, so the version is accepted
+			Eventually(cl.versionNegotiationChan).Should(BeClosed())
+			rejectSession(firstSession)
+			Eventually(sessionChan).Should(Receive(&secondSession))
+			Expect(<-connIDs).To(BeEquivalentTo(0xdecafbad))
+			Expect(<-stks).To(Equal([]byte("foobar")))
+			handlers := cl.packetHandlers.(*packetHandlerMap)
+			handlers.mutex.RLock()
+			Expect(handlers.handlers[0xdecafbad]).To(Equal(cl))
+			Expect(handlers.handlers[0x1337]).To(BeNil())
+			handlers.mutex.RUnlock()
+			Consistently(established).ShouldNot(BeClosed())
+			secondSession.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionSecure}
+			Eventually(established).Should(BeClosed())
+			// shut down the run loop
+			secondSession.Close(nil)
+		})
+
+		It("errors if it receives a second stateless reject", func() {
+			errChan := make(chan error)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- cl.establishSecureConnection()
+			}()
+			var firstSession, secondSession *mockSession
+			Eventually(sessionChan).Should(Receive(&firstSession))
+			rejectSession(firstSession)
+			Eventually(sessionChan).Should(Receive(&secondSession))
+			rejectSession(secondSession)
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.CryptoTooManyRejects))
+			Expect(sessionChan).To(BeEmpty())
+		})
+
+		It("closes early sessions instead of starting a new connection", func() {
+			cl.early = true
+			Expect(cl.establishSecureConnection()).To(Succeed())
+			var firstSession *mockSession
+			Eventually(sessionChan).Should(Receive(&firstSession))
+			rejectSession(firstSession)
+			Consistently(sessionChan).ShouldNot(Receive())
+		})
+	})
+
 	It("ignores packets with an invalid public header", func() {
 		cl.handlePacket(addr, []byte("invalid packet"))
 		Expect(sess.packetCount).To(BeZero())
@@ -488,6 +771,7 @@ var _ = Describe("Client", func() {
 			configP *Config,
 			_ protocol.VersionNumber,
 			_ []protocol.VersionNumber,
+			_ []byte,
 		) (packetHandler, <-chan handshakeEvent, error) {
 			cconn = connP
 			hostname = hostnameP
@@ -525,23 +809,16 @@ var _ = Describe("Client", func() {
 			packetConn.dataToRead = b.Bytes()
 
 			Expect(sess.packetCount).To(BeZero())
-			stoppedListening := make(chan struct{})
-			go func() {
-				cl.listen()
-				// it should continue listening when receiving valid packets
-				close(stoppedListening)
-			}()
-
+			Expect(cl.packetHandlers.Add(cl.connectionID, cl)).To(Succeed())
 			Eventually(func() int { return sess.packetCount }).Should(Equal(1))
 			Expect(sess.closed).To(BeFalse())
-			Consistently(stoppedListening).ShouldNot(BeClosed())
 		})
 
 		It("closes the session when encountering an error while reading from the connection", func() {
 			testErr := errors.New("test error")
 			packetConn.readErr = testErr
-			cl.listen()
-			Expect(sess.closed).To(BeTrue())
+			Expect(cl.packetHandlers.Add(cl.connectionID, cl)).To(Succeed())
+			Eventually(func() bool { return sess.closed }).Should(BeTrue())
 			Expect(sess.closeReason).To(MatchError(testErr))
 		})
 	})
@@ -0,0 +1,139 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+)
+
+// A bandwidthSample is a delivery rate sample, taken when a packet is acknowledged
+type bandwidthSample struct {
+	// The bandwidth at that particular sample. Zero if no valid bandwidth sample is available.
+	bandwidth Bandwidth
+	// The RTT measurement at this particular sample. Zero if no RTT sample is available.
+	rtt time.Duration
+}
+
+// The state of the connection at the time a packet was sent.
+type sentPacketState struct {
+	sentTime time.Time
+	size     protocol.ByteCount
+
+	// The value of totalBytesSent, including this packet.
+	totalBytesSent protocol.ByteCount
+	// The values of the corresponding fields of the bandwidthSampler when the packet was sent.
+	totalBytesSentAtLastAckedPacket protocol.ByteCount
+	lastAckedPacketSentTime         time.Time
+	lastAckedPacketAckTime          time.Time
+	totalBytesAcked                 protocol.ByteCount
+}
+
+// The bandwidthSampler estimates the delivery rate of the connection, as described in
+// https://tools.ietf.org/html/draft-cheng-iccrg-delivery-rate-estimation.
+// It is a port of Chromium's BandwidthSampler.
+//
+// For every packet acknowledged, two rates are calculated:
+// the send rate, which is the rate at which packets were sent between the last acknowledged packet and this packet,
+// and the ack rate, which is the rate at which packets were acknowledged in the same interval.
+// The bandwidth sample is the smaller of these two rates, since neither can exceed the bottleneck bandwidth.
+type bandwidthSampler struct {
+	// The total number of congestion controlled bytes sent during the connection.
+	totalBytesSent protocol.ByteCount
+	// The total number of congestion controlled bytes which were acknowledged.
+	totalBytesAcked protocol.ByteCount
+	// The value of totalBytesSent at the time the last acknowledged packet was sent.
+	totalBytesSentAtLastAckedPacket protocol.ByteCount
+	// The time at which the last acknowledged packet was sent.
+	lastAckedPacketSentTime time.Time
+	// The time at which the most recent packet was acknowledged.
+	lastAckedPacketAckTime time.Time
+
+	// The state of the connection at the time every packet in flight was sent.
+	packets map[protocol.PacketNumber]*sentPacketState
+}
+
+func newBandwidthSampler() *bandwidthSampler {
+	return &bandwidthSampler{packets: make(map[protocol.PacketNumber]*sentPacketState)}
+}
+
+// OnPacketSent should be called for every congestion controlled packet sent.
+// priorInFlight are the bytes in flight before this packet was sent.
+func (s *bandwidthSampler) OnPacketSent(sentTime time.Time, packetNumber protocol.PacketNumber, bytes, priorInFlight protocol.ByteCount) {
+	s.totalBytesSent += bytes
+	// If there are no packets in flight, the time at which the new transmission opens can be treated as the point in time
+	// when the last packet was acknowledged.
+	// Ack compression is not a concern in this situation, so the send rate is effectively infinite.
+	if priorInFlight == 0 {
+		s.lastAckedPacketAckTime = sentTime
+		s.totalBytesSentAtLastAckedPacket = s.totalBytesSent
+		s.lastAckedPacketSentTime = sentTime
+	}
+	s.packets[packetNumber] = &sentPacketState{
+		sentTime:                        sentTime,
+		size:                            bytes,
+		totalBytesSent:                  s.totalBytesSent,
+		totalBytesSentAtLastAckedPacket: s.totalBytesSentAtLastAckedPacket,
+		lastAckedPacketSentTime:         s.lastAckedPacketSentTime,
+		lastAckedPacketAckTime:          s.lastAckedPacketAckTime,
+		totalBytesAcked:                 s.totalBytesAcked,
+	}
+}
+
+// OnPacketAcked should be called when a packet is acknowledged.
+// It returns a bandwidth sample, which might be empty if no sample can be taken.
+func (s *bandwidthSampler) OnPacketAcked(ackTime time.Time, packetNumber protocol.PacketNumber) bandwidthSample {
+	packet, ok := s.packets[packetNumber]
+	if !ok {
+		return bandwidthSample{}
+	}
+	delete(s.packets, packetNumber)
+
+	s.totalBytesAcked += packet.size
+	s.totalBytesSentAtLastAckedPacket = packet.totalBytesSent
+	s.lastAckedPacketSentTime = packet.sentTime
+	s.lastAckedPacketAckTime = ackTime
+
+	// There might have been no packets acknowledged at the moment when this packet was sent.
+	// In that case, there is no bandwidth sample to make.
+	if packet.lastAckedPacketSentTime.IsZero() {
+		return bandwidthSample{}
+	}
+
+	// An infinite send rate indicates that only the ack rate should be used.
+	sendRate := Bandwidth(1<<64 - 1)
+	if packet.sentTime.After(packet.lastAckedPacketSentTime) {
+		sendRate = BandwidthFromDelta(
+			packet.totalBytesSent-packet.totalBytesSentAtLastAckedPacket,
+			packet.sentTime.Sub(packet.lastAckedPacketSentTime),
+		)
+	}
+	// Make sure that the ack time of this packet is larger than the ack time of the previous packet.
+	// Otherwise, the ack rate can't be calculated.
+	if !ackTime.After(packet.lastAckedPacketAckTime) {
+		return bandwidthSample{}
+	}
+	ackRate := BandwidthFromDelta(
+		s.totalBytesAcked-packet.totalBytesAcked,
+		ackTime.Sub(packet.lastAckedPacketAckTime),
+	)
+
+	bandwidth := sendRate
+	if ackRate < sendRate {
+		bandwidth = ackRate
+	}
+	return bandwidthSample{
+		bandwidth: bandwidth,
+		rtt:       ackTime.Sub(packet.sentTime),
+	}
+}
+
+// OnPacketLost should be called when a packet is declared lost.
+// No bandwidth sample is taken for lost packets.
+func (s *bandwidthSampler) OnPacketLost(packetNumber protocol.PacketNumber) {
+	delete(s.packets, packetNumber)
+}
+
+// TotalBytesAcked returns the total number of bytes acknowledged
+func (s *bandwidthSampler) TotalBytesAcked() protocol.ByteCount {
+	return s.totalBytesAcked
+}
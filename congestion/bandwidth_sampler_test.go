@@ -0,0 +1,89 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Sampler", func() {
+	const packetSize = protocol.DefaultTCPMSS
+
+	var (
+		sampler       *bandwidthSampler
+		now           time.Time
+		bytesInFlight protocol.ByteCount
+	)
+
+	BeforeEach(func() {
+		sampler = newBandwidthSampler()
+		now = time.Now()
+		bytesInFlight = 0
+	})
+
+	sendPacket := func(pn protocol.PacketNumber) {
+		sampler.OnPacketSent(now, pn, packetSize, bytesInFlight)
+		bytesInFlight += packetSize
+	}
+
+	ackPacket := func(pn protocol.PacketNumber) bandwidthSample {
+		bytesInFlight -= packetSize
+		return sampler.OnPacketAcked(now, pn)
+	}
+
+	It("takes samples when the sending is paced", func() {
+		// send one packet every 10ms, and ack it 20ms later
+		timeBetweenPackets := 10 * time.Millisecond
+		expectedBandwidth := BandwidthFromDelta(packetSize, timeBetweenPackets)
+		sendPacket(1)
+		now = now.Add(timeBetweenPackets)
+		for pn := protocol.PacketNumber(2); pn <= 20; pn++ {
+			sendPacket(pn)
+			now = now.Add(timeBetweenPackets)
+			sample := ackPacket(pn - 1)
+			// it takes a few packets until the sampler has a steady state
+			if pn <= 3 {
+				continue
+			}
+			Expect(sample.bandwidth).To(Equal(expectedBandwidth))
+			Expect(sample.rtt).To(Equal(2 * timeBetweenPackets))
+		}
+	})
+
+	It("uses the ack rate if it's lower than the send rate", func() {
+		// send a burst of packets, and ack them one by one
+		start := now
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			sendPacket(pn)
+		}
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			now = now.Add(5 * time.Millisecond)
+			sample := ackPacket(pn)
+			Expect(sample.bandwidth).To(Equal(BandwidthFromDelta(packetSize*protocol.ByteCount(pn), now.Sub(start))))
+			Expect(sample.rtt).To(Equal(now.Sub(start)))
+		}
+	})
+
+	It("doesn't take a sample for unknown packets", func() {
+		Expect(ackPacket(42)).To(BeZero())
+	})
+
+	It("doesn't take a sample for lost packets", func() {
+		sendPacket(1)
+		sendPacket(2)
+		sampler.OnPacketLost(2)
+		Expect(sampler.packets).To(HaveLen(1))
+		Expect(sampler.OnPacketAcked(now, 2)).To(BeZero())
+	})
+
+	It("counts the acknowledged bytes", func() {
+		sendPacket(1)
+		sendPacket(2)
+		ackPacket(1)
+		Expect(sampler.TotalBytesAcked()).To(Equal(packetSize))
+		ackPacket(2)
+		Expect(sampler.TotalBytesAcked()).To(Equal(2 * packetSize))
+	})
+})
@@ -0,0 +1,439 @@
+package congestion
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+)
+
+// The mode of the BBR sender
+type bbrMode int
+
+const (
+	// Startup phase of the connection.
+	bbrModeStartup bbrMode = iota
+	// After achieving the highest possible bandwidth during the startup, lower the pacing rate in order to drain the queue.
+	bbrModeDrain
+	// Cruising mode.
+	bbrModeProbeBW
+	// Temporarily slow down sending in order to empty the buffer and measure the real minimum RTT.
+	bbrModeProbeRTT
+)
+
+const (
+	// The gain used for the STARTUP, equal to 2/ln(2).
+	bbrHighGain = 2.885
+	// The pacing gain used in DRAIN, the inverse of the STARTUP gain.
+	// The queue built up during STARTUP is drained in the DRAIN mode.
+	bbrDrainGain = 1 / bbrHighGain
+	// The cycle of gains used during the PROBE_BW stage.
+	bbrGainCycleLength = 8
+	// The size of the bandwidth filter window, in round trips.
+	bbrBandwidthWindowSize = bbrGainCycleLength + 2
+	// The congestion window gain used during the PROBE_BW stage.
+	bbrCongestionWindowGain = 2.0
+	// The time after which the current min RTT value expires.
+	bbrMinRTTExpiry = 10 * time.Second
+	// The minimum time the connection can spend in PROBE_RTT mode.
+	bbrProbeRTTTime = 200 * time.Millisecond
+	// If the bandwidth does not increase by this factor within bbrRoundTripsWithoutGrowthBeforeExitingStartup rounds,
+	// the bandwidth is assumed to be fully utilized and STARTUP is exited.
+	bbrStartupGrowthTarget                         = 1.25
+	bbrRoundTripsWithoutGrowthBeforeExitingStartup = 3
+	// The minimum congestion window, in bytes.
+	bbrMinimumCongestionWindow = 4 * protocol.DefaultTCPMSS
+)
+
+var bbrPacingGainCycle = [bbrGainCycleLength]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+// bbrSender implements BBR (v1) congestion control, as described in
+// https://tools.ietf.org/html/draft-cardwell-iccrg-bbr-congestion-control.
+// It is a port of Chromium's BbrSender.
+type bbrSender struct {
+	clock    Clock
+	rttStats *RTTStats
+
+	mode bbrMode
+
+	// Bandwidth sampler provides BBR with the bandwidth measurements at individual points.
+	sampler *bandwidthSampler
+	// The maximum bandwidth over the last bbrBandwidthWindowSize round trips.
+	maxBandwidth *maxBandwidthFilter
+
+	// The number of the round trips that have occurred during the connection.
+	roundTripCount uint64
+	// The packet number of the most recently sent packet.
+	lastSentPacket protocol.PacketNumber
+	// Acknowledgement of any packet after currentRoundTripEnd will cause the round trip counter to advance.
+	currentRoundTripEnd protocol.PacketNumber
+
+	// Minimum RTT estimate.
+	// Automatically expires within 10 seconds (and triggers PROBE_RTT mode) if no new value is sampled during that period.
+	minRTT time.Duration
+	// The time at which the current value of minRTT was assigned.
+	minRTTTimestamp time.Time
+
+	congestionWindow        protocol.ByteCount
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+
+	// The current pacing rate of the connection.
+	pacingRate Bandwidth
+	// The gain currently applied to the pacing rate.
+	pacingGain float64
+	// The gain currently applied to the congestion window.
+	congestionWindowGain float64
+
+	// The number of the current gain cycle phase, used in the PROBE_BW mode.
+	cycleCurrentOffset int
+	// The time at which the last pacing gain cycle was started.
+	lastCycleStart time.Time
+
+	// Indicates whether the connection has reached the full bandwidth mode.
+	isAtFullBandwidth bool
+	// Number of rounds during which there was no significant bandwidth increase.
+	roundsWithoutBandwidthGain int
+	// The bandwidth compared to which the increase is measured.
+	bandwidthAtLastRound Bandwidth
+
+	// Time at which PROBE_RTT has to be exited.
+	// Setting it to zero indicates that the time is yet unknown as the number of packets in flight has not reached the required value.
+	exitProbeRTTAt time.Time
+	// Indicates whether a round trip has passed since PROBE_RTT became active.
+	probeRTTRoundPassed bool
+
+	// Indicates whether a packet was declared lost since the last acknowledgement.
+	lossSinceLastAck bool
+
+	tracer Tracer
+}
+
+var _ PacingSendAlgorithm = &bbrSender{}
+var _ TracingSendAlgorithm = &bbrSender{}
+
+// NewBBRSender makes a new BBR sender
+func NewBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	b := &bbrSender{
+		clock:                   clock,
+		rttStats:                rttStats,
+		initialCongestionWindow: protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		maxCongestionWindow:     protocol.ByteCount(maxCongestionWindow) * protocol.DefaultTCPMSS,
+	}
+	b.reset()
+	return b
+}
+
+func (b *bbrSender) reset() {
+	b.sampler = newBandwidthSampler()
+	b.maxBandwidth = newMaxBandwidthFilter(bbrBandwidthWindowSize)
+	b.roundTripCount = 0
+	b.currentRoundTripEnd = 0
+	b.minRTT = 0
+	b.minRTTTimestamp = time.Time{}
+	b.congestionWindow = b.initialCongestionWindow
+	b.pacingRate = 0
+	b.isAtFullBandwidth = false
+	b.roundsWithoutBandwidthGain = 0
+	b.bandwidthAtLastRound = 0
+	b.exitProbeRTTAt = time.Time{}
+	b.probeRTTRoundPassed = false
+	b.lossSinceLastAck = false
+	b.enterStartupMode()
+}
+
+func (b *bbrSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if b.GetCongestionWindow() > bytesInFlight {
+		return 0
+	}
+	return utils.InfDuration
+}
+
+func (b *bbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	if !isRetransmittable {
+		return false
+	}
+	b.lastSentPacket = packetNumber
+	// bytesInFlight already includes this packet
+	b.sampler.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight-bytes)
+	return true
+}
+
+func (b *bbrSender) OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	defer b.traceChanges(b.GetCongestionWindow(), b.mode == bbrModeStartup)
+	now := b.clock.Now()
+	isRoundStart := b.updateRoundTripCounter(number)
+	minRTTExpired := b.updateBandwidthAndMinRTT(now, number)
+	if b.mode == bbrModeProbeBW {
+		b.updateGainCyclePhase(now, bytesInFlight+ackedBytes, b.lossSinceLastAck)
+	}
+	if isRoundStart && !b.isAtFullBandwidth {
+		b.checkIfFullBandwidthReached()
+	}
+	b.maybeExitStartupOrDrain(now, bytesInFlight)
+	b.maybeEnterOrExitProbeRTT(now, isRoundStart, minRTTExpired, bytesInFlight)
+
+	b.calculatePacingRate()
+	b.calculateCongestionWindow(ackedBytes)
+	b.lossSinceLastAck = false
+}
+
+func (b *bbrSender) OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	b.sampler.OnPacketLost(number)
+	b.lossSinceLastAck = true
+}
+
+func (b *bbrSender) GetCongestionWindow() protocol.ByteCount {
+	if b.mode == bbrModeProbeRTT {
+		return bbrMinimumCongestionWindow
+	}
+	return b.congestionWindow
+}
+
+// PacingRate returns the rate at which packets should be sent
+func (b *bbrSender) PacingRate(bytesInFlight protocol.ByteCount) Bandwidth {
+	if b.pacingRate == 0 {
+		return Bandwidth(bbrHighGain * float64(BandwidthFromDelta(b.initialCongestionWindow, b.getMinRTT())))
+	}
+	return b.pacingRate
+}
+
+// BandwidthEstimate returns the maximum bandwidth measured over the last round trips
+func (b *bbrSender) BandwidthEstimate() Bandwidth {
+	return b.maxBandwidth.GetBest()
+}
+
+// RetransmissionDelay gives the time to retransmission
+func (b *bbrSender) RetransmissionDelay() time.Duration {
+	if b.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return b.rttStats.SmoothedRTT() + b.rttStats.MeanDeviation()*4
+}
+
+// OnConnectionMigration resets all estimates, since they were obtained on a different path
+func (b *bbrSender) OnConnectionMigration() {
+	defer b.traceChanges(b.GetCongestionWindow(), b.mode == bbrModeStartup)
+	b.reset()
+}
+
+// SetTracer sets the tracer that is notified about changes of the congestion window
+func (b *bbrSender) SetTracer(tracer Tracer) {
+	b.tracer = tracer
+}
+
+// traceChanges reports the changes of the congestion window and leaving STARTUP to the tracer.
+// STARTUP is BBR's equivalent of slow start.
+// It is deferred by the functions that change the state, with the old values as arguments.
+func (b *bbrSender) traceChanges(oldCongestionWindow protocol.ByteCount, wasInStartup bool) {
+	if b.tracer == nil {
+		return
+	}
+	if b.GetCongestionWindow() != oldCongestionWindow {
+		b.tracer.UpdatedCongestionWindow(b.GetCongestionWindow())
+	}
+	if wasInStartup && b.mode != bbrModeStartup {
+		b.tracer.ExitedSlowStart()
+	}
+}
+
+// BBR doesn't use slow start, doesn't emulate multiple connections and doesn't reduce the congestion window on RTOs.
+func (b *bbrSender) MaybeExitSlowStart()                               {}
+func (b *bbrSender) SetNumEmulatedConnections(n int)                   {}
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {}
+func (b *bbrSender) SetSlowStartLargeReduction(enabled bool)           {}
+func (b *bbrSender) HybridSlowStart() *HybridSlowStart                 { return nil }
+func (b *bbrSender) SlowstartThreshold() protocol.PacketNumber         { return 0 }
+func (b *bbrSender) RenoBeta() float32                                 { return 0 }
+func (b *bbrSender) InRecovery() bool                                  { return false }
+
+func (b *bbrSender) getMinRTT() time.Duration {
+	if b.minRTT == 0 {
+		return time.Duration(b.rttStats.InitialRTTus()) * time.Microsecond
+	}
+	return b.minRTT
+}
+
+// getTargetCongestionWindow returns the congestion window that BBR aims for, given the bandwidth estimate, the min RTT and the gain
+func (b *bbrSender) getTargetCongestionWindow(gain float64) protocol.ByteCount {
+	bdp := protocol.ByteCount(float64(b.BandwidthEstimate()) / float64(BytesPerSecond) * b.getMinRTT().Seconds())
+	congestionWindow := protocol.ByteCount(gain * float64(bdp))
+	// BDP estimate will be zero if no bandwidth samples are available yet.
+	if congestionWindow == 0 {
+		congestionWindow = protocol.ByteCount(gain * float64(b.initialCongestionWindow))
+	}
+	return utils.MaxByteCount(congestionWindow, bbrMinimumCongestionWindow)
+}
+
+func (b *bbrSender) enterStartupMode() {
+	b.mode = bbrModeStartup
+	b.pacingGain = bbrHighGain
+	b.congestionWindowGain = bbrHighGain
+}
+
+func (b *bbrSender) enterProbeBandwidthMode(now time.Time) {
+	b.mode = bbrModeProbeBW
+	b.congestionWindowGain = bbrCongestionWindowGain
+	// Pick a random offset for the gain cycle out of {0, 2..7} range.
+	// 1 is excluded because in that case increased gain and decreased gain would not follow each other.
+	b.cycleCurrentOffset = rand.Intn(bbrGainCycleLength - 1)
+	if b.cycleCurrentOffset >= 1 {
+		b.cycleCurrentOffset++
+	}
+	b.lastCycleStart = now
+	b.pacingGain = bbrPacingGainCycle[b.cycleCurrentOffset]
+}
+
+// updateRoundTripCounter advances the round trip counter, if the acknowledged packet marks the end of a round trip.
+// It returns true if a new round trip started.
+func (b *bbrSender) updateRoundTripCounter(lastAckedPacket protocol.PacketNumber) bool {
+	if lastAckedPacket > b.currentRoundTripEnd {
+		b.roundTripCount++
+		b.currentRoundTripEnd = b.lastSentPacket
+		return true
+	}
+	return false
+}
+
+// updateBandwidthAndMinRTT updates the bandwidth and the min RTT estimates.
+// It returns true if the min RTT expired.
+func (b *bbrSender) updateBandwidthAndMinRTT(now time.Time, packetNumber protocol.PacketNumber) bool {
+	sample := b.sampler.OnPacketAcked(now, packetNumber)
+	if sample.bandwidth != 0 {
+		b.maxBandwidth.Update(sample.bandwidth, b.roundTripCount)
+	}
+	// If no RTT sample was taken, the min RTT can't be updated.
+	if sample.rtt == 0 {
+		return false
+	}
+	minRTTExpired := b.minRTT != 0 && now.After(b.minRTTTimestamp.Add(bbrMinRTTExpiry))
+	if minRTTExpired || sample.rtt < b.minRTT || b.minRTT == 0 {
+		b.minRTT = sample.rtt
+		b.minRTTTimestamp = now
+	}
+	return minRTTExpired
+}
+
+// updateGainCyclePhase advances the pacing gain cycle in the PROBE_BW mode
+func (b *bbrSender) updateGainCyclePhase(now time.Time, priorInFlight protocol.ByteCount, hasLosses bool) {
+	// In most cases, the cycle is advanced after an RTT passes.
+	shouldAdvanceGainCycling := now.Sub(b.lastCycleStart) > b.getMinRTT()
+
+	// If the pacing gain is above 1.0, the connection is trying to probe the bandwidth by increasing the number of bytes in flight to at least pacingGain * BDP.
+	// Make sure that it actually reaches the target, as long as there are no losses suggesting that the buffers are not able to hold that much.
+	if b.pacingGain > 1 && !hasLosses && priorInFlight < b.getTargetCongestionWindow(b.pacingGain) {
+		shouldAdvanceGainCycling = false
+	}
+
+	// If pacing gain is below 1.0, the connection is trying to drain the extra queue which could have been incurred by probing prior to it.
+	// If the number of bytes in flight falls down to the estimated BDP value earlier, conclude that the queue has been successfully drained and exit this cycle early.
+	if b.pacingGain < 1 && priorInFlight <= b.getTargetCongestionWindow(1) {
+		shouldAdvanceGainCycling = true
+	}
+
+	if shouldAdvanceGainCycling {
+		b.cycleCurrentOffset = (b.cycleCurrentOffset + 1) % bbrGainCycleLength
+		b.lastCycleStart = now
+		b.pacingGain = bbrPacingGainCycle[b.cycleCurrentOffset]
+	}
+}
+
+// checkIfFullBandwidthReached tracks for how many round trips the bandwidth has not increased significantly
+func (b *bbrSender) checkIfFullBandwidthReached() {
+	target := Bandwidth(float64(b.bandwidthAtLastRound) * bbrStartupGrowthTarget)
+	if b.BandwidthEstimate() >= target {
+		b.bandwidthAtLastRound = b.BandwidthEstimate()
+		b.roundsWithoutBandwidthGain = 0
+		return
+	}
+	b.roundsWithoutBandwidthGain++
+	if b.roundsWithoutBandwidthGain >= bbrRoundTripsWithoutGrowthBeforeExitingStartup {
+		b.isAtFullBandwidth = true
+	}
+}
+
+// maybeExitStartupOrDrain transitions from STARTUP to DRAIN and from DRAIN to PROBE_BW if appropriate
+func (b *bbrSender) maybeExitStartupOrDrain(now time.Time, bytesInFlight protocol.ByteCount) {
+	if b.mode == bbrModeStartup && b.isAtFullBandwidth {
+		b.mode = bbrModeDrain
+		b.pacingGain = bbrDrainGain
+		b.congestionWindowGain = bbrHighGain
+	}
+	if b.mode == bbrModeDrain && bytesInFlight <= b.getTargetCongestionWindow(1) {
+		b.enterProbeBandwidthMode(now)
+	}
+}
+
+// maybeEnterOrExitProbeRTT decides whether to enter or exit PROBE_RTT
+func (b *bbrSender) maybeEnterOrExitProbeRTT(now time.Time, isRoundStart, minRTTExpired bool, bytesInFlight protocol.ByteCount) {
+	if minRTTExpired && b.mode != bbrModeProbeRTT {
+		b.mode = bbrModeProbeRTT
+		b.pacingGain = 1
+		// Do not decide on the time to exit PROBE_RTT until the bytesInFlight is at the target small value.
+		b.exitProbeRTTAt = time.Time{}
+	}
+
+	if b.mode != bbrModeProbeRTT {
+		return
+	}
+	if b.exitProbeRTTAt.IsZero() {
+		// If the window has reached the appropriate size, schedule exiting PROBE_RTT.
+		// The CWND during PROBE_RTT is bbrMinimumCongestionWindow, but we allow an extra packet since QUIC checks CWND before sending a packet.
+		if bytesInFlight < bbrMinimumCongestionWindow+protocol.MaxPacketSize {
+			b.exitProbeRTTAt = now.Add(bbrProbeRTTTime)
+			b.probeRTTRoundPassed = false
+		}
+		return
+	}
+	if isRoundStart {
+		b.probeRTTRoundPassed = true
+	}
+	if !now.Before(b.exitProbeRTTAt) && b.probeRTTRoundPassed {
+		b.minRTTTimestamp = now
+		if !b.isAtFullBandwidth {
+			b.enterStartupMode()
+		} else {
+			b.enterProbeBandwidthMode(now)
+		}
+	}
+}
+
+// calculatePacingRate determines the appropriate pacing rate for the connection
+func (b *bbrSender) calculatePacingRate() {
+	if b.BandwidthEstimate() == 0 {
+		return
+	}
+	targetRate := Bandwidth(b.pacingGain * float64(b.BandwidthEstimate()))
+	if b.isAtFullBandwidth {
+		b.pacingRate = targetRate
+		return
+	}
+	// Pace at the rate of initialCongestionWindow / RTT as soon as RTT measurements are available, multiplied by the STARTUP gain.
+	if b.pacingRate == 0 && b.minRTT != 0 {
+		b.pacingRate = Bandwidth(bbrHighGain * float64(BandwidthFromDelta(b.initialCongestionWindow, b.minRTT)))
+		return
+	}
+	// Do not decrease the pacing rate during the startup.
+	if targetRate > b.pacingRate {
+		b.pacingRate = targetRate
+	}
+}
+
+// calculateCongestionWindow determines the appropriate congestion window for the connection
+func (b *bbrSender) calculateCongestionWindow(ackedBytes protocol.ByteCount) {
+	if b.mode == bbrModeProbeRTT {
+		return
+	}
+	targetWindow := b.getTargetCongestionWindow(b.congestionWindowGain)
+	if b.isAtFullBandwidth {
+		// Grow the window towards the target, but never beyond it.
+		b.congestionWindow = utils.MinByteCount(targetWindow, b.congestionWindow+ackedBytes)
+	} else if b.congestionWindow < targetWindow || b.sampler.TotalBytesAcked() < b.initialCongestionWindow {
+		// If the connection is not yet out of startup phase, do not decrease the window.
+		b.congestionWindow += ackedBytes
+	}
+	// Enforce the limits on the congestion window.
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, bbrMinimumCongestionWindow)
+	b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.maxCongestionWindow)
+}
@@ -0,0 +1,234 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		packetSize = protocol.DefaultTCPMSS
+		// the properties of the simulated link
+		rtt                = 100 * time.Millisecond
+		timeBetweenPackets = time.Millisecond
+	)
+	bottleneckBandwidth := BandwidthFromDelta(packetSize, timeBetweenPackets)
+
+	var (
+		sender        *bbrSender
+		clock         mockClock
+		rttStats      *RTTStats
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		// the time when the last packet left the bottleneck
+		lastDelivery time.Time
+		// the times when the packets in flight will be acknowledged
+		ackTimes  map[protocol.PacketNumber]time.Time
+		sendTimes map[protocol.PacketNumber]time.Time
+	)
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		clock.Advance(time.Hour)
+		rttStats = NewRTTStats()
+		sender = NewBBRSender(&clock, rttStats, initialCongestionWindowPackets, MaxCongestionWindow).(*bbrSender)
+		bytesInFlight = 0
+		packetNumber = 1
+		lastDelivery = time.Time{}
+		ackTimes = make(map[protocol.PacketNumber]time.Time)
+		sendTimes = make(map[protocol.PacketNumber]time.Time)
+	})
+
+	sendPacket := func() {
+		now := clock.Now()
+		bytesInFlight += packetSize
+		sender.OnPacketSent(now, bytesInFlight, packetNumber, packetSize, true)
+		arrival := now.Add(rtt / 2)
+		if !lastDelivery.IsZero() && lastDelivery.Add(timeBetweenPackets).After(arrival) {
+			arrival = lastDelivery.Add(timeBetweenPackets)
+		}
+		lastDelivery = arrival
+		ackTimes[packetNumber] = arrival.Add(rtt / 2)
+		sendTimes[packetNumber] = now
+		packetNumber++
+	}
+
+	ackPacket := func(pn protocol.PacketNumber) {
+		bytesInFlight -= packetSize
+		rttStats.UpdateRTT(clock.Now().Sub(sendTimes[pn]), 0, clock.Now())
+		sender.OnPacketAcked(pn, packetSize, bytesInFlight)
+		delete(ackTimes, pn)
+		delete(sendTimes, pn)
+	}
+
+	// simulate sends packets at the pacing rate, as long as the congestion window allows,
+	// and acknowledges them when they arrive
+	simulate := func(duration time.Duration) {
+		end := clock.Now().Add(duration)
+		nextSendTime := clock.Now()
+		for clock.Now().Before(end) {
+			canSend := sender.TimeUntilSend(clock.Now(), bytesInFlight) == 0
+			if canSend && !nextSendTime.After(clock.Now()) {
+				sendPacket()
+				rate := sender.PacingRate(bytesInFlight)
+				nextSendTime = clock.Now().Add(time.Duration(Bandwidth(packetSize) * BytesPerSecond * Bandwidth(time.Second) / rate))
+				continue
+			}
+			// find the next packet to be acknowledged
+			var next protocol.PacketNumber
+			for pn, t := range ackTimes {
+				if next == 0 || t.Before(ackTimes[next]) {
+					next = pn
+				}
+			}
+			if canSend && nextSendTime.Before(ackTimes[next]) {
+				clock.Advance(nextSendTime.Sub(clock.Now()))
+				continue
+			}
+			if ackTimes[next].After(clock.Now()) {
+				clock.Advance(ackTimes[next].Sub(clock.Now()))
+			}
+			ackPacket(next)
+		}
+	}
+
+	It("starts in STARTUP, with the initial congestion window", func() {
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		Expect(sender.TimeUntilSend(clock.Now(), 0)).To(BeZero())
+		Expect(sender.TimeUntilSend(clock.Now(), defaultWindowTCP)).ToNot(BeZero())
+	})
+
+	It("paces at a high gain before the first RTT sample", func() {
+		expected := Bandwidth(bbrHighGain * float64(BandwidthFromDelta(defaultWindowTCP, initialRTTus*time.Microsecond)))
+		Expect(sender.PacingRate(0)).To(Equal(expected))
+	})
+
+	It("paces at a high gain after the first RTT sample", func() {
+		sendPacket()
+		clock.Advance(rtt)
+		ackPacket(1)
+		Expect(sender.minRTT).To(Equal(rtt))
+		expected := Bandwidth(bbrHighGain * float64(BandwidthFromDelta(defaultWindowTCP, rtt)))
+		Expect(sender.PacingRate(0)).To(Equal(expected))
+	})
+
+	It("grows the congestion window in STARTUP", func() {
+		for i := 0; i < int(initialCongestionWindowPackets); i++ {
+			sendPacket()
+		}
+		clock.Advance(rtt)
+		for pn := protocol.PacketNumber(1); pn <= protocol.PacketNumber(initialCongestionWindowPackets); pn++ {
+			ackPacket(pn)
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * defaultWindowTCP))
+	})
+
+	It("doesn't count non-retransmittable packets", func() {
+		Expect(sender.OnPacketSent(clock.Now(), 0, 1, packetSize, false)).To(BeFalse())
+		Expect(sender.sampler.packets).To(BeEmpty())
+	})
+
+	It("estimates the bottleneck bandwidth, and enters PROBE_BW", func() {
+		simulate(5 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(sender.isAtFullBandwidth).To(BeTrue())
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", bottleneckBandwidth, bottleneckBandwidth/20))
+		Expect(sender.minRTT).To(BeNumerically("~", rtt, 2*timeBetweenPackets))
+		// the congestion window is 2 * BDP
+		bdp := protocol.ByteCount(rtt / timeBetweenPackets * time.Duration(packetSize))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("~", 2*bdp, bdp/10))
+	})
+
+	It("enters PROBE_RTT when the min RTT expires", func() {
+		simulate(5 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		// make sure the min RTT is not updated
+		sender.minRTT = rtt / 2
+		sender.minRTTTimestamp = clock.Now()
+		simulate(bbrMinRTTExpiry - rtt)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		for i := 0; i < 20 && sender.mode != bbrModeProbeRTT; i++ {
+			simulate(rtt / 10)
+		}
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinimumCongestionWindow))
+		Expect(sender.minRTT).To(BeNumerically("~", rtt, 2*timeBetweenPackets))
+		simulate(bbrProbeRTTTime + 2*rtt)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+	})
+
+	It("cycles through the pacing gains in PROBE_BW", func() {
+		simulate(5 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		gains := make(map[float64]bool)
+		for i := 0; i < 2*bbrGainCycleLength; i++ {
+			simulate(rtt)
+			gains[sender.pacingGain] = true
+		}
+		Expect(gains).To(HaveKey(1.25))
+		Expect(gains).To(HaveKey(0.75))
+		Expect(gains).To(HaveKey(1.0))
+	})
+
+	It("ignores losses for the congestion window", func() {
+		sendPacket()
+		sendPacket()
+		cwnd := sender.GetCongestionWindow()
+		bytesInFlight -= packetSize
+		sender.OnPacketLost(2, packetSize, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		Expect(sender.sampler.packets).ToNot(HaveKey(protocol.PacketNumber(2)))
+	})
+
+	It("resets on connection migration", func() {
+		simulate(5 * time.Second)
+		sender.OnConnectionMigration()
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+	})
+
+	Context("tracing", func() {
+		var tracer *mockTracer
+
+		BeforeEach(func() {
+			tracer = &mockTracer{}
+			sender.SetTracer(tracer)
+		})
+
+		It("traces congestion window updates in STARTUP", func() {
+			for i := 0; i < int(initialCongestionWindowPackets); i++ {
+				sendPacket()
+			}
+			clock.Advance(rtt)
+			ackPacket(1)
+			Expect(tracer.congestionWindows).To(Equal([]protocol.ByteCount{defaultWindowTCP + packetSize}))
+			Expect(tracer.exitedSlowStart).To(BeZero())
+		})
+
+		It("traces leaving STARTUP", func() {
+			simulate(5 * time.Second)
+			Expect(sender.mode).To(Equal(bbrModeProbeBW))
+			Expect(tracer.exitedSlowStart).To(Equal(1))
+			Expect(tracer.congestionWindows).ToNot(BeEmpty())
+			Expect(tracer.congestionWindows[len(tracer.congestionWindows)-1]).To(Equal(sender.GetCongestionWindow()))
+		})
+
+		It("traces the congestion window reset on connection migration", func() {
+			simulate(5 * time.Second)
+			tracer.congestionWindows = nil
+			sender.OnConnectionMigration()
+			Expect(tracer.congestionWindows).To(Equal([]protocol.ByteCount{defaultWindowTCP}))
+		})
+	})
+
+	It("calculates the retransmission delay", func() {
+		Expect(sender.RetransmissionDelay()).To(BeZero())
+		rttStats.UpdateRTT(rtt, 0, clock.Now())
+		Expect(sender.RetransmissionDelay()).To(Equal(rtt + rtt/2*4))
+	})
+})
@@ -58,6 +58,8 @@ type cubicSender struct {
 
 	initialCongestionWindow    protocol.PacketNumber
 	initialMaxCongestionWindow protocol.PacketNumber
+
+	tracer Tracer
 }
 
 // NewCubicSender makes a new cubic sender
@@ -126,12 +128,14 @@ func (c *cubicSender) SlowstartThreshold() protocol.PacketNumber {
 }
 
 func (c *cubicSender) MaybeExitSlowStart() {
+	defer c.traceChanges(c.congestionWindow, c.InSlowStart())
 	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
 		c.ExitSlowstart()
 	}
 }
 
 func (c *cubicSender) OnPacketAcked(ackedPacketNumber protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	defer c.traceChanges(c.congestionWindow, c.InSlowStart())
 	c.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, c.largestAckedPacketNumber)
 	if c.InRecovery() {
 		// PRR is used when in recovery.
@@ -145,6 +149,7 @@ func (c *cubicSender) OnPacketAcked(ackedPacketNumber protocol.PacketNumber, ack
 }
 
 func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	defer c.traceChanges(c.congestionWindow, c.InSlowStart())
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
@@ -259,6 +264,7 @@ func (c *cubicSender) SetNumEmulatedConnections(n int) {
 
 // OnRetransmissionTimeout is called on an retransmission timeout
 func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	defer c.traceChanges(c.congestionWindow, c.InSlowStart())
 	c.largestSentAtLastCutback = 0
 	if !packetsRetransmitted {
 		return
@@ -271,6 +277,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 
 // OnConnectionMigration is called when the connection is migrated (?)
 func (c *cubicSender) OnConnectionMigration() {
+	defer c.traceChanges(c.congestionWindow, c.InSlowStart())
 	c.hybridSlowStart.Restart()
 	c.prr = PrrSender{}
 	c.largestSentPacketNumber = 0
@@ -284,6 +291,25 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.maxTCPCongestionWindow = c.initialMaxCongestionWindow
 }
 
+// SetTracer sets the tracer that is notified about changes of the congestion window
+func (c *cubicSender) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+// traceChanges reports the changes of the congestion window and the slow start state to the tracer.
+// It is deferred by the functions that change the state, with the old values as arguments.
+func (c *cubicSender) traceChanges(oldCongestionWindow protocol.PacketNumber, wasInSlowStart bool) {
+	if c.tracer == nil {
+		return
+	}
+	if c.congestionWindow != oldCongestionWindow {
+		c.tracer.UpdatedCongestionWindow(c.GetCongestionWindow())
+	}
+	if wasInSlowStart && !c.InSlowStart() {
+		c.tracer.ExitedSlowStart()
+	}
+}
+
 // SetSlowStartLargeReduction allows enabling the SSLR experiment
 func (c *cubicSender) SetSlowStartLargeReduction(enabled bool) {
 	c.slowStartLargeReduction = enabled
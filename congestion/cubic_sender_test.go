@@ -23,6 +23,19 @@ func (c *mockClock) Advance(d time.Duration) {
 
 const MaxCongestionWindow = protocol.PacketNumber(200)
 
+type mockTracer struct {
+	congestionWindows []protocol.ByteCount
+	exitedSlowStart   int
+}
+
+func (t *mockTracer) UpdatedCongestionWindow(cwnd protocol.ByteCount) {
+	t.congestionWindows = append(t.congestionWindows, cwnd)
+}
+
+func (t *mockTracer) ExitedSlowStart() {
+	t.exitedSlowStart++
+}
+
 var _ = Describe("Cubic Sender", func() {
 	var (
 		sender            SendAlgorithmWithDebugInfo
@@ -811,4 +824,40 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.SlowstartThreshold()).To(Equal(MaxCongestionWindow))
 		Expect(sender.HybridSlowStart().Started()).To(BeFalse())
 	})
+
+	Context("tracing", func() {
+		var tracer *mockTracer
+
+		BeforeEach(func() {
+			tracer = &mockTracer{}
+			sender.(TracingSendAlgorithm).SetTracer(tracer)
+		})
+
+		It("traces congestion window updates in slow start", func() {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+			Expect(tracer.congestionWindows).To(Equal([]protocol.ByteCount{
+				defaultWindowTCP + protocol.DefaultTCPMSS,
+				defaultWindowTCP + 2*protocol.DefaultTCPMSS,
+			}))
+			Expect(tracer.exitedSlowStart).To(BeZero())
+		})
+
+		It("traces exiting slow start when a packet is lost", func() {
+			SendAvailableSendWindow()
+			LoseNPackets(1)
+			expectedSendWindow := protocol.ByteCount(float32(initialCongestionWindowPackets)*sender.RenoBeta()) * protocol.DefaultTCPMSS
+			Expect(tracer.congestionWindows).To(Equal([]protocol.ByteCount{expectedSendWindow}))
+			Expect(tracer.exitedSlowStart).To(Equal(1))
+			// a second loss in the same loss event doesn't change the congestion window
+			LoseNPackets(1)
+			Expect(tracer.congestionWindows).To(HaveLen(1))
+			Expect(tracer.exitedSlowStart).To(Equal(1))
+		})
+
+		It("traces the congestion window reduction after an RTO", func() {
+			sender.OnRetransmissionTimeout(true)
+			Expect(tracer.congestionWindows).To(Equal([]protocol.ByteCount{protocol.ByteCount(defaultMinimumCongestionWindow) * protocol.DefaultTCPMSS}))
+		})
+	})
 })
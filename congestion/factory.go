@@ -0,0 +1,22 @@
+package congestion
+
+var (
+	_ SendAlgorithmFactory = CubicSenderFactory
+	_ SendAlgorithmFactory = RenoSenderFactory
+	_ SendAlgorithmFactory = BBRSenderFactory
+)
+
+// CubicSenderFactory creates a sender using Cubic congestion control
+func CubicSenderFactory(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow PacketNumber) SendAlgorithm {
+	return NewCubicSender(clock, rttStats, false, initialCongestionWindow, maxCongestionWindow)
+}
+
+// RenoSenderFactory creates a sender using Reno congestion control
+func RenoSenderFactory(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow PacketNumber) SendAlgorithm {
+	return NewCubicSender(clock, rttStats, true, initialCongestionWindow, maxCongestionWindow)
+}
+
+// BBRSenderFactory creates a sender using BBR congestion control
+func BBRSenderFactory(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow PacketNumber) SendAlgorithm {
+	return NewBBRSender(clock, rttStats, initialCongestionWindow, maxCongestionWindow)
+}
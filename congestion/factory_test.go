@@ -0,0 +1,30 @@
+package congestion
+
+import (
+	"github.com/seong889/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Send Algorithm Factories", func() {
+	var clock mockClock
+
+	It("creates Cubic senders", func() {
+		sender := CubicSenderFactory(&clock, NewRTTStats(), 10, 100).(*cubicSender)
+		Expect(sender.reno).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * protocol.DefaultTCPMSS))
+		Expect(sender.maxTCPCongestionWindow).To(Equal(PacketNumber(100)))
+	})
+
+	It("creates Reno senders", func() {
+		sender := RenoSenderFactory(&clock, NewRTTStats(), 10, 100).(*cubicSender)
+		Expect(sender.reno).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * protocol.DefaultTCPMSS))
+	})
+
+	It("creates BBR senders", func() {
+		sender := BBRSenderFactory(&clock, NewRTTStats(), 10, 100).(*bbrSender)
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * protocol.DefaultTCPMSS))
+		Expect(sender.maxCongestionWindow).To(Equal(100 * protocol.DefaultTCPMSS))
+	})
+})
@@ -6,6 +6,16 @@ import (
 	"github.com/seong889/quic-go/internal/protocol"
 )
 
+// A ByteCount in QUIC
+type ByteCount = protocol.ByteCount
+
+// A PacketNumber in QUIC
+type PacketNumber = protocol.PacketNumber
+
+// A SendAlgorithmFactory creates a SendAlgorithm for a new connection.
+// The congestion windows are given in packets of the maximum packet size.
+type SendAlgorithmFactory func(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow PacketNumber) SendAlgorithm
+
 // A SendAlgorithm performs congestion control and calculates the congestion window
 type SendAlgorithm interface {
 	TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration
@@ -23,6 +33,24 @@ type SendAlgorithm interface {
 	SetSlowStartLargeReduction(enabled bool)
 }
 
+// A PacingSendAlgorithm is a SendAlgorithm that determines the rate at which packets should be paced
+type PacingSendAlgorithm interface {
+	SendAlgorithm
+	PacingRate(bytesInFlight protocol.ByteCount) Bandwidth
+}
+
+// A Tracer is notified about changes of the congestion controller's state
+type Tracer interface {
+	UpdatedCongestionWindow(congestionWindow ByteCount)
+	ExitedSlowStart()
+}
+
+// A TracingSendAlgorithm is a SendAlgorithm that reports its state changes to a Tracer
+type TracingSendAlgorithm interface {
+	SendAlgorithm
+	SetTracer(Tracer)
+}
+
 // SendAlgorithmWithDebugInfo adds some debug functions to SendAlgorithm
 type SendAlgorithmWithDebugInfo interface {
 	SendAlgorithm
@@ -38,6 +38,8 @@ type RTTStats struct {
 	recentMinRTT     rttSample
 	halfWindowRTT    rttSample
 	quarterWindowRTT rttSample
+
+	logger utils.Logger
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -81,10 +83,23 @@ func (r *RTTStats) SetRecentMinRTTwindow(recentMinRTTwindow time.Duration) {
 	r.recentMinRTTwindow = recentMinRTTwindow
 }
 
+// SetLogger sets the logger used for debug output.
+// If no logger is set, the DefaultLogger is used.
+func (r *RTTStats) SetLogger(logger utils.Logger) {
+	r.logger = logger
+}
+
+func (r *RTTStats) log() utils.Logger {
+	if r.logger == nil {
+		return utils.DefaultLogger
+	}
+	return r.logger
+}
+
 // UpdateRTT updates the RTT based on a new sample.
 func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	if sendDelta == utils.InfDuration || sendDelta <= 0 {
-		utils.Debugf("Ignoring measured sendDelta, because it's is either infinite, zero, or negative: %d", sendDelta/time.Microsecond)
+		r.log().Debugf("Ignoring measured sendDelta, because it's is either infinite, zero, or negative: %d", sendDelta/time.Microsecond)
 		return
 	}
 
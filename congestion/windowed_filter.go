@@ -0,0 +1,75 @@
+package congestion
+
+// A bandwidthFilterSample is a sample tracked by the maxBandwidthFilter
+type bandwidthFilterSample struct {
+	bandwidth Bandwidth
+	time      uint64
+}
+
+// maxBandwidthFilter tracks the maximum bandwidth over a window of round trips.
+// It implements Kathleen Nichols' algorithm for tracking the minimum (or maximum) estimate of a stream of samples over some fixed time interval,
+// as used by Chromium's WindowedFilter.
+// The best, second best and third best estimates are tracked, such that the best estimate can be replaced when it expires.
+type maxBandwidthFilter struct {
+	// Time length of the window, in round trips.
+	windowLength uint64
+	estimates    [3]bandwidthFilterSample
+}
+
+func newMaxBandwidthFilter(windowLength uint64) *maxBandwidthFilter {
+	return &maxBandwidthFilter{windowLength: windowLength}
+}
+
+// Update updates the best estimates with the sample, and expires and updates the best estimates as necessary.
+func (f *maxBandwidthFilter) Update(bandwidth Bandwidth, time uint64) {
+	sample := bandwidthFilterSample{bandwidth: bandwidth, time: time}
+	// Reset all estimates if they have not yet been initialized, if the sample is a new best, or if the newest recorded estimate is too old.
+	if f.estimates[0].bandwidth == 0 || bandwidth >= f.estimates[0].bandwidth || time-f.estimates[2].time > f.windowLength {
+		f.Reset(bandwidth, time)
+		return
+	}
+
+	if bandwidth >= f.estimates[1].bandwidth {
+		f.estimates[1] = sample
+		f.estimates[2] = sample
+	} else if bandwidth >= f.estimates[2].bandwidth {
+		f.estimates[2] = sample
+	}
+
+	// Expire and update estimates as necessary.
+	if time-f.estimates[0].time > f.windowLength {
+		// The best estimate hasn't been updated for an entire window, so promote the second and third best estimates.
+		f.estimates[0] = f.estimates[1]
+		f.estimates[1] = f.estimates[2]
+		f.estimates[2] = sample
+		// The new best estimate might have been recorded a long time ago as well.
+		if time-f.estimates[0].time > f.windowLength {
+			f.estimates[0] = f.estimates[1]
+			f.estimates[1] = f.estimates[2]
+		}
+		return
+	}
+	if f.estimates[1].bandwidth == f.estimates[0].bandwidth && time-f.estimates[1].time > f.windowLength/4 {
+		// A quarter of the window has passed without a better sample, so the second best estimate is taken from the second quarter of the window.
+		f.estimates[1] = sample
+		f.estimates[2] = sample
+		return
+	}
+	if f.estimates[2].bandwidth == f.estimates[1].bandwidth && time-f.estimates[2].time > f.windowLength/2 {
+		// We've passed a half of the window without a better estimate, so take a third best estimate from the second half of the window.
+		f.estimates[2] = sample
+	}
+}
+
+// Reset resets all estimates to the sample
+func (f *maxBandwidthFilter) Reset(bandwidth Bandwidth, time uint64) {
+	sample := bandwidthFilterSample{bandwidth: bandwidth, time: time}
+	f.estimates[0] = sample
+	f.estimates[1] = sample
+	f.estimates[2] = sample
+}
+
+// GetBest returns the best estimate
+func (f *maxBandwidthFilter) GetBest() Bandwidth {
+	return f.estimates[0].bandwidth
+}
@@ -0,0 +1,50 @@
+package congestion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Max Bandwidth Filter", func() {
+	const windowLength = 10
+
+	var f *maxBandwidthFilter
+
+	BeforeEach(func() {
+		f = newMaxBandwidthFilter(windowLength)
+	})
+
+	It("is zero before the first sample", func() {
+		Expect(f.GetBest()).To(BeZero())
+	})
+
+	It("tracks the maximum", func() {
+		f.Update(100, 1)
+		Expect(f.GetBest()).To(Equal(Bandwidth(100)))
+		f.Update(200, 2)
+		Expect(f.GetBest()).To(Equal(Bandwidth(200)))
+		f.Update(150, 3)
+		Expect(f.GetBest()).To(Equal(Bandwidth(200)))
+	})
+
+	It("expires the maximum after the window", func() {
+		f.Update(200, 1)
+		f.Update(150, 5)
+		f.Update(100, 8)
+		Expect(f.GetBest()).To(Equal(Bandwidth(200)))
+		f.Update(50, 1+windowLength+1)
+		Expect(f.GetBest()).To(Equal(Bandwidth(150)))
+	})
+
+	It("resets if all estimates are too old", func() {
+		f.Update(200, 1)
+		f.Update(50, 3*windowLength)
+		Expect(f.GetBest()).To(Equal(Bandwidth(50)))
+	})
+
+	It("resets", func() {
+		f.Update(200, 1)
+		f.Reset(100, 2)
+		Expect(f.GetBest()).To(Equal(Bandwidth(100)))
+	})
+})
@@ -39,7 +39,7 @@ func (c *mockPacketConn) WriteTo(b []byte, addr net.Addr) (n int, err error) {
 func (c *mockPacketConn) Close() error                       { c.closed = true; return nil }
 func (c *mockPacketConn) LocalAddr() net.Addr                { return c.addr }
 func (c *mockPacketConn) SetDeadline(t time.Time) error      { panic("not implemented") }
-func (c *mockPacketConn) SetReadDeadline(t time.Time) error  { panic("not implemented") }
+func (c *mockPacketConn) SetReadDeadline(t time.Time) error  { return nil }
 func (c *mockPacketConn) SetWriteDeadline(t time.Time) error { panic("not implemented") }
 
 var _ net.PacketConn = &mockPacketConn{}
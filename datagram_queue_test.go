@@ -0,0 +1,142 @@
+package quic
+
+import (
+	"errors"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagram Queue", func() {
+	var (
+		queue         *datagramQueue
+		hasDataCalled bool
+	)
+
+	BeforeEach(func() {
+		hasDataCalled = false
+		queue = newDatagramQueue(func() { hasDataCalled = true }, utils.DefaultLogger)
+	})
+
+	Context("sending", func() {
+		It("returns nil if no frame is queued", func() {
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("queues frames", func() {
+			f := &wire.DatagramFrame{Data: []byte("foobar")}
+			Expect(queue.AddAndWait(f)).To(Succeed())
+			Expect(hasDataCalled).To(BeTrue())
+			Expect(queue.Peek()).To(Equal(f))
+			// peeking doesn't remove the frame
+			Expect(queue.Peek()).To(Equal(f))
+			queue.Pop()
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("returns frames in order", func() {
+			f1 := &wire.DatagramFrame{Data: []byte("foo")}
+			f2 := &wire.DatagramFrame{Data: []byte("bar")}
+			Expect(queue.AddAndWait(f1)).To(Succeed())
+			Expect(queue.AddAndWait(f2)).To(Succeed())
+			Expect(queue.Peek()).To(Equal(f1))
+			queue.Pop()
+			Expect(queue.Peek()).To(Equal(f2))
+		})
+
+		It("blocks when the queue is full", func() {
+			for i := 0; i < protocol.DatagramSendQueueLen; i++ {
+				Expect(queue.AddAndWait(&wire.DatagramFrame{})).To(Succeed())
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(queue.AddAndWait(&wire.DatagramFrame{})).To(Succeed())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(queue.Peek()).ToNot(BeNil())
+			queue.Pop()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the error when adding a frame to a closed queue", func() {
+			testErr := errors.New("test error")
+			for i := 0; i < protocol.DatagramSendQueueLen; i++ {
+				Expect(queue.AddAndWait(&wire.DatagramFrame{})).To(Succeed())
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(queue.AddAndWait(&wire.DatagramFrame{})).To(MatchError(testErr))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			queue.CloseWithError(testErr)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("receiving", func() {
+		It("receives datagrams", func() {
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
+			data, err := queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			data, err = queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("bar")))
+		})
+
+		It("copies the data", func() {
+			b := []byte("foobar")
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: b})
+			b[0] = 'l'
+			data, err := queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("drops datagrams when the receive queue is full", func() {
+			for i := 0; i < protocol.DatagramRcvQueueLen+1; i++ {
+				queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte{byte(i)}})
+			}
+			Expect(queue.rcvQueue).To(HaveLen(protocol.DatagramRcvQueueLen))
+			data, err := queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte{0}))
+		})
+
+		It("blocks until a datagram is received", func() {
+			dataChan := make(chan []byte)
+			go func() {
+				defer GinkgoRecover()
+				data, err := queue.Receive()
+				Expect(err).ToNot(HaveOccurred())
+				dataChan <- data
+			}()
+			Consistently(dataChan).ShouldNot(Receive())
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			Eventually(dataChan).Should(Receive(Equal([]byte("foobar"))))
+		})
+
+		It("returns the error when the queue is closed", func() {
+			testErr := errors.New("test error")
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := queue.Receive()
+				Expect(err).To(MatchError(testErr))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			queue.CloseWithError(testErr)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+})
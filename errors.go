@@ -0,0 +1,108 @@
+package quic
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/seong889/quic-go/qerr"
+)
+
+// Application error codes are sent in the CONNECTION_CLOSE and RST_STREAM frame, offset by this value.
+// This way they can't be confused with the error codes used by QUIC itself.
+const applicationErrorCodeOffset qerr.ErrorCode = 0x10000
+
+// An ApplicationError is an error defined by the application protocol.
+// When passed to Session.Close, the error code and the reason phrase are sent to the peer.
+// When passed to Stream.Reset, the error code is sent to the peer.
+// If the peer closes the session with an ApplicationError, it is returned by all calls on the session and its streams.
+type ApplicationError struct {
+	ErrorCode    ErrorCode
+	ReasonPhrase string
+}
+
+var _ error = &ApplicationError{}
+
+func (e *ApplicationError) Error() string {
+	if len(e.ReasonPhrase) == 0 {
+		return fmt.Sprintf("Application error %d", e.ErrorCode)
+	}
+	return fmt.Sprintf("Application error %d: %s", e.ErrorCode, e.ReasonPhrase)
+}
+
+// A StreamError is returned by Read and Write when the peer resets the stream,
+// or asks us to stop sending on it.
+type StreamError struct {
+	StreamID  StreamID
+	ErrorCode ErrorCode
+}
+
+var _ error = &StreamError{}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("Stream %d was reset with error code %d", e.StreamID, e.ErrorCode)
+}
+
+// A StreamTransportError is returned by Read and Write when the peer resets the stream
+// with an error code that was not defined by the application, e.g. QUIC_STREAM_CANCELLED.
+type StreamTransportError struct {
+	StreamID  StreamID
+	ErrorCode uint32
+}
+
+var _ error = &StreamTransportError{}
+
+func (e *StreamTransportError) Error() string {
+	return fmt.Sprintf("Stream %d was reset with transport error code %d", e.StreamID, e.ErrorCode)
+}
+
+// refusedStreamErrorCode is sent in a RST_STREAM frame for streams that the peer opened after we sent a GOAWAY (QUIC_REFUSED_STREAM).
+const refusedStreamErrorCode uint32 = 8
+
+// rstStreamErrorCode returns the error code that is sent in a RST_STREAM frame.
+// Errors that were not defined by the application use error code 0 (QUIC_STREAM_NO_ERROR).
+func rstStreamErrorCode(err error) uint32 {
+	switch e := err.(type) {
+	case *ApplicationError:
+		return applicationRstStreamErrorCode(e.ErrorCode)
+	case *StreamError:
+		return applicationRstStreamErrorCode(e.ErrorCode)
+	}
+	return 0
+}
+
+// applicationRstStreamErrorCode returns the error code that is sent in a RST_STREAM frame for an application error code
+func applicationRstStreamErrorCode(code ErrorCode) uint32 {
+	return uint32(applicationErrorCodeOffset) + uint32(code)
+}
+
+// parseRstStreamError converts the error code of a RST_STREAM frame to an error.
+// Application error codes are returned as a StreamError, all other codes as a StreamTransportError.
+func parseRstStreamError(streamID StreamID, code uint32) error {
+	if code >= uint32(applicationErrorCodeOffset) && code-uint32(applicationErrorCodeOffset) <= math.MaxUint16 {
+		return &StreamError{
+			StreamID:  streamID,
+			ErrorCode: ErrorCode(code - uint32(applicationErrorCodeOffset)),
+		}
+	}
+	return &StreamTransportError{StreamID: streamID, ErrorCode: code}
+}
+
+// toQuicError converts an error to the QuicError that is sent in a CONNECTION_CLOSE frame
+func toQuicError(err error) *qerr.QuicError {
+	if e, ok := err.(*ApplicationError); ok {
+		return qerr.Error(applicationErrorCodeOffset+qerr.ErrorCode(e.ErrorCode), e.ReasonPhrase)
+	}
+	return qerr.ToQuicError(err)
+}
+
+// parseCloseError converts the error code and reason phrase of a CONNECTION_CLOSE frame to an error.
+// Application error codes are returned as an ApplicationError, all other codes as a QuicError.
+func parseCloseError(code qerr.ErrorCode, reason string) error {
+	if code >= applicationErrorCodeOffset && code-applicationErrorCodeOffset <= math.MaxUint16 {
+		return &ApplicationError{
+			ErrorCode:    ErrorCode(code - applicationErrorCodeOffset),
+			ReasonPhrase: reason,
+		}
+	}
+	return qerr.Error(code, reason)
+}
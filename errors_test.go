@@ -0,0 +1,65 @@
+package quic
+
+import (
+	"errors"
+
+	"github.com/seong889/quic-go/qerr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Errors", func() {
+	Context("application errors", func() {
+		It("has a string representation", func() {
+			Expect((&ApplicationError{ErrorCode: 42}).Error()).To(Equal("Application error 42"))
+			Expect((&ApplicationError{ErrorCode: 42, ReasonPhrase: "foobar"}).Error()).To(Equal("Application error 42: foobar"))
+		})
+
+		It("converts to a QuicError", func() {
+			err := toQuicError(&ApplicationError{ErrorCode: 42, ReasonPhrase: "foobar"})
+			Expect(err.ErrorCode).To(Equal(qerr.ErrorCode(0x10000 + 42)))
+			Expect(err.ErrorMessage).To(Equal("foobar"))
+		})
+
+		It("converts other errors to a QuicError", func() {
+			Expect(toQuicError(qerr.Error(qerr.ProofInvalid, "foobar"))).To(Equal(qerr.Error(qerr.ProofInvalid, "foobar")))
+			Expect(toQuicError(errors.New("foobar")).ErrorCode).To(Equal(qerr.InternalError))
+		})
+
+		It("parses application error codes from CONNECTION_CLOSE frames", func() {
+			Expect(parseCloseError(0x10000+42, "foobar")).To(Equal(&ApplicationError{ErrorCode: 42, ReasonPhrase: "foobar"}))
+			Expect(parseCloseError(0x10000+0xffff, "")).To(Equal(&ApplicationError{ErrorCode: 0xffff}))
+		})
+
+		It("parses QUIC error codes from CONNECTION_CLOSE frames", func() {
+			Expect(parseCloseError(qerr.ProofInvalid, "foobar")).To(Equal(qerr.Error(qerr.ProofInvalid, "foobar")))
+			Expect(parseCloseError(0x20000, "foobar")).To(Equal(qerr.Error(0x20000, "foobar")))
+		})
+	})
+
+	Context("stream errors", func() {
+		It("has a string representation", func() {
+			Expect((&StreamError{StreamID: 5, ErrorCode: 42}).Error()).To(Equal("Stream 5 was reset with error code 42"))
+			Expect((&StreamTransportError{StreamID: 5, ErrorCode: 6}).Error()).To(Equal("Stream 5 was reset with transport error code 6"))
+		})
+
+		It("parses application error codes from RST_STREAM frames", func() {
+			Expect(parseRstStreamError(5, 0x10000+42)).To(Equal(&StreamError{StreamID: 5, ErrorCode: 42}))
+			Expect(parseRstStreamError(5, 0x10000+0xffff)).To(Equal(&StreamError{StreamID: 5, ErrorCode: 0xffff}))
+		})
+
+		It("parses transport error codes from RST_STREAM frames", func() {
+			// QUIC_STREAM_CANCELLED
+			Expect(parseRstStreamError(5, 6)).To(Equal(&StreamTransportError{StreamID: 5, ErrorCode: 6}))
+			Expect(parseRstStreamError(5, 0xffff)).To(Equal(&StreamTransportError{StreamID: 5, ErrorCode: 0xffff}))
+			Expect(parseRstStreamError(5, 0x20000)).To(Equal(&StreamTransportError{StreamID: 5, ErrorCode: 0x20000}))
+		})
+	})
+
+	It("tells the error code used for RST_STREAM frames", func() {
+		Expect(rstStreamErrorCode(&ApplicationError{ErrorCode: 42})).To(Equal(uint32(0x10000 + 42)))
+		Expect(rstStreamErrorCode(&StreamError{StreamID: 5, ErrorCode: 1337})).To(Equal(uint32(0x10000 + 1337)))
+		Expect(rstStreamErrorCode(errors.New("foobar"))).To(BeZero())
+	})
+})
@@ -22,6 +22,7 @@ import (
 
 type roundTripperOpts struct {
 	DisableCompression bool
+	Logger             quic.Logger
 }
 
 var dialAddr = quic.DialAddr
@@ -33,6 +34,7 @@ type client struct {
 	tlsConf *tls.Config
 	config  *quic.Config
 	opts    *roundTripperOpts
+	logger  utils.Logger
 
 	hostname        string
 	encryptionLevel protocol.EncryptionLevel
@@ -71,8 +73,9 @@ func newClient(
 		responses:       make(map[protocol.StreamID]chan *http.Response),
 		encryptionLevel: protocol.EncryptionUnencrypted,
 		tlsConf:         tlsConfig,
-		config:          config,
+		config:          configWithLogger(config, opts.Logger),
 		opts:            opts,
+		logger:          getLogger(opts.Logger, config),
 		headerErrored:   make(chan struct{}),
 	}
 }
@@ -90,7 +93,7 @@ func (c *client) dial() error {
 	if err != nil {
 		return err
 	}
-	c.requestWriter = newRequestWriter(c.headerStream)
+	c.requestWriter = newRequestWriter(c.headerStream, c.logger)
 	go c.handleHeaderStream()
 	return nil
 }
@@ -136,7 +139,7 @@ func (c *client) handleHeaderStream() {
 	}
 
 	// stop all running request
-	utils.Debugf("Error handling header stream %d: %s", lastStream, c.headerErr.Error())
+	c.logger.Debugf("Error handling header stream %d: %s", lastStream, c.headerErr.Error())
 	close(c.headerErrored)
 }
 
@@ -14,6 +14,7 @@ import (
 
 	quic "github.com/seong889/quic-go"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/qerr"
 
 	"time"
@@ -42,7 +43,7 @@ var _ = Describe("Client", func() {
 
 		headerStream = newMockStream(3)
 		client.headerStream = headerStream
-		client.requestWriter = newRequestWriter(headerStream)
+		client.requestWriter = newRequestWriter(headerStream, utils.DefaultLogger)
 		var err error
 		req, err = http.NewRequest("GET", "https://localhost:1337", nil)
 		Expect(err).ToNot(HaveOccurred())
@@ -70,6 +71,32 @@ var _ = Describe("Client", func() {
 		Expect(client.config).To(Equal(defaultQuicConfig))
 	})
 
+	It("uses the Logger for the QUIC layer", func() {
+		logger := utils.DefaultLogger.WithField("foo", "bar")
+		quicConf := &quic.Config{HandshakeTimeout: time.Nanosecond}
+		client = newClient("", &tls.Config{}, &roundTripperOpts{Logger: logger}, quicConf)
+		Expect(client.logger).To(Equal(logger))
+		Expect(client.config.Logger).To(Equal(logger))
+		Expect(client.config.HandshakeTimeout).To(Equal(time.Nanosecond))
+		Expect(quicConf.Logger).To(BeNil())
+	})
+
+	It("uses the Logger of the QUIC config, if no Logger is set", func() {
+		logger := utils.DefaultLogger.WithField("foo", "bar")
+		quicConf := &quic.Config{Logger: logger}
+		client = newClient("", &tls.Config{}, &roundTripperOpts{}, quicConf)
+		Expect(client.logger).To(Equal(logger))
+		Expect(client.config).To(Equal(quicConf))
+	})
+
+	It("doesn't overwrite the Logger of the QUIC config", func() {
+		quicLogger := utils.DefaultLogger.WithField("layer", "quic")
+		quicConf := &quic.Config{Logger: quicLogger}
+		client = newClient("", &tls.Config{}, &roundTripperOpts{Logger: utils.DefaultLogger}, quicConf)
+		Expect(client.logger).To(Equal(utils.DefaultLogger))
+		Expect(client.config.Logger).To(Equal(quicLogger))
+	})
+
 	It("adds the port to the hostname, if none is given", func() {
 		client = newClient("quic.clemente.io", nil, &roundTripperOpts{}, nil)
 		Expect(client.hostname).To(Equal("quic.clemente.io:443"))
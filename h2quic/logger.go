@@ -0,0 +1,33 @@
+package h2quic
+
+import (
+	quic "github.com/seong889/quic-go"
+	"github.com/seong889/quic-go/internal/utils"
+)
+
+// getLogger returns the logger used for HTTP-level messages.
+// If no logger is set, it uses the Logger of the quic.Config.
+func getLogger(logger quic.Logger, config *quic.Config) quic.Logger {
+	if logger != nil {
+		return logger
+	}
+	if config != nil && config.Logger != nil {
+		return config.Logger
+	}
+	return utils.DefaultLogger
+}
+
+// configWithLogger returns a quic.Config that uses the logger for the QUIC layer.
+// A Logger set on the quic.Config takes precedence.
+// The quic.Config passed in is never modified.
+func configWithLogger(config *quic.Config, logger quic.Logger) *quic.Config {
+	if logger == nil || (config != nil && config.Logger != nil) {
+		return config
+	}
+	var conf quic.Config
+	if config != nil {
+		conf = *config
+	}
+	conf.Logger = logger
+	return &conf
+}
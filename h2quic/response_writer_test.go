@@ -12,6 +12,7 @@ import (
 	"golang.org/x/net/http2/hpack"
 
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -38,14 +39,17 @@ func newMockStream(id protocol.StreamID) *mockStream {
 	return s
 }
 
-func (s *mockStream) Close() error                          { s.closed = true; s.ctxCancel(); return nil }
-func (s *mockStream) Reset(error)                           { s.reset = true }
-func (s *mockStream) CloseRemote(offset protocol.ByteCount) { s.remoteClosed = true; s.ctxCancel() }
-func (s mockStream) StreamID() protocol.StreamID            { return s.id }
-func (s *mockStream) Context() context.Context              { return s.ctx }
-func (s *mockStream) SetDeadline(time.Time) error           { panic("not implemented") }
-func (s *mockStream) SetReadDeadline(time.Time) error       { panic("not implemented") }
-func (s *mockStream) SetWriteDeadline(time.Time) error      { panic("not implemented") }
+func (s *mockStream) Close() error                                    { s.closed = true; s.ctxCancel(); return nil }
+func (s *mockStream) Reset(error)                                     { s.reset = true }
+func (s *mockStream) CancelRead(protocol.ApplicationErrorCode) error  { panic("not implemented") }
+func (s *mockStream) CancelWrite(protocol.ApplicationErrorCode) error { panic("not implemented") }
+func (s *mockStream) CloseRemote(offset protocol.ByteCount)           { s.remoteClosed = true; s.ctxCancel() }
+func (s mockStream) StreamID() protocol.StreamID                      { return s.id }
+func (s *mockStream) Context() context.Context                        { return s.ctx }
+func (s *mockStream) SetDeadline(time.Time) error                     { panic("not implemented") }
+func (s *mockStream) SetPriority(protocol.StreamUrgency, bool)        { panic("not implemented") }
+func (s *mockStream) SetReadDeadline(time.Time) error                 { panic("not implemented") }
+func (s *mockStream) SetWriteDeadline(time.Time) error                { panic("not implemented") }
 
 func (s *mockStream) Read(p []byte) (int, error) {
 	n, _ := s.dataToRead.Read(p)
@@ -67,7 +71,7 @@ var _ = Describe("Response Writer", func() {
 	BeforeEach(func() {
 		headerStream = &mockStream{}
 		dataStream = &mockStream{}
-		w = newResponseWriter(headerStream, &sync.Mutex{}, dataStream, 5)
+		w = newResponseWriter(headerStream, &sync.Mutex{}, dataStream, 5, utils.DefaultLogger)
 	})
 
 	decodeHeaderFields := func() map[string][]string {
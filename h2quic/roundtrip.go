@@ -41,6 +41,11 @@ type RoundTripper struct {
 	// If nil, reasonable default values will be used.
 	QuicConfig *quic.Config
 
+	// Logger is used for logging.
+	// Unless the QuicConfig sets a Logger, it is also used by the QUIC layer.
+	// If nil, the Logger of the QuicConfig is used, or the default logger if that isn't set either.
+	Logger quic.Logger
+
 	clients map[string]roundTripCloser
 }
 
@@ -120,7 +125,7 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTr
 		if onlyCached {
 			return nil, ErrNoCachedConn
 		}
-		client = newClient(hostname, r.TLSClientConfig, &roundTripperOpts{DisableCompression: r.DisableCompression}, r.QuicConfig)
+		client = newClient(hostname, r.TLSClientConfig, &roundTripperOpts{DisableCompression: r.DisableCompression, Logger: r.Logger}, r.QuicConfig)
 		r.clients[hostname] = client
 	}
 	return client, nil
@@ -14,7 +14,6 @@ import (
 
 	quic "github.com/seong889/quic-go"
 	"github.com/seong889/quic-go/internal/protocol"
-	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/qerr"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
@@ -43,6 +42,11 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
+	// Logger is used for logging.
+	// Unless the QuicConfig sets a Logger, it is also used by the QUIC layer.
+	// If nil, the Logger of the QuicConfig is used, or the default logger if that isn't set either.
+	Logger quic.Logger
+
 	// Private flag for demo, do not use
 	CloseAfterFirstRequest bool
 
@@ -93,12 +97,13 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn net.PacketConn) error {
 		return errors.New("ListenAndServe may only be called once")
 	}
 
+	quicConfig := configWithLogger(s.QuicConfig, s.Logger)
 	var ln quic.Listener
 	var err error
 	if conn == nil {
-		ln, err = quicListenAddr(s.Addr, tlsConfig, s.QuicConfig)
+		ln, err = quicListenAddr(s.Addr, tlsConfig, quicConfig)
 	} else {
-		ln, err = quicListen(conn, tlsConfig, s.QuicConfig)
+		ln, err = quicListen(conn, tlsConfig, quicConfig)
 	}
 	if err != nil {
 		s.listenerMutex.Unlock()
@@ -116,6 +121,10 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn net.PacketConn) error {
 	}
 }
 
+func (s *Server) logger() quic.Logger {
+	return getLogger(s.Logger, s.QuicConfig)
+}
+
 func (s *Server) handleHeaderStream(session streamCreator) {
 	stream, err := session.AcceptStream()
 	if err != nil {
@@ -134,7 +143,7 @@ func (s *Server) handleHeaderStream(session streamCreator) {
 				// In this case, the session has already logged the error, so we don't
 				// need to log it again.
 				if _, ok := err.(*qerr.QuicError); !ok {
-					utils.Errorf("error handling h2 request: %s", err.Error())
+					s.logger().Errorf("error handling h2 request: %s", err.Error())
 				}
 				session.Close(err)
 				return
@@ -157,7 +166,7 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	}
 	headers, err := hpackDecoder.DecodeFull(h2headersFrame.HeaderBlockFragment())
 	if err != nil {
-		utils.Errorf("invalid http2 headers encoding: %s", err.Error())
+		s.logger().Errorf("invalid http2 headers encoding: %s", err.Error())
 		return err
 	}
 
@@ -168,10 +177,11 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 
 	req.RemoteAddr = session.RemoteAddr().String()
 
-	if utils.Debug() {
-		utils.Infof("%s %s%s, on data stream %d", req.Method, req.Host, req.RequestURI, h2headersFrame.StreamID)
+	logger := s.logger()
+	if logger.Debug() {
+		logger.Infof("%s %s%s, on data stream %d", req.Method, req.Host, req.RequestURI, h2headersFrame.StreamID)
 	} else {
-		utils.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
+		logger.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
 	}
 
 	dataStream, err := session.GetOrOpenStream(protocol.StreamID(h2headersFrame.StreamID))
@@ -194,7 +204,7 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	reqBody := newRequestBody(dataStream)
 	req.Body = reqBody
 
-	responseWriter := newResponseWriter(headerStream, headerStreamMutex, dataStream, protocol.StreamID(h2headersFrame.StreamID))
+	responseWriter := newResponseWriter(headerStream, headerStreamMutex, dataStream, protocol.StreamID(h2headersFrame.StreamID), logger)
 
 	go func() {
 		handler := s.Handler
@@ -209,7 +219,7 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 					const size = 64 << 10
 					buf := make([]byte, size)
 					buf = buf[:runtime.Stack(buf, false)]
-					utils.Errorf("http: panic serving: %v\n%s", p, buf)
+					logger.Errorf("http: panic serving: %v\n%s", p, buf)
 					panicked = true
 				}
 			}()
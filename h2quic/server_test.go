@@ -19,6 +19,7 @@ import (
 	quic "github.com/seong889/quic-go"
 	"github.com/seong889/quic-go/internal/protocol"
 	"github.com/seong889/quic-go/internal/testdata"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/qerr"
 
 	. "github.com/onsi/ginkgo"
@@ -55,12 +56,30 @@ func (s *mockSession) OpenStreamSync() (quic.Stream, error) {
 	}
 	return s.OpenStream()
 }
+func (s *mockSession) AcceptUniStream() (quic.ReceiveStream, error) {
+	panic("not implemented")
+}
+func (s *mockSession) OpenUniStream() (quic.SendStream, error) {
+	panic("not implemented")
+}
+func (s *mockSession) OpenUniStreamSync() (quic.SendStream, error) {
+	panic("not implemented")
+}
+func (s *mockSession) SendMessage([]byte) error {
+	panic("not implemented")
+}
+func (s *mockSession) ReceiveMessage() ([]byte, error) {
+	panic("not implemented")
+}
 func (s *mockSession) Close(e error) error {
 	s.closed = true
 	s.closedWithError = e
 	s.ctxCancel()
 	return nil
 }
+func (s *mockSession) CloseGracefully(context.Context) error {
+	panic("not implemented")
+}
 func (s *mockSession) LocalAddr() net.Addr {
 	panic("not implemented")
 }
@@ -70,6 +89,18 @@ func (s *mockSession) RemoteAddr() net.Addr {
 func (s *mockSession) Context() context.Context {
 	return s.ctx
 }
+func (s *mockSession) ConnectionState() quic.ConnectionState {
+	panic("not implemented")
+}
+func (s *mockSession) Stats() quic.ConnectionStats {
+	panic("not implemented")
+}
+func (s *mockSession) HandshakeComplete() <-chan struct{} {
+	panic("not implemented")
+}
+func (s *mockSession) Used0RTT() bool {
+	panic("not implemented")
+}
 
 var _ = Describe("H2 server", func() {
 	var (
@@ -447,6 +478,24 @@ var _ = Describe("H2 server", func() {
 			go s.ListenAndServe()
 			Eventually(func() *quic.Config { return receivedConf }).Should(Equal(conf))
 		})
+
+		It("uses the Logger for the quic server", func() {
+			logger := utils.DefaultLogger.WithField("foo", "bar")
+			conf := &quic.Config{HandshakeTimeout: time.Nanosecond}
+			confChan := make(chan *quic.Config, 1)
+			quicListenAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.Listener, error) {
+				confChan <- config
+				return nil, errors.New("listen err")
+			}
+			s.QuicConfig = conf
+			s.Logger = logger
+			go s.ListenAndServe()
+			var receivedConf *quic.Config
+			Eventually(confChan).Should(Receive(&receivedConf))
+			Expect(receivedConf.Logger).To(Equal(logger))
+			Expect(receivedConf.HandshakeTimeout).To(Equal(time.Nanosecond))
+			Expect(conf.Logger).To(BeNil())
+		})
 	})
 
 	Context("ListenAndServeTLS", func() {
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"sync"
 
 	quic "github.com/seong889/quic-go"
 	"github.com/seong889/quic-go/internal/protocol"
@@ -75,6 +76,32 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	Context("stateless rejects", func() {
+		It("retries the connection with the cookie from the stateless reject", func() {
+			var mutex sync.Mutex
+			var cookies []*quic.Cookie
+			serverConfig.Versions = []protocol.VersionNumber{protocol.Version39}
+			serverConfig.AdmitSession = func(net.Addr) quic.AdmissionDecision { return quic.AdmissionRequireCookie }
+			serverConfig.AcceptCookie = func(_ net.Addr, cookie *quic.Cookie) bool {
+				mutex.Lock()
+				defer mutex.Unlock()
+				cookies = append(cookies, cookie)
+				return cookie != nil
+			}
+			runServer()
+			clientConfig := &quic.Config{Versions: []protocol.VersionNumber{protocol.Version39}}
+			sess, err := quic.DialAddr(server.Addr().String(), &tls.Config{InsecureSkipVerify: true}, clientConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.Close(nil)).To(Succeed())
+			mutex.Lock()
+			defer mutex.Unlock()
+			// the first connection didn't present a cookie, the new connection presents the cookie from the SREJ
+			Expect(len(cookies)).To(BeNumerically(">=", 2))
+			Expect(cookies[0]).To(BeNil())
+			Expect(cookies[1]).ToNot(BeNil())
+		})
+	})
+
 	Context("Certifiate validation", func() {
 		for _, v := range []protocol.VersionNumber{protocol.Version39, protocol.VersionTLS} {
 			version := v
@@ -2,23 +2,106 @@ package quic
 
 import (
 	"context"
+	"crypto/x509"
 	"io"
 	"net"
 	"time"
 
+	"github.com/seong889/quic-go/congestion"
 	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/tracing"
 )
 
 // The StreamID is the ID of a QUIC stream.
 type StreamID = protocol.StreamID
 
+// An ErrorCode is an application-defined error code.
+// It is sent to the peer when canceling the read or the write side of a stream.
+type ErrorCode = protocol.ApplicationErrorCode
+
+// A StreamUrgency is the urgency of a stream, ranging from 0 (most urgent) to 7 (least urgent).
+type StreamUrgency = protocol.StreamUrgency
+
+// A Logger receives the log messages of quic-go.
+// Implementations must be safe for concurrent use.
+type Logger = utils.Logger
+
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
 // A Cookie can be used to verify the ownership of the client address.
 type Cookie = handshake.Cookie
 
+// TransportParameters are the transport parameters sent by a peer during the handshake.
+type TransportParameters = handshake.TransportParameters
+
+// ClientSessionState is the state of a gQUIC handshake that a client caches to resume a connection to the same server.
+type ClientSessionState = handshake.ClientSessionState
+
+// A ClientSessionCache caches the ClientSessionState of gQUIC servers, keyed by the hostname.
+// It must be safe for concurrent use by multiple goroutines.
+type ClientSessionCache = handshake.ClientSessionCache
+
+// ServerConfigParameters are the secrets of a gQUIC server config.
+type ServerConfigParameters = handshake.ServerConfigParameters
+
+// A ServerConfigProvider provides the secrets of the gQUIC server configs, including the secrets used to encrypt Cookies.
+// It must be safe for concurrent use by multiple goroutines.
+type ServerConfigProvider = handshake.ServerConfigProvider
+
+// A StrikeRegister detects replayed gQUIC CHLOs by recording the nonces used in handshakes.
+type StrikeRegister = handshake.StrikeRegister
+
+// A StrikeRegisterStore stores the nonces recorded by a StrikeRegister.
+// It can be backed by an external store shared by multiple servers.
+// It must be safe for concurrent use by multiple goroutines.
+type StrikeRegisterStore = handshake.StrikeRegisterStore
+
+// An AdmissionDecision determines how the server handles a new connection.
+type AdmissionDecision int
+
+const (
+	// AdmissionAccept accepts the connection.
+	AdmissionAccept AdmissionDecision = iota
+	// AdmissionRequireCookie only accepts the connection if the client presents a valid Cookie (see Config.AcceptCookie) in its first packet.
+	// This is the case if the client obtained a Cookie in a previous connection.
+	// Otherwise, the server doesn't create a session, but statelessly responds with a stateless reject (SREJ) containing a Cookie.
+	// The client then starts a new connection, presenting the Cookie in its first packet.
+	// Cookies can only be checked for gQUIC, packets of IETF QUIC clients are dropped.
+	AdmissionRequireCookie
+	// AdmissionReject rejects the connection.
+	// The server doesn't create a session, but statelessly responds with a Public Reset.
+	// Packets of IETF QUIC clients are dropped.
+	AdmissionReject
+)
+
+// ConnectionState records basic details about the QUIC connection.
+// Warning: This API should not be considered stable and might change soon.
+type ConnectionState struct {
+	// Version is the QUIC version used by the connection.
+	Version VersionNumber
+	// HandshakeComplete is true once the handshake is complete.
+	HandshakeComplete bool
+	// Used0RTT is true if the handshake completed without the server rejecting the first client hello.
+	// It is only set for gQUIC connections.
+	Used0RTT bool
+	// ServerName is the server name requested by the client.
+	ServerName string
+	// CipherSuite is the AEAD (AESG or CC20) for gQUIC, and the TLS 1.3 cipher suite for TLS.
+	CipherSuite string
+	// KeyExchange is the key exchange algorithm (C255) used by gQUIC.
+	KeyExchange string
+	// NegotiatedProtocol is the application protocol negotiated using ALPN (TLS only).
+	NegotiatedProtocol string
+	// PeerCertificates is the verified certificate chain presented by the peer.
+	PeerCertificates []*x509.Certificate
+	// PeerTransportParameters are the transport parameters sent by the peer.
+	// It is nil until they have been received.
+	PeerTransportParameters *TransportParameters
+}
+
 // Stream is the interface implemented by QUIC streams
 type Stream interface {
 	// Read reads data from the stream.
@@ -32,7 +115,26 @@ type Stream interface {
 	io.Closer
 	StreamID() StreamID
 	// Reset closes the stream with an error.
+	// If the error is an ApplicationError, its error code is sent to the peer in the RST_STREAM frame.
+	// When the peer resets the stream, Read and Write return a StreamError,
+	// or a StreamTransportError if the error code was not defined by the application.
 	Reset(error)
+	// CancelRead aborts receiving on this stream.
+	// Buffered data is discarded, and for IETF QUIC versions the peer is asked to stop sending (using a STOP_SENDING frame).
+	// Read will unblock immediately, and future Read calls will fail.
+	// The write side of the stream is not affected.
+	CancelRead(ErrorCode) error
+	// CancelWrite aborts sending on this stream.
+	// Data that wasn't sent yet is discarded, and a RST_STREAM frame is sent.
+	// Write will unblock immediately, and future calls to Write will fail.
+	// The read side of the stream is not affected.
+	CancelWrite(ErrorCode) error
+	// SetPriority sets the priority of the stream.
+	// Streams with a lower urgency are served first. The default urgency is 3.
+	// Incremental streams of the same urgency share the bandwidth,
+	// while non-incremental streams are sent one after another, in the order they were opened.
+	// By default, streams are incremental.
+	SetPriority(urgency StreamUrgency, incremental bool)
 	// The context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() is called, or when the stream is reset (either locally or remotely).
 	// Warning: This API should not be considered stable and might change soon.
@@ -53,6 +155,87 @@ type Stream interface {
 	SetDeadline(t time.Time) error
 }
 
+// A SendStream is a unidirectional stream that can only be written to.
+// It is opened using Session.OpenUniStream or Session.OpenUniStreamSync.
+type SendStream interface {
+	// Write writes data to the stream.
+	// Write can be made to time out and return a net.Error with Timeout() == true
+	// after a fixed time limit; see SetWriteDeadline.
+	io.Writer
+	io.Closer
+	StreamID() StreamID
+	// Reset closes the stream with an error.
+	// If the error is an ApplicationError, its error code is sent to the peer in the RST_STREAM frame.
+	Reset(error)
+	// CancelWrite aborts sending on this stream.
+	// Data that wasn't sent yet is discarded, and a RST_STREAM frame is sent.
+	// Write will unblock immediately, and future calls to Write will fail.
+	CancelWrite(ErrorCode) error
+	// SetPriority sets the priority of the stream.
+	// Streams with a lower urgency are served first. The default urgency is 3.
+	// Incremental streams of the same urgency share the bandwidth,
+	// while non-incremental streams are sent one after another, in the order they were opened.
+	// By default, streams are incremental.
+	SetPriority(urgency StreamUrgency, incremental bool)
+	// The context is canceled as soon as the stream is closed.
+	// This happens when Close() is called, or when the stream is reset (either locally or remotely).
+	// Warning: This API should not be considered stable and might change soon.
+	Context() context.Context
+	// SetWriteDeadline sets the deadline for future Write calls
+	// and any currently-blocked Write call.
+	// A zero value for t means Write will not time out.
+	SetWriteDeadline(t time.Time) error
+}
+
+// A ReceiveStream is a unidirectional stream that can only be read from.
+// It is accepted using Session.AcceptUniStream.
+type ReceiveStream interface {
+	// Read reads data from the stream.
+	// Read can be made to time out and return a net.Error with Timeout() == true
+	// after a fixed time limit; see SetReadDeadline.
+	io.Reader
+	StreamID() StreamID
+	// Reset closes the stream with an error, and asks the peer to stop sending.
+	// If the error is an ApplicationError, its error code is sent to the peer in the RST_STREAM frame.
+	Reset(error)
+	// CancelRead aborts receiving on this stream.
+	// Buffered data is discarded, and for IETF QUIC versions the peer is asked to stop sending (using a STOP_SENDING frame).
+	// Read will unblock immediately, and future Read calls will fail.
+	CancelRead(ErrorCode) error
+	// SetReadDeadline sets the deadline for future Read calls and
+	// any currently-blocked Read call.
+	// A zero value for t means Read will not time out.
+	SetReadDeadline(t time.Time) error
+}
+
+// ConnectionStats are statistics about a QUIC connection.
+// Warning: This API should not be considered stable and might change soon.
+type ConnectionStats struct {
+	// The RTT estimates, as used by the congestion controller.
+	SmoothedRTT   time.Duration
+	MinRTT        time.Duration
+	LatestRTT     time.Duration
+	MeanDeviation time.Duration
+	// CongestionWindow is the congestion window, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes that were sent, but neither acknowledged nor declared lost yet.
+	BytesInFlight uint64
+	// PacketsSent and BytesSent count all packets sent, including retransmissions.
+	PacketsSent uint64
+	BytesSent   uint64
+	// PacketsReceived and BytesReceived count all packets that were successfully decrypted.
+	PacketsReceived uint64
+	BytesReceived   uint64
+	// PacketsLost is the number of packets declared lost, either by loss detection or by a retransmission timeout.
+	PacketsLost uint64
+	// RTOCount is the number of retransmission timeouts.
+	RTOCount uint64
+	// Retransmissions is the number of packets that were queued for retransmission.
+	Retransmissions uint64
+	// SendWindow is the connection-level flow control credit that is available for sending.
+	SendWindow uint64
+}
+
 // A Session is a QUIC connection between two peers.
 type Session interface {
 	// AcceptStream returns the next stream opened by the peer, blocking until one is available.
@@ -65,15 +248,52 @@ type Session interface {
 	// OpenStreamSync opens a new QUIC stream, blocking until the peer's concurrent stream limit allows a new stream to be opened.
 	// It always picks the smallest possible stream ID.
 	OpenStreamSync() (Stream, error)
+	// AcceptUniStream returns the next unidirectional stream opened by the peer, blocking until one is available.
+	AcceptUniStream() (ReceiveStream, error)
+	// OpenUniStream opens a new unidirectional QUIC stream.
+	// Unidirectional streams use a separate stream ID space and a separate concurrent stream limit.
+	// If the peer doesn't support unidirectional streams, or its limit is reached, a special error is returned.
+	OpenUniStream() (SendStream, error)
+	// OpenUniStreamSync opens a new unidirectional QUIC stream, blocking until the peer's concurrent stream limit allows a new stream to be opened.
+	OpenUniStreamSync() (SendStream, error)
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
 	RemoteAddr() net.Addr
 	// Close closes the connection. The error will be sent to the remote peer in a CONNECTION_CLOSE frame. An error value of nil is allowed and will cause a normal PeerGoingAway to be sent.
+	// Application-defined error codes can be sent by passing an ApplicationError. The peer will receive the same ApplicationError.
+	// All other errors are converted to a QUIC error code.
 	Close(error) error
+	// CloseGracefully sends a GOAWAY frame to the peer, and closes the connection as soon as all open streams are closed.
+	// New streams opened by the peer are refused, and no new streams can be opened.
+	// If the context is done before all streams are closed, the connection is closed immediately and the context's error is returned.
+	CloseGracefully(context.Context) error
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// ConnectionState returns basic details about the QUIC connection.
+	// Warning: This API should not be considered stable and might change soon.
+	ConnectionState() ConnectionState
+	// HandshakeComplete returns a channel that is closed as soon as the handshake completes.
+	// Sessions returned by DialEarly and ListenEarly can be used before that.
+	// If the handshake fails, the channel is never closed, but the session's context is cancelled.
+	HandshakeComplete() <-chan struct{}
+	// Used0RTT says if the handshake was performed without a round trip for a REJ (gQUIC only).
+	// Data received in 0-RTT can be replayed by an attacker, unless all servers use a shared StrikeRegister.
+	// Applications should defer non-idempotent work until HandshakeComplete is closed.
+	Used0RTT() bool
+	// Stats returns a snapshot of the statistics of the connection.
+	// It is updated every time the session processes an event.
+	// Warning: This API should not be considered stable and might change soon.
+	Stats() ConnectionStats
+	// SendMessage sends an unreliable datagram to the peer.
+	// Datagrams are congestion controlled, but they are never retransmitted.
+	// It returns an error if the datagram extension was not negotiated, or if the message is too large.
+	// Warning: This API is experimental and might change soon.
+	SendMessage([]byte) error
+	// ReceiveMessage returns the next datagram received from the peer, blocking until one is available.
+	// Warning: This API is experimental and might change soon.
+	ReceiveMessage() ([]byte, error)
 }
 
 // A NonFWSession is a QUIC connection between two peers half-way through the handshake.
@@ -93,6 +313,11 @@ type Config struct {
 	// This saves 8 bytes in the Public Header in every packet. However, if the IP address of the server changes, the connection cannot be migrated.
 	// Currently only valid for the client.
 	RequestConnectionIDOmission bool
+	// ClientSessionCache caches the server config, the source address token and the certificate chain of gQUIC servers.
+	// Subsequent connections to the same server can then complete the handshake in 0-RTT.
+	// If not set, every connection performs a full handshake.
+	// This option is only valid for the client.
+	ClientSessionCache ClientSessionCache
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 10 seconds.
@@ -107,6 +332,43 @@ type Config struct {
 	// If not set, it verifies that the address matches, and that the Cookie was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptCookie func(clientAddr net.Addr, cookie *Cookie) bool
+	// ServerConfigProvider provides the secrets of the gQUIC server configs, including the secrets used to encrypt Cookies.
+	// Servers using providers that return the same values (e.g. all servers behind a load balancer) present a consistent crypto identity,
+	// such that clients can use a cached server config and Cookie for a 0-RTT handshake with every one of them.
+	// If not set, the secrets are generated randomly.
+	// This option is only valid for the server.
+	ServerConfigProvider ServerConfigProvider
+	// StrikeRegister records the nonces of gQUIC handshakes, to detect replayed CHLOs.
+	// A CHLO with a nonce that was already used doesn't complete the handshake in 0-RTT, the client then falls back to a 1-RTT handshake.
+	// Servers sharing a ServerConfigProvider should use a StrikeRegister backed by a shared StrikeRegisterStore.
+	// If not set, an in-memory strike register is used.
+	// This option is only valid for the server.
+	StrikeRegister StrikeRegister
+	// MaxIncomingHandshakes is the maximum number of handshakes that are performed concurrently.
+	// New connections exceeding this limit are rejected.
+	// If zero, the number of concurrent handshakes is not limited.
+	// This option is only valid for the server.
+	MaxIncomingHandshakes int
+	// MaxIncomingSessions is the maximum number of sessions, including sessions that are still handshaking.
+	// New connections exceeding this limit are rejected.
+	// If zero, the number of sessions is not limited.
+	// This option is only valid for the server.
+	MaxIncomingSessions int
+	// MaxNewSessionsPerSourcePerSecond is the maximum number of new sessions per second from a single IP address.
+	// New connections exceeding this limit are only accepted if the client presents a valid Cookie.
+	// If zero, the rate of new sessions is not limited.
+	// This option is only valid for the server.
+	MaxNewSessionsPerSourcePerSecond int
+	// AdmitSession is called for every new connection.
+	// If the connection exceeds any of the limits above, the stricter decision is used.
+	// If not set, all connections that don't exceed any limits are accepted.
+	// This option is only valid for the server.
+	AdmitSession func(clientAddr net.Addr) AdmissionDecision
+	// AcceptQueueSize is the maximum number of sessions that completed the handshake, but weren't returned by Listener.Accept yet.
+	// If the queue is full, new sessions are closed with a TooManySessionsOnServer error as soon as the handshake completes.
+	// If zero, the queue size is set to 32.
+	// This option is only valid for the server.
+	AcceptQueueSize int
 	// MaxReceiveStreamFlowControlWindow is the maximum stream-level flow control window for receiving data.
 	// If this value is zero, it will default to 1 MB for the server and 6 MB for the client.
 	MaxReceiveStreamFlowControlWindow uint64
@@ -115,14 +377,63 @@ type Config struct {
 	MaxReceiveConnectionFlowControlWindow uint64
 	// KeepAlive defines whether this peer will periodically send PING frames to keep the connection alive.
 	KeepAlive bool
+	// CongestionControl creates the congestion controller used for every connection.
+	// The congestion package provides factories for Cubic, Reno and BBR.
+	// If not set, Cubic is used.
+	CongestionControl congestion.SendAlgorithmFactory
+	// InitialCongestionWindow is the initial congestion window, in packets.
+	// If this value is zero, it will default to 32 packets.
+	InitialCongestionWindow uint64
+	// MaxCongestionWindow is the maximum congestion window, in packets.
+	// If this value is zero, it will default to 1000 packets.
+	MaxCongestionWindow uint64
+	// EnableDatagrams enables the experimental unreliable datagram extension.
+	// It is only used if both peers enable it.
+	// Datagrams are sent using Session.SendMessage and received using Session.ReceiveMessage.
+	EnableDatagrams bool
+	// Logger is used for logging.
+	// Every session logs using a child logger (see Logger.WithField), tagged with the connection ID, the perspective and the remote address.
+	// If not set, messages are logged using the log package, at the log level set by the QUIC_GO_LOG_LEVEL environment variable.
+	Logger Logger
+	// Tracer is notified about packets, losses, RTT and congestion window updates and encryption level changes.
+	// If not set, sessions are not traced.
+	Tracer tracing.Tracer
+	// KeyLogWriter optionally specifies a destination for the keys derived during the handshake, in the NSS key log format.
+	// Instead of the client random, every line is keyed by the connection ID.
+	// For gQUIC, the initial and the forward-secure keys and IVs are logged.
+	// For TLS, the 1-RTT traffic secrets are logged, but not the TLS handshake secrets.
+	// Use of KeyLogWriter compromises security and should only be used for debugging.
+	KeyLogWriter io.Writer
+	// PacketCapture receives the raw bytes of every packet sent and received, e.g. to write them to a pcapng file (see the pcapng package).
+	// If not set, packets are not captured.
+	PacketCapture tracing.PacketCapture
+}
+
+// ListenerStats are statistics about a Listener.
+// Warning: This API should not be considered stable and might change soon.
+type ListenerStats struct {
+	// AcceptQueueLength is the number of sessions that completed the handshake, but weren't returned by Accept yet.
+	AcceptQueueLength int
+	// AcceptQueueSize is the capacity of the accept queue (see Config.AcceptQueueSize).
+	AcceptQueueSize int
+	// QueuedSessions is the number of sessions that were added to the accept queue.
+	QueuedSessions uint64
+	// RejectedSessions is the number of sessions that were closed because the accept queue was full.
+	RejectedSessions uint64
 }
 
 // A Listener for incoming QUIC connections
 type Listener interface {
 	// Close the server, sending CONNECTION_CLOSE frames to each peer.
 	Close() error
+	// Shutdown stops accepting new connections, and gracefully closes all sessions (see Session.CloseGracefully).
+	// It returns as soon as all sessions are closed, or the context is done.
+	Shutdown(context.Context) error
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new sessions. It should be called in a loop.
 	Accept() (Session, error)
+	// Stats returns statistics about the accept queue.
+	// Warning: This API should not be considered stable and might change soon.
+	Stats() ListenerStats
 }
@@ -45,7 +45,7 @@ var _ = Describe("Cert compression and decompression", func() {
 	It("decompresses empty", func() {
 		compressed, err := compressChain(nil, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
-		uncompressed, err := decompressChain(compressed)
+		uncompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(uncompressed).To(BeEmpty())
 	})
@@ -72,7 +72,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		chain := [][]byte{cert}
 		compressed, err := compressChain(chain, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
-		uncompressed, err := decompressChain(compressed)
+		uncompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(uncompressed).To(Equal(chain))
 	})
@@ -103,7 +103,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		chain := [][]byte{cert1, cert2}
 		compressed, err := compressChain(chain, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
-		decompressed, err := decompressChain(compressed)
+		decompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(decompressed).To(Equal(chain))
 	})
@@ -119,6 +119,35 @@ var _ = Describe("Cert compression and decompression", func() {
 		Expect(compressed).To(Equal(expected))
 	})
 
+	It("decompresses cached certificates", func() {
+		cert := []byte{0xde, 0xca, 0xfb, 0xad}
+		chain := [][]byte{cert}
+		compressed, err := compressChain(chain, nil, byteHash(cert))
+		Expect(err).ToNot(HaveOccurred())
+		decompressed, err := decompressChain(compressed, [][]byte{{0x1, 0x2}, cert})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decompressed).To(Equal(chain))
+	})
+
+	It("errors if a cached certificate is not known", func() {
+		cert := []byte{0xde, 0xca, 0xfb, 0xad}
+		compressed, err := compressChain([][]byte{cert}, nil, byteHash(cert))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = decompressChain(compressed, [][]byte{{0x1, 0x2}})
+		Expect(err).To(MatchError("unexpected cached certificate"))
+	})
+
+	It("decompresses cached certificates and compressed combined", func() {
+		cert1 := []byte{0xde, 0xca, 0xfb, 0xad}
+		cert2 := []byte{0xde, 0xad, 0xbe, 0xef}
+		chain := [][]byte{cert1, cert2}
+		compressed, err := compressChain(chain, nil, byteHash(cert2))
+		Expect(err).ToNot(HaveOccurred())
+		decompressed, err := decompressChain(compressed, [][]byte{cert2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decompressed).To(Equal(chain))
+	})
+
 	It("uses cached certificates and compressed combined", func() {
 		cert1 := []byte{0xde, 0xca, 0xfb, 0xad}
 		cert2 := []byte{0xde, 0xad, 0xbe, 0xef}
@@ -161,7 +190,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		chain := [][]byte{cert}
 		compressed, err := compressChain(chain, setHash, nil)
 		Expect(err).ToNot(HaveOccurred())
-		decompressed, err := decompressChain(compressed)
+		decompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(decompressed).To(Equal(chain))
 	})
@@ -175,7 +204,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		chain := [][]byte{cert1, cert2}
 		compressed, err := compressChain(chain, setHash, nil)
 		Expect(err).ToNot(HaveOccurred())
-		decompressed, err := decompressChain(compressed)
+		decompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(decompressed).To(Equal(chain))
 	})
@@ -207,7 +236,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		compressed, err := compressChain(chain, setHash, nil)
 		Expect(err).ToNot(HaveOccurred())
 		delete(certSets, certsets.CertSet3Hash)
-		_, err = decompressChain(compressed)
+		_, err = decompressChain(compressed, nil)
 		Expect(err).To(MatchError(errors.New("unknown certSet")))
 	})
 
@@ -224,7 +253,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		compressed, err := compressChain(chain, setHash, nil)
 		Expect(err).ToNot(HaveOccurred())
 		certSets[0x1337] = certSet[:1] // delete the last certificate from the certSet
-		_, err = decompressChain(compressed)
+		_, err = decompressChain(compressed, nil)
 		Expect(err).To(MatchError(errors.New("certificate not found in certSet")))
 	})
 
@@ -259,7 +288,7 @@ var _ = Describe("Cert compression and decompression", func() {
 		chain := [][]byte{cert1, cert2}
 		compressed, err := compressChain(chain, setHash, nil)
 		Expect(err).ToNot(HaveOccurred())
-		decompressed, err := decompressChain(compressed)
+		decompressed, err := decompressChain(compressed, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(decompressed).To(Equal(chain))
 	})
@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
 	"errors"
 	"hash/fnv"
 	"time"
@@ -13,8 +14,11 @@ import (
 // CertManager manages the certificates sent by the server
 type CertManager interface {
 	SetData([]byte) error
+	SetChain([][]byte) error
 	GetCommonCertificateHashes() []byte
+	GetCachedCertificateHashes() []byte
 	GetLeafCert() []byte
+	GetChain() []*x509.Certificate
 	GetLeafCertHash() (uint64, error)
 	VerifyServerProof(proof, chlo, serverConfigData []byte) bool
 	Verify(hostname string) error
@@ -35,12 +39,21 @@ func NewCertManager(tlsConfig *tls.Config) CertManager {
 }
 
 // SetData takes the byte-slice sent in the SHLO and decompresses it into the certificate chain
+// The certificates of the current chain can be referenced as cached certificates.
 func (c *certManager) SetData(data []byte) error {
-	byteChain, err := decompressChain(data)
+	cachedCerts := make([][]byte, len(c.chain))
+	for i, cert := range c.chain {
+		cachedCerts[i] = cert.Raw
+	}
+	byteChain, err := decompressChain(data, cachedCerts)
 	if err != nil {
 		return qerr.Error(qerr.InvalidCryptoMessageParameter, "Certificate data invalid")
 	}
+	return c.SetChain(byteChain)
+}
 
+// SetChain sets the certificate chain, e.g. a chain that was cached from a previous connection
+func (c *certManager) SetChain(byteChain [][]byte) error {
 	chain := make([]*x509.Certificate, len(byteChain))
 	for i, data := range byteChain {
 		cert, err := x509.ParseCertificate(data)
@@ -58,6 +71,19 @@ func (c *certManager) GetCommonCertificateHashes() []byte {
 	return getCommonCertificateHashes()
 }
 
+// GetCachedCertificateHashes returns the hashes of the certificates of the current chain, to be sent in the CCRT
+// it returns nil if the certificate chain has not yet been set
+func (c *certManager) GetCachedCertificateHashes() []byte {
+	if len(c.chain) == 0 {
+		return nil
+	}
+	ccrt := make([]byte, 8*len(c.chain))
+	for i, cert := range c.chain {
+		binary.LittleEndian.PutUint64(ccrt[i*8:(i+1)*8], HashCert(cert.Raw))
+	}
+	return ccrt
+}
+
 // GetLeafCert returns the leaf certificate of the certificate chain
 // it returns nil if the certificate chain has not yet been set
 func (c *certManager) GetLeafCert() []byte {
@@ -67,6 +93,12 @@ func (c *certManager) GetLeafCert() []byte {
 	return c.chain[0].Raw
 }
 
+// GetChain returns the certificate chain
+// it returns nil if the certificate chain has not yet been set
+func (c *certManager) GetChain() []*x509.Certificate {
+	return c.chain
+}
+
 // GetLeafCertHash calculates the FNV1a_64 hash of the leaf certificate
 func (c *certManager) GetLeafCertHash() (uint64, error) {
 	leafCert := c.GetLeafCert()
@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/binary"
 	"math/big"
 	"runtime"
 	"time"
@@ -64,6 +65,17 @@ var _ = Describe("Cert Manager", func() {
 			Expect(cm.chain[1].Raw).To(Equal(cert2))
 		})
 
+		It("decompresses a certificate chain referencing cached certificates", func() {
+			err := cm.SetChain([][]byte{cert1, cert2})
+			Expect(err).ToNot(HaveOccurred())
+			compressed, err := compressChain([][]byte{cert1, cert2}, nil, cm.GetCachedCertificateHashes())
+			Expect(err).ToNot(HaveOccurred())
+			err = cm.SetData(compressed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.chain[0].Raw).To(Equal(cert1))
+			Expect(cm.chain[1].Raw).To(Equal(cert2))
+		})
+
 		It("errors if it can't decompress the chain", func() {
 			err := cm.SetData([]byte("invalid data"))
 			Expect(err).To(MatchError(qerr.Error(qerr.InvalidCryptoMessageParameter, "Certificate data invalid")))
@@ -79,6 +91,35 @@ var _ = Describe("Cert Manager", func() {
 		})
 	})
 
+	Context("setting the chain", func() {
+		It("parses the certificates", func() {
+			err := cm.SetChain([][]byte{cert1, cert2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.chain[0].Raw).To(Equal(cert1))
+			Expect(cm.chain[1].Raw).To(Equal(cert2))
+		})
+
+		It("errors if it can't parse a certificate", func() {
+			err := cm.SetChain([][]byte{[]byte("cert1")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("getting the cached certificate hashes", func() {
+		It("returns the hashes of the chain", func() {
+			err := cm.SetChain([][]byte{cert1, cert2})
+			Expect(err).ToNot(HaveOccurred())
+			ccrt := cm.GetCachedCertificateHashes()
+			Expect(ccrt).To(HaveLen(16))
+			Expect(binary.LittleEndian.Uint64(ccrt[:8])).To(Equal(HashCert(cert1)))
+			Expect(binary.LittleEndian.Uint64(ccrt[8:])).To(Equal(HashCert(cert2)))
+		})
+
+		It("returns nil if the chain hasn't been set yet", func() {
+			Expect(cm.GetCachedCertificateHashes()).To(BeNil())
+		})
+	})
+
 	Context("getting the leaf cert", func() {
 		It("gets it", func() {
 			xcert1, err := x509.ParseCertificate(cert1)
@@ -96,6 +137,21 @@ var _ = Describe("Cert Manager", func() {
 		})
 	})
 
+	Context("getting the certificate chain", func() {
+		It("gets it", func() {
+			xcert1, err := x509.ParseCertificate(cert1)
+			Expect(err).ToNot(HaveOccurred())
+			xcert2, err := x509.ParseCertificate(cert2)
+			Expect(err).ToNot(HaveOccurred())
+			cm.chain = []*x509.Certificate{xcert1, xcert2}
+			Expect(cm.GetChain()).To(Equal([]*x509.Certificate{xcert1, xcert2}))
+		})
+
+		It("returns nil if the chain hasn't been set yet", func() {
+			Expect(cm.GetChain()).To(BeNil())
+		})
+	})
+
 	Context("getting the leaf cert hash", func() {
 		It("calculates the FVN1a 64 hash", func() {
 			cm.chain = make([]*x509.Certificate, 1)
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"bytes"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -24,4 +26,20 @@ var _ = Describe("ProofRsa", func() {
 		_, err = a.CalculateSharedKey(nil)
 		Expect(err).To(MatchError("Curve25519: expected public key of 32 byte"))
 	})
+	It("creates the same key from the same private key", func() {
+		privateKey := bytes.Repeat([]byte{0x42}, 32)
+		a, err := NewCurve25519KEXFromPrivateKey(privateKey)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := NewCurve25519KEXFromPrivateKey(privateKey)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.PublicKey()).To(Equal(b.PublicKey()))
+		c, err := NewCurve25519KEX()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.PublicKey()).ToNot(Equal(c.PublicKey()))
+	})
+
+	It("rejects private keys of the wrong length", func() {
+		_, err := NewCurve25519KEXFromPrivateKey(make([]byte, 31))
+		Expect(err).To(MatchError("Curve25519: expected private key of 32 byte"))
+	})
 })
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"io"
+
 	"github.com/bifurcation/mint"
 	"github.com/seong889/quic-go/internal/protocol"
 )
@@ -18,32 +20,60 @@ type TLSExporter interface {
 
 // DeriveAESKeys derives the AES keys and creates a matching AES-GCM AEAD instance
 func DeriveAESKeys(tls TLSExporter, pers protocol.Perspective) (AEAD, error) {
+	return deriveAESKeys(tls, pers, nil, 0)
+}
+
+// NewAESKeyDerivation returns a key derivation function that works like DeriveAESKeys.
+// The 1-RTT secrets are written to the keyLogWriter, if it is not nil.
+func NewAESKeyDerivation(keyLogWriter io.Writer, connID protocol.ConnectionID) func(TLSExporter, protocol.Perspective) (AEAD, error) {
+	return func(tls TLSExporter, pers protocol.Perspective) (AEAD, error) {
+		return deriveAESKeys(tls, pers, keyLogWriter, connID)
+	}
+}
+
+func deriveAESKeys(tls TLSExporter, pers protocol.Perspective, keyLogWriter io.Writer, connID protocol.ConnectionID) (AEAD, error) {
 	var myLabel, otherLabel string
+	var myKeyLogLabel, otherKeyLogLabel string
 	if pers == protocol.PerspectiveClient {
 		myLabel = clientExporterLabel
 		otherLabel = serverExporterLabel
+		myKeyLogLabel = keyLogLabelClientTrafficSecret
+		otherKeyLogLabel = keyLogLabelServerTrafficSecret
 	} else {
 		myLabel = serverExporterLabel
 		otherLabel = clientExporterLabel
+		myKeyLogLabel = keyLogLabelServerTrafficSecret
+		otherKeyLogLabel = keyLogLabelClientTrafficSecret
 	}
-	myKey, myIV, err := computeKeyAndIV(tls, myLabel)
+	mySecret, err := computeSecret(tls, myLabel)
 	if err != nil {
 		return nil, err
 	}
-	otherKey, otherIV, err := computeKeyAndIV(tls, otherLabel)
+	otherSecret, err := computeSecret(tls, otherLabel)
 	if err != nil {
 		return nil, err
 	}
+	if keyLogWriter != nil {
+		if err := writeKeyLog(keyLogWriter, myKeyLogLabel, connID, mySecret); err != nil {
+			return nil, err
+		}
+		if err := writeKeyLog(keyLogWriter, otherKeyLogLabel, connID, otherSecret); err != nil {
+			return nil, err
+		}
+	}
+	cs := tls.GetCipherSuite()
+	myKey, myIV := computeKeyAndIV(cs, mySecret)
+	otherKey, otherIV := computeKeyAndIV(cs, otherSecret)
 	return NewAEADAESGCM(otherKey, myKey, otherIV, myIV)
 }
 
-func computeKeyAndIV(tls TLSExporter, label string) (key, iv []byte, err error) {
+func computeSecret(tls TLSExporter, label string) ([]byte, error) {
 	cs := tls.GetCipherSuite()
-	secret, err := tls.ComputeExporter(label, nil, cs.Hash.Size())
-	if err != nil {
-		return nil, nil, err
-	}
+	return tls.ComputeExporter(label, nil, cs.Hash.Size())
+}
+
+func computeKeyAndIV(cs mint.CipherSuiteParams, secret []byte) (key, iv []byte) {
 	key = mint.HkdfExpandLabel(cs.Hash, secret, "key", nil, cs.KeyLen)
 	iv = mint.HkdfExpandLabel(cs.Hash, secret, "iv", nil, cs.IvLen)
-	return key, iv, nil
+	return key, iv
 }
@@ -22,6 +22,18 @@ import (
 
 // DeriveQuicCryptoAESKeys derives the client and server keys and creates a matching AES-GCM AEAD instance
 func DeriveQuicCryptoAESKeys(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
+	return deriveQuicCryptoAESKeys(nil, forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, pers)
+}
+
+// NewQuicCryptoAESKeyDerivation returns a key derivation function that works like DeriveQuicCryptoAESKeys.
+// The derived keys and IVs are written to the keyLogWriter, if it is not nil.
+func NewQuicCryptoAESKeyDerivation(keyLogWriter io.Writer) func(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
+	return func(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
+		return deriveQuicCryptoAESKeys(keyLogWriter, forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, pers)
+	}
+}
+
+func deriveQuicCryptoAESKeys(keyLogWriter io.Writer, forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
 	var swap bool
 	if pers == protocol.PerspectiveClient {
 		swap = true
@@ -30,9 +42,31 @@ func DeriveQuicCryptoAESKeys(forwardSecure bool, sharedSecret, nonces []byte, co
 	if err != nil {
 		return nil, err
 	}
+	if keyLogWriter != nil {
+		clientKey, serverKey, clientIV, serverIV := myKey, otherKey, myIV, otherIV
+		if pers == protocol.PerspectiveServer {
+			clientKey, serverKey, clientIV, serverIV = otherKey, myKey, otherIV, myIV
+		}
+		if err := logQuicCryptoKeys(keyLogWriter, forwardSecure, connID, clientKey, serverKey, clientIV, serverIV); err != nil {
+			return nil, err
+		}
+	}
 	return NewAEADAESGCM12(otherKey, myKey, otherIV, myIV)
 }
 
+func logQuicCryptoKeys(w io.Writer, forwardSecure bool, connID protocol.ConnectionID, clientKey, serverKey, clientIV, serverIV []byte) error {
+	labels := []string{keyLogLabelClientInitialKey, keyLogLabelClientInitialIV, keyLogLabelServerInitialKey, keyLogLabelServerInitialIV}
+	if forwardSecure {
+		labels = []string{keyLogLabelClientForwardSecureKey, keyLogLabelClientForwardSecureIV, keyLogLabelServerForwardSecureKey, keyLogLabelServerForwardSecureIV}
+	}
+	for i, secret := range [][]byte{clientKey, clientIV, serverKey, serverIV} {
+		if err := writeKeyLog(w, labels[i], connID, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // deriveKeys derives the keys and the IVs
 // swap should be set true if generating the values for the client, and false for the server
 func deriveKeys(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo, scfg, cert, divNonce []byte, keyLen int, swap bool) ([]byte, []byte, []byte, []byte, error) {
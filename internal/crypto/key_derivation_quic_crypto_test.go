@@ -1,6 +1,9 @@
 package crypto
 
 import (
+	"bytes"
+	"strings"
+
 	"github.com/seong889/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
@@ -194,4 +197,51 @@ var _ = Describe("QUIC Crypto Key Derivation", func() {
 			Expect(aesgcm.otherIV).To(Equal([]byte{0xf2, 0x7a, 0xcc, 0x42}))
 		})
 	})
+
+	Context("key log", func() {
+		derive := func(w *bytes.Buffer, forwardSecure bool, pers protocol.Perspective) {
+			_, err := NewQuicCryptoAESKeyDerivation(w)(
+				forwardSecure,
+				[]byte("0123456789012345678901"),
+				[]byte("nonce"),
+				protocol.ConnectionID(0x2a00000000000000),
+				[]byte("chlo"),
+				[]byte("scfg"),
+				[]byte("cert"),
+				[]byte("divnonce"),
+				pers,
+			)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		It("logs the initial keys", func() {
+			buf := &bytes.Buffer{}
+			derive(buf, false, protocol.PerspectiveServer)
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(MatchRegexp("^GQUIC_CLIENT_INITIAL_KEY 2a00000000000000 [0-9a-f]{32}$"))
+			Expect(lines[1]).To(Equal("GQUIC_CLIENT_INITIAL_IV 2a00000000000000 64ef3c09"))
+			Expect(lines[2]).To(MatchRegexp("^GQUIC_SERVER_INITIAL_KEY 2a00000000000000 [0-9a-f]{32}$"))
+			Expect(lines[3]).To(Equal("GQUIC_SERVER_INITIAL_IV 2a00000000000000 1cecac9b"))
+		})
+
+		It("logs the forward-secure keys", func() {
+			buf := &bytes.Buffer{}
+			derive(buf, true, protocol.PerspectiveServer)
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HavePrefix("GQUIC_CLIENT_FORWARD_SECURE_KEY 2a00000000000000 "))
+			Expect(lines[1]).To(Equal("GQUIC_CLIENT_FORWARD_SECURE_IV 2a00000000000000 f27acc42"))
+			Expect(lines[2]).To(HavePrefix("GQUIC_SERVER_FORWARD_SECURE_KEY 2a00000000000000 "))
+			Expect(lines[3]).To(Equal("GQUIC_SERVER_FORWARD_SECURE_IV 2a00000000000000 07adabb8"))
+		})
+
+		It("logs the same keys on the client and on the server side", func() {
+			clientBuf := &bytes.Buffer{}
+			serverBuf := &bytes.Buffer{}
+			derive(clientBuf, false, protocol.PerspectiveClient)
+			derive(serverBuf, false, protocol.PerspectiveServer)
+			Expect(clientBuf.String()).To(Equal(serverBuf.String()))
+		})
+	})
 })
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto"
 	"errors"
 
@@ -61,4 +62,17 @@ var _ = Describe("Key Derivation", func() {
 		_, err := DeriveAESKeys(&mockTLSExporter{hash: crypto.SHA256, computerError: testErr}, protocol.PerspectiveClient)
 		Expect(err).To(MatchError(testErr))
 	})
+
+	It("logs the 1-RTT secrets", func() {
+		clientBuf := &bytes.Buffer{}
+		serverBuf := &bytes.Buffer{}
+		_, err := NewAESKeyDerivation(clientBuf, 0x1337)(&mockTLSExporter{hash: crypto.SHA256}, protocol.PerspectiveClient)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = NewAESKeyDerivation(serverBuf, 0x1337)(&mockTLSExporter{hash: crypto.SHA256}, protocol.PerspectiveServer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientBuf.String()).To(ContainSubstring("QUIC_CLIENT_TRAFFIC_SECRET_0 0000000000001337 %x\n", clientExporterLabel))
+		Expect(clientBuf.String()).To(ContainSubstring("QUIC_SERVER_TRAFFIC_SECRET_0 0000000000001337 %x\n", serverExporterLabel))
+		Expect(serverBuf.String()).To(ContainSubstring("QUIC_CLIENT_TRAFFIC_SECRET_0 0000000000001337 %x\n", clientExporterLabel))
+		Expect(serverBuf.String()).To(ContainSubstring("QUIC_SERVER_TRAFFIC_SECRET_0 0000000000001337 %x\n", serverExporterLabel))
+	})
 })
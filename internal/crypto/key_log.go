@@ -0,0 +1,37 @@
+package crypto
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/seong889/quic-go/internal/protocol"
+)
+
+// Labels used in the key log
+const (
+	keyLogLabelClientTrafficSecret = "QUIC_CLIENT_TRAFFIC_SECRET_0"
+	keyLogLabelServerTrafficSecret = "QUIC_SERVER_TRAFFIC_SECRET_0"
+
+	keyLogLabelClientInitialKey       = "GQUIC_CLIENT_INITIAL_KEY"
+	keyLogLabelClientInitialIV        = "GQUIC_CLIENT_INITIAL_IV"
+	keyLogLabelServerInitialKey       = "GQUIC_SERVER_INITIAL_KEY"
+	keyLogLabelServerInitialIV        = "GQUIC_SERVER_INITIAL_IV"
+	keyLogLabelClientForwardSecureKey = "GQUIC_CLIENT_FORWARD_SECURE_KEY"
+	keyLogLabelClientForwardSecureIV  = "GQUIC_CLIENT_FORWARD_SECURE_IV"
+	keyLogLabelServerForwardSecureKey = "GQUIC_SERVER_FORWARD_SECURE_KEY"
+	keyLogLabelServerForwardSecureIV  = "GQUIC_SERVER_FORWARD_SECURE_IV"
+)
+
+// The same io.Writer is usually used by many sessions.
+// Like crypto/tls, we use a global mutex to serialize writes.
+var keyLogMutex sync.Mutex
+
+// writeKeyLog writes a line in the NSS key log format.
+// Instead of the client random, the line is keyed by the connection ID.
+func writeKeyLog(w io.Writer, label string, connID protocol.ConnectionID, secret []byte) error {
+	keyLogMutex.Lock()
+	defer keyLogMutex.Unlock()
+	_, err := fmt.Fprintf(w, "%s %016x %x\n", label, uint64(connID), secret)
+	return err
+}
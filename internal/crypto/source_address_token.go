@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
@@ -35,6 +36,15 @@ func NewStkSource() (StkSource, error) {
 	if _, err := rand.Read(secret); err != nil {
 		return nil, err
 	}
+	return NewStkSourceFromSecret(secret)
+}
+
+// NewStkSourceFromSecret creates a source for source address tokens, using the given secret.
+// Sources created with the same secret can decode each other's tokens.
+func NewStkSourceFromSecret(secret []byte) (StkSource, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("STK secret must not be empty")
+	}
 	key, err := deriveKey(secret)
 	if err != nil {
 		return nil, err
@@ -37,5 +37,24 @@ var _ = Describe("Source Address Tokens", func() {
 			_, err := source.DecodeToken(nil)
 			Expect(err).To(MatchError("STK too short: 0"))
 		})
+
+		It("decodes tokens created by a source with the same secret", func() {
+			s1, err := NewStkSourceFromSecret([]byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+			s2, err := NewStkSourceFromSecret([]byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+			token, err := s1.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			data, err := s2.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			_, err = source.DecodeToken(token)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects empty secrets", func() {
+			_, err := NewStkSourceFromSecret(nil)
+			Expect(err).To(MatchError("STK secret must not be empty"))
+		})
 	})
 })
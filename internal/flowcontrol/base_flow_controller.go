@@ -14,6 +14,8 @@ type baseFlowController struct {
 
 	rttStats *congestion.RTTStats
 
+	logger utils.Logger
+
 	bytesSent  protocol.ByteCount
 	sendWindow protocol.ByteCount
 
@@ -20,6 +20,8 @@ type StreamFlowController interface {
 	// UpdateHighestReceived should be called when a new highest offset is received
 	// final has to be to true if this is the final offset of the stream, as contained in a STREAM frame with FIN bit, and the RST_STREAM frame
 	UpdateHighestReceived(offset protocol.ByteCount, final bool) error
+	// Abandon should be called when the application is not interested in reading any more data from the stream
+	Abandon()
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
@@ -18,6 +18,8 @@ type streamFlowController struct {
 	contributesToConnection bool // does the stream contribute to connection level flow control
 
 	receivedFinalOffset bool
+	// abandoned is set when the application stops reading from the stream
+	abandoned bool
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -31,6 +33,7 @@ func NewStreamFlowController(
 	maxReceiveWindow protocol.ByteCount,
 	initialSendWindow protocol.ByteCount,
 	rttStats *congestion.RTTStats,
+	logger utils.Logger,
 ) StreamFlowController {
 	return &streamFlowController{
 		streamID:                streamID,
@@ -38,6 +41,7 @@ func NewStreamFlowController(
 		connection:              cfc.(connectionFlowControllerI),
 		baseFlowController: baseFlowController{
 			rttStats:                  rttStats,
+			logger:                    logger,
 			receiveWindow:             receiveWindow,
 			receiveWindowIncrement:    receiveWindow,
 			maxReceiveWindowIncrement: maxReceiveWindow,
@@ -81,8 +85,17 @@ func (c *streamFlowController) UpdateHighestReceived(byteOffset protocol.ByteCou
 	if c.checkFlowControlViolation() {
 		return qerr.Error(qerr.FlowControlReceivedTooMuchData, fmt.Sprintf("Received %d bytes on stream %d, allowed %d bytes", byteOffset, c.streamID, c.receiveWindow))
 	}
+	if c.abandoned {
+		// nobody is going to read this data, so consider it consumed right away
+		c.bytesRead += increment
+	}
 	if c.contributesToConnection {
-		return c.connection.IncrementHighestReceived(increment)
+		if err := c.connection.IncrementHighestReceived(increment); err != nil {
+			return err
+		}
+		if c.abandoned {
+			c.connection.AddBytesRead(increment)
+		}
 	}
 	return nil
 }
@@ -94,6 +107,24 @@ func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) {
 	}
 }
 
+// Abandon is called when the application stops reading from the stream.
+// All data received so far is considered consumed, such that it doesn't block connection-level flow control,
+// and no more window updates are sent for this stream.
+func (c *streamFlowController) Abandon() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.abandoned {
+		return
+	}
+	c.abandoned = true
+	unread := c.highestReceived - c.bytesRead
+	c.bytesRead = c.highestReceived
+	if c.contributesToConnection {
+		c.connection.AddBytesRead(unread)
+	}
+}
+
 func (c *streamFlowController) AddBytesSent(n protocol.ByteCount) {
 	c.baseFlowController.AddBytesSent(n)
 	if c.contributesToConnection {
@@ -116,10 +147,13 @@ func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.abandoned {
+		return 0
+	}
 	oldWindowIncrement := c.receiveWindowIncrement
 	offset := c.baseFlowController.getWindowUpdate()
 	if c.receiveWindowIncrement > oldWindowIncrement { // auto-tuning enlarged the window increment
-		utils.Debugf("Increasing receive flow control window for the connection to %d kB", c.receiveWindowIncrement/(1<<10))
+		c.logger.Debugf("Increasing receive flow control window for the connection to %d kB", c.receiveWindowIncrement/(1<<10))
 		if c.contributesToConnection {
 			c.connection.EnsureMinimumWindowIncrement(protocol.ByteCount(float64(c.receiveWindowIncrement) * protocol.ConnectionFlowControlMultiplier))
 		}
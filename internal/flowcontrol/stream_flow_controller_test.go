@@ -5,6 +5,7 @@ import (
 
 	"github.com/seong889/quic-go/congestion"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/qerr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,10 +18,11 @@ var _ = Describe("Stream Flow controller", func() {
 		rttStats := &congestion.RTTStats{}
 		controller = &streamFlowController{
 			streamID:   10,
-			connection: NewConnectionFlowController(1000, 1000, rttStats).(*connectionFlowController),
+			connection: NewConnectionFlowController(1000, 1000, rttStats, utils.DefaultLogger).(*connectionFlowController),
 		}
 		controller.maxReceiveWindowIncrement = 10000
 		controller.rttStats = rttStats
+		controller.logger = utils.DefaultLogger
 	})
 
 	Context("Constructor", func() {
@@ -31,8 +33,8 @@ var _ = Describe("Stream Flow controller", func() {
 			maxReceiveWindow := protocol.ByteCount(3000)
 			sendWindow := protocol.ByteCount(4000)
 
-			cc := NewConnectionFlowController(0, 0, nil)
-			fc := NewStreamFlowController(5, true, cc, receiveWindow, maxReceiveWindow, sendWindow, rttStats).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, true, cc, receiveWindow, maxReceiveWindow, sendWindow, rttStats, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowIncrement).To(Equal(maxReceiveWindow))
@@ -156,6 +158,53 @@ var _ = Describe("Stream Flow controller", func() {
 			})
 		})
 
+		Context("abandoning", func() {
+			BeforeEach(func() {
+				controller.contributesToConnection = true
+				controller.receiveWindow = 10000
+				controller.receiveWindowIncrement = 600
+			})
+
+			It("considers all received data as read", func() {
+				err := controller.UpdateHighestReceived(1000, false)
+				Expect(err).ToNot(HaveOccurred())
+				controller.AddBytesRead(100)
+				controller.Abandon()
+				Expect(controller.bytesRead).To(Equal(protocol.ByteCount(1000)))
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(1000)))
+			})
+
+			It("considers data received after abandoning as read", func() {
+				controller.Abandon()
+				err := controller.UpdateHighestReceived(300, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(controller.bytesRead).To(Equal(protocol.ByteCount(300)))
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(300)))
+			})
+
+			It("doesn't count data twice when abandoning multiple times", func() {
+				err := controller.UpdateHighestReceived(1000, false)
+				Expect(err).ToNot(HaveOccurred())
+				controller.Abandon()
+				controller.Abandon()
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(1000)))
+			})
+
+			It("doesn't send window updates", func() {
+				controller.receiveWindow = 1000
+				err := controller.UpdateHighestReceived(900, false)
+				Expect(err).ToNot(HaveOccurred())
+				controller.Abandon()
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+			})
+
+			It("still detects flow control violations", func() {
+				controller.Abandon()
+				err := controller.UpdateHighestReceived(10001, false)
+				Expect(err).To(MatchError("FlowControlReceivedTooMuchData: Received 10001 bytes on stream 10, allowed 10000 bytes"))
+			})
+		})
+
 		Context("generating window updates", func() {
 			var oldIncrement protocol.ByteCount
 
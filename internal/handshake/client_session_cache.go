@@ -0,0 +1,60 @@
+package handshake
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// ClientSessionState is the state of a gQUIC handshake that a client caches to resume a connection to the same server.
+// With this state, the first CHLO of a new connection is a full CHLO, and the handshake completes without a round trip for a REJ.
+type ClientSessionState struct {
+	// ServerConfig is the serialized server config (SCFG)
+	ServerConfig []byte
+	// STK is the source address token
+	STK []byte
+	// Certificates is the DER-encoded certificate chain of the server, leaf certificate first.
+	// It was verified when the state was cached.
+	Certificates [][]byte
+}
+
+// A ClientSessionCache caches the ClientSessionState of gQUIC servers, keyed by the hostname.
+// It must be safe for concurrent use by multiple goroutines.
+type ClientSessionCache interface {
+	// Get returns the ClientSessionState for a hostname
+	Get(hostname string) (*ClientSessionState, bool)
+	// Put stores the ClientSessionState for a hostname.
+	// It is called with a nil state if a cached state could not be used, which should remove the entry.
+	Put(hostname string, state *ClientSessionState)
+}
+
+type lruClientSessionCache struct {
+	cache *lru.Cache
+}
+
+var _ ClientSessionCache = &lruClientSessionCache{}
+
+// NewLRUClientSessionCache returns a ClientSessionCache that holds the state of up to capacity servers, evicting the least recently used entries
+func NewLRUClientSessionCache(capacity int) ClientSessionCache {
+	cache, err := lru.New(capacity)
+	if err != nil {
+		panic(fmt.Sprintf("fatal error in quic-go: could not create lru cache: %s", err.Error()))
+	}
+	return &lruClientSessionCache{cache: cache}
+}
+
+func (c *lruClientSessionCache) Get(hostname string) (*ClientSessionState, bool) {
+	state, ok := c.cache.Get(hostname)
+	if !ok {
+		return nil, false
+	}
+	return state.(*ClientSessionState), true
+}
+
+func (c *lruClientSessionCache) Put(hostname string, state *ClientSessionState) {
+	if state == nil {
+		c.cache.Remove(hostname)
+		return
+	}
+	c.cache.Add(hostname, state)
+}
@@ -0,0 +1,57 @@
+package handshake
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LRU Client Session Cache", func() {
+	var cache ClientSessionCache
+
+	BeforeEach(func() {
+		cache = NewLRUClientSessionCache(2)
+	})
+
+	It("returns a stored session state", func() {
+		state := &ClientSessionState{STK: []byte("stk")}
+		cache.Put("hostname", state)
+		s, ok := cache.Get("hostname")
+		Expect(ok).To(BeTrue())
+		Expect(s).To(Equal(state))
+	})
+
+	It("doesn't return a session state for unknown hostnames", func() {
+		s, ok := cache.Get("hostname")
+		Expect(ok).To(BeFalse())
+		Expect(s).To(BeNil())
+	})
+
+	It("replaces session states", func() {
+		cache.Put("hostname", &ClientSessionState{STK: []byte("foo")})
+		cache.Put("hostname", &ClientSessionState{STK: []byte("bar")})
+		s, ok := cache.Get("hostname")
+		Expect(ok).To(BeTrue())
+		Expect(s.STK).To(Equal([]byte("bar")))
+	})
+
+	It("removes a session state when putting nil", func() {
+		cache.Put("hostname", &ClientSessionState{})
+		cache.Put("hostname", nil)
+		_, ok := cache.Get("hostname")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("evicts the least recently used session state", func() {
+		cache.Put("host1", &ClientSessionState{})
+		cache.Put("host2", &ClientSessionState{})
+		_, ok := cache.Get("host1")
+		Expect(ok).To(BeTrue())
+		cache.Put("host3", &ClientSessionState{})
+		_, ok = cache.Get("host2")
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Get("host1")
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get("host3")
+		Expect(ok).To(BeTrue())
+	})
+})
@@ -11,11 +11,13 @@ type cookieHandler struct {
 	callback func(net.Addr, *Cookie) bool
 
 	cookieGenerator *CookieGenerator
+
+	logger utils.Logger
 }
 
 var _ mint.CookieHandler = &cookieHandler{}
 
-func newCookieHandler(callback func(net.Addr, *Cookie) bool) (*cookieHandler, error) {
+func newCookieHandler(callback func(net.Addr, *Cookie) bool, logger utils.Logger) (*cookieHandler, error) {
 	cookieGenerator, err := NewCookieGenerator()
 	if err != nil {
 		return nil, err
@@ -23,6 +25,7 @@ func newCookieHandler(callback func(net.Addr, *Cookie) bool) (*cookieHandler, er
 	return &cookieHandler{
 		callback:        callback,
 		cookieGenerator: cookieGenerator,
+		logger:          logger,
 	}, nil
 }
 
@@ -36,7 +39,7 @@ func (h *cookieHandler) Generate(conn *mint.Conn) ([]byte, error) {
 func (h *cookieHandler) Validate(conn *mint.Conn, token []byte) bool {
 	data, err := h.cookieGenerator.DecodeToken(token)
 	if err != nil {
-		utils.Debugf("Couldn't decode cookie from %s: %s", conn.RemoteAddr(), err.Error())
+		h.logger.Debugf("Couldn't decode cookie from %s: %s", conn.RemoteAddr(), err.Error())
 		return false
 	}
 	return h.callback(conn.RemoteAddr(), data)
@@ -4,6 +4,7 @@ import (
 	"net"
 
 	"github.com/bifurcation/mint"
+	"github.com/seong889/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -21,7 +22,7 @@ var _ = Describe("Cookie Handler", func() {
 	BeforeEach(func() {
 		callbackReturn = false
 		var err error
-		ch, err = newCookieHandler(mockCallback)
+		ch, err = newCookieHandler(mockCallback, utils.DefaultLogger)
 		Expect(err).ToNot(HaveOccurred())
 		addr := &net.UDPAddr{IP: net.IPv4(42, 43, 44, 45), Port: 46}
 		conn = mint.NewConn(&fakeConn{remoteAddr: addr}, &mint.Config{}, false)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -38,10 +39,13 @@ type cryptoSetupClient struct {
 	lastSentCHLO     []byte
 	certManager      crypto.CertManager
 
+	sessionCache ClientSessionCache
+
 	divNonceChan         chan []byte
 	diversificationNonce []byte
 
 	clientHelloCounter int
+	receivedREJ        bool
 	serverVerified     bool // has the certificate chain and the proof already been verified
 	keyDerivation      QuicCryptoKeyDerivationFunction
 	keyExchange        KeyExchangeFunction
@@ -50,11 +54,14 @@ type cryptoSetupClient struct {
 	nullAEAD             crypto.AEAD
 	secureAEAD           crypto.AEAD
 	forwardSecureAEAD    crypto.AEAD
+	peerCertificates     []*x509.Certificate
 
 	paramsChan  chan<- TransportParameters
 	aeadChanged chan<- protocol.EncryptionLevel
 
 	params *TransportParameters
+
+	logger utils.Logger
 }
 
 var _ CryptoSetup = &cryptoSetupClient{}
@@ -65,6 +72,17 @@ var (
 	errConflictingDiversificationNonces = errors.New("Received two different diversification nonces")
 )
 
+// A StatelessRejectError is returned by HandleCryptoStream when the server rejected the connection statelessly (SREJ).
+// The client has to start a new connection using the ConnectionID designated by the server, and present the STK in its CHLO.
+type StatelessRejectError struct {
+	ConnectionID protocol.ConnectionID
+	STK          []byte
+}
+
+func (e *StatelessRejectError) Error() string {
+	return fmt.Sprintf("stateless reject, new connection ID: %x", e.ConnectionID)
+}
+
 // NewCryptoSetupClient creates a new CryptoSetup instance for a client
 func NewCryptoSetupClient(
 	cryptoStream io.ReadWriter,
@@ -77,6 +95,10 @@ func NewCryptoSetupClient(
 	aeadChanged chan<- protocol.EncryptionLevel,
 	initialVersion protocol.VersionNumber,
 	negotiatedVersions []protocol.VersionNumber,
+	stk []byte,
+	keyLogWriter io.Writer,
+	sessionCache ClientSessionCache,
+	logger utils.Logger,
 ) (CryptoSetup, error) {
 	nullAEAD, err := crypto.NewNullAEAD(protocol.PerspectiveClient, connID, version)
 	if err != nil {
@@ -89,14 +111,17 @@ func NewCryptoSetupClient(
 		version:            version,
 		certManager:        crypto.NewCertManager(tlsConfig),
 		params:             params,
-		keyDerivation:      crypto.DeriveQuicCryptoAESKeys,
+		keyDerivation:      crypto.NewQuicCryptoAESKeyDerivation(keyLogWriter),
 		keyExchange:        getEphermalKEX,
 		nullAEAD:           nullAEAD,
 		paramsChan:         paramsChan,
 		aeadChanged:        aeadChanged,
 		initialVersion:     initialVersion,
 		negotiatedVersions: negotiatedVersions,
+		stk:                stk,
 		divNonceChan:       make(chan []byte),
+		sessionCache:       sessionCache,
+		logger:             logger,
 	}, nil
 }
 
@@ -104,6 +129,8 @@ func (h *cryptoSetupClient) HandleCryptoStream() error {
 	messageChan := make(chan HandshakeMessage)
 	errorChan := make(chan error)
 
+	h.restoreSessionState()
+
 	go func() {
 		for {
 			message, err := ParseHandshakeMessage(h.cryptoStream)
@@ -146,17 +173,23 @@ func (h *cryptoSetupClient) HandleCryptoStream() error {
 			return err
 		}
 
-		utils.Debugf("Got %s", message)
+		h.logger.Debugf("Got %s", message)
 		switch message.Tag {
 		case TagREJ:
+			h.mutex.Lock()
+			h.receivedREJ = true
+			h.mutex.Unlock()
 			if err := h.handleREJMessage(message.Data); err != nil {
 				return err
 			}
+		case TagSREJ:
+			return h.handleSREJMessage(message.Data)
 		case TagSHLO:
 			params, err := h.handleSHLOMessage(message.Data)
 			if err != nil {
 				return err
 			}
+			h.saveSessionState()
 			// blocks until the session has received the parameters
 			h.paramsChan <- *params
 			h.aeadChanged <- protocol.EncryptionForwardSecure
@@ -180,6 +213,7 @@ func (h *cryptoSetupClient) handleREJMessage(cryptoData map[Tag][]byte) error {
 
 	// TODO: what happens if the server sends a different server config in two packets?
 	if scfg, ok := cryptoData[TagSCFG]; ok {
+		oldServerConfig := h.serverConfig
 		h.serverConfig, err = parseServerConfig(scfg)
 		if err != nil {
 			return err
@@ -189,6 +223,16 @@ func (h *cryptoSetupClient) handleREJMessage(cryptoData map[Tag][]byte) error {
 			return qerr.CryptoServerConfigExpired
 		}
 
+		// The server sent a different server config than the one we had, e.g. the one restored from the ClientSessionCache.
+		// Since the REJ is not authenticated, the whole server config is compared, not only its ID.
+		// The client nonce has to be generated from the OBIT of the new server config, and the new server config has to be verified.
+		if oldServerConfig != nil && !bytes.Equal(oldServerConfig.Get(), h.serverConfig.Get()) {
+			h.nonc = nil
+			h.proof = nil
+			h.chloForSignature = nil
+			h.serverVerified = false
+		}
+
 		// now that we have a server config, we can use its OBIT value to generate a client nonce
 		if len(h.nonc) == 0 {
 			err = h.generateClientNonce()
@@ -211,7 +255,7 @@ func (h *cryptoSetupClient) handleREJMessage(cryptoData map[Tag][]byte) error {
 
 		err = h.certManager.Verify(h.hostname)
 		if err != nil {
-			utils.Infof("Certificate validation failed: %s", err.Error())
+			h.logger.Infof("Certificate validation failed: %s", err.Error())
 			return qerr.ProofInvalid
 		}
 	}
@@ -219,7 +263,7 @@ func (h *cryptoSetupClient) handleREJMessage(cryptoData map[Tag][]byte) error {
 	if h.serverConfig != nil && len(h.proof) != 0 && h.certManager.GetLeafCert() != nil {
 		validProof := h.certManager.VerifyServerProof(h.proof, h.chloForSignature, h.serverConfig.Get())
 		if !validProof {
-			utils.Infof("Server proof verification failed")
+			h.logger.Infof("Server proof verification failed")
 			return qerr.ProofInvalid
 		}
 
@@ -229,6 +273,32 @@ func (h *cryptoSetupClient) handleREJMessage(cryptoData map[Tag][]byte) error {
 	return nil
 }
 
+// handleSREJMessage handles a stateless reject
+// It is only accepted as the first response of the server.
+func (h *cryptoSetupClient) handleSREJMessage(cryptoData map[Tag][]byte) error {
+	h.mutex.RLock()
+	receivedResponse := h.receivedREJ || h.receivedSecurePacket
+	h.mutex.RUnlock()
+	if receivedResponse {
+		return qerr.Error(qerr.InvalidCryptoMessageType, "unexpected SREJ")
+	}
+	stk, ok := cryptoData[TagSTK]
+	if !ok {
+		return qerr.Error(qerr.CryptoMessageParameterNotFound, "STK")
+	}
+	rcid, ok := cryptoData[TagRCID]
+	if !ok {
+		return qerr.Error(qerr.CryptoMessageParameterNotFound, "RCID")
+	}
+	if len(rcid) != 8 {
+		return qerr.Error(qerr.InvalidCryptoMessageParameter, "RCID")
+	}
+	return &StatelessRejectError{
+		ConnectionID: protocol.ConnectionID(binary.LittleEndian.Uint64(rcid)),
+		STK:          stk,
+	}
+}
+
 func (h *cryptoSetupClient) handleSHLOMessage(cryptoData map[Tag][]byte) (*TransportParameters, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -277,6 +347,7 @@ func (h *cryptoSetupClient) handleSHLOMessage(cryptoData map[Tag][]byte) (*Trans
 	if err != nil {
 		return nil, err
 	}
+	h.peerCertificates = h.certManager.GetChain()
 
 	params, err := readHelloMap(cryptoData)
 	if err != nil {
@@ -336,6 +407,23 @@ func (h *cryptoSetupClient) Open(dst, src []byte, packetNumber protocol.PacketNu
 	return res, protocol.EncryptionUnencrypted, nil
 }
 
+// ConnectionState returns the state of the connection
+func (h *cryptoSetupClient) ConnectionState() ConnectionState {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	state := ConnectionState{
+		HandshakeComplete: h.forwardSecureAEAD != nil,
+		Used0RTT:          h.forwardSecureAEAD != nil && !h.receivedREJ,
+		ServerName:        h.hostname,
+		PeerCertificates:  h.peerCertificates,
+	}
+	if h.forwardSecureAEAD != nil {
+		state.CipherSuite = "AESG"
+		state.KeyExchange = "C255"
+	}
+	return state
+}
+
 func (h *cryptoSetupClient) GetSealer() (protocol.EncryptionLevel, Sealer) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
@@ -403,7 +491,7 @@ func (h *cryptoSetupClient) sendCHLO() error {
 		Data: tags,
 	}
 
-	utils.Debugf("Sending %s", message)
+	h.logger.Debugf("Sending %s", message)
 	message.Write(b)
 
 	_, err = h.cryptoStream.Write(b.Bytes())
@@ -424,6 +512,10 @@ func (h *cryptoSetupClient) getTags() (map[Tag][]byte, error) {
 	if len(ccs) > 0 {
 		tags[TagCCS] = ccs
 	}
+	ccrt := h.certManager.GetCachedCertificateHashes()
+	if len(ccrt) > 0 {
+		tags[TagCCRT] = ccrt
+	}
 
 	versionTag := make([]byte, 4)
 	binary.BigEndian.PutUint32(versionTag, uint32(h.initialVersion))
@@ -507,6 +599,73 @@ func (h *cryptoSetupClient) maybeUpgradeCrypto() error {
 	return nil
 }
 
+// restoreSessionState restores the server config, the STK and the certificate chain cached from a previous connection to the same server.
+// The first CHLO then is a full CHLO, allowing the server to complete the handshake without sending a REJ.
+func (h *cryptoSetupClient) restoreSessionState() {
+	if h.sessionCache == nil {
+		return
+	}
+	state, ok := h.sessionCache.Get(h.hostname)
+	if !ok {
+		return
+	}
+	if err := h.restoreSessionStateImpl(state); err != nil {
+		h.logger.Debugf("Not using the cached session state for %s: %s", h.hostname, err)
+		h.sessionCache.Put(h.hostname, nil)
+	}
+}
+
+func (h *cryptoSetupClient) restoreSessionStateImpl(state *ClientSessionState) error {
+	serverConfig, err := parseServerConfig(state.ServerConfig)
+	if err != nil {
+		return err
+	}
+	if serverConfig.IsExpired() {
+		return qerr.CryptoServerConfigExpired
+	}
+	if err := h.certManager.SetChain(state.Certificates); err != nil {
+		return err
+	}
+	// the certificate might have expired since it was cached
+	if err := h.certManager.Verify(h.hostname); err != nil {
+		_ = h.certManager.SetChain(nil)
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.serverConfig = serverConfig
+	if err := h.generateClientNonce(); err != nil {
+		h.serverConfig = nil
+		_ = h.certManager.SetChain(nil)
+		return err
+	}
+	// a STK received in a stateless reject is more recent than the cached one
+	if len(h.stk) == 0 {
+		h.stk = state.STK
+	}
+	// the proof was verified when the state was cached
+	h.serverVerified = true
+	return nil
+}
+
+// saveSessionState saves the server config, the STK and the certificate chain to the ClientSessionCache
+func (h *cryptoSetupClient) saveSessionState() {
+	if h.sessionCache == nil || h.serverConfig == nil {
+		return
+	}
+	chain := h.certManager.GetChain()
+	certs := make([][]byte, len(chain))
+	for i, cert := range chain {
+		certs[i] = cert.Raw
+	}
+	h.sessionCache.Put(h.hostname, &ClientSessionState{
+		ServerConfig: h.serverConfig.Get(),
+		STK:          h.stk,
+		Certificates: certs,
+	})
+}
+
 func (h *cryptoSetupClient) generateClientNonce() error {
 	if len(h.nonc) > 0 {
 		return errClientNonceAlreadyExists
@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"bytes"
+	"crypto/x509"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -32,9 +33,14 @@ type mockCertManager struct {
 	setDataCalledWith []byte
 	setDataError      error
 
+	setChainCalledWith [][]byte
+	setChainError      error
+
 	commonCertificateHashes []byte
+	cachedCertificateHashes []byte
 
 	leafCert          []byte
+	chain             []*x509.Certificate
 	leafCertHash      uint64
 	leafCertHashError error
 
@@ -50,14 +56,27 @@ func (m *mockCertManager) SetData(data []byte) error {
 	return m.setDataError
 }
 
+func (m *mockCertManager) SetChain(chain [][]byte) error {
+	m.setChainCalledWith = chain
+	return m.setChainError
+}
+
 func (m *mockCertManager) GetCommonCertificateHashes() []byte {
 	return m.commonCertificateHashes
 }
 
+func (m *mockCertManager) GetCachedCertificateHashes() []byte {
+	return m.cachedCertificateHashes
+}
+
 func (m *mockCertManager) GetLeafCert() []byte {
 	return m.leafCert
 }
 
+func (m *mockCertManager) GetChain() []*x509.Certificate {
+	return m.chain
+}
+
 func (m *mockCertManager) GetLeafCertHash() (uint64, error) {
 	return m.leafCertHash, m.leafCertHashError
 }
@@ -72,6 +91,29 @@ func (m *mockCertManager) Verify(hostname string) error {
 	return m.verifyError
 }
 
+type mockClientSessionCache struct {
+	states map[string]*ClientSessionState
+}
+
+var _ ClientSessionCache = &mockClientSessionCache{}
+
+func newMockClientSessionCache() *mockClientSessionCache {
+	return &mockClientSessionCache{states: make(map[string]*ClientSessionState)}
+}
+
+func (c *mockClientSessionCache) Get(hostname string) (*ClientSessionState, bool) {
+	state, ok := c.states[hostname]
+	return state, ok
+}
+
+func (c *mockClientSessionCache) Put(hostname string, state *ClientSessionState) {
+	if state == nil {
+		delete(c.states, hostname)
+		return
+	}
+	c.states[hostname] = state
+}
+
 var _ = Describe("Client Crypto Setup", func() {
 	var (
 		cs                      *cryptoSetupClient
@@ -120,6 +162,10 @@ var _ = Describe("Client Crypto Setup", func() {
 			aeadChanged,
 			protocol.Version39,
 			nil,
+			nil,
+			nil,
+			nil,
+			utils.DefaultLogger,
 		)
 		Expect(err).ToNot(HaveOccurred())
 		cs = csInt.(*cryptoSetupClient)
@@ -365,6 +411,47 @@ var _ = Describe("Client Crypto Setup", func() {
 		})
 	})
 
+	Context("Reading SREJ", func() {
+		var tagMap map[Tag][]byte
+
+		BeforeEach(func() {
+			tagMap = map[Tag][]byte{
+				TagSTK:  []byte("foobar"),
+				TagRCID: []byte{0xad, 0xfb, 0xca, 0xde, 0, 0, 0, 0},
+			}
+		})
+
+		It("returns the new connection ID and the STK", func() {
+			HandshakeMessage{Tag: TagSREJ, Data: tagMap}.Write(&stream.dataToRead)
+			err := cs.HandleCryptoStream()
+			Expect(err).To(Equal(&StatelessRejectError{ConnectionID: 0xdecafbad, STK: []byte("foobar")}))
+		})
+
+		It("errors if the SREJ doesn't contain an STK", func() {
+			delete(tagMap, TagSTK)
+			err := cs.handleSREJMessage(tagMap)
+			Expect(err).To(MatchError(qerr.Error(qerr.CryptoMessageParameterNotFound, "STK")))
+		})
+
+		It("errors if the SREJ doesn't contain a connection ID", func() {
+			delete(tagMap, TagRCID)
+			err := cs.handleSREJMessage(tagMap)
+			Expect(err).To(MatchError(qerr.Error(qerr.CryptoMessageParameterNotFound, "RCID")))
+		})
+
+		It("errors if the connection ID has the wrong length", func() {
+			tagMap[TagRCID] = []byte{1, 2, 3, 4}
+			err := cs.handleSREJMessage(tagMap)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidCryptoMessageParameter, "RCID")))
+		})
+
+		It("errors if it already received a REJ", func() {
+			cs.receivedREJ = true
+			err := cs.handleSREJMessage(tagMap)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidCryptoMessageType, "unexpected SREJ")))
+		})
+	})
+
 	Context("Reading SHLO", func() {
 		BeforeEach(func() {
 			kex, err := crypto.NewCurve25519KEX()
@@ -423,6 +510,27 @@ var _ = Describe("Client Crypto Setup", func() {
 			Expect(cs.forwardSecureAEAD).ToNot(BeNil())
 		})
 
+		It("reports the connection state", func() {
+			certManager.chain = []*x509.Certificate{{Raw: []byte("leaf")}}
+			Expect(cs.ConnectionState().HandshakeComplete).To(BeFalse())
+			_, err := cs.handleSHLOMessage(shloMap)
+			Expect(err).ToNot(HaveOccurred())
+			state := cs.ConnectionState()
+			Expect(state.HandshakeComplete).To(BeTrue())
+			Expect(state.Used0RTT).To(BeTrue())
+			Expect(state.ServerName).To(Equal("hostname"))
+			Expect(state.CipherSuite).To(Equal("AESG"))
+			Expect(state.KeyExchange).To(Equal("C255"))
+			Expect(state.PeerCertificates).To(Equal(certManager.chain))
+		})
+
+		It("doesn't report 0-RTT if the server sent a REJ", func() {
+			cs.receivedREJ = true
+			_, err := cs.handleSHLOMessage(shloMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cs.ConnectionState().Used0RTT).To(BeFalse())
+		})
+
 		It("reads the connection paramaters", func() {
 			shloMap[TagICSL] = []byte{13, 0, 0, 0} // 13 seconds
 			params, err := cs.handleSHLOMessage(shloMap)
@@ -520,6 +628,19 @@ var _ = Describe("Client Crypto Setup", func() {
 			}
 		})
 
+		It("sends the hashes of the cached certificates", func() {
+			certManager.cachedCertificateHashes = []byte("cached")
+			tags, err := cs.getTags()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags[TagCCRT]).To(Equal([]byte("cached")))
+		})
+
+		It("doesn't send a CCRT if there are no cached certificates", func() {
+			tags, err := cs.getTags()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags).ToNot(HaveKey(TagCCRT))
+		})
+
 		It("doesn't send a CCS if there are no common certificate sets available", func() {
 			certManager.commonCertificateHashes = nil
 			tags, err := cs.getTags()
@@ -593,6 +714,173 @@ var _ = Describe("Client Crypto Setup", func() {
 		})
 	})
 
+	Context("caching the session state", func() {
+		var (
+			sessionCache *mockClientSessionCache
+			state        *ClientSessionState
+		)
+
+		getServerConfig := func(id byte) []byte {
+			kex, err := crypto.NewCurve25519KEX()
+			Expect(err).ToNot(HaveOccurred())
+			scfg := getDefaultServerConfigClient()
+			scfg[TagSCID] = bytes.Repeat([]byte{id}, 16)
+			scfg[TagPUBS] = append([]byte{0x20, 0x00, 0x00}, kex.PublicKey()...)
+			b := &bytes.Buffer{}
+			HandshakeMessage{Tag: TagSCFG, Data: scfg}.Write(b)
+			return b.Bytes()
+		}
+
+		BeforeEach(func() {
+			sessionCache = newMockClientSessionCache()
+			cs.sessionCache = sessionCache
+			state = &ClientSessionState{
+				ServerConfig: getServerConfig('F'),
+				STK:          []byte("stk"),
+				Certificates: [][]byte{[]byte("leaf"), []byte("intermediate")},
+			}
+		})
+
+		It("restores a cached session state", func() {
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			Expect(certManager.setChainCalledWith).To(Equal(state.Certificates))
+			Expect(certManager.verifyCalled).To(BeTrue())
+			Expect(cs.serverConfig).ToNot(BeNil())
+			Expect(cs.serverConfig.Get()).To(Equal(state.ServerConfig))
+			Expect(cs.stk).To(Equal([]byte("stk")))
+			Expect(cs.nonc).To(HaveLen(32))
+			Expect(cs.serverVerified).To(BeTrue())
+		})
+
+		It("sends a full CHLO using the cached session state", func() {
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			certManager.leafCert = []byte("leaf")
+			tags, err := cs.getTags()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags[TagSCID]).To(Equal(bytes.Repeat([]byte{'F'}, 16)))
+			Expect(tags[TagSTK]).To(Equal([]byte("stk")))
+			Expect(tags[TagNONC]).To(Equal(cs.nonc))
+			Expect(tags).To(HaveKey(TagPUBS))
+			Expect(tags).To(HaveKey(TagXLCT))
+		})
+
+		It("sends the STK from a stateless reject instead of the cached one", func() {
+			sessionCache.Put("hostname", state)
+			cs.stk = []byte("stk from the SREJ")
+			cs.restoreSessionState()
+			tags, err := cs.getTags()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags[TagSTK]).To(Equal([]byte("stk from the SREJ")))
+		})
+
+		It("doesn't do anything if there's no cached session state for the hostname", func() {
+			sessionCache.Put("other-hostname", state)
+			cs.restoreSessionState()
+			Expect(cs.serverConfig).To(BeNil())
+			Expect(cs.serverVerified).To(BeFalse())
+			Expect(sessionCache.states).To(HaveKey("other-hostname"))
+		})
+
+		It("removes cached session states with an expired server config", func() {
+			scfg := getDefaultServerConfigClient()
+			scfg[TagEXPY] = []byte{0x80, 0x54, 0x72, 0x4F, 0, 0, 0, 0} // 2012-03-28
+			b := &bytes.Buffer{}
+			HandshakeMessage{Tag: TagSCFG, Data: scfg}.Write(b)
+			state.ServerConfig = b.Bytes()
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			Expect(cs.serverConfig).To(BeNil())
+			Expect(cs.serverVerified).To(BeFalse())
+			Expect(sessionCache.states).To(BeEmpty())
+		})
+
+		It("removes cached session states with an invalid server config", func() {
+			state.ServerConfig = []byte("invalid")
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			Expect(cs.serverConfig).To(BeNil())
+			Expect(sessionCache.states).To(BeEmpty())
+		})
+
+		It("removes cached session states if the certificate chain is not valid anymore", func() {
+			certManager.verifyError = errors.New("expired")
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			Expect(certManager.setChainCalledWith).To(BeNil())
+			Expect(cs.serverConfig).To(BeNil())
+			Expect(cs.stk).To(BeEmpty())
+			Expect(cs.serverVerified).To(BeFalse())
+			Expect(sessionCache.states).To(BeEmpty())
+		})
+
+		It("regenerates the client nonce if the server rejects the cached server config", func() {
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			nonc := cs.nonc
+			err := cs.handleREJMessage(map[Tag][]byte{TagSCFG: getServerConfig('G')})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cs.serverConfig.ID).To(Equal(bytes.Repeat([]byte{'G'}, 16)))
+			Expect(cs.nonc).To(HaveLen(32))
+			Expect(cs.nonc).ToNot(Equal(nonc))
+			Expect(cs.serverVerified).To(BeFalse())
+		})
+
+		It("requires a new proof if the server sends a different server config with the same ID", func() {
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			cs.proof = []byte("old proof")
+			nonc := cs.nonc
+			scfg := getServerConfig('F')
+			Expect(scfg).ToNot(Equal(state.ServerConfig))
+			err := cs.handleREJMessage(map[Tag][]byte{TagSCFG: scfg})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cs.serverConfig.ID).To(Equal(bytes.Repeat([]byte{'F'}, 16)))
+			Expect(cs.serverConfig.Get()).To(Equal(scfg))
+			Expect(cs.nonc).ToNot(Equal(nonc))
+			Expect(cs.proof).To(BeEmpty())
+			Expect(cs.serverVerified).To(BeFalse())
+			// the crypto is not upgraded before the new server config is verified
+			certManager.leafCert = []byte("leaf")
+			cs.diversificationNonce = []byte("div")
+			cs.lastSentCHLO = []byte("last CHLO")
+			Expect(cs.maybeUpgradeCrypto()).To(Succeed())
+			Expect(cs.secureAEAD).To(BeNil())
+		})
+
+		It("keeps the cached session state if the server sends the same server config", func() {
+			sessionCache.Put("hostname", state)
+			cs.restoreSessionState()
+			nonc := cs.nonc
+			err := cs.handleREJMessage(map[Tag][]byte{TagSCFG: state.ServerConfig})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cs.nonc).To(Equal(nonc))
+			Expect(cs.serverVerified).To(BeTrue())
+		})
+
+		It("saves the session state when receiving the SHLO", func() {
+			kex, err := crypto.NewCurve25519KEX()
+			Expect(err).ToNot(HaveOccurred())
+			cs.serverConfig = &serverConfigClient{kex: kex, raw: []byte("raw server config")}
+			cs.stk = []byte("new stk")
+			cs.receivedSecurePacket = true
+			certManager.chain = []*x509.Certificate{{Raw: []byte("leaf")}, {Raw: []byte("intermediate")}}
+			HandshakeMessage{Tag: TagSHLO, Data: shloMap}.Write(&stream.dataToRead)
+			go func() {
+				defer GinkgoRecover()
+				err := cs.HandleCryptoStream()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			Eventually(aeadChanged).Should(BeClosed())
+			Expect(sessionCache.states).To(HaveKeyWithValue("hostname", &ClientSessionState{
+				ServerConfig: []byte("raw server config"),
+				STK:          []byte("new stk"),
+				Certificates: [][]byte{[]byte("leaf"), []byte("intermediate")},
+			}))
+		})
+	})
+
 	Context("escalating crypto", func() {
 		doCompleteREJ := func() {
 			cs.serverVerified = true
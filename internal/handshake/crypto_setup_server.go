@@ -25,7 +25,7 @@ type KeyExchangeFunction func() crypto.KeyExchange
 type cryptoSetupServer struct {
 	connID               protocol.ConnectionID
 	remoteAddr           net.Addr
-	scfg                 *ServerConfig
+	scfgs                *ServerConfigManager
 	diversificationNonce []byte
 
 	version           protocol.VersionNumber
@@ -39,6 +39,7 @@ type cryptoSetupServer struct {
 	receivedForwardSecurePacket bool
 	receivedSecurePacket        bool
 	sentSHLO                    chan struct{} // this channel is closed as soon as the SHLO has been written
+	sentREJ                     bool
 
 	receivedParams bool
 	paramsChan     chan<- TransportParameters
@@ -51,6 +52,12 @@ type cryptoSetupServer struct {
 
 	params *TransportParameters
 
+	sni  string
+	aead string
+	kexs string
+
+	logger utils.Logger
+
 	mutex sync.RWMutex
 }
 
@@ -65,18 +72,23 @@ var ErrHOLExperiment = qerr.Error(qerr.InvalidCryptoMessageParameter, "HOL exper
 // This is an experiment implemented by Chrome in QUIC 38, which we don't support at this point.
 var ErrNSTPExperiment = qerr.Error(qerr.InvalidCryptoMessageParameter, "NSTP experiment. Unsupported")
 
+// errReplayedCHLO is returned by handleCHLO if the nonce of the CHLO was already used
+var errReplayedCHLO = errors.New("CHLO nonce already used")
+
 // NewCryptoSetup creates a new CryptoSetup instance for a server
 func NewCryptoSetup(
 	cryptoStream io.ReadWriter,
 	connID protocol.ConnectionID,
 	remoteAddr net.Addr,
 	version protocol.VersionNumber,
-	scfg *ServerConfig,
+	scfgs *ServerConfigManager,
 	params *TransportParameters,
 	supportedVersions []protocol.VersionNumber,
 	acceptSTK func(net.Addr, *Cookie) bool,
 	paramsChan chan<- TransportParameters,
 	aeadChanged chan<- protocol.EncryptionLevel,
+	keyLogWriter io.Writer,
+	logger utils.Logger,
 ) (CryptoSetup, error) {
 	nullAEAD, err := crypto.NewNullAEAD(protocol.PerspectiveServer, connID, version)
 	if err != nil {
@@ -88,8 +100,8 @@ func NewCryptoSetup(
 		remoteAddr:        remoteAddr,
 		version:           version,
 		supportedVersions: supportedVersions,
-		scfg:              scfg,
-		keyDerivation:     crypto.DeriveQuicCryptoAESKeys,
+		scfgs:             scfgs,
+		keyDerivation:     crypto.NewQuicCryptoAESKeyDerivation(keyLogWriter),
 		keyExchange:       getEphermalKEX,
 		nullAEAD:          nullAEAD,
 		params:            params,
@@ -97,6 +109,7 @@ func NewCryptoSetup(
 		sentSHLO:          make(chan struct{}),
 		paramsChan:        paramsChan,
 		aeadChanged:       aeadChanged,
+		logger:            logger,
 	}, nil
 }
 
@@ -112,7 +125,7 @@ func (h *cryptoSetupServer) HandleCryptoStream() error {
 			return qerr.InvalidCryptoMessageType
 		}
 
-		utils.Debugf("Got %s", message)
+		h.logger.Debugf("Got %s", message)
 		done, err := h.handleMessage(chloData.Bytes(), message.Data)
 		if err != nil {
 			return err
@@ -158,7 +171,7 @@ func (h *cryptoSetupServer) handleMessage(chloData []byte, cryptoData map[Tag][]
 	var reply []byte
 	var err error
 
-	certUncompressed, err := h.scfg.certChain.GetLeafCert(sni)
+	certUncompressed, err := h.scfgs.certChain.GetLeafCert(sni)
 	if err != nil {
 		return false, err
 	}
@@ -173,18 +186,22 @@ func (h *cryptoSetupServer) handleMessage(chloData []byte, cryptoData map[Tag][]
 		h.paramsChan <- *params
 	}
 
-	if !h.isInchoateCHLO(cryptoData, certUncompressed) {
+	if scfg := h.getServerConfigForCHLO(cryptoData, certUncompressed); scfg != nil {
 		// We have a CHLO with a proper server config ID, do a 0-RTT handshake
-		reply, err = h.handleCHLO(sni, chloData, cryptoData)
-		if err != nil {
-			return false, err
+		reply, err = h.handleCHLO(scfg, sni, chloData, cryptoData)
+		if err == nil {
+			if _, err := h.cryptoStream.Write(reply); err != nil {
+				return false, err
+			}
+			h.aeadChanged <- protocol.EncryptionForwardSecure
+			close(h.sentSHLO)
+			return true, nil
 		}
-		if _, err := h.cryptoStream.Write(reply); err != nil {
+		if err != errReplayedCHLO {
 			return false, err
 		}
-		h.aeadChanged <- protocol.EncryptionForwardSecure
-		close(h.sentSHLO)
-		return true, nil
+		// The CHLO might have been replayed. Send a rejection, so the client completes the handshake in 1-RTT.
+		h.logger.Debugf("Rejecting CHLO with a replayed nonce")
 	}
 
 	// We have an inchoate or non-matching CHLO, we now send a rejection
@@ -192,6 +209,9 @@ func (h *cryptoSetupServer) handleMessage(chloData []byte, cryptoData map[Tag][]
 	if err != nil {
 		return false, err
 	}
+	h.mutex.Lock()
+	h.sentREJ = true
+	h.mutex.Unlock()
 	_, err = h.cryptoStream.Write(reply)
 	return false, err
 }
@@ -233,6 +253,19 @@ func (h *cryptoSetupServer) Open(dst, src []byte, packetNumber protocol.PacketNu
 	return res, protocol.EncryptionUnencrypted, err
 }
 
+// ConnectionState returns the state of the connection
+func (h *cryptoSetupServer) ConnectionState() ConnectionState {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return ConnectionState{
+		HandshakeComplete: h.receivedForwardSecurePacket,
+		Used0RTT:          h.forwardSecureAEAD != nil && !h.sentREJ,
+		ServerName:        h.sni,
+		CipherSuite:       h.aead,
+		KeyExchange:       h.kexs,
+	}
+}
+
 func (h *cryptoSetupServer) GetSealer() (protocol.EncryptionLevel, Sealer) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
@@ -272,29 +305,38 @@ func (h *cryptoSetupServer) GetSealerWithEncryptionLevel(encLevel protocol.Encry
 	return nil, errors.New("CryptoSetupServer: no encryption level specified")
 }
 
-func (h *cryptoSetupServer) isInchoateCHLO(cryptoData map[Tag][]byte, cert []byte) bool {
+// getServerConfigForCHLO returns the server config that a full CHLO refers to.
+// It returns nil for inchoate CHLOs, and for CHLOs that refer to an unknown or expired server config.
+func (h *cryptoSetupServer) getServerConfigForCHLO(cryptoData map[Tag][]byte, cert []byte) *ServerConfig {
 	if _, ok := cryptoData[TagPUBS]; !ok {
-		return true
+		return nil
 	}
 	scid, ok := cryptoData[TagSCID]
-	if !ok || !bytes.Equal(h.scfg.ID, scid) {
-		return true
+	if !ok {
+		return nil
+	}
+	scfg := h.scfgs.Get(scid)
+	if scfg == nil {
+		return nil
 	}
 	xlctTag, ok := cryptoData[TagXLCT]
 	if !ok || len(xlctTag) != 8 {
-		return true
+		return nil
 	}
 	xlct := binary.LittleEndian.Uint64(xlctTag)
 	if crypto.HashCert(cert) != xlct {
-		return true
+		return nil
+	}
+	if !h.acceptSTK(cryptoData[TagSTK]) {
+		return nil
 	}
-	return !h.acceptSTK(cryptoData[TagSTK])
+	return scfg
 }
 
 func (h *cryptoSetupServer) acceptSTK(token []byte) bool {
-	stk, err := h.scfg.cookieGenerator.DecodeToken(token)
+	stk, err := h.scfgs.cookieGenerator.DecodeToken(token)
 	if err != nil {
-		utils.Debugf("STK invalid: %s", err.Error())
+		h.logger.Debugf("STK invalid: %s", err.Error())
 		return false
 	}
 	return h.acceptSTKCallback(h.remoteAddr, stk)
@@ -305,19 +347,30 @@ func (h *cryptoSetupServer) handleInchoateCHLO(sni string, chlo []byte, cryptoDa
 		return nil, qerr.Error(qerr.CryptoInvalidValueLength, "CHLO too small")
 	}
 
-	token, err := h.scfg.cookieGenerator.NewToken(h.remoteAddr)
+	scfg, err := h.scfgs.Primary()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := h.scfgs.cookieGenerator.NewToken(h.remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	serverNonce, err := h.scfgs.newServerNonce(scfg)
 	if err != nil {
 		return nil, err
 	}
 
 	replyMap := map[Tag][]byte{
-		TagSCFG: h.scfg.Get(),
+		TagSCFG: scfg.Get(),
 		TagSTK:  token,
+		TagSNO:  serverNonce,
 		TagSVID: []byte("quic-go"),
 	}
 
 	if h.acceptSTK(cryptoData[TagSTK]) {
-		proof, err := h.scfg.Sign(sni, chlo)
+		proof, err := scfg.Sign(sni, chlo)
 		if err != nil {
 			return nil, err
 		}
@@ -325,7 +378,7 @@ func (h *cryptoSetupServer) handleInchoateCHLO(sni string, chlo []byte, cryptoDa
 		commonSetHashes := cryptoData[TagCCS]
 		cachedCertsHashes := cryptoData[TagCCRT]
 
-		certCompressed, err := h.scfg.GetCertsCompressed(sni, commonSetHashes, cachedCertsHashes)
+		certCompressed, err := scfg.GetCertsCompressed(sni, commonSetHashes, cachedCertsHashes)
 		if err != nil {
 			return nil, err
 		}
@@ -341,13 +394,13 @@ func (h *cryptoSetupServer) handleInchoateCHLO(sni string, chlo []byte, cryptoDa
 
 	var serverReply bytes.Buffer
 	message.Write(&serverReply)
-	utils.Debugf("Sending %s", message)
+	h.logger.Debugf("Sending %s", message)
 	return serverReply.Bytes(), nil
 }
 
-func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[Tag][]byte) ([]byte, error) {
+func (h *cryptoSetupServer) handleCHLO(scfg *ServerConfig, sni string, data []byte, cryptoData map[Tag][]byte) ([]byte, error) {
 	// We have a CHLO matching our server config, we can continue with the 0-RTT handshake
-	sharedSecret, err := h.scfg.kex.CalculateSharedKey(cryptoData[TagPUBS])
+	sharedSecret, err := scfg.kex.CalculateSharedKey(cryptoData[TagPUBS])
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +408,7 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	certUncompressed, err := h.scfg.certChain.GetLeafCert(sni)
+	certUncompressed, err := scfg.certChain.GetLeafCert(sni)
 	if err != nil {
 		return nil, err
 	}
@@ -371,7 +424,7 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	}
 
 	clientNonce := cryptoData[TagNONC]
-	err = h.validateClientNonce(clientNonce)
+	err = h.validateClientNonce(scfg, clientNonce)
 	if err != nil {
 		return nil, err
 	}
@@ -386,13 +439,23 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 		return nil, qerr.Error(qerr.CryptoNoSupport, "Unsupported AEAD or KEXS")
 	}
 
+	// the server nonce is set if the client received a REJ before
+	echoedServerNonce := cryptoData[TagSNO]
+	if !h.scfgs.acceptNonces(clientNonce, echoedServerNonce) {
+		return nil, errReplayedCHLO
+	}
+
+	h.sni = sni
+	h.aead = string(aead)
+	h.kexs = string(kexs)
+
 	h.secureAEAD, err = h.keyDerivation(
 		false,
 		sharedSecret,
-		clientNonce,
+		append(append([]byte{}, clientNonce...), echoedServerNonce...),
 		h.connID,
 		data,
-		h.scfg.Get(),
+		scfg.Get(),
 		certUncompressed,
 		h.diversificationNonce,
 		protocol.PerspectiveServer,
@@ -419,7 +482,7 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 		fsNonce.Bytes(),
 		h.connID,
 		data,
-		h.scfg.Get(),
+		scfg.Get(),
 		certUncompressed,
 		nil,
 		protocol.PerspectiveServer,
@@ -445,7 +508,7 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	}
 	var reply bytes.Buffer
 	message.Write(&reply)
-	utils.Debugf("Sending %s", message)
+	h.logger.Debugf("Sending %s", message)
 	return reply.Bytes(), nil
 }
 
@@ -462,11 +525,11 @@ func (h *cryptoSetupServer) GetNextPacketType() protocol.PacketType {
 	panic("not needed for cryptoSetupServer")
 }
 
-func (h *cryptoSetupServer) validateClientNonce(nonce []byte) error {
+func (h *cryptoSetupServer) validateClientNonce(scfg *ServerConfig, nonce []byte) error {
 	if len(nonce) != 32 {
 		return qerr.Error(qerr.InvalidCryptoMessageParameter, "invalid client nonce length")
 	}
-	if !bytes.Equal(nonce[4:12], h.scfg.obit) {
+	if !bytes.Equal(nonce[4:12], scfg.obit) {
 		return qerr.Error(qerr.InvalidCryptoMessageParameter, "OBIT not matching")
 	}
 	return nil
@@ -116,6 +116,8 @@ var _ = Describe("Server Crypto Setup", func() {
 	var (
 		kex               *mockKEX
 		signer            *mockSigner
+		scfgs             *ServerConfigManager
+		strikeRegister    StrikeRegister
 		scfg              *ServerConfig
 		cs                *cryptoSetupServer
 		stream            *mockStream
@@ -146,10 +148,14 @@ var _ = Describe("Server Crypto Setup", func() {
 		stream = newMockStream()
 		kex = &mockKEX{}
 		signer = &mockSigner{}
-		scfg, err = NewServerConfig(kex, signer)
+		strikeRegister = NewInMemoryStrikeRegister(time.Minute)
+		scfgs, err = newServerConfigManager(signer, newRandomServerConfigProvider(2*time.Hour), strikeRegister, func([]byte) (crypto.KeyExchange, error) { return kex, nil }, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		scfg, err = scfgs.Primary()
 		nonce32 = make([]byte, 32)
 		aead = []byte("AESG")
 		kexs = []byte("C255")
+		binary.BigEndian.PutUint32(nonce32, uint32(time.Now().Unix()))
 		copy(nonce32[4:12], scfg.obit) // set the OBIT value at the right position
 		versionTag = make([]byte, 4)
 		binary.BigEndian.PutUint32(versionTag, uint32(protocol.VersionWhatever))
@@ -161,17 +167,19 @@ var _ = Describe("Server Crypto Setup", func() {
 			protocol.ConnectionID(42),
 			remoteAddr,
 			version,
-			scfg,
+			scfgs,
 			&TransportParameters{IdleTimeout: protocol.DefaultIdleTimeout},
 			supportedVersions,
 			nil,
 			paramsChan,
 			aeadChanged,
+			nil,
+			utils.DefaultLogger,
 		)
 		Expect(err).NotTo(HaveOccurred())
 		cs = csInt.(*cryptoSetupServer)
-		cs.scfg.cookieGenerator.cookieSource = &mockCookieSource{}
-		validSTK, err = cs.scfg.cookieGenerator.NewToken(remoteAddr)
+		cs.scfgs.cookieGenerator.cookieSource = &mockCookieSource{}
+		validSTK, err = cs.scfgs.cookieGenerator.NewToken(remoteAddr)
 		Expect(err).NotTo(HaveOccurred())
 		sourceAddrValid = true
 		cs.acceptSTKCallback = func(_ net.Addr, _ *Cookie) bool { return sourceAddrValid }
@@ -192,7 +200,7 @@ var _ = Describe("Server Crypto Setup", func() {
 
 			Expect(cs.DiversificationNonce()).To(BeEmpty())
 			// Div nonce is created after CHLO
-			cs.handleCHLO("", nil, map[Tag][]byte{TagNONC: nonce32})
+			cs.handleCHLO(scfg, "", nil, map[Tag][]byte{TagNONC: nonce32})
 		})
 
 		It("returns diversification nonces", func() {
@@ -208,7 +216,7 @@ var _ = Describe("Server Crypto Setup", func() {
 		BeforeEach(func() {
 			xlct = make([]byte, 8)
 			var err error
-			cert, err = cs.scfg.certChain.GetLeafCert("")
+			cert, err = cs.scfgs.certChain.GetLeafCert("")
 			Expect(err).ToNot(HaveOccurred())
 			binary.LittleEndian.PutUint64(xlct, crypto.HashCert(cert))
 			fullCHLO = map[Tag][]byte{
@@ -305,7 +313,7 @@ var _ = Describe("Server Crypto Setup", func() {
 				return mockcrypto.NewMockAEAD(mockCtrl), nil
 			}
 
-			response, err := cs.handleCHLO("", []byte("chlo-data"), map[Tag][]byte{
+			response, err := cs.handleCHLO(scfg, "", []byte("chlo-data"), map[Tag][]byte{
 				TagPUBS: []byte("pubs-c"),
 				TagNONC: nonce32,
 				TagAEAD: aead,
@@ -377,39 +385,185 @@ var _ = Describe("Server Crypto Setup", func() {
 			Expect(aeadChanged).ToNot(BeClosed())
 		})
 
+		It("reports the connection state after a 0-RTT handshake", func() {
+			HandshakeMessage{Tag: TagCHLO, Data: fullCHLO}.Write(&stream.dataToRead)
+			err := cs.HandleCryptoStream()
+			Expect(err).NotTo(HaveOccurred())
+			state := cs.ConnectionState()
+			Expect(state.Used0RTT).To(BeTrue())
+			Expect(state.ServerName).To(Equal("quic.clemente.io"))
+			Expect(state.CipherSuite).To(Equal("AESG"))
+			Expect(state.KeyExchange).To(Equal("C255"))
+			Expect(state.HandshakeComplete).To(BeFalse())
+		})
+
+		It("reports the connection state after a long handshake", func() {
+			HandshakeMessage{
+				Tag: TagCHLO,
+				Data: map[Tag][]byte{
+					TagSNI: []byte("quic.clemente.io"),
+					TagSTK: validSTK,
+					TagPAD: bytes.Repeat([]byte{'a'}, protocol.ClientHelloMinimumSize),
+					TagVER: versionTag,
+				},
+			}.Write(&stream.dataToRead)
+			Expect(cs.ConnectionState()).To(Equal(ConnectionState{}))
+			HandshakeMessage{Tag: TagCHLO, Data: fullCHLO}.Write(&stream.dataToRead)
+			err := cs.HandleCryptoStream()
+			Expect(err).NotTo(HaveOccurred())
+			state := cs.ConnectionState()
+			Expect(state.Used0RTT).To(BeFalse())
+			Expect(state.CipherSuite).To(Equal("AESG"))
+		})
+
+		Context("replay protection", func() {
+			chloData := bytes.Repeat([]byte{'a'}, protocol.ClientHelloMinimumSize)
+
+			getServerNonce := func(rej []byte) []byte {
+				msg, err := ParseHandshakeMessage(bytes.NewReader(rej))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(msg.Tag).To(Equal(TagREJ))
+				Expect(msg.Data).To(HaveKey(TagSNO))
+				return msg.Data[TagSNO]
+			}
+
+			It("sends a server nonce in the REJ", func() {
+				response, err := cs.handleInchoateCHLO("", chloData, nil)
+				Expect(err).ToNot(HaveOccurred())
+				sno, err := cs.scfgs.cookieGenerator.cookieSource.DecodeToken(getServerNonce(response))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sno).To(HaveLen(32))
+				Expect(sno[4:12]).To(Equal(scfg.obit))
+			})
+
+			It("rejects a 0-RTT CHLO with a client nonce that was already used", func() {
+				Expect(strikeRegister.Insert(nonce32, time.Now())).To(BeTrue())
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeFalse())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("REJ"))
+				Expect(aeadChanged).ToNot(Receive())
+			})
+
+			It("completes the handshake in 1-RTT after rejecting a replayed client nonce", func() {
+				var initialNonces []byte
+				cs.keyDerivation = func(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (crypto.AEAD, error) {
+					if !forwardSecure {
+						initialNonces = nonces
+					}
+					return mockcrypto.NewMockAEAD(mockCtrl), nil
+				}
+				Expect(strikeRegister.Insert(nonce32, time.Now())).To(BeTrue())
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeFalse())
+				sno := getServerNonce(stream.dataWritten.Bytes())
+				stream.dataWritten.Reset()
+				fullCHLO[TagSNO] = sno
+				done, err = cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeTrue())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("SHLO"))
+				Expect(initialNonces).To(Equal(append(append([]byte{}, nonce32...), sno...)))
+				Expect(cs.ConnectionState().Used0RTT).To(BeFalse())
+			})
+
+			It("doesn't check the client nonce of a CHLO with a server nonce", func() {
+				Expect(strikeRegister.Insert(nonce32, time.Now())).To(BeTrue())
+				sno, err := cs.scfgs.newServerNonce(scfg)
+				Expect(err).ToNot(HaveOccurred())
+				fullCHLO[TagSNO] = sno
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeTrue())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("SHLO"))
+			})
+
+			It("rejects a replayed CHLO with a server nonce", func() {
+				sno, err := cs.scfgs.newServerNonce(scfg)
+				Expect(err).ToNot(HaveOccurred())
+				nonce, err := cs.scfgs.cookieGenerator.cookieSource.DecodeToken(sno)
+				Expect(err).ToNot(HaveOccurred())
+				// the server nonce was used by the CHLO that is replayed
+				Expect(strikeRegister.Insert(nonce, time.Now())).To(BeTrue())
+				fullCHLO[TagSNO] = sno
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeFalse())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("REJ"))
+				Expect(aeadChanged).ToNot(Receive())
+			})
+
+			It("doesn't record the client nonce of a CHLO with an unsupported AEAD", func() {
+				fullCHLO[TagAEAD] = []byte("FOOO")
+				_, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).To(MatchError(qerr.Error(qerr.CryptoNoSupport, "Unsupported AEAD or KEXS")))
+				Expect(strikeRegister.Insert(nonce32, time.Now())).To(BeTrue())
+			})
+
+			It("rejects a CHLO with an invalid server nonce", func() {
+				fullCHLO[TagSNO] = []byte("foo")
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeFalse())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("REJ"))
+			})
+
+			It("rejects a CHLO with a client nonce outside of the strike register window", func() {
+				binary.BigEndian.PutUint32(nonce32, uint32(time.Now().Add(-time.Hour).Unix()))
+				done, err := cs.handleMessage(chloData, fullCHLO)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(done).To(BeFalse())
+				Expect(stream.dataWritten.Bytes()).To(HavePrefix("REJ"))
+			})
+		})
+
 		It("recognizes inchoate CHLOs missing SCID", func() {
 			delete(fullCHLO, TagSCID)
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes inchoate CHLOs missing PUBS", func() {
 			delete(fullCHLO, TagPUBS)
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes inchoate CHLOs with missing XLCT", func() {
 			delete(fullCHLO, TagXLCT)
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes inchoate CHLOs with wrong length XLCT", func() {
 			fullCHLO[TagXLCT] = bytes.Repeat([]byte{'f'}, 7) // should be 8 bytes
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes inchoate CHLOs with wrong XLCT", func() {
 			fullCHLO[TagXLCT] = bytes.Repeat([]byte{'f'}, 8)
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes inchoate CHLOs with an invalid STK", func() {
 			testErr := errors.New("STK invalid")
-			cs.scfg.cookieGenerator.cookieSource.(*mockCookieSource).decodeErr = testErr
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeTrue())
+			cs.scfgs.cookieGenerator.cookieSource.(*mockCookieSource).decodeErr = testErr
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("recognizes proper CHLOs", func() {
-			Expect(cs.isInchoateCHLO(fullCHLO, cert)).To(BeFalse())
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(Equal(scfg))
+		})
+
+		It("recognizes CHLOs for a server config that is not the primary server config any more", func() {
+			scfgs.nextRotation = time.Now().Add(-time.Second)
+			primary, err := scfgs.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(primary).ToNot(Equal(scfg))
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(Equal(scfg))
+		})
+
+		It("recognizes CHLOs for an expired server config as inchoate", func() {
+			scfg.expiry = time.Now().Add(-time.Second)
+			Expect(cs.getServerConfigForCHLO(fullCHLO, cert)).To(BeNil())
 		})
 
 		It("errors on too short inchoate CHLOs", func() {
@@ -528,7 +682,7 @@ var _ = Describe("Server Crypto Setup", func() {
 
 	Context("escalating crypto", func() {
 		doCHLO := func() {
-			_, err := cs.handleCHLO("", []byte("chlo-data"), map[Tag][]byte{
+			_, err := cs.handleCHLO(scfg, "", []byte("chlo-data"), map[Tag][]byte{
 				TagPUBS: []byte("pubs-c"),
 				TagNONC: nonce32,
 				TagAEAD: aead,
@@ -658,6 +812,7 @@ var _ = Describe("Server Crypto Setup", func() {
 				_, _, err := cs.Open(nil, []byte("forward secure encrypted"), 200, []byte{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(aeadChanged).To(BeClosed())
+				Expect(cs.ConnectionState().HandshakeComplete).To(BeTrue())
 			})
 		})
 
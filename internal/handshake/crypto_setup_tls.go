@@ -10,6 +10,7 @@ import (
 	"github.com/bifurcation/mint"
 	"github.com/seong889/quic-go/internal/crypto"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 )
 
 // KeyDerivationFunction is used for key derivation
@@ -19,6 +20,7 @@ type cryptoSetupTLS struct {
 	mutex sync.RWMutex
 
 	perspective protocol.Perspective
+	hostname    string
 
 	tls  mintTLS
 	conn *fakeConn
@@ -44,13 +46,15 @@ func NewCryptoSetupTLSServer(
 	checkCookie func(net.Addr, *Cookie) bool,
 	supportedVersions []protocol.VersionNumber,
 	version protocol.VersionNumber,
+	keyLogWriter io.Writer,
+	logger utils.Logger,
 ) (CryptoSetup, error) {
 	mintConf, err := tlsToMintConfig(tlsConfig, protocol.PerspectiveServer)
 	if err != nil {
 		return nil, err
 	}
 	mintConf.RequireCookie = true
-	mintConf.CookieHandler, err = newCookieHandler(checkCookie)
+	mintConf.CookieHandler, err = newCookieHandler(checkCookie, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +79,7 @@ func NewCryptoSetupTLSServer(
 		tls:           &mintController{mintConn},
 		conn:          conn,
 		nullAEAD:      nullAEAD,
-		keyDerivation: crypto.DeriveAESKeys,
+		keyDerivation: crypto.NewAESKeyDerivation(keyLogWriter, connID),
 		aeadChanged:   aeadChanged,
 	}, nil
 }
@@ -92,6 +96,7 @@ func NewCryptoSetupTLSClient(
 	initialVersion protocol.VersionNumber,
 	supportedVersions []protocol.VersionNumber,
 	version protocol.VersionNumber,
+	keyLogWriter io.Writer,
 ) (CryptoSetup, error) {
 	mintConf, err := tlsToMintConfig(tlsConfig, protocol.PerspectiveClient)
 	if err != nil {
@@ -116,9 +121,10 @@ func NewCryptoSetupTLSClient(
 	return &cryptoSetupTLS{
 		conn:           conn,
 		perspective:    protocol.PerspectiveClient,
+		hostname:       hostname,
 		tls:            &mintController{mintConn},
 		nullAEAD:       nullAEAD,
-		keyDerivation:  crypto.DeriveAESKeys,
+		keyDerivation:  crypto.NewAESKeyDerivation(keyLogWriter, connID),
 		aeadChanged:    aeadChanged,
 		nextPacketType: protocol.PacketTypeInitial,
 	}, nil
@@ -204,6 +210,22 @@ func (h *cryptoSetupTLS) GetSealerForCryptoStream() (protocol.EncryptionLevel, S
 	return protocol.EncryptionUnencrypted, h.nullAEAD
 }
 
+// ConnectionState returns the state of the connection
+func (h *cryptoSetupTLS) ConnectionState() ConnectionState {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	state := ConnectionState{ServerName: h.hostname}
+	if h.aead == nil {
+		return state
+	}
+	mintState := h.tls.State()
+	state.HandshakeComplete = true
+	state.CipherSuite = cipherSuiteName(mintState.CipherSuite.Suite)
+	state.NegotiatedProtocol = mintState.NextProto
+	state.PeerCertificates = mintState.PeerCertificates
+	return state
+}
+
 func (h *cryptoSetupTLS) determineNextPacketType() error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
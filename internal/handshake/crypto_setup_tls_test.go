@@ -11,6 +11,7 @@ import (
 	"github.com/seong889/quic-go/internal/mocks/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
 	"github.com/seong889/quic-go/internal/testdata"
+	"github.com/seong889/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -41,6 +42,8 @@ var _ = Describe("TLS Crypto Setup", func() {
 			nil,
 			nil,
 			protocol.VersionTLS,
+			nil,
+			utils.DefaultLogger,
 		)
 		Expect(err).ToNot(HaveOccurred())
 		cs = csInt.(*cryptoSetupTLS)
@@ -76,6 +79,23 @@ var _ = Describe("TLS Crypto Setup", func() {
 		Expect(aeadChanged).To(BeClosed())
 	})
 
+	It("reports the connection state", func() {
+		Expect(cs.ConnectionState().HandshakeComplete).To(BeFalse())
+		cs.tls = mockhandshake.NewMockmintTLS(mockCtrl)
+		cs.tls.(*mockhandshake.MockmintTLS).EXPECT().Handshake().Return(mint.AlertNoAlert)
+		cs.keyDerivation = mockKeyDerivation
+		err := cs.HandleCryptoStream()
+		Expect(err).ToNot(HaveOccurred())
+		cs.tls.(*mockhandshake.MockmintTLS).EXPECT().State().Return(mint.ConnectionState{
+			CipherSuite: mint.CipherSuiteParams{Suite: mint.TLS_AES_128_GCM_SHA256},
+			NextProto:   "h2",
+		})
+		state := cs.ConnectionState()
+		Expect(state.HandshakeComplete).To(BeTrue())
+		Expect(state.CipherSuite).To(Equal("TLS_AES_128_GCM_SHA256"))
+		Expect(state.NegotiatedProtocol).To(Equal("h2"))
+	})
+
 	Context("determining the packet type", func() {
 		Context("for the client", func() {
 			var csClient *cryptoSetupTLS
@@ -92,6 +112,7 @@ var _ = Describe("TLS Crypto Setup", func() {
 					protocol.VersionTLS,
 					[]protocol.VersionNumber{protocol.VersionTLS},
 					protocol.VersionTLS,
+					nil,
 				)
 				Expect(err).ToNot(HaveOccurred())
 				csClient = csInt.(*cryptoSetupTLS)
@@ -1,6 +1,8 @@
 package handshake
 
 import (
+	"crypto/x509"
+
 	"github.com/seong889/quic-go/internal/protocol"
 )
 
@@ -10,6 +12,17 @@ type Sealer interface {
 	Overhead() int
 }
 
+// ConnectionState records basic details about the QUIC connection.
+type ConnectionState struct {
+	HandshakeComplete  bool                // handshake is complete
+	Used0RTT           bool                // the handshake completed without a round trip for a REJ (gQUIC only)
+	ServerName         string              // server name requested by the client, if any
+	CipherSuite        string              // AEAD (AESG, CC20) for gQUIC, cipher suite for TLS
+	KeyExchange        string              // key exchange algorithm (gQUIC only)
+	NegotiatedProtocol string              // negotiated next protocol (TLS only)
+	PeerCertificates   []*x509.Certificate // certificate chain presented by the peer
+}
+
 // CryptoSetup is a crypto setup
 type CryptoSetup interface {
 	Open(dst, src []byte, packetNumber protocol.PacketNumber, associatedData []byte) ([]byte, protocol.EncryptionLevel, error)
@@ -22,4 +35,6 @@ type CryptoSetup interface {
 	GetSealer() (protocol.EncryptionLevel, Sealer)
 	GetSealerWithEncryptionLevel(protocol.EncryptionLevel) (Sealer, error)
 	GetSealerForCryptoStream() (protocol.EncryptionLevel, Sealer)
+
+	ConnectionState() ConnectionState
 }
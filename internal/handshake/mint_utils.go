@@ -5,6 +5,7 @@ import (
 	gocrypto "crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io"
 	"net"
 	"time"
@@ -23,6 +24,7 @@ func tlsToMintConfig(tlsConf *tls.Config, pers protocol.Perspective) (*mint.Conf
 		},
 	}
 	if tlsConf != nil {
+		mconf.NextProtos = tlsConf.NextProtos
 		mconf.Certificates = make([]*mint.Certificate, len(tlsConf.Certificates))
 		for i, certChain := range tlsConf.Certificates {
 			mconf.Certificates[i] = &mint.Certificate{
@@ -44,6 +46,17 @@ func tlsToMintConfig(tlsConf *tls.Config, pers protocol.Perspective) (*mint.Conf
 	return mconf, nil
 }
 
+func cipherSuiteName(suite mint.CipherSuite) string {
+	switch suite {
+	case mint.TLS_AES_128_GCM_SHA256:
+		return "TLS_AES_128_GCM_SHA256"
+	case mint.TLS_AES_256_GCM_SHA384:
+		return "TLS_AES_256_GCM_SHA384"
+	default:
+		return fmt.Sprintf("unknown cipher suite (%#04x)", uint16(suite))
+	}
+}
+
 type mintTLS interface {
 	// These two methods are the same as the crypto.TLSExporter interface.
 	// Cannot use embedding here, because mockgen source mode refuses to generate mocks then.
@@ -2,7 +2,8 @@ package handshake
 
 import (
 	"bytes"
-	"crypto/rand"
+	"encoding/binary"
+	"time"
 
 	"github.com/seong889/quic-go/internal/crypto"
 )
@@ -13,39 +14,27 @@ type ServerConfig struct {
 	certChain crypto.CertChain
 	ID        []byte
 	obit      []byte
-	cookieGenerator *CookieGenerator
+	expiry    time.Time
+	// cookieSource encrypts the source address tokens issued while this is the primary server config
+	cookieSource crypto.StkSource
 }
 
-// NewServerConfig creates a new server config
-func NewServerConfig(kex crypto.KeyExchange, certChain crypto.CertChain) (*ServerConfig, error) {
-	id := make([]byte, 16)
-	_, err := rand.Read(id)
-	if err != nil {
-		return nil, err
-	}
-
-	obit := make([]byte, 8)
-	if _, err = rand.Read(obit); err != nil {
-		return nil, err
-	}
-
-	cookieGenerator, err := NewCookieGenerator()
-
-	if err != nil {
-		return nil, err
-	}
-
+// newServerConfig creates a new server config, which is valid until expiry
+func newServerConfig(kex crypto.KeyExchange, certChain crypto.CertChain, id, obit []byte, expiry time.Time) *ServerConfig {
 	return &ServerConfig{
 		kex:       kex,
 		certChain: certChain,
 		ID:        id,
 		obit:      obit,
-		cookieGenerator: cookieGenerator,
-	}, nil
+		expiry:    expiry,
+	}
 }
 
 // Get the server config binary representation
 func (s *ServerConfig) Get() []byte {
+	expy := make([]byte, 8)
+	binary.LittleEndian.PutUint64(expy, uint64(s.expiry.Unix()))
+
 	var serverConfig bytes.Buffer
 	msg := HandshakeMessage{
 		Tag: TagSCFG,
@@ -55,13 +44,18 @@ func (s *ServerConfig) Get() []byte {
 			TagAEAD: []byte("AESG"),
 			TagPUBS: append([]byte{0x20, 0x00, 0x00}, s.kex.PublicKey()...),
 			TagOBIT: s.obit,
-			TagEXPY: {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			TagEXPY: expy,
 		},
 	}
 	msg.Write(&serverConfig)
 	return serverConfig.Bytes()
 }
 
+// IsExpired returns true if the server config must not be used any more
+func (s *ServerConfig) IsExpired() bool {
+	return !time.Now().Before(s.expiry)
+}
+
 // Sign the server config and CHLO with the server's keyData
 func (s *ServerConfig) Sign(sni string, chlo []byte) ([]byte, error) {
 	return s.certChain.SignServerProof(sni, chlo, s.Get())
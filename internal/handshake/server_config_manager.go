@@ -0,0 +1,263 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/crypto"
+	"github.com/seong889/quic-go/internal/protocol"
+)
+
+// The ServerConfigManager manages the server configs of a server.
+// The server configs are obtained from a ServerConfigProvider. Every rotation interval, the provider is asked for the current server configs.
+// The primary server config is sent to clients in the REJ.
+// Server configs are valid for longer than the rotation interval.
+// This allows clients that cached a server config to use it for a 0-RTT handshake until it expires, even if it isn't the primary server config any more.
+// Likewise, source address tokens are issued using the cookie secret of the primary server config,
+// and are accepted until the server config they were issued with expires.
+type ServerConfigManager struct {
+	mutex sync.Mutex
+
+	certChain       crypto.CertChain
+	cookieGenerator *CookieGenerator
+	strikeRegister  StrikeRegister
+	provider        ServerConfigProvider
+	newKEX          func(privateKey []byte) (crypto.KeyExchange, error)
+
+	rotationInterval time.Duration
+
+	// all server configs that are not expired yet. The last server config is the primary server config.
+	configs      []*ServerConfig
+	nextRotation time.Time
+}
+
+// NewServerConfigManager creates a new ServerConfigManager.
+// If provider is nil, server configs and their cookie secrets are generated randomly.
+// If strikeRegister is nil, an in-memory strike register is used.
+func NewServerConfigManager(certChain crypto.CertChain, provider ServerConfigProvider, strikeRegister StrikeRegister) (*ServerConfigManager, error) {
+	if provider == nil {
+		provider = newRandomServerConfigProvider(protocol.ServerConfigLifetime)
+	}
+	if strikeRegister == nil {
+		strikeRegister = NewInMemoryStrikeRegister(protocol.StrikeRegisterWindow)
+	}
+	return newServerConfigManager(certChain, provider, strikeRegister, crypto.NewCurve25519KEXFromPrivateKey, protocol.ServerConfigRotationInterval)
+}
+
+func newServerConfigManager(
+	certChain crypto.CertChain,
+	provider ServerConfigProvider,
+	strikeRegister StrikeRegister,
+	newKEX func(privateKey []byte) (crypto.KeyExchange, error),
+	rotationInterval time.Duration,
+) (*ServerConfigManager, error) {
+	m := &ServerConfigManager{
+		certChain:        certChain,
+		strikeRegister:   strikeRegister,
+		provider:         provider,
+		newKEX:           newKEX,
+		rotationInterval: rotationInterval,
+	}
+	m.cookieGenerator = &CookieGenerator{cookieSource: &serverConfigCookieSource{manager: m}}
+	if err := m.rotate(time.Now()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Primary returns the primary server config.
+// If the rotation interval has passed, or a server config expired, the server configs are updated.
+func (m *ServerConfigManager) Primary() (*ServerConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if now := time.Now(); !now.Before(m.nextRotation) {
+		if err := m.rotate(now); err != nil {
+			return nil, err
+		}
+	}
+	return m.configs[len(m.configs)-1], nil
+}
+
+// Get returns the server config with the given ID.
+// It returns nil if the server config is unknown or expired.
+func (m *ServerConfigManager) Get(id []byte) *ServerConfig {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.get(id)
+}
+
+// get returns the server config with the given ID.
+// It must be called with the mutex held.
+func (m *ServerConfigManager) get(id []byte) *ServerConfig {
+	for _, scfg := range m.configs {
+		if bytes.Equal(scfg.ID, id) && !scfg.IsExpired() {
+			return scfg
+		}
+	}
+	return nil
+}
+
+// rotate gets the current server configs from the provider.
+// Server configs that are already known are reused.
+// It must be called with the mutex held.
+func (m *ServerConfigManager) rotate(now time.Time) error {
+	params, err := m.provider.ServerConfigs()
+	if err != nil {
+		return err
+	}
+	configs := make([]*ServerConfig, 0, len(params))
+	nextRotation := now.Add(m.rotationInterval)
+	for _, p := range params {
+		if !now.Before(p.Expiry) {
+			continue
+		}
+		scfg := m.get(p.ID)
+		if scfg == nil {
+			if scfg, err = m.newServerConfig(p); err != nil {
+				return err
+			}
+		}
+		configs = append(configs, scfg)
+		if p.Expiry.Before(nextRotation) {
+			nextRotation = p.Expiry
+		}
+	}
+	if len(configs) == 0 {
+		return errors.New("ServerConfigProvider didn't return any valid server configs")
+	}
+	m.configs = configs
+	m.nextRotation = nextRotation
+	return nil
+}
+
+func (m *ServerConfigManager) newServerConfig(p ServerConfigParameters) (*ServerConfig, error) {
+	if len(p.ID) != serverConfigIDLen {
+		return nil, fmt.Errorf("invalid server config ID length: %d", len(p.ID))
+	}
+	if len(p.Orbit) != serverConfigOrbitLen {
+		return nil, fmt.Errorf("invalid server config orbit length: %d", len(p.Orbit))
+	}
+	kex, err := m.newKEX(p.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	cookieSource, err := crypto.NewStkSourceFromSecret(p.CookieSecret)
+	if err != nil {
+		return nil, err
+	}
+	scfg := newServerConfig(kex, m.certChain, p.ID, p.Orbit, p.Expiry)
+	scfg.cookieSource = cookieSource
+	return scfg, nil
+}
+
+// The serverConfigCookieSource encrypts source address tokens and server nonces using the cookie secret of the primary server config.
+// When decrypting, the cookie secrets of all server configs that didn't expire yet are tried,
+// such that tokens issued before a rotation are still accepted.
+type serverConfigCookieSource struct {
+	manager *ServerConfigManager
+}
+
+var _ crypto.StkSource = &serverConfigCookieSource{}
+
+func (s *serverConfigCookieSource) NewToken(data []byte) ([]byte, error) {
+	scfg, err := s.manager.Primary()
+	if err != nil {
+		return nil, err
+	}
+	return scfg.cookieSource.NewToken(data)
+}
+
+func (s *serverConfigCookieSource) DecodeToken(token []byte) ([]byte, error) {
+	s.manager.mutex.Lock()
+	defer s.manager.mutex.Unlock()
+
+	// start with the primary server config, since most tokens were issued using its cookie secret
+	for i := len(s.manager.configs) - 1; i >= 0; i-- {
+		scfg := s.manager.configs[i]
+		if scfg.IsExpired() {
+			continue
+		}
+		if data, err := scfg.cookieSource.DecodeToken(token); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("source address token can't be decrypted with any valid cookie secret")
+}
+
+// AcceptCookie checks if a CHLO contains a cookie (source address token) that is accepted by the callback.
+// This allows checking the cookie before a session is created.
+func (m *ServerConfigManager) AcceptCookie(chlo []byte, remoteAddr net.Addr, accept func(net.Addr, *Cookie) bool) bool {
+	message, err := ParseHandshakeMessage(bytes.NewReader(chlo))
+	if err != nil || message.Tag != TagCHLO {
+		return false
+	}
+	cookie, err := m.cookieGenerator.DecodeToken(message.Data[TagSTK])
+	if err != nil {
+		return false
+	}
+	return accept(remoteAddr, cookie)
+}
+
+// StatelessReject creates a SREJ, which is sent without creating a session.
+// It contains a fresh STK for the remoteAddr, and the connection ID that the client has to use for a new connection.
+// The client presents the STK in the first CHLO of that connection.
+func (m *ServerConfigManager) StatelessReject(remoteAddr net.Addr, connID protocol.ConnectionID) ([]byte, error) {
+	token, err := m.cookieGenerator.NewToken(remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	rcid := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rcid, uint64(connID))
+	message := HandshakeMessage{
+		Tag: TagSREJ,
+		Data: map[Tag][]byte{
+			TagSTK:  token,
+			TagRCID: rcid,
+			TagSVID: []byte("quic-go"),
+		},
+	}
+	var b bytes.Buffer
+	message.Write(&b)
+	return b.Bytes(), nil
+}
+
+// newServerNonce generates a server nonce, which is sent in the REJ.
+// A CHLO that contains this server nonce completes the handshake in 1-RTT, so its client nonce isn't checked for replays.
+// The server nonce is encrypted, so that servers sharing the server configs can decrypt it.
+func (m *ServerConfigManager) newServerNonce(scfg *ServerConfig) ([]byte, error) {
+	nonce := make([]byte, strikeRegisterNonceLen)
+	binary.BigEndian.PutUint32(nonce, uint32(time.Now().Unix()))
+	copy(nonce[4:12], scfg.obit)
+	if _, err := rand.Read(nonce[12:]); err != nil {
+		return nil, err
+	}
+	return m.cookieGenerator.cookieSource.NewToken(nonce)
+}
+
+// acceptNonces checks the nonces of a CHLO.
+// A server nonce is accepted if it was issued by a server sharing the server configs within the StrikeRegisterWindow.
+// It is recorded in the strike register, so that a CHLO echoing it can't be replayed.
+// Without a server nonce, the CHLO is a 0-RTT CHLO, and its client nonce is checked against the strike register.
+func (m *ServerConfigManager) acceptNonces(clientNonce, serverNonce []byte) bool {
+	now := time.Now()
+	if len(serverNonce) == 0 {
+		return m.strikeRegister.Insert(clientNonce, now)
+	}
+	nonce, err := m.cookieGenerator.cookieSource.DecodeToken(serverNonce)
+	if err != nil || len(nonce) != strikeRegisterNonceLen {
+		return false
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint32(nonce[:4])), 0)
+	if now.Sub(issued) > protocol.StrikeRegisterWindow {
+		return false
+	}
+	return m.strikeRegister.Insert(nonce, now)
+}
@@ -0,0 +1,384 @@
+package handshake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/seong889/quic-go/internal/crypto"
+	"github.com/seong889/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockServerConfigProvider struct {
+	configs []ServerConfigParameters
+	err     error
+	calls   int
+}
+
+var _ ServerConfigProvider = &mockServerConfigProvider{}
+
+func (p *mockServerConfigProvider) ServerConfigs() ([]ServerConfigParameters, error) {
+	p.calls++
+	return p.configs, p.err
+}
+
+func newServerConfigParameters(id byte, expiry time.Time) ServerConfigParameters {
+	return ServerConfigParameters{
+		ID:           bytes.Repeat([]byte{id}, 16),
+		Orbit:        bytes.Repeat([]byte{id}, 8),
+		PrivateKey:   bytes.Repeat([]byte{id}, 32),
+		CookieSecret: bytes.Repeat([]byte{id}, 32),
+		Expiry:       expiry,
+	}
+}
+
+var _ = Describe("ServerConfigManager", func() {
+	var m *ServerConfigManager
+
+	BeforeEach(func() {
+		var err error
+		m, err = newServerConfigManager(nil, newRandomServerConfigProvider(2*time.Hour), NewInMemoryStrikeRegister(time.Minute), crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("generates a server config when it is created", func() {
+		scfg, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scfg).ToNot(BeNil())
+		Expect(scfg.expiry).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Second))
+		Expect(m.nextRotation).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+	})
+
+	It("returns the same primary server config until the rotation interval has passed", func() {
+		scfg1, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		scfg2, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scfg1).To(Equal(scfg2))
+		Expect(m.configs).To(HaveLen(1))
+	})
+
+	It("rotates the server config", func() {
+		scfg1, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		m.nextRotation = time.Now().Add(-time.Second)
+		scfg2, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scfg2.ID).ToNot(Equal(scfg1.ID))
+		Expect(scfg2.obit).ToNot(Equal(scfg1.obit))
+		Expect(m.configs).To(HaveLen(2))
+		// the old server config can still be used by clients
+		Expect(m.Get(scfg1.ID)).To(BeIdenticalTo(scfg1))
+		Expect(m.Get(scfg2.ID)).To(BeIdenticalTo(scfg2))
+	})
+
+	It("doesn't return expired server configs", func() {
+		scfg, err := m.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		scfg.expiry = time.Now().Add(-time.Second)
+		Expect(m.Get(scfg.ID)).To(BeNil())
+	})
+
+	It("doesn't return unknown server configs", func() {
+		Expect(m.Get([]byte("foobar"))).To(BeNil())
+	})
+
+	Context("using a ServerConfigProvider", func() {
+		var provider *mockServerConfigProvider
+
+		BeforeEach(func() {
+			provider = &mockServerConfigProvider{
+				configs: []ServerConfigParameters{newServerConfigParameters(1, time.Now().Add(2*time.Hour))},
+			}
+		})
+
+		It("uses the parameters returned by the provider", func() {
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			scfg, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scfg.ID).To(Equal(provider.configs[0].ID))
+			Expect(scfg.obit).To(Equal(provider.configs[0].Orbit))
+			Expect(scfg.expiry).To(Equal(provider.configs[0].Expiry))
+			kex, err := crypto.NewCurve25519KEXFromPrivateKey(provider.configs[0].PrivateKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scfg.kex.PublicKey()).To(Equal(kex.PublicKey()))
+		})
+
+		It("creates the same server config on different servers", func() {
+			m1, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			m2, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			scfg1, err := m1.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			scfg2, err := m2.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scfg1.Get()).To(Equal(scfg2.Get()))
+		})
+
+		It("uses the last server config as the primary server config", func() {
+			provider.configs = append(provider.configs, newServerConfigParameters(2, time.Now().Add(3*time.Hour)))
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			scfg, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scfg.ID).To(Equal(provider.configs[1].ID))
+			Expect(m.Get(provider.configs[0].ID)).ToNot(BeNil())
+		})
+
+		It("reuses known server configs when rotating", func() {
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			scfg1, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			provider.configs = append(provider.configs, newServerConfigParameters(2, time.Now().Add(3*time.Hour)))
+			m.nextRotation = time.Now().Add(-time.Second)
+			scfg2, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scfg2.ID).To(Equal(provider.configs[1].ID))
+			Expect(m.Get(scfg1.ID)).To(BeIdenticalTo(scfg1))
+		})
+
+		It("asks the provider again when a server config expires", func() {
+			provider.configs[0].Expiry = time.Now().Add(time.Minute)
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.nextRotation).To(Equal(provider.configs[0].Expiry))
+		})
+
+		It("ignores expired server configs", func() {
+			provider.configs = append([]ServerConfigParameters{newServerConfigParameters(2, time.Now().Add(-time.Second))}, provider.configs...)
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.configs).To(HaveLen(1))
+			Expect(m.Get(provider.configs[0].ID)).To(BeNil())
+		})
+
+		It("errors if the provider doesn't return any valid server configs", func() {
+			provider.configs[0].Expiry = time.Now().Add(-time.Second)
+			_, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).To(MatchError("ServerConfigProvider didn't return any valid server configs"))
+		})
+
+		It("errors if the provider returns invalid server configs", func() {
+			provider.configs[0].ID = []byte("foobar")
+			_, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).To(MatchError("invalid server config ID length: 6"))
+			provider.configs[0] = newServerConfigParameters(1, time.Now().Add(time.Hour))
+			provider.configs[0].Orbit = []byte("foo")
+			_, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).To(MatchError("invalid server config orbit length: 3"))
+			provider.configs[0] = newServerConfigParameters(1, time.Now().Add(time.Hour))
+			provider.configs[0].PrivateKey = []byte("foo")
+			_, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).To(MatchError("Curve25519: expected private key of 32 byte"))
+			provider.configs[0] = newServerConfigParameters(1, time.Now().Add(time.Hour))
+			provider.configs[0].CookieSecret = nil
+			_, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).To(MatchError("STK secret must not be empty"))
+		})
+
+		It("errors if the provider returns an error when rotating", func() {
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			testErr := errors.New("provider failed")
+			provider.err = testErr
+			m.nextRotation = time.Now().Add(-time.Second)
+			_, err = m.Primary()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("accepts cookies issued by another server using the same server configs", func() {
+			m1, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			m2, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+			stk, err := m1.cookieGenerator.NewToken(remoteAddr)
+			Expect(err).ToNot(HaveOccurred())
+			cookie, err := m2.cookieGenerator.DecodeToken(stk)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cookie.RemoteAddr).To(Equal("192.168.13.37"))
+		})
+
+		It("issues cookies using the cookie secret of the primary server config", func() {
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+			stk1, err := m.cookieGenerator.NewToken(remoteAddr)
+			Expect(err).ToNot(HaveOccurred())
+			provider.configs = append(provider.configs, newServerConfigParameters(2, time.Now().Add(3*time.Hour)))
+			m.nextRotation = time.Now().Add(-time.Second)
+			stk2, err := m.cookieGenerator.NewToken(remoteAddr)
+			Expect(err).ToNot(HaveOccurred())
+			scfg1 := m.Get(provider.configs[0].ID)
+			scfg2 := m.Get(provider.configs[1].ID)
+			_, err = scfg1.cookieSource.DecodeToken(stk1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = scfg2.cookieSource.DecodeToken(stk2)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = scfg1.cookieSource.DecodeToken(stk2)
+			Expect(err).To(HaveOccurred())
+			// cookies issued with the previous cookie secret are still accepted
+			_, err = m.cookieGenerator.DecodeToken(stk1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.cookieGenerator.DecodeToken(stk2)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("rejects cookies issued with the cookie secret of an expired server config", func() {
+			var err error
+			m, err = newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			stk, err := m.cookieGenerator.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337})
+			Expect(err).ToNot(HaveOccurred())
+			provider.configs = append(provider.configs, newServerConfigParameters(2, time.Now().Add(3*time.Hour)))
+			m.nextRotation = time.Now().Add(-time.Second)
+			_, err = m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			m.Get(provider.configs[0].ID).expiry = time.Now().Add(-time.Second)
+			_, err = m.cookieGenerator.DecodeToken(stk)
+			Expect(err).To(MatchError("source address token can't be decrypted with any valid cookie secret"))
+		})
+	})
+
+	Context("server nonces", func() {
+		It("accepts server nonces issued by another server using the same server configs", func() {
+			provider := &mockServerConfigProvider{
+				configs: []ServerConfigParameters{newServerConfigParameters(1, time.Now().Add(2*time.Hour))},
+			}
+			m1, err := newServerConfigManager(nil, provider, nil, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			m2, err := newServerConfigManager(nil, provider, &mockStrikeRegister{}, crypto.NewCurve25519KEXFromPrivateKey, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			scfg, err := m1.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			sno, err := m1.newServerNonce(scfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m2.acceptNonces(nil, sno)).To(BeTrue())
+		})
+
+		It("records server nonces in the strike register", func() {
+			strikeRegister := &mockStrikeRegister{}
+			m.strikeRegister = strikeRegister
+			scfg, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			sno, err := m.newServerNonce(scfg)
+			Expect(err).ToNot(HaveOccurred())
+			nonce, err := m.cookieGenerator.cookieSource.DecodeToken(sno)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.acceptNonces([]byte("client nonce"), sno)).To(BeTrue())
+			Expect(strikeRegister.nonces).To(Equal([][]byte{nonce}))
+			// a replayed CHLO is rejected, even if it uses a different client nonce
+			Expect(m.acceptNonces([]byte("another client nonce"), sno)).To(BeFalse())
+		})
+
+		It("rejects server nonces issued outside of the strike register window", func() {
+			nonce := make([]byte, 32)
+			binary.BigEndian.PutUint32(nonce, uint32(time.Now().Add(-protocol.StrikeRegisterWindow-time.Second).Unix()))
+			sno, err := m.cookieGenerator.cookieSource.NewToken(nonce)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.acceptNonces(nil, sno)).To(BeFalse())
+		})
+
+		It("checks the client nonce, if there's no server nonce", func() {
+			scfg, err := m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			nonce := make([]byte, 32)
+			binary.BigEndian.PutUint32(nonce, uint32(time.Now().Unix()))
+			copy(nonce[4:12], scfg.obit)
+			Expect(m.acceptNonces(nonce, nil)).To(BeTrue())
+			Expect(m.acceptNonces(nonce, nil)).To(BeFalse())
+		})
+
+		It("rejects invalid server nonces", func() {
+			Expect(m.acceptNonces(nil, []byte("foobar"))).To(BeFalse())
+		})
+	})
+
+	Context("accepting cookies", func() {
+		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+
+		getCHLO := func(stk []byte) []byte {
+			data := map[Tag][]byte{TagSNI: []byte("quic.clemente.io")}
+			if stk != nil {
+				data[TagSTK] = stk
+			}
+			b := &bytes.Buffer{}
+			HandshakeMessage{Tag: TagCHLO, Data: data}.Write(b)
+			return b.Bytes()
+		}
+
+		It("accepts a CHLO with a valid cookie", func() {
+			stk, err := m.cookieGenerator.NewToken(remoteAddr)
+			Expect(err).ToNot(HaveOccurred())
+			var cookie *Cookie
+			accepted := m.AcceptCookie(getCHLO(stk), remoteAddr, func(addr net.Addr, c *Cookie) bool {
+				Expect(addr).To(Equal(remoteAddr))
+				cookie = c
+				return true
+			})
+			Expect(accepted).To(BeTrue())
+			Expect(cookie.RemoteAddr).To(Equal("192.168.13.37"))
+		})
+
+		It("accepts cookies issued before the server config was rotated", func() {
+			stk, err := m.cookieGenerator.NewToken(remoteAddr)
+			Expect(err).ToNot(HaveOccurred())
+			m.nextRotation = time.Now().Add(-time.Second)
+			_, err = m.Primary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.AcceptCookie(getCHLO(stk), remoteAddr, func(net.Addr, *Cookie) bool { return true })).To(BeTrue())
+		})
+
+		It("sends a stateless reject with a cookie for the new connection", func() {
+			srej, err := m.StatelessReject(remoteAddr, 0xdecafbad)
+			Expect(err).ToNot(HaveOccurred())
+			msg, err := ParseHandshakeMessage(bytes.NewReader(srej))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msg.Tag).To(Equal(TagSREJ))
+			Expect(msg.Data[TagRCID]).To(Equal([]byte{0xad, 0xfb, 0xca, 0xde, 0, 0, 0, 0}))
+			Expect(m.AcceptCookie(getCHLO(msg.Data[TagSTK]), remoteAddr, func(net.Addr, *Cookie) bool { return true })).To(BeTrue())
+		})
+
+		It("calls the callback with a nil cookie, if the CHLO doesn't contain a cookie", func() {
+			var called bool
+			accepted := m.AcceptCookie(getCHLO(nil), remoteAddr, func(_ net.Addr, c *Cookie) bool {
+				called = true
+				Expect(c).To(BeNil())
+				return false
+			})
+			Expect(called).To(BeTrue())
+			Expect(accepted).To(BeFalse())
+		})
+
+		It("rejects invalid cookies", func() {
+			accepted := m.AcceptCookie(getCHLO([]byte("foobar")), remoteAddr, func(net.Addr, *Cookie) bool {
+				Fail("callback should not be called")
+				return true
+			})
+			Expect(accepted).To(BeFalse())
+		})
+
+		It("rejects messages that are not a CHLO", func() {
+			b := &bytes.Buffer{}
+			HandshakeMessage{Tag: TagREJ, Data: map[Tag][]byte{}}.Write(b)
+			accepted := m.AcceptCookie(b.Bytes(), remoteAddr, func(net.Addr, *Cookie) bool { return true })
+			Expect(accepted).To(BeFalse())
+			Expect(m.AcceptCookie([]byte("foobar"), remoteAddr, func(net.Addr, *Cookie) bool { return true })).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package handshake
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	serverConfigIDLen         = 16
+	serverConfigOrbitLen      = 8
+	serverConfigPrivateKeyLen = 32
+	cookieSecretLen           = 32
+)
+
+// ServerConfigParameters are the secrets of a server config.
+// Servers using the same parameters present the same server config to clients.
+type ServerConfigParameters struct {
+	// ID is the server config ID (SCID). It must be 16 bytes long.
+	ID []byte
+	// Orbit is the orbit value (OBIT). It must be 8 bytes long.
+	Orbit []byte
+	// PrivateKey is the Curve25519 private key. It must be 32 bytes long.
+	PrivateKey []byte
+	// CookieSecret is the secret used to encrypt source address tokens. It must not be empty.
+	// Tokens are issued using the secret of the primary server config,
+	// and are accepted as long as the server config they were issued with didn't expire.
+	CookieSecret []byte
+	// Expiry is the time when the server config expires.
+	Expiry time.Time
+}
+
+// A ServerConfigProvider provides the server configs, including the secrets used to encrypt source address tokens.
+// Servers that use providers returning the same values present a consistent crypto identity,
+// such that clients can use a cached server config and source address token with every one of them.
+type ServerConfigProvider interface {
+	// ServerConfigs returns all server configs that clients are allowed to use.
+	// The last server config is the primary server config, which is sent to clients.
+	// It is called when the server is started, and again when the rotation interval has passed, or when one of the server configs expires.
+	ServerConfigs() ([]ServerConfigParameters, error)
+}
+
+// The randomServerConfigProvider generates a new server config with random secrets every time it is called.
+// It is used if no ServerConfigProvider is configured.
+type randomServerConfigProvider struct {
+	mutex sync.Mutex
+
+	lifetime time.Duration
+	configs  []ServerConfigParameters
+}
+
+var _ ServerConfigProvider = &randomServerConfigProvider{}
+
+func newRandomServerConfigProvider(lifetime time.Duration) *randomServerConfigProvider {
+	return &randomServerConfigProvider{lifetime: lifetime}
+}
+
+func (p *randomServerConfigProvider) ServerConfigs() ([]ServerConfigParameters, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	params := ServerConfigParameters{
+		ID:           make([]byte, serverConfigIDLen),
+		Orbit:        make([]byte, serverConfigOrbitLen),
+		PrivateKey:   make([]byte, serverConfigPrivateKeyLen),
+		CookieSecret: make([]byte, cookieSecretLen),
+		Expiry:       now.Add(p.lifetime),
+	}
+	for _, b := range [][]byte{params.ID, params.Orbit, params.PrivateKey, params.CookieSecret} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	configs := make([]ServerConfigParameters, 0, len(p.configs)+1)
+	for _, c := range p.configs {
+		if now.Before(c.Expiry) {
+			configs = append(configs, c)
+		}
+	}
+	p.configs = append(configs, params)
+	return p.configs, nil
+}
+
+// The sharedSecretServerConfigProvider derives the server configs, including their cookie secrets, from a secret.
+// Time is divided into rotation intervals, starting at the Unix epoch. For every interval, a new server config is derived.
+// Servers using the same secret rotate their server configs at the same time, as long as their clocks are synchronized.
+// If a rotationSecret function is set, the secret of every rotation interval is mixed into the derivation.
+type sharedSecretServerConfigProvider struct {
+	secret           []byte
+	rotationSecret   func(intervalStart time.Time) ([]byte, error)
+	rotationInterval time.Duration
+	lifetime         time.Duration
+}
+
+var _ ServerConfigProvider = &sharedSecretServerConfigProvider{}
+
+// NewSharedSecretServerConfigProvider creates a ServerConfigProvider that derives all secrets from the given secret.
+// Servers that use the same secret present the same server configs and accept each other's source address tokens.
+// The private keys of all past and future server configs are derived from this secret alone,
+// so the key exchange is not forward secret: anyone who learns the secret can decrypt every recorded connection.
+// Use NewRotatingSecretServerConfigProvider to avoid this.
+func NewSharedSecretServerConfigProvider(secret []byte) (ServerConfigProvider, error) {
+	return newSharedSecretServerConfigProvider(secret, nil, protocol.ServerConfigRotationInterval, protocol.ServerConfigLifetime)
+}
+
+// NewRotatingSecretServerConfigProvider creates a ServerConfigProvider that derives the server config of every rotation interval
+// from the given secret and the secret that rotationSecret returns for this interval, e.g. fetched from a key management service.
+// rotationSecret is called with the start of the interval, possibly multiple times, and must return the same secret on all servers.
+// Once the secret of an interval is deleted, the private key of its server config can't be derived from the long-lived secret any more.
+func NewRotatingSecretServerConfigProvider(secret []byte, rotationSecret func(intervalStart time.Time) ([]byte, error)) (ServerConfigProvider, error) {
+	if rotationSecret == nil {
+		return nil, errors.New("no rotation secret function given")
+	}
+	return newSharedSecretServerConfigProvider(secret, rotationSecret, protocol.ServerConfigRotationInterval, protocol.ServerConfigLifetime)
+}
+
+func newSharedSecretServerConfigProvider(
+	secret []byte,
+	rotationSecret func(intervalStart time.Time) ([]byte, error),
+	rotationInterval, lifetime time.Duration,
+) (*sharedSecretServerConfigProvider, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("server config secret too short, need at least 16 bytes")
+	}
+	if rotationInterval < time.Second {
+		return nil, errors.New("server config rotation interval must be at least one second")
+	}
+	if lifetime < rotationInterval {
+		return nil, errors.New("server config lifetime must not be shorter than the rotation interval")
+	}
+	return &sharedSecretServerConfigProvider{
+		secret:           secret,
+		rotationSecret:   rotationSecret,
+		rotationInterval: rotationInterval,
+		lifetime:         lifetime,
+	}, nil
+}
+
+func (p *sharedSecretServerConfigProvider) ServerConfigs() ([]ServerConfigParameters, error) {
+	return p.serverConfigsAt(time.Now())
+}
+
+func (p *sharedSecretServerConfigProvider) serverConfigsAt(now time.Time) ([]ServerConfigParameters, error) {
+	interval := int64(p.rotationInterval / time.Second)
+	current := now.Unix() / interval
+	// find the oldest interval whose server config didn't expire yet
+	first := current
+	for first > 0 && now.Before(p.intervalStart(first-1).Add(p.lifetime)) {
+		first--
+	}
+	configs := make([]ServerConfigParameters, 0, current-first+1)
+	for i := first; i <= current; i++ {
+		params, err := p.deriveServerConfig(i)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, *params)
+	}
+	return configs, nil
+}
+
+func (p *sharedSecretServerConfigProvider) intervalStart(i int64) time.Time {
+	return time.Unix(i*int64(p.rotationInterval/time.Second), 0)
+}
+
+func (p *sharedSecretServerConfigProvider) deriveServerConfig(i int64) (*ServerConfigParameters, error) {
+	info := make([]byte, 8)
+	binary.BigEndian.PutUint64(info, uint64(i))
+	// The rotation secret is used as the HKDF salt.
+	// Without a rotation secret, the salt is empty, and the server configs only depend on the long-lived secret.
+	var salt []byte
+	if p.rotationSecret != nil {
+		var err error
+		salt, err = p.rotationSecret(p.intervalStart(i))
+		if err != nil {
+			return nil, err
+		}
+		if len(salt) < 16 {
+			return nil, errors.New("server config rotation secret too short, need at least 16 bytes")
+		}
+	}
+	r := hkdf.New(sha256.New, p.secret, salt, append([]byte("QUIC server config"), info...))
+	params := &ServerConfigParameters{
+		ID:           make([]byte, serverConfigIDLen),
+		Orbit:        make([]byte, serverConfigOrbitLen),
+		PrivateKey:   make([]byte, serverConfigPrivateKeyLen),
+		CookieSecret: make([]byte, cookieSecretLen),
+		Expiry:       p.intervalStart(i).Add(p.lifetime),
+	}
+	for _, b := range [][]byte{params.ID, params.Orbit, params.PrivateKey, params.CookieSecret} {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,193 @@
+package handshake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServerConfigProvider", func() {
+	Context("generating random server configs", func() {
+		It("generates a new server config every time it is called", func() {
+			p := newRandomServerConfigProvider(time.Hour)
+			configs, err := p.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(1))
+			Expect(configs[0].ID).To(HaveLen(16))
+			Expect(configs[0].Orbit).To(HaveLen(8))
+			Expect(configs[0].PrivateKey).To(HaveLen(32))
+			Expect(configs[0].CookieSecret).To(HaveLen(32))
+			Expect(configs[0].Expiry).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+			first := configs[0]
+			configs, err = p.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0]).To(Equal(first))
+			Expect(configs[1].ID).ToNot(Equal(first.ID))
+			Expect(configs[1].Orbit).ToNot(Equal(first.Orbit))
+			Expect(configs[1].PrivateKey).ToNot(Equal(first.PrivateKey))
+			Expect(configs[1].CookieSecret).ToNot(Equal(first.CookieSecret))
+		})
+
+		It("deletes expired server configs", func() {
+			p := newRandomServerConfigProvider(time.Hour)
+			_, err := p.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			p.configs[0].Expiry = time.Now().Add(-time.Second)
+			configs, err := p.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(1))
+			Expect(configs[0].Expiry).To(BeTemporally(">", time.Now()))
+		})
+	})
+
+	Context("deriving server configs from a shared secret", func() {
+		var secret = []byte("a secret shared by all servers")
+
+		It("rejects short secrets", func() {
+			_, err := NewSharedSecretServerConfigProvider([]byte("foobar"))
+			Expect(err).To(MatchError("server config secret too short, need at least 16 bytes"))
+		})
+
+		It("rejects invalid rotation intervals", func() {
+			_, err := newSharedSecretServerConfigProvider(secret, nil, time.Millisecond, time.Hour)
+			Expect(err).To(MatchError("server config rotation interval must be at least one second"))
+			_, err = newSharedSecretServerConfigProvider(secret, nil, time.Hour, time.Minute)
+			Expect(err).To(MatchError("server config lifetime must not be shorter than the rotation interval"))
+		})
+
+		It("derives the same server configs from the same secret", func() {
+			p1, err := NewSharedSecretServerConfigProvider(secret)
+			Expect(err).ToNot(HaveOccurred())
+			p2, err := NewSharedSecretServerConfigProvider(secret)
+			Expect(err).ToNot(HaveOccurred())
+			c1, err := p1.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := p2.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1).To(Equal(c2))
+			Expect(c1[0].CookieSecret).To(HaveLen(32))
+		})
+
+		It("derives different server configs from different secrets", func() {
+			p1, err := NewSharedSecretServerConfigProvider(secret)
+			Expect(err).ToNot(HaveOccurred())
+			p2, err := NewSharedSecretServerConfigProvider([]byte("another secret shared by all servers"))
+			Expect(err).ToNot(HaveOccurred())
+			c1, err := p1.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := p2.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1[0].ID).ToNot(Equal(c2[0].ID))
+			Expect(c1[0].PrivateKey).ToNot(Equal(c2[0].PrivateKey))
+			Expect(c1[0].CookieSecret).ToNot(Equal(c2[0].CookieSecret))
+		})
+
+		It("rotates the server config every rotation interval", func() {
+			p, err := newSharedSecretServerConfigProvider(secret, nil, time.Hour, 90*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			// 10 minutes into an interval: the server config of the previous interval is still valid
+			now := time.Unix(100*3600+10*60, 0)
+			configs, err := p.serverConfigsAt(now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0].Expiry).To(Equal(time.Unix(99*3600+90*60, 0)))
+			Expect(configs[1].Expiry).To(Equal(time.Unix(100*3600+90*60, 0)))
+			primary := configs[1]
+			// 40 minutes into the interval: the server config of the previous interval expired
+			configs, err = p.serverConfigsAt(now.Add(30 * time.Minute))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(Equal([]ServerConfigParameters{primary}))
+			// in the next interval, a new server config is used
+			configs, err = p.serverConfigsAt(now.Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0]).To(Equal(primary))
+			Expect(configs[1].ID).ToNot(Equal(primary.ID))
+			Expect(configs[1].CookieSecret).ToNot(Equal(primary.CookieSecret))
+		})
+	})
+
+	Context("deriving server configs from a shared secret and rotation secrets", func() {
+		var secret = []byte("a secret shared by all servers")
+
+		rotationSecret := func(intervalStart time.Time) ([]byte, error) {
+			return []byte(fmt.Sprintf("rotation secret %d", intervalStart.Unix())), nil
+		}
+
+		It("requires a rotation secret function", func() {
+			_, err := NewRotatingSecretServerConfigProvider(secret, nil)
+			Expect(err).To(MatchError("no rotation secret function given"))
+		})
+
+		It("derives the same server configs from the same secrets", func() {
+			p1, err := NewRotatingSecretServerConfigProvider(secret, rotationSecret)
+			Expect(err).ToNot(HaveOccurred())
+			p2, err := NewRotatingSecretServerConfigProvider(secret, rotationSecret)
+			Expect(err).ToNot(HaveOccurred())
+			c1, err := p1.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := p2.ServerConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1).To(Equal(c2))
+		})
+
+		It("mixes the rotation secret into the server config", func() {
+			p1, err := newSharedSecretServerConfigProvider(secret, nil, time.Hour, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			p2, err := newSharedSecretServerConfigProvider(secret, rotationSecret, time.Hour, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			p3, err := newSharedSecretServerConfigProvider(secret, func(time.Time) ([]byte, error) {
+				return []byte("another rotation secret"), nil
+			}, time.Hour, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			now := time.Unix(100*3600+10*60, 0)
+			c1, err := p1.serverConfigsAt(now)
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := p2.serverConfigsAt(now)
+			Expect(err).ToNot(HaveOccurred())
+			c3, err := p3.serverConfigsAt(now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c2[0].Expiry).To(Equal(c1[0].Expiry))
+			Expect(c2[0].PrivateKey).ToNot(Equal(c1[0].PrivateKey))
+			Expect(c2[0].PrivateKey).ToNot(Equal(c3[0].PrivateKey))
+			Expect(c2[0].CookieSecret).ToNot(Equal(c1[0].CookieSecret))
+			Expect(c2[0].CookieSecret).ToNot(Equal(c3[0].CookieSecret))
+		})
+
+		It("requests the rotation secret for the start of every interval", func() {
+			var starts []time.Time
+			p, err := newSharedSecretServerConfigProvider(secret, func(intervalStart time.Time) ([]byte, error) {
+				starts = append(starts, intervalStart)
+				return rotationSecret(intervalStart)
+			}, time.Hour, 90*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			configs, err := p.serverConfigsAt(time.Unix(100*3600+10*60, 0))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(starts).To(Equal([]time.Time{time.Unix(99*3600, 0), time.Unix(100*3600, 0)}))
+		})
+
+		It("returns errors from the rotation secret function", func() {
+			testErr := errors.New("key management service unavailable")
+			p, err := NewRotatingSecretServerConfigProvider(secret, func(time.Time) ([]byte, error) {
+				return nil, testErr
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.ServerConfigs()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("rejects short rotation secrets", func() {
+			p, err := NewRotatingSecretServerConfigProvider(secret, func(time.Time) ([]byte, error) {
+				return []byte("foobar"), nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.ServerConfigs()
+			Expect(err).To(MatchError("server config rotation secret too short, need at least 16 bytes"))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"bytes"
+	"time"
 
 	"github.com/seong889/quic-go/internal/crypto"
 
@@ -20,26 +21,22 @@ var _ = Describe("ServerConfig", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
-	It("generates a random ID and OBIT", func() {
-		scfg1, err := NewServerConfig(kex, nil)
-		Expect(err).ToNot(HaveOccurred())
-		scfg2, err := NewServerConfig(kex, nil)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(scfg1.ID).ToNot(Equal(scfg2.ID))
-		Expect(scfg1.obit).ToNot(Equal(scfg2.obit))
-		Expect(scfg1.cookieGenerator).ToNot(Equal(scfg2.cookieGenerator))
-	})
-
 	It("gets the proper binary representation", func() {
-		scfg, err := NewServerConfig(kex, nil)
-		Expect(err).NotTo(HaveOccurred())
+		scfg := newServerConfig(kex, nil, bytes.Repeat([]byte{'i'}, 16), bytes.Repeat([]byte{'o'}, 8), time.Unix(0x1337, 0))
 		expected := bytes.NewBuffer([]byte{0x53, 0x43, 0x46, 0x47, 0x6, 0x0, 0x0, 0x0, 0x41, 0x45, 0x41, 0x44, 0x4, 0x0, 0x0, 0x0, 0x53, 0x43, 0x49, 0x44, 0x14, 0x0, 0x0, 0x0, 0x50, 0x55, 0x42, 0x53, 0x37, 0x0, 0x0, 0x0, 0x4b, 0x45, 0x58, 0x53, 0x3b, 0x0, 0x0, 0x0, 0x4f, 0x42, 0x49, 0x54, 0x43, 0x0, 0x0, 0x0, 0x45, 0x58, 0x50, 0x59, 0x4b, 0x0, 0x0, 0x0, 0x41, 0x45, 0x53, 0x47})
 		expected.Write(scfg.ID)
 		expected.Write([]byte{0x20, 0x0, 0x0})
 		expected.Write(kex.PublicKey())
 		expected.Write([]byte{0x43, 0x32, 0x35, 0x35})
 		expected.Write(scfg.obit)
-		expected.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		expected.Write([]byte{0x37, 0x13, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})
 		Expect(scfg.Get()).To(Equal(expected.Bytes()))
 	})
+
+	It("expires", func() {
+		scfg := newServerConfig(kex, nil, nil, nil, time.Now().Add(time.Hour))
+		Expect(scfg.IsExpired()).To(BeFalse())
+		scfg.expiry = time.Now().Add(-time.Second)
+		Expect(scfg.IsExpired()).To(BeTrue())
+	})
 })
@@ -0,0 +1,160 @@
+package handshake
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+)
+
+// A StrikeRegister detects replayed CHLOs by recording the nonces used in handshakes.
+// A nonce consists of a 4 byte timestamp, the 8 byte orbit of the server config and 20 random bytes.
+type StrikeRegister interface {
+	// Insert records a nonce.
+	// It returns false if the nonce was already used, or if it can't be ruled out that it was already used.
+	Insert(nonce []byte, now time.Time) bool
+}
+
+// A StrikeRegisterStore stores the nonces recorded by a StrikeRegister.
+// It can be backed by an external store shared by multiple servers.
+// It must be safe for concurrent use by multiple goroutines.
+type StrikeRegisterStore interface {
+	// Add stores a key until the expiry time.
+	// It returns false if the key is already stored, or if it can't be ruled out that it was stored before.
+	Add(key []byte, expiry time.Time) (bool, error)
+}
+
+const strikeRegisterNonceLen = 32
+
+type strikeRegister struct {
+	store  StrikeRegisterStore
+	window time.Duration
+}
+
+var _ StrikeRegister = &strikeRegister{}
+
+// NewStrikeRegister creates a new StrikeRegister using the store.
+// Nonces are only accepted if their timestamp is within the window of the current time.
+func NewStrikeRegister(store StrikeRegisterStore, window time.Duration) StrikeRegister {
+	return &strikeRegister{
+		store:  store,
+		window: window,
+	}
+}
+
+// NewInMemoryStrikeRegister creates a new StrikeRegister that stores the nonces in memory.
+// Since nonces used before the strike register was created are unknown, all nonces with a timestamp before that are rejected.
+func NewInMemoryStrikeRegister(window time.Duration) StrikeRegister {
+	return &strikeRegister{
+		store:  newMemoryStrikeRegisterStore(protocol.MaxStrikeRegisterEntries, time.Unix(time.Now().Unix(), 0).Add(window)),
+		window: window,
+	}
+}
+
+func (r *strikeRegister) Insert(nonce []byte, now time.Time) bool {
+	if len(nonce) != strikeRegisterNonceLen {
+		return false
+	}
+	t := time.Unix(int64(binary.BigEndian.Uint32(nonce[:4])), 0)
+	if t.Before(now.Add(-r.window)) || t.After(now.Add(r.window)) {
+		return false
+	}
+	// After the expiry, the timestamp is outside of the window, so the nonce doesn't need to be stored any more.
+	ok, err := r.store.Add(nonce, t.Add(r.window))
+	if err != nil {
+		return false
+	}
+	return ok
+}
+
+type memoryStrikeRegisterStore struct {
+	mutex sync.Mutex
+
+	entries map[string]time.Time
+	// expiries contains all entries, ordered by their expiry
+	// It is used to delete expired entries, and to evict the oldest entries when the store is full.
+	expiries   strikeRegisterEntryHeap
+	maxEntries int
+	// keys with an expiry before the horizon are rejected,
+	// since they might have been added before the store was created, or might have been evicted
+	horizon time.Time
+}
+
+var _ StrikeRegisterStore = &memoryStrikeRegisterStore{}
+
+func newMemoryStrikeRegisterStore(maxEntries int, horizon time.Time) *memoryStrikeRegisterStore {
+	return &memoryStrikeRegisterStore{
+		entries:    make(map[string]time.Time),
+		maxEntries: maxEntries,
+		horizon:    horizon,
+	}
+}
+
+func (s *memoryStrikeRegisterStore) Add(key []byte, expiry time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(time.Now())
+	if expiry.Before(s.horizon) {
+		return false, nil
+	}
+	// all remaining entries are not expired yet
+	if _, ok := s.entries[string(key)]; ok {
+		return false, nil
+	}
+	if len(s.entries) >= s.maxEntries {
+		s.evictOldest()
+		if expiry.Before(s.horizon) {
+			return false, nil
+		}
+	}
+	s.entries[string(key)] = expiry
+	heap.Push(&s.expiries, strikeRegisterEntry{key: string(key), expiry: expiry})
+	return true, nil
+}
+
+// evictOldest deletes the entries with the earliest expiry, and advances the horizon past them.
+// It must be called with the mutex held.
+func (s *memoryStrikeRegisterStore) evictOldest() {
+	oldest := s.expiries[0].expiry
+	for len(s.expiries) > 0 && s.expiries[0].expiry.Equal(oldest) {
+		delete(s.entries, heap.Pop(&s.expiries).(strikeRegisterEntry).key)
+	}
+	s.horizon = oldest.Add(time.Nanosecond)
+}
+
+// prune deletes all expired entries.
+// It must be called with the mutex held.
+func (s *memoryStrikeRegisterStore) prune(now time.Time) {
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].expiry) {
+		delete(s.entries, heap.Pop(&s.expiries).(strikeRegisterEntry).key)
+	}
+}
+
+type strikeRegisterEntry struct {
+	key    string
+	expiry time.Time
+}
+
+// strikeRegisterEntryHeap is a min-heap of entries, ordered by their expiry
+type strikeRegisterEntryHeap []strikeRegisterEntry
+
+var _ heap.Interface = &strikeRegisterEntryHeap{}
+
+func (h strikeRegisterEntryHeap) Len() int           { return len(h) }
+func (h strikeRegisterEntryHeap) Less(i, j int) bool { return h[i].expiry.Before(h[j].expiry) }
+func (h strikeRegisterEntryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *strikeRegisterEntryHeap) Push(x interface{}) {
+	*h = append(*h, x.(strikeRegisterEntry))
+}
+
+func (h *strikeRegisterEntryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,188 @@
+package handshake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockStrikeRegisterStore struct {
+	keys    map[string]time.Time
+	addErr  error
+	expires []time.Time
+}
+
+var _ StrikeRegisterStore = &mockStrikeRegisterStore{}
+
+func (s *mockStrikeRegisterStore) Add(key []byte, expiry time.Time) (bool, error) {
+	if s.addErr != nil {
+		return false, s.addErr
+	}
+	if _, ok := s.keys[string(key)]; ok {
+		return false, nil
+	}
+	s.keys[string(key)] = expiry
+	s.expires = append(s.expires, expiry)
+	return true, nil
+}
+
+type mockStrikeRegister struct {
+	nonces [][]byte
+}
+
+var _ StrikeRegister = &mockStrikeRegister{}
+
+func (r *mockStrikeRegister) Insert(nonce []byte, _ time.Time) bool {
+	for _, n := range r.nonces {
+		if bytes.Equal(n, nonce) {
+			return false
+		}
+	}
+	r.nonces = append(r.nonces, nonce)
+	return true
+}
+
+var _ = Describe("Strike Register", func() {
+	getNonce := func(t time.Time, random byte) []byte {
+		nonce := make([]byte, 32)
+		binary.BigEndian.PutUint32(nonce, uint32(t.Unix()))
+		copy(nonce[4:12], []byte("orbit---"))
+		copy(nonce[12:], bytes.Repeat([]byte{random}, 20))
+		return nonce
+	}
+
+	Context("using a store", func() {
+		var (
+			store *mockStrikeRegisterStore
+			r     StrikeRegister
+		)
+
+		BeforeEach(func() {
+			store = &mockStrikeRegisterStore{keys: make(map[string]time.Time)}
+			r = NewStrikeRegister(store, time.Minute)
+		})
+
+		It("accepts new nonces", func() {
+			now := time.Now()
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeTrue())
+			Expect(r.Insert(getNonce(now, 2), now)).To(BeTrue())
+		})
+
+		It("rejects nonces that were already used", func() {
+			now := time.Now()
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeTrue())
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeFalse())
+		})
+
+		It("stores nonces until their timestamp is outside of the window", func() {
+			now := time.Now()
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeTrue())
+			Expect(store.expires).To(Equal([]time.Time{time.Unix(now.Unix(), 0).Add(time.Minute)}))
+		})
+
+		It("rejects nonces with a timestamp outside of the window", func() {
+			now := time.Now()
+			Expect(r.Insert(getNonce(now.Add(-2*time.Minute), 1), now)).To(BeFalse())
+			Expect(r.Insert(getNonce(now.Add(2*time.Minute), 2), now)).To(BeFalse())
+			Expect(r.Insert(getNonce(now.Add(-30*time.Second), 3), now)).To(BeTrue())
+			Expect(r.Insert(getNonce(now.Add(30*time.Second), 4), now)).To(BeTrue())
+			Expect(store.keys).To(HaveLen(2))
+		})
+
+		It("rejects nonces with the wrong length", func() {
+			Expect(r.Insert([]byte("foobar"), time.Now())).To(BeFalse())
+			Expect(store.keys).To(BeEmpty())
+		})
+
+		It("rejects nonces if the store returns an error", func() {
+			store.addErr = errors.New("store unavailable")
+			Expect(r.Insert(getNonce(time.Now(), 1), time.Now())).To(BeFalse())
+		})
+	})
+
+	Context("in memory", func() {
+		It("rejects nonces that were already used", func() {
+			r := NewInMemoryStrikeRegister(time.Minute)
+			now := time.Now()
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeTrue())
+			Expect(r.Insert(getNonce(now, 1), now)).To(BeFalse())
+			Expect(r.Insert(getNonce(now, 2), now)).To(BeTrue())
+		})
+
+		It("rejects nonces with a timestamp before the strike register was created", func() {
+			now := time.Now()
+			r := NewInMemoryStrikeRegister(time.Minute)
+			Expect(r.Insert(getNonce(now.Add(-2*time.Second), 1), now)).To(BeFalse())
+		})
+	})
+
+	Context("in-memory store", func() {
+		It("stores keys until they expire", func() {
+			s := newMemoryStrikeRegisterStore(10, time.Time{})
+			Expect(s.Add([]byte("foo"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.Add([]byte("foo"), time.Now().Add(time.Hour))).To(BeFalse())
+			Expect(s.Add([]byte("bar"), time.Now().Add(-time.Second))).To(BeTrue())
+			// bar already expired
+			Expect(s.Add([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+		})
+
+		It("deletes expired keys", func() {
+			s := newMemoryStrikeRegisterStore(10, time.Time{})
+			Expect(s.Add([]byte("foo"), time.Now().Add(-time.Second))).To(BeTrue())
+			Expect(s.Add([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.Add([]byte("baz"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.entries).To(HaveLen(2))
+			Expect(s.entries).ToNot(HaveKey("foo"))
+			Expect(s.expiries).To(HaveLen(2))
+		})
+
+		It("rejects keys expiring before the horizon", func() {
+			s := newMemoryStrikeRegisterStore(10, time.Now())
+			Expect(s.Add([]byte("foo"), time.Now().Add(-time.Second))).To(BeFalse())
+			Expect(s.Add([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+		})
+
+		It("evicts the oldest keys and advances the horizon when it is full", func() {
+			s := newMemoryStrikeRegisterStore(2, time.Time{})
+			foo := time.Now().Add(time.Minute)
+			Expect(s.Add([]byte("foo"), foo)).To(BeTrue())
+			Expect(s.Add([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.Add([]byte("baz"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.entries).To(HaveLen(2))
+			Expect(s.entries).ToNot(HaveKey("foo"))
+			// foo might have been used, since it was evicted
+			Expect(s.Add([]byte("foo"), foo)).To(BeFalse())
+			Expect(s.Add([]byte("qux"), foo)).To(BeFalse())
+		})
+
+		It("evicts all keys with the earliest expiry at once", func() {
+			s := newMemoryStrikeRegisterStore(4, time.Time{})
+			early := time.Now().Add(time.Minute)
+			late := time.Now().Add(time.Hour)
+			Expect(s.Add([]byte("foo"), late)).To(BeTrue())
+			Expect(s.Add([]byte("bar"), early)).To(BeTrue())
+			Expect(s.Add([]byte("baz"), late)).To(BeTrue())
+			Expect(s.Add([]byte("qux"), early)).To(BeTrue())
+			Expect(s.Add([]byte("quux"), late)).To(BeTrue())
+			Expect(s.entries).To(HaveLen(3))
+			Expect(s.entries).ToNot(HaveKey("bar"))
+			Expect(s.entries).ToNot(HaveKey("qux"))
+			Expect(s.expiries).To(HaveLen(3))
+			Expect(s.horizon).To(Equal(early.Add(time.Nanosecond)))
+		})
+
+		It("doesn't evict keys when expired keys can be deleted", func() {
+			s := newMemoryStrikeRegisterStore(2, time.Time{})
+			Expect(s.Add([]byte("foo"), time.Now().Add(-time.Second))).To(BeTrue())
+			Expect(s.Add([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(s.Add([]byte("baz"), time.Now().Add(time.Minute))).To(BeTrue())
+			Expect(s.entries).To(HaveLen(2))
+			Expect(s.entries).To(HaveKey("bar"))
+			Expect(s.horizon).To(BeZero())
+		})
+	})
+})
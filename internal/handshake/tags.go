@@ -8,6 +8,11 @@ const (
 	TagCHLO Tag = 'C' + 'H'<<8 + 'L'<<16 + 'O'<<24
 	// TagREJ is a server hello rejection
 	TagREJ Tag = 'R' + 'E'<<8 + 'J'<<16
+	// TagSREJ is a stateless rejection
+	// The client has to start a new connection, using the connection ID designated by the server
+	TagSREJ Tag = 'S' + 'R'<<8 + 'E'<<16 + 'J'<<24
+	// TagRCID is the connection ID designated by the server in a SREJ
+	TagRCID Tag = 'R' + 'C'<<8 + 'I'<<16 + 'D'<<24
 	// TagSCFG is a server config
 	TagSCFG Tag = 'S' + 'C'<<8 + 'F'<<16 + 'G'<<24
 
@@ -25,6 +30,10 @@ const (
 	TagMSPC Tag = 'M' + 'S'<<8 + 'P'<<16 + 'C'<<24
 	// TagMIDS is max incoming dyanamic streams
 	TagMIDS Tag = 'M' + 'I'<<8 + 'D'<<16 + 'S'<<24
+	// TagMUDS is max incoming unidirectional streams (unofficial tag by us :)
+	TagMUDS Tag = 'M' + 'U'<<8 + 'D'<<16 + 'S'<<24
+	// TagMDFS is the max DATAGRAM frame size (unofficial tag by us :)
+	TagMDFS Tag = 'M' + 'D'<<8 + 'F'<<16 + 'S'<<24
 	// TagUAID is the user agent ID
 	TagUAID Tag = 'U' + 'A'<<8 + 'I'<<16 + 'D'<<24
 	// TagSVID is the server ID (unofficial tag by us :)
@@ -16,6 +16,8 @@ const (
 	omitConnectionIDParameterID
 	maxPacketSizeParameterID
 	statelessResetTokenParameterID
+	maxUniStreamsParameterID
+	maxDatagramFrameSizeParameterID
 )
 
 type transportParameter struct {
@@ -27,6 +27,20 @@ var _ = Describe("Transport Parameters", func() {
 				Expect(params.OmitConnectionID).To(BeFalse())
 			})
 
+			It("reads the maximum number of unidirectional streams", func() {
+				values := map[Tag][]byte{TagMUDS: {0x37, 0x13, 0, 0}}
+				params, err := readHelloMap(values)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(params.MaxUniStreams).To(Equal(uint32(0x1337)))
+			})
+
+			It("reads the maximum DATAGRAM frame size", func() {
+				values := map[Tag][]byte{TagMDFS: {0x37, 0x13}}
+				params, err := readHelloMap(values)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(params.MaxDatagramFrameSize).To(Equal(protocol.ByteCount(0x1337)))
+			})
+
 			It("reads if the connection ID should be omitted", func() {
 				values := map[Tag][]byte{TagTCID: {0, 0, 0, 0}}
 				params, err := readHelloMap(values)
@@ -74,6 +88,18 @@ var _ = Describe("Transport Parameters", func() {
 				_, err := readHelloMap(values)
 				Expect(err).To(MatchError(errMalformedTag))
 			})
+
+			It("errors when given an invalid MUDS value", func() {
+				values := map[Tag][]byte{TagMUDS: {2, 0, 0}} // 1 byte too short
+				_, err := readHelloMap(values)
+				Expect(err).To(MatchError(errMalformedTag))
+			})
+
+			It("errors when given an invalid MDFS value", func() {
+				values := map[Tag][]byte{TagMDFS: {2}} // 1 byte too short
+				_, err := readHelloMap(values)
+				Expect(err).To(MatchError(errMalformedTag))
+			})
 		})
 
 		Context("writing", func() {
@@ -93,6 +119,23 @@ var _ = Describe("Transport Parameters", func() {
 				Expect(entryMap).To(HaveKeyWithValue(TagMIDS, []byte{0x37, 0x13, 0, 0}))
 			})
 
+			It("sends the maximum number of unidirectional streams", func() {
+				params := &TransportParameters{MaxUniStreams: 0x42}
+				entryMap := params.getHelloMap()
+				Expect(entryMap).To(HaveKeyWithValue(TagMUDS, []byte{0x42, 0, 0, 0}))
+			})
+
+			It("sends the maximum DATAGRAM frame size", func() {
+				params := &TransportParameters{MaxDatagramFrameSize: 0x42}
+				entryMap := params.getHelloMap()
+				Expect(entryMap).To(HaveKeyWithValue(TagMDFS, []byte{0x42, 0}))
+			})
+
+			It("doesn't send the maximum DATAGRAM frame size if DATAGRAM frames are not supported", func() {
+				params := &TransportParameters{}
+				Expect(params.getHelloMap()).ToNot(HaveKey(TagMDFS))
+			})
+
 			It("requests omission of the connection ID", func() {
 				params := &TransportParameters{OmitConnectionID: true}
 				entryMap := params.getHelloMap()
@@ -200,6 +243,32 @@ var _ = Describe("Transport Parameters", func() {
 				Expect(err).To(MatchError("wrong length for omit_connection_id: 1 (expected empty)"))
 			})
 
+			It("reads the maximum number of unidirectional streams", func() {
+				parameters[maxUniStreamsParameterID] = []byte{0, 0, 0x13, 0x37}
+				params, err := readTransportParamters(paramsMapToList(parameters))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(params.MaxUniStreams).To(Equal(uint32(0x1337)))
+			})
+
+			It("errors if the max_uni_streams has the wrong length", func() {
+				parameters[maxUniStreamsParameterID] = []byte{0x13, 0x37} // should be 4 bytes
+				_, err := readTransportParamters(paramsMapToList(parameters))
+				Expect(err).To(MatchError("wrong length for max_uni_streams: 2 (expected 4)"))
+			})
+
+			It("reads the maximum DATAGRAM frame size", func() {
+				parameters[maxDatagramFrameSizeParameterID] = []byte{0x13, 0x37}
+				params, err := readTransportParamters(paramsMapToList(parameters))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(params.MaxDatagramFrameSize).To(Equal(protocol.ByteCount(0x1337)))
+			})
+
+			It("errors if the max_datagram_frame_size has the wrong length", func() {
+				parameters[maxDatagramFrameSizeParameterID] = []byte{0x13, 0x37, 0} // should be 2 bytes
+				_, err := readTransportParamters(paramsMapToList(parameters))
+				Expect(err).To(MatchError("wrong length for max_datagram_frame_size: 3 (expected 2)"))
+			})
+
 			It("ignores unknown parameters", func() {
 				parameters[1337] = []byte{42}
 				_, err := readTransportParamters(paramsMapToList(parameters))
@@ -241,6 +310,18 @@ var _ = Describe("Transport Parameters", func() {
 				values := paramsListToMap(params.getTransportParameters())
 				Expect(values).To(HaveKeyWithValue(omitConnectionIDParameterID, []byte{}))
 			})
+
+			It("sends the maximum number of unidirectional streams", func() {
+				params.MaxUniStreams = 0x1337
+				values := paramsListToMap(params.getTransportParameters())
+				Expect(values).To(HaveKeyWithValue(maxUniStreamsParameterID, []byte{0, 0, 0x13, 0x37}))
+			})
+
+			It("sends the maximum DATAGRAM frame size", func() {
+				params.MaxDatagramFrameSize = 0x1337
+				values := paramsListToMap(params.getTransportParameters())
+				Expect(values).To(HaveKeyWithValue(maxDatagramFrameSizeParameterID, []byte{0x13, 0x37}))
+			})
 		})
 	})
 })
@@ -22,6 +22,12 @@ type TransportParameters struct {
 	ConnectionFlowControlWindow protocol.ByteCount
 
 	MaxStreams uint32
+	// MaxUniStreams is the number of unidirectional streams the peer may open.
+	// Peers that don't support unidirectional streams don't send this parameter, so it is 0.
+	MaxUniStreams uint32
+	// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame the peer accepts.
+	// It is 0 if the peer doesn't support DATAGRAM frames.
+	MaxDatagramFrameSize protocol.ByteCount
 
 	OmitConnectionID bool
 	IdleTimeout      time.Duration
@@ -44,6 +50,20 @@ func readHelloMap(tags map[Tag][]byte) (*TransportParameters, error) {
 		}
 		params.MaxStreams = v
 	}
+	if value, ok := tags[TagMUDS]; ok {
+		v, err := utils.LittleEndian.ReadUint32(bytes.NewBuffer(value))
+		if err != nil {
+			return nil, errMalformedTag
+		}
+		params.MaxUniStreams = v
+	}
+	if value, ok := tags[TagMDFS]; ok {
+		v, err := utils.LittleEndian.ReadUint16(bytes.NewBuffer(value))
+		if err != nil {
+			return nil, errMalformedTag
+		}
+		params.MaxDatagramFrameSize = protocol.ByteCount(v)
+	}
 	if value, ok := tags[TagICSL]; ok {
 		v, err := utils.LittleEndian.ReadUint32(bytes.NewBuffer(value))
 		if err != nil {
@@ -88,6 +108,16 @@ func (p *TransportParameters) getHelloMap() map[Tag][]byte {
 	if p.OmitConnectionID {
 		tags[TagTCID] = []byte{0, 0, 0, 0}
 	}
+	if p.MaxUniStreams > 0 {
+		muds := bytes.NewBuffer([]byte{})
+		utils.LittleEndian.WriteUint32(muds, p.MaxUniStreams)
+		tags[TagMUDS] = muds.Bytes()
+	}
+	if p.MaxDatagramFrameSize > 0 {
+		mdfs := bytes.NewBuffer([]byte{})
+		utils.LittleEndian.WriteUint16(mdfs, uint16(p.MaxDatagramFrameSize))
+		tags[TagMDFS] = mdfs.Bytes()
+	}
 	return tags
 }
 
@@ -131,6 +161,16 @@ func readTransportParamters(paramsList []transportParameter) (*TransportParamete
 				return nil, fmt.Errorf("wrong length for omit_connection_id: %d (expected empty)", len(p.Value))
 			}
 			params.OmitConnectionID = true
+		case maxUniStreamsParameterID:
+			if len(p.Value) != 4 {
+				return nil, fmt.Errorf("wrong length for max_uni_streams: %d (expected 4)", len(p.Value))
+			}
+			params.MaxUniStreams = binary.BigEndian.Uint32(p.Value)
+		case maxDatagramFrameSizeParameterID:
+			if len(p.Value) != 2 {
+				return nil, fmt.Errorf("wrong length for max_datagram_frame_size: %d (expected 2)", len(p.Value))
+			}
+			params.MaxDatagramFrameSize = protocol.ByteCount(binary.BigEndian.Uint16(p.Value))
 		}
 	}
 
@@ -163,5 +203,15 @@ func (p *TransportParameters) getTransportParameters() []transportParameter {
 	if p.OmitConnectionID {
 		params = append(params, transportParameter{omitConnectionIDParameterID, []byte{}})
 	}
+	if p.MaxUniStreams > 0 {
+		maxUniStreams := make([]byte, 4)
+		binary.BigEndian.PutUint32(maxUniStreams, p.MaxUniStreams)
+		params = append(params, transportParameter{maxUniStreamsParameterID, maxUniStreams})
+	}
+	if p.MaxDatagramFrameSize > 0 {
+		maxDatagramFrameSize := make([]byte, 2)
+		binary.BigEndian.PutUint16(maxDatagramFrameSize, uint16(p.MaxDatagramFrameSize))
+		params = append(params, transportParameter{maxDatagramFrameSizeParameterID, maxDatagramFrameSize})
+	}
 	return params
 }
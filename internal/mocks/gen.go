@@ -5,4 +5,5 @@ package mocks
 //go:generate sh -c "./mockgen_internal.sh mocks connection_flow_controller.go github.com/seong889/quic-go/internal/flowcontrol ConnectionFlowController"
 //go:generate sh -c "./mockgen_internal.sh mockcrypto crypto/aead.go github.com/seong889/quic-go/internal/crypto AEAD"
 //go:generate sh -c "./mockgen_stream.sh mocks stream.go github.com/seong889/quic-go StreamI"
+//go:generate sh -c "mockgen -package mocks -destination tracing.go github.com/seong889/quic-go/tracing SessionTracer"
 //go:generate sh -c "goimports -w ."
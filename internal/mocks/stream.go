@@ -58,6 +58,30 @@ func (_mr *MockStreamIMockRecorder) Cancel(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Cancel", reflect.TypeOf((*MockStreamI)(nil).Cancel), arg0)
 }
 
+// CancelRead mocks base method
+func (_m *MockStreamI) CancelRead(_param0 protocol.ApplicationErrorCode) error {
+	ret := _m.ctrl.Call(_m, "CancelRead", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRead indicates an expected call of CancelRead
+func (_mr *MockStreamIMockRecorder) CancelRead(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "CancelRead", reflect.TypeOf((*MockStreamI)(nil).CancelRead), arg0)
+}
+
+// CancelWrite mocks base method
+func (_m *MockStreamI) CancelWrite(_param0 protocol.ApplicationErrorCode) error {
+	ret := _m.ctrl.Call(_m, "CancelWrite", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWrite indicates an expected call of CancelWrite
+func (_mr *MockStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "CancelWrite", reflect.TypeOf((*MockStreamI)(nil).CancelWrite), arg0)
+}
+
 // Close mocks base method
 func (_m *MockStreamI) Close() error {
 	ret := _m.ctrl.Call(_m, "Close")
@@ -130,6 +154,16 @@ func (_mr *MockStreamIMockRecorder) GetWriteOffset() *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetWriteOffset", reflect.TypeOf((*MockStreamI)(nil).GetWriteOffset))
 }
 
+// HandleStopSendingFrame mocks base method
+func (_m *MockStreamI) HandleStopSendingFrame(_param0 *wire.StopSendingFrame) {
+	_m.ctrl.Call(_m, "HandleStopSendingFrame", _param0)
+}
+
+// HandleStopSendingFrame indicates an expected call of HandleStopSendingFrame
+func (_mr *MockStreamIMockRecorder) HandleStopSendingFrame(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "HandleStopSendingFrame", reflect.TypeOf((*MockStreamI)(nil).HandleStopSendingFrame), arg0)
+}
+
 // IsFlowControlBlocked mocks base method
 func (_m *MockStreamI) IsFlowControlBlocked() bool {
 	ret := _m.ctrl.Call(_m, "IsFlowControlBlocked")
@@ -154,6 +188,19 @@ func (_mr *MockStreamIMockRecorder) LenOfDataForWriting() *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "LenOfDataForWriting", reflect.TypeOf((*MockStreamI)(nil).LenOfDataForWriting))
 }
 
+// Priority mocks base method
+func (_m *MockStreamI) Priority() (protocol.StreamUrgency, bool) {
+	ret := _m.ctrl.Call(_m, "Priority")
+	ret0, _ := ret[0].(protocol.StreamUrgency)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Priority indicates an expected call of Priority
+func (_mr *MockStreamIMockRecorder) Priority() *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Priority", reflect.TypeOf((*MockStreamI)(nil).Priority))
+}
+
 // Read mocks base method
 func (_m *MockStreamI) Read(_param0 []byte) (int, error) {
 	ret := _m.ctrl.Call(_m, "Read", _param0)
@@ -211,6 +258,16 @@ func (_mr *MockStreamIMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), arg0)
 }
 
+// SetPriority mocks base method
+func (_m *MockStreamI) SetPriority(_param0 protocol.StreamUrgency, _param1 bool) {
+	_m.ctrl.Call(_m, "SetPriority", _param0, _param1)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (_mr *MockStreamIMockRecorder) SetPriority(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "SetPriority", reflect.TypeOf((*MockStreamI)(nil).SetPriority), arg0, arg1)
+}
+
 // SetReadDeadline mocks base method
 func (_m *MockStreamI) SetReadDeadline(_param0 time.Time) error {
 	ret := _m.ctrl.Call(_m, "SetReadDeadline", _param0)
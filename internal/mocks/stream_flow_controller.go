@@ -33,6 +33,16 @@ func (_m *MockStreamFlowController) EXPECT() *MockStreamFlowControllerMockRecord
 	return _m.recorder
 }
 
+// Abandon mocks base method
+func (_m *MockStreamFlowController) Abandon() {
+	_m.ctrl.Call(_m, "Abandon")
+}
+
+// Abandon indicates an expected call of Abandon
+func (_mr *MockStreamFlowControllerMockRecorder) Abandon() *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Abandon", reflect.TypeOf((*MockStreamFlowController)(nil).Abandon))
+}
+
 // AddBytesRead mocks base method
 func (_m *MockStreamFlowController) AddBytesRead(_param0 protocol.ByteCount) {
 	_m.ctrl.Call(_m, "AddBytesRead", _param0)
@@ -0,0 +1,19 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Perspective", func() {
+	It("returns the opposite", func() {
+		Expect(PerspectiveClient.Opposite()).To(Equal(PerspectiveServer))
+		Expect(PerspectiveServer.Opposite()).To(Equal(PerspectiveClient))
+	})
+
+	It("has a string representation", func() {
+		Expect(PerspectiveClient.String()).To(Equal("Client"))
+		Expect(PerspectiveServer.String()).To(Equal("Server"))
+		Expect(Perspective(0).String()).To(Equal("invalid perspective"))
+	})
+})
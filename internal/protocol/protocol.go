@@ -46,6 +46,19 @@ type StreamID uint32
 // A ByteCount in QUIC
 type ByteCount uint64
 
+// An ApplicationErrorCode is an error code defined by the application protocol
+type ApplicationErrorCode uint16
+
+// A StreamUrgency is the urgency of a stream. Streams with a lower urgency are sent first.
+type StreamUrgency uint8
+
+const (
+	// DefaultStreamUrgency is the urgency of a stream, if no priority was set
+	DefaultStreamUrgency StreamUrgency = 3
+	// MaxStreamUrgency is the highest urgency value, i.e. the lowest priority
+	MaxStreamUrgency StreamUrgency = 7
+)
+
 // MaxByteCount is the maximum value of a ByteCount
 const MaxByteCount = ByteCount(math.MaxUint64)
 
@@ -59,6 +59,9 @@ const ConnectionFlowControlMultiplier = 1.5
 // MaxIncomingStreams is the maximum number of streams that a peer may open
 const MaxIncomingStreams = 100
 
+// MaxIncomingUniStreams is the maximum number of unidirectional streams that a peer may open
+const MaxIncomingUniStreams = 100
+
 // MaxStreamsMultiplier is the slack the client is allowed for the maximum number of streams per connection, needed e.g. when packets are out of order or dropped. The minimum of this procentual increase and the absolute increment specified by MaxStreamsMinimumIncrement is used.
 const MaxStreamsMultiplier = 1.1
 
@@ -119,9 +122,36 @@ const DefaultIdleTimeout = 30 * time.Second
 // DefaultHandshakeTimeout is the default timeout for a connection until the crypto handshake succeeds.
 const DefaultHandshakeTimeout = 10 * time.Second
 
+// DefaultAcceptQueueSize is the default number of sessions that completed the handshake, but weren't accepted by the application yet.
+const DefaultAcceptQueueSize = 32
+
 // ClosedSessionDeleteTimeout the server ignores packets arriving on a connection that is already closed
 // after this time all information about the old connection will be deleted
 const ClosedSessionDeleteTimeout = time.Minute
 
+// ServerConfigRotationInterval is the interval at which the server generates a new server config
+const ServerConfigRotationInterval = 12 * time.Hour
+
+// ServerConfigLifetime is the time that a server config is valid.
+// It is longer than the ServerConfigRotationInterval, such that clients can use a cached server config for some time after it was rotated.
+const ServerConfigLifetime = 24 * time.Hour
+
+// StrikeRegisterWindow is the maximum difference between the timestamp of a client nonce and the current time.
+// CHLOs with a client nonce outside of this window can't be checked for replays, and don't complete the handshake in 0-RTT.
+const StrikeRegisterWindow = 10 * time.Minute
+
+// MaxStrikeRegisterEntries is the maximum number of nonces saved in the in-memory strike register
+const MaxStrikeRegisterEntries = 1 << 16
+
 // NumCachedCertificates is the number of cached compressed certificate chains, each taking ~1K space
 const NumCachedCertificates = 128
+
+// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame that we accept, and that we send.
+// It is chosen such that a DATAGRAM frame always fits into a forward-secure packet.
+const MaxDatagramFrameSize ByteCount = 1100
+
+// DatagramRcvQueueLen is the number of received DATAGRAM frames that we buffer before dropping them
+const DatagramRcvQueueLen = 128
+
+// DatagramSendQueueLen is the number of DATAGRAM frames that are queued for sending
+const DatagramSendQueueLen = 32
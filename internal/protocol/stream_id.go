@@ -0,0 +1,26 @@
+package protocol
+
+// Unidirectional streams use a separate stream ID space, which is marked by the most significant bit of the stream ID.
+// Within each stream ID space, client-initiated streams have odd, and server-initiated streams even stream IDs.
+const unidirectionalStreamFlag StreamID = 1 << 31
+
+// IsUnidirectional says if the stream is a unidirectional stream
+func (s StreamID) IsUnidirectional() bool {
+	return s&unidirectionalStreamFlag > 0
+}
+
+// InitiatedBy says which endpoint opened the stream
+func (s StreamID) InitiatedBy() Perspective {
+	if s%2 == 1 {
+		return PerspectiveClient
+	}
+	return PerspectiveServer
+}
+
+// FirstUnidirectionalStream returns the stream ID of the first unidirectional stream opened by an endpoint
+func FirstUnidirectionalStream(pers Perspective) StreamID {
+	if pers == PerspectiveClient {
+		return unidirectionalStreamFlag | 1
+	}
+	return unidirectionalStreamFlag | 2
+}
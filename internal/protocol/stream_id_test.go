@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream ID", func() {
+	It("says if a stream is unidirectional", func() {
+		Expect(StreamID(3).IsUnidirectional()).To(BeFalse())
+		Expect(StreamID(4).IsUnidirectional()).To(BeFalse())
+		Expect(FirstUnidirectionalStream(PerspectiveClient).IsUnidirectional()).To(BeTrue())
+		Expect(FirstUnidirectionalStream(PerspectiveServer).IsUnidirectional()).To(BeTrue())
+		Expect((FirstUnidirectionalStream(PerspectiveClient) + 2).IsUnidirectional()).To(BeTrue())
+	})
+
+	It("says who initiated a stream", func() {
+		Expect(StreamID(3).InitiatedBy()).To(Equal(PerspectiveClient))
+		Expect(StreamID(4).InitiatedBy()).To(Equal(PerspectiveServer))
+		Expect(FirstUnidirectionalStream(PerspectiveClient).InitiatedBy()).To(Equal(PerspectiveClient))
+		Expect(FirstUnidirectionalStream(PerspectiveServer).InitiatedBy()).To(Equal(PerspectiveServer))
+	})
+
+	It("uses a separate stream ID space for unidirectional streams", func() {
+		Expect(FirstUnidirectionalStream(PerspectiveClient)).To(Equal(StreamID(0x80000001)))
+		Expect(FirstUnidirectionalStream(PerspectiveServer)).To(Equal(StreamID(0x80000002)))
+	})
+})
@@ -69,6 +69,11 @@ func (vn VersionNumber) UsesMaxDataFrame() bool {
 	return vn.CryptoStreamID() == 0
 }
 
+// UsesStopSendingFrame tells if this version supports the STOP_SENDING frame
+func (vn VersionNumber) UsesStopSendingFrame() bool {
+	return !vn.isGQUIC()
+}
+
 // StreamContributesToConnectionFlowControl says if a stream contributes to connection-level flow control
 func (vn VersionNumber) StreamContributesToConnectionFlowControl(id StreamID) bool {
 	if id == vn.CryptoStreamID() {
@@ -47,6 +47,11 @@ var _ = Describe("Version", func() {
 		Expect(VersionTLS.UsesMaxDataFrame()).To(BeTrue())
 	})
 
+	It("tells if a version uses the STOP_SENDING frame", func() {
+		Expect(Version39.UsesStopSendingFrame()).To(BeFalse())
+		Expect(VersionTLS.UsesStopSendingFrame()).To(BeTrue())
+	})
+
 	It("says if a stream contributes to connection-level flowcontrol, for gQUIC", func() {
 		Expect(Version39.StreamContributesToConnectionFlowControl(1)).To(BeFalse())
 		Expect(Version39.StreamContributesToConnectionFlowControl(2)).To(BeTrue())
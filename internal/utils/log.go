@@ -75,6 +75,66 @@ func Debug() bool {
 	return logLevel == LogLevelDebug
 }
 
+// A Logger logs messages.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	// Debug returns true if debug messages are logged.
+	// It is used to avoid expensive formatting of debug messages that would be discarded anyway.
+	Debug() bool
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// WithField returns a child logger that adds the key-value pair to every message it logs.
+	WithField(key string, value interface{}) Logger
+}
+
+// DefaultLogger logs using the log package, at the level set by SetLogLevel
+var DefaultLogger Logger = &defaultLogger{}
+
+type defaultLogger struct {
+	prefix string
+}
+
+var _ Logger = &defaultLogger{}
+
+func (l *defaultLogger) Debug() bool {
+	return Debug()
+}
+
+func (l *defaultLogger) Debugf(format string, args ...interface{}) {
+	if logLevel == LogLevelDebug {
+		l.logMessage(format, args...)
+	}
+}
+
+func (l *defaultLogger) Infof(format string, args ...interface{}) {
+	if logLevel >= LogLevelInfo {
+		l.logMessage(format, args...)
+	}
+}
+
+func (l *defaultLogger) Errorf(format string, args ...interface{}) {
+	if logLevel >= LogLevelError {
+		l.logMessage(format, args...)
+	}
+}
+
+func (l *defaultLogger) WithField(key string, value interface{}) Logger {
+	field := fmt.Sprintf("%s=%v", key, value)
+	if len(l.prefix) > 0 {
+		field = l.prefix + " " + field
+	}
+	return &defaultLogger{prefix: field}
+}
+
+func (l *defaultLogger) logMessage(format string, args ...interface{}) {
+	if len(l.prefix) == 0 {
+		logMessage(format, args...)
+		return
+	}
+	logMessage("[%s] "+format, append([]interface{}{l.prefix}, args...)...)
+}
+
 func init() {
 	readLoggingEnv()
 }
@@ -97,6 +97,31 @@ var _ = Describe("Log", func() {
 		Expect(Debug()).To(BeTrue())
 	})
 
+	Context("the default logger", func() {
+		It("logs at the log level", func() {
+			SetLogLevel(LogLevelInfo)
+			DefaultLogger.Debugf("debug")
+			DefaultLogger.Infof("info")
+			DefaultLogger.Errorf("err")
+			Expect(b.String()).To(Equal("info\nerr\n"))
+			Expect(DefaultLogger.Debug()).To(BeFalse())
+		})
+
+		It("adds fields", func() {
+			SetLogLevel(LogLevelDebug)
+			logger := DefaultLogger.WithField("connection", "deadbeef").WithField("perspective", "server")
+			logger.Debugf("foo %d", 42)
+			Expect(b.String()).To(Equal("[connection=deadbeef perspective=server] foo 42\n"))
+		})
+
+		It("doesn't modify the parent logger when adding fields", func() {
+			SetLogLevel(LogLevelDebug)
+			DefaultLogger.WithField("foo", "bar")
+			DefaultLogger.Debugf("debug")
+			Expect(b.String()).To(Equal("debug\n"))
+		})
+	})
+
 	Context("reading from env", func() {
 		BeforeEach(func() {
 			Expect(logLevel).To(Equal(LogLevelNothing))
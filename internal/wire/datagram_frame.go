@@ -0,0 +1,49 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/qerr"
+)
+
+// A DatagramFrame is a DATAGRAM frame.
+// It carries unreliable application data, which is never retransmitted.
+type DatagramFrame struct {
+	Data []byte
+}
+
+// ParseDatagramFrame parses a DATAGRAM frame
+func ParseDatagramFrame(r *bytes.Reader, version protocol.VersionNumber) (*DatagramFrame, error) {
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	dataLen, err := utils.GetByteOrder(version).ReadUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	if 1+2+protocol.ByteCount(dataLen) > protocol.MaxDatagramFrameSize {
+		return nil, qerr.Error(qerr.InvalidFrameData, "DATAGRAM frame too large")
+	}
+
+	frame := &DatagramFrame{Data: make([]byte, dataLen)}
+	if _, err := io.ReadFull(r, frame.Data); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (f *DatagramFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x30)
+	utils.GetByteOrder(version).WriteUint16(b, uint16(len(f.Data)))
+	b.Write(f.Data)
+	return nil
+}
+
+// MinLength of a written frame
+func (f *DatagramFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
+	return 1 + 2 + protocol.ByteCount(len(f.Data)), nil
+}
@@ -0,0 +1,68 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/qerr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DatagramFrame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x30,
+				0x0, 0x6, // data length
+				'f', 'o', 'o', 'b', 'a', 'r',
+			})
+			frame, err := ParseDatagramFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal([]byte("foobar")))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("accepts empty frames", func() {
+			b := bytes.NewReader([]byte{0x30, 0x0, 0x0})
+			frame, err := ParseDatagramFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(BeEmpty())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("rejects frames that are too large", func() {
+			b := bytes.NewReader([]byte{0x30, 0xff, 0xff})
+			_, err := ParseDatagramFrame(b, versionBigEndian)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "DATAGRAM frame too large")))
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x30,
+				0x0, 0x3, // data length
+				'f', 'o', 'o',
+			}
+			_, err := ParseDatagramFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParseDatagramFrame(bytes.NewReader(data[0:i]), versionBigEndian)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			b := &bytes.Buffer{}
+			frame := &DatagramFrame{Data: []byte("foobar")}
+			err := frame.Write(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Bytes()).To(Equal([]byte{0x30, 0x0, 0x6, 'f', 'o', 'o', 'b', 'a', 'r'}))
+		})
+
+		It("has the correct min length", func() {
+			frame := &DatagramFrame{Data: []byte("foobar")}
+			Expect(frame.MinLength(protocol.VersionWhatever)).To(Equal(protocol.ByteCount(1 + 2 + 6)))
+		})
+	})
+})
@@ -218,14 +218,14 @@ var _ = Describe("Header", func() {
 		It("logs an IETF draft header", func() {
 			(&Header{
 				IsLongHeader: true,
-			}).Log()
+			}).Log(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Long Header"))
 		})
 
 		It("logs a Public Header", func() {
 			(&Header{
 				isPublicHeader: true,
-			}).Log()
+			}).Log(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Public Header"))
 		})
 	})
@@ -393,7 +393,7 @@ var _ = Describe("IETF draft Header", func() {
 				PacketNumber: 0x1337,
 				ConnectionID: 0xdeadbeef,
 				Version:      253,
-			}).logHeader()
+			}).logHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Long Header{Type: 0x5, ConnectionID: 0xdeadbeef, PacketNumber: 0x1337, Version: 253}"))
 		})
 
@@ -403,7 +403,7 @@ var _ = Describe("IETF draft Header", func() {
 				PacketNumber:    0x1337,
 				PacketNumberLen: 4,
 				ConnectionID:    0xdeadbeef,
-			}).logHeader()
+			}).logHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Short Header{ConnectionID: 0xdeadbeef, PacketNumber: 0x1337, PacketNumberLen: 4, KeyPhase: 1}"))
 		})
 
@@ -412,7 +412,7 @@ var _ = Describe("IETF draft Header", func() {
 				PacketNumber:     0x12,
 				PacketNumberLen:  1,
 				OmitConnectionID: true,
-			}).logHeader()
+			}).logHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Short Header{ConnectionID: (omitted), PacketNumber: 0x12, PacketNumberLen: 1, KeyPhase: 0}"))
 		})
 	})
@@ -31,17 +31,17 @@ var _ = Describe("Frame logging", func() {
 
 	It("doesn't log when debug is disabled", func() {
 		utils.SetLogLevel(utils.LogLevelInfo)
-		LogFrame(&RstStreamFrame{}, true)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, true)
 		Expect(buf.Len()).To(BeZero())
 	})
 
 	It("logs sent frames", func() {
-		LogFrame(&RstStreamFrame{}, true)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, true)
 		Expect(buf.Bytes()).To(ContainSubstring("\t-> &wire.RstStreamFrame{StreamID:0x0, ErrorCode:0x0, ByteOffset:0x0}\n"))
 	})
 
 	It("logs received frames", func() {
-		LogFrame(&RstStreamFrame{}, false)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, false)
 		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.RstStreamFrame{StreamID:0x0, ErrorCode:0x0, ByteOffset:0x0}\n"))
 	})
 
@@ -51,7 +51,7 @@ var _ = Describe("Frame logging", func() {
 			Offset:   0x1337,
 			Data:     bytes.Repeat([]byte{'f'}, 0x100),
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.StreamFrame{StreamID: 42, FinBit: false, Offset: 0x1337, Data length: 0x100, Offset + Data length: 0x1437}\n"))
 	})
 
@@ -61,15 +61,21 @@ var _ = Describe("Frame logging", func() {
 			LowestAcked:  0x42,
 			DelayTime:    1 * time.Millisecond,
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.AckFrame{LargestAcked: 0x1337, LowestAcked: 0x42, AckRanges: []wire.AckRange(nil), DelayTime: 1ms}\n"))
 	})
 
+	It("logs DATAGRAM frames", func() {
+		frame := &DatagramFrame{Data: bytes.Repeat([]byte{'f'}, 0x100)}
+		LogFrame(utils.DefaultLogger, frame, true)
+		Expect(buf.Bytes()).To(ContainSubstring("\t-> &wire.DatagramFrame{Data length: 0x100}\n"))
+	})
+
 	It("logs incoming StopWaiting frames", func() {
 		frame := &StopWaitingFrame{
 			LeastUnacked: 0x1337,
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.StopWaitingFrame{LeastUnacked: 0x1337}\n"))
 	})
 
@@ -78,7 +84,7 @@ var _ = Describe("Frame logging", func() {
 			LeastUnacked:    0x1337,
 			PacketNumberLen: protocol.PacketNumberLen4,
 		}
-		LogFrame(frame, true)
+		LogFrame(utils.DefaultLogger, frame, true)
 		Expect(buf.Bytes()).To(ContainSubstring("\t-> &wire.StopWaitingFrame{LeastUnacked: 0x1337, PacketNumberLen: 0x4}\n"))
 	})
 })
@@ -513,7 +513,7 @@ var _ = Describe("Public Header", func() {
 				PacketNumber:    0x1337,
 				PacketNumberLen: 6,
 				Version:         protocol.Version39,
-			}).logPublicHeader()
+			}).logPublicHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Public Header{ConnectionID: 0xdecafbad, PacketNumber: 0x1337, PacketNumberLen: 6, Version: gQUIC 39"))
 		})
 
@@ -523,7 +523,7 @@ var _ = Describe("Public Header", func() {
 				PacketNumber:     0x1337,
 				PacketNumberLen:  6,
 				Version:          protocol.Version39,
-			}).logPublicHeader()
+			}).logPublicHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Public Header{ConnectionID: (omitted)"))
 		})
 
@@ -532,7 +532,7 @@ var _ = Describe("Public Header", func() {
 				OmitConnectionID: true,
 				PacketNumber:     0x1337,
 				PacketNumberLen:  6,
-			}).logPublicHeader()
+			}).logPublicHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("Version: (unset)"))
 		})
 
@@ -540,7 +540,7 @@ var _ = Describe("Public Header", func() {
 			(&Header{
 				ConnectionID:         0xdecafbad,
 				DiversificationNonce: []byte{0xba, 0xdf, 0x00, 0x0d},
-			}).logPublicHeader()
+			}).logPublicHeader(utils.DefaultLogger)
 			Expect(string(buf.Bytes())).To(ContainSubstring("DiversificationNonce: []byte{0xba, 0xdf, 0x0, 0xd}"))
 		})
 
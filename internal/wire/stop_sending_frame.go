@@ -0,0 +1,48 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+)
+
+// A StopSendingFrame is a STOP_SENDING frame
+type StopSendingFrame struct {
+	StreamID  protocol.StreamID
+	ErrorCode protocol.ApplicationErrorCode
+}
+
+// ParseStopSendingFrame parses a STOP_SENDING frame
+func ParseStopSendingFrame(r *bytes.Reader, version protocol.VersionNumber) (*StopSendingFrame, error) {
+	// read the TypeByte
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	sid, err := utils.GetByteOrder(version).ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	errorCode, err := utils.GetByteOrder(version).ReadUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	return &StopSendingFrame{
+		StreamID:  protocol.StreamID(sid),
+		ErrorCode: protocol.ApplicationErrorCode(errorCode),
+	}, nil
+}
+
+// MinLength of a written frame
+func (f *StopSendingFrame) MinLength(_ protocol.VersionNumber) (protocol.ByteCount, error) {
+	return 1 + 4 + 2, nil
+}
+
+// Write writes a STOP_SENDING frame
+func (f *StopSendingFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x0c)
+	utils.GetByteOrder(version).WriteUint32(b, uint32(f.StreamID))
+	utils.GetByteOrder(version).WriteUint16(b, uint16(f.ErrorCode))
+	return nil
+}
@@ -0,0 +1,62 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("STOP_SENDING frame", func() {
+	Context("when parsing", func() {
+		It("parses a sample frame", func() {
+			b := bytes.NewReader([]byte{0x0c,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x13, 0x37, // error code
+			})
+			frame, err := ParseStopSendingFrame(b, versionMaxDataFrame)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.ErrorCode).To(Equal(protocol.ApplicationErrorCode(0x1337)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x0c,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x13, 0x37, // error code
+			}
+			_, err := ParseStopSendingFrame(bytes.NewReader(data), versionMaxDataFrame)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParseStopSendingFrame(bytes.NewReader(data[:i]), versionMaxDataFrame)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes", func() {
+			frame := &StopSendingFrame{
+				StreamID:  0xdeadbeef,
+				ErrorCode: 0x1337,
+			}
+			buf := &bytes.Buffer{}
+			err := frame.Write(buf, versionMaxDataFrame)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.Bytes()).To(Equal([]byte{0x0c,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x13, 0x37, // error code
+			}))
+		})
+
+		It("has the correct min length", func() {
+			frame := &StopSendingFrame{
+				StreamID:  0xdeadbeef,
+				ErrorCode: 0x10,
+			}
+			Expect(frame.MinLength(versionMaxDataFrame)).To(Equal(protocol.ByteCount(7)))
+		})
+	})
+})
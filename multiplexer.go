@@ -0,0 +1,62 @@
+package quic
+
+import (
+	"net"
+	"sync"
+
+	"github.com/seong889/quic-go/internal/utils"
+)
+
+var (
+	connMuxerOnce sync.Once
+	connMuxer     *multiplexer
+)
+
+// The multiplexer allows a single net.PacketConn to be used by a Listener and any number of client sessions.
+// For every net.PacketConn, it creates a packetHandlerMap, which reads from the connection
+// and passes the packets to the server and the client sessions, based on the connection ID.
+type multiplexer struct {
+	mutex sync.Mutex
+
+	conns map[net.PacketConn]*packetHandlerMap
+}
+
+func getMultiplexer() *multiplexer {
+	connMuxerOnce.Do(func() {
+		connMuxer = &multiplexer{
+			conns: make(map[net.PacketConn]*packetHandlerMap),
+		}
+	})
+	return connMuxer
+}
+
+// AddConn returns the packetHandlerManager for a net.PacketConn.
+// If the connection is not yet used by any server or client session, a new packetHandlerManager is created.
+func (m *multiplexer) AddConn(c net.PacketConn, logger utils.Logger) packetHandlerManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if h, ok := m.conns[c]; ok {
+		return h
+	}
+	h := newPacketHandlerMap(c, logger)
+	h.onClose = func() { m.removeConn(c, h) }
+	m.conns[c] = h
+	return h
+}
+
+// RemoveConn removes the packetHandlerManager of a net.PacketConn that was created for a client session that failed to start.
+func (m *multiplexer) RemoveConn(c net.PacketConn) {
+	m.mutex.Lock()
+	delete(m.conns, c)
+	m.mutex.Unlock()
+}
+
+func (m *multiplexer) removeConn(c net.PacketConn, h *packetHandlerMap) {
+	m.mutex.Lock()
+	// the connection might already have been replaced by a new packetHandlerMap
+	if m.conns[c] == h {
+		delete(m.conns, c)
+	}
+	m.mutex.Unlock()
+}
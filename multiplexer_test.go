@@ -0,0 +1,55 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/seong889/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multiplexer", func() {
+	It("returns the same packetHandlerManager for the same connection", func() {
+		conn := &mockPacketConn{addr: &net.UDPAddr{}}
+		h1 := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		h2 := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		Expect(h2).To(BeIdenticalTo(h1))
+		Expect(getMultiplexer().AddConn(&mockPacketConn{}, utils.DefaultLogger)).ToNot(BeIdenticalTo(h1))
+	})
+
+	It("creates a new packetHandlerManager after the connection was closed", func() {
+		conn := &mockPacketConn{addr: &net.UDPAddr{}}
+		h1 := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		Expect(h1.SetServer(&mockRawPacketHandler{})).To(Succeed())
+		Expect(h1.CloseServer()).To(Succeed())
+		Expect(conn.closed).To(BeTrue())
+		h2 := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		Expect(h2).ToNot(BeIdenticalTo(h1))
+	})
+
+	It("removes a connection", func() {
+		conn := &mockPacketConn{addr: &net.UDPAddr{}}
+		h1 := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		getMultiplexer().RemoveConn(conn)
+		Expect(getMultiplexer().AddConn(conn, utils.DefaultLogger)).ToNot(BeIdenticalTo(h1))
+		getMultiplexer().RemoveConn(conn)
+	})
+
+	It("removes the packetHandlerManager when all client sessions are removed, without closing the connection", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		h := getMultiplexer().AddConn(conn, utils.DefaultLogger)
+		Expect(h.Add(1, &mockRawPacketHandler{})).To(Succeed())
+		h.Remove(1)
+		Eventually(func() bool {
+			getMultiplexer().mutex.Lock()
+			defer getMultiplexer().mutex.Unlock()
+			_, ok := getMultiplexer().conns[conn]
+			return ok
+		}).Should(BeFalse())
+		_, err = conn.WriteTo([]byte("foobar"), conn.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,280 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/internal/wire"
+)
+
+// A rawPacketHandler handles packets read from a net.PacketConn.
+// It is implemented by the server and by the client.
+type rawPacketHandler interface {
+	handleRawPacket(remoteAddr net.Addr, data []byte)
+	// closeWithError is called when reading from the net.PacketConn fails
+	closeWithError(error)
+}
+
+// A packetHandlerManager passes the packets received on a net.PacketConn to the right handler.
+type packetHandlerManager interface {
+	// Add adds the handler of a client session
+	Add(protocol.ConnectionID, rawPacketHandler) error
+	// Remove removes the handler of a client session
+	Remove(protocol.ConnectionID)
+	// SetServer sets the handler for all packets that don't belong to a client session
+	SetServer(rawPacketHandler) error
+	// CloseServer removes the server.
+	// The net.PacketConn is closed as soon as there are no client sessions left.
+	CloseServer() error
+}
+
+var errConnectionClosed = errors.New("the net.PacketConn was already closed")
+
+// The packetHandlerMap reads packets from a net.PacketConn.
+// Packets are passed to the client session with a matching connection ID.
+// All other packets are passed to the server.
+// The net.PacketConn is only closed by the packetHandlerMap after the server was closed,
+// as soon as there are no client sessions left.
+// If there's no server, the packetHandlerMap stops reading from the net.PacketConn when the last client session is removed,
+// but doesn't close it, since it is owned by the application.
+type packetHandlerMap struct {
+	mutex sync.RWMutex
+
+	conn   net.PacketConn
+	logger utils.Logger
+
+	// the handlers of closed client sessions are set to nil, and deleted after deleteClosedSessionsAfter
+	handlers                  map[protocol.ConnectionID]rawPacketHandler
+	deleteClosedSessionsAfter time.Duration
+	server                    rawPacketHandler
+	listening                 bool
+	// set by CloseServer. The net.PacketConn is closed when the last client session is removed.
+	closeWhenUnused bool
+	// set when the last client session is removed, if there's no server.
+	// The read deadline is used to unblock the run loop, which then stops reading from the net.PacketConn.
+	stopping bool
+	closed   bool
+
+	// called when the net.PacketConn is closed
+	onClose func()
+}
+
+var _ packetHandlerManager = &packetHandlerMap{}
+
+func newPacketHandlerMap(conn net.PacketConn, logger utils.Logger) *packetHandlerMap {
+	return &packetHandlerMap{
+		conn:                      conn,
+		logger:                    logger,
+		handlers:                  make(map[protocol.ConnectionID]rawPacketHandler),
+		deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
+		onClose:                   func() {},
+	}
+}
+
+func (h *packetHandlerMap) Add(id protocol.ConnectionID, handler rawPacketHandler) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return errConnectionClosed
+	}
+	h.handlers[id] = handler
+	h.maybeStartListening()
+	return nil
+}
+
+// Remove removes the handler of a client session.
+// Packets arriving for this connection ID after the session was removed are dropped.
+func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
+	h.mutex.Lock()
+	if _, ok := h.handlers[id]; ok {
+		h.handlers[id] = nil
+		time.AfterFunc(h.deleteClosedSessionsAfter, func() {
+			h.mutex.Lock()
+			// the connection ID might have been reused in the meantime
+			if handler, ok := h.handlers[id]; ok && handler == nil {
+				delete(h.handlers, id)
+			}
+			h.mutex.Unlock()
+		})
+	}
+	_ = h.maybeClose()
+	h.mutex.Unlock()
+}
+
+func (h *packetHandlerMap) SetServer(server rawPacketHandler) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return errConnectionClosed
+	}
+	if h.server != nil {
+		return errors.New("a Listener is already using this net.PacketConn")
+	}
+	h.server = server
+	h.maybeStartListening()
+	return nil
+}
+
+func (h *packetHandlerMap) CloseServer() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.server = nil
+	h.closeWhenUnused = true
+	return h.maybeClose()
+}
+
+// maybeStartListening starts the run loop, when the first handler is added.
+// It must be called with the mutex held.
+func (h *packetHandlerMap) maybeStartListening() {
+	if h.listening {
+		return
+	}
+	h.listening = true
+	go h.listen()
+}
+
+// maybeClose is called when a handler is removed.
+// If the server was closed and there are no client sessions left, it closes the net.PacketConn.
+// If there never was a server, it stops the run loop when the last client session is removed.
+// It must be called with the mutex held.
+func (h *packetHandlerMap) maybeClose() error {
+	if h.closed || h.server != nil || h.numClientSessions() > 0 {
+		return nil
+	}
+	if !h.closeWhenUnused {
+		if !h.listening {
+			return nil
+		}
+		h.stopping = true
+		return h.conn.SetReadDeadline(time.Now())
+	}
+	h.closed = true
+	h.onClose()
+	return h.conn.Close()
+}
+
+// handleReadError is called when reading from the net.PacketConn fails.
+// It returns true if the run loop should continue reading.
+func (h *packetHandlerMap) handleReadError(err error) bool {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return false
+	}
+	if h.stopping {
+		h.stopping = false
+		_ = h.conn.SetReadDeadline(time.Time{})
+		// a handler might have been added after the read deadline was set
+		if h.server != nil || h.numClientSessions() > 0 {
+			h.mutex.Unlock()
+			return true
+		}
+		h.closed = true
+		h.onClose()
+		h.mutex.Unlock()
+		return false
+	}
+	h.mutex.Unlock()
+	h.closeWithError(err)
+	return false
+}
+
+// numClientSessions returns the number of client sessions that were not removed yet.
+// It must be called with the mutex held.
+func (h *packetHandlerMap) numClientSessions() int {
+	var n int
+	for _, handler := range h.handlers {
+		if handler != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (h *packetHandlerMap) listen() {
+	for {
+		data := getPacketBuffer()
+		data = data[:protocol.MaxReceivePacketSize]
+		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+		// If it does, we only read a truncated packet, which will then end up undecryptable
+		n, remoteAddr, err := h.conn.ReadFrom(data)
+		if err != nil {
+			putPacketBuffer(data)
+			if h.handleReadError(err) {
+				continue
+			}
+			return
+		}
+		data = data[:n]
+
+		h.handlePacket(remoteAddr, data)
+	}
+}
+
+func (h *packetHandlerMap) handlePacket(remoteAddr net.Addr, data []byte) {
+	connID, err := wire.PeekConnectionID(bytes.NewReader(data))
+
+	h.mutex.RLock()
+	handler, ok := h.handlers[connID]
+	if ok && err == nil && handler == nil {
+		h.mutex.RUnlock()
+		// Late packet for a closed session
+		putPacketBuffer(data)
+		return
+	}
+	if err != nil || !ok {
+		handler = h.server
+		// If there's no server, this packet might be a packet without a connection ID, sent to a client.
+		// This can only be handled if there's only a single client session.
+		if handler == nil && h.numClientSessions() == 1 {
+			for _, hdlr := range h.handlers {
+				if hdlr != nil {
+					handler = hdlr
+				}
+			}
+		}
+	}
+	h.mutex.RUnlock()
+
+	if handler == nil {
+		h.logger.Debugf("Dropping packet from %s for unknown connection %x", remoteAddr, connID)
+		putPacketBuffer(data)
+		return
+	}
+	handler.handleRawPacket(remoteAddr, data)
+}
+
+// closeWithError is called when reading from the net.PacketConn fails.
+// All handlers are notified, unless the connection was closed because all handlers were removed.
+func (h *packetHandlerMap) closeWithError(e error) {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return
+	}
+	h.closed = true
+	h.onClose()
+	handlers := make([]rawPacketHandler, 0, len(h.handlers)+1)
+	for _, handler := range h.handlers {
+		if handler != nil {
+			handlers = append(handlers, handler)
+		}
+	}
+	if h.server != nil {
+		handlers = append(handlers, h.server)
+	}
+	h.mutex.Unlock()
+
+	_ = h.conn.Close()
+	for _, handler := range handlers {
+		handler.closeWithError(e)
+	}
+}
@@ -0,0 +1,310 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockRawPacketHandler struct {
+	mutex      sync.Mutex
+	packets    [][]byte
+	closeError error
+}
+
+func (h *mockRawPacketHandler) handleRawPacket(_ net.Addr, data []byte) {
+	h.mutex.Lock()
+	h.packets = append(h.packets, data)
+	h.mutex.Unlock()
+}
+
+func (h *mockRawPacketHandler) closeWithError(e error) {
+	h.mutex.Lock()
+	h.closeError = e
+	h.mutex.Unlock()
+}
+
+func (h *mockRawPacketHandler) getPackets() [][]byte {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.packets
+}
+
+func (h *mockRawPacketHandler) getCloseError() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.closeError
+}
+
+var _ rawPacketHandler = &mockRawPacketHandler{}
+
+var _ = Describe("Packet Handler Map", func() {
+	var (
+		handler    *packetHandlerMap
+		conn       *net.UDPConn
+		remoteConn *net.UDPConn
+	)
+
+	getPacket := func(connID protocol.ConnectionID) []byte {
+		b := &bytes.Buffer{}
+		err := (&wire.Header{
+			ConnectionID:    connID,
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen1,
+		}).Write(b, protocol.PerspectiveServer, protocol.VersionWhatever)
+		Expect(err).ToNot(HaveOccurred())
+		return b.Bytes()
+	}
+
+	sendPacket := func(data []byte) {
+		_, err := remoteConn.WriteTo(data, conn.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		remoteConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		handler = newPacketHandlerMap(conn, utils.DefaultLogger)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		remoteConn.Close()
+	})
+
+	It("passes packets to the client sessions, based on the connection ID", func() {
+		client1 := &mockRawPacketHandler{}
+		client2 := &mockRawPacketHandler{}
+		Expect(handler.Add(1, client1)).To(Succeed())
+		Expect(handler.Add(2, client2)).To(Succeed())
+		sendPacket(getPacket(1))
+		sendPacket(getPacket(2))
+		sendPacket(getPacket(2))
+		Eventually(client1.getPackets).Should(HaveLen(1))
+		Eventually(client2.getPackets).Should(HaveLen(2))
+		Expect(client1.getPackets()[0]).To(Equal(getPacket(1)))
+		Expect(client2.getPackets()[0]).To(Equal(getPacket(2)))
+	})
+
+	It("passes packets for unknown connections to the server", func() {
+		client := &mockRawPacketHandler{}
+		server := &mockRawPacketHandler{}
+		Expect(handler.Add(1, client)).To(Succeed())
+		Expect(handler.SetServer(server)).To(Succeed())
+		sendPacket(getPacket(1))
+		sendPacket(getPacket(42))
+		Eventually(server.getPackets).Should(HaveLen(1))
+		Expect(server.getPackets()[0]).To(Equal(getPacket(42)))
+		Eventually(client.getPackets).Should(HaveLen(1))
+		Expect(client.getPackets()[0]).To(Equal(getPacket(1)))
+	})
+
+	It("passes packets for unknown connections to the only client session, if there's no server", func() {
+		// this is the case if the server omits the connection ID
+		client := &mockRawPacketHandler{}
+		Expect(handler.Add(1, client)).To(Succeed())
+		sendPacket(getPacket(42))
+		Eventually(client.getPackets).Should(HaveLen(1))
+	})
+
+	It("drops packets for unknown connections, if there's no server and multiple client sessions", func() {
+		client1 := &mockRawPacketHandler{}
+		client2 := &mockRawPacketHandler{}
+		Expect(handler.Add(1, client1)).To(Succeed())
+		Expect(handler.Add(2, client2)).To(Succeed())
+		sendPacket(getPacket(42))
+		sendPacket(getPacket(1))
+		Eventually(client1.getPackets).Should(HaveLen(1))
+		Expect(client1.getPackets()[0]).To(Equal(getPacket(1)))
+		Consistently(client2.getPackets).Should(BeEmpty())
+	})
+
+	It("handles many client sessions", func() {
+		const num = 100
+		clients := make([]*mockRawPacketHandler, num)
+		for i := range clients {
+			clients[i] = &mockRawPacketHandler{}
+			Expect(handler.Add(protocol.ConnectionID(i+1), clients[i])).To(Succeed())
+		}
+		for i := range clients {
+			sendPacket(getPacket(protocol.ConnectionID(i + 1)))
+		}
+		for i, client := range clients {
+			Eventually(client.getPackets).Should(HaveLen(1))
+			Expect(client.getPackets()[0]).To(Equal(getPacket(protocol.ConnectionID(i + 1))))
+		}
+	})
+
+	Context("removing client sessions", func() {
+		It("drops packets for removed client sessions", func() {
+			client1 := &mockRawPacketHandler{}
+			client2 := &mockRawPacketHandler{}
+			server := &mockRawPacketHandler{}
+			Expect(handler.Add(1, client1)).To(Succeed())
+			Expect(handler.Add(2, client2)).To(Succeed())
+			Expect(handler.SetServer(server)).To(Succeed())
+			handler.Remove(1)
+			sendPacket(getPacket(1))
+			sendPacket(getPacket(2))
+			Eventually(client2.getPackets).Should(HaveLen(1))
+			Consistently(client2.getPackets).Should(HaveLen(1))
+			Expect(client1.getPackets()).To(BeEmpty())
+			Expect(server.getPackets()).To(BeEmpty())
+		})
+
+		It("doesn't pass packets for removed client sessions to the only remaining client session", func() {
+			client1 := &mockRawPacketHandler{}
+			client2 := &mockRawPacketHandler{}
+			Expect(handler.Add(1, client1)).To(Succeed())
+			Expect(handler.Add(2, client2)).To(Succeed())
+			handler.Remove(1)
+			sendPacket(getPacket(1))
+			Consistently(client2.getPackets).Should(BeEmpty())
+			sendPacket(getPacket(42))
+			Eventually(client2.getPackets).Should(HaveLen(1))
+		})
+
+		It("deletes removed client sessions after a while", func() {
+			handler.deleteClosedSessionsAfter = 50 * time.Millisecond
+			server := &mockRawPacketHandler{}
+			Expect(handler.SetServer(server)).To(Succeed())
+			Expect(handler.Add(1, &mockRawPacketHandler{})).To(Succeed())
+			handler.Remove(1)
+			Eventually(func() bool {
+				handler.mutex.RLock()
+				defer handler.mutex.RUnlock()
+				_, ok := handler.handlers[1]
+				return ok
+			}).Should(BeFalse())
+			sendPacket(getPacket(1))
+			Eventually(server.getPackets).Should(HaveLen(1))
+		})
+
+		It("allows adding a client session with the connection ID of a removed session", func() {
+			handler.deleteClosedSessionsAfter = 50 * time.Millisecond
+			client := &mockRawPacketHandler{}
+			Expect(handler.Add(1, &mockRawPacketHandler{})).To(Succeed())
+			handler.Remove(1)
+			Expect(handler.Add(1, client)).To(Succeed())
+			time.Sleep(100 * time.Millisecond) // wait for the removed session to be deleted
+			sendPacket(getPacket(1))
+			Eventually(client.getPackets).Should(HaveLen(1))
+		})
+	})
+
+	It("only allows a single server", func() {
+		Expect(handler.SetServer(&mockRawPacketHandler{})).To(Succeed())
+		Expect(handler.SetServer(&mockRawPacketHandler{})).To(MatchError("a Listener is already using this net.PacketConn"))
+	})
+
+	Context("closing", func() {
+		var closed chan struct{}
+
+		BeforeEach(func() {
+			c := make(chan struct{})
+			closed = c
+			handler.onClose = func() { close(c) }
+		})
+
+		It("closes the connection when the server and all client sessions are removed", func() {
+			Expect(handler.Add(1, &mockRawPacketHandler{})).To(Succeed())
+			Expect(handler.SetServer(&mockRawPacketHandler{})).To(Succeed())
+			Expect(handler.CloseServer()).To(Succeed())
+			Expect(closed).ToNot(BeClosed())
+			sendPacket(getPacket(1)) // the connection is still open
+			handler.Remove(1)
+			Expect(closed).To(BeClosed())
+			_, err := conn.WriteTo([]byte("foobar"), remoteConn.LocalAddr())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("keeps the connection open, when the server is closed, as long as there are client sessions", func() {
+			client := &mockRawPacketHandler{}
+			Expect(handler.SetServer(&mockRawPacketHandler{})).To(Succeed())
+			Expect(handler.Add(1, client)).To(Succeed())
+			Expect(handler.CloseServer()).To(Succeed())
+			Expect(closed).ToNot(BeClosed())
+			sendPacket(getPacket(1))
+			Eventually(client.getPackets).Should(HaveLen(1))
+		})
+
+		It("doesn't add handlers after the connection was closed", func() {
+			Expect(handler.SetServer(&mockRawPacketHandler{})).To(Succeed())
+			Expect(handler.CloseServer()).To(Succeed())
+			Expect(handler.Add(1, &mockRawPacketHandler{})).To(MatchError(errConnectionClosed))
+			Expect(handler.SetServer(&mockRawPacketHandler{})).To(MatchError(errConnectionClosed))
+		})
+
+		It("stops reading from the connection when all client sessions are removed, if there's no server", func() {
+			client := &mockRawPacketHandler{}
+			Expect(handler.Add(1, client)).To(Succeed())
+			Expect(handler.Add(2, &mockRawPacketHandler{})).To(Succeed())
+			handler.Remove(1)
+			handler.Remove(2)
+			Eventually(closed).Should(BeClosed())
+			Expect(handler.Add(3, client)).To(MatchError(errConnectionClosed))
+			Expect(client.getCloseError()).ToNot(HaveOccurred())
+			// the connection is not closed, and can be used by a new packetHandlerMap
+			handler = newPacketHandlerMap(conn, utils.DefaultLogger)
+			Expect(handler.Add(3, client)).To(Succeed())
+			sendPacket(getPacket(3))
+			Eventually(client.getPackets).Should(HaveLen(1))
+		})
+
+		It("continues reading from the connection if a client session is added while stopping", func() {
+			client := &mockRawPacketHandler{}
+			Expect(handler.Add(1, &mockRawPacketHandler{})).To(Succeed())
+			handler.mutex.Lock()
+			handler.handlers[1] = nil
+			Expect(handler.maybeClose()).To(Succeed())
+			Expect(handler.stopping).To(BeTrue())
+			handler.handlers[2] = client
+			handler.mutex.Unlock()
+			Eventually(func() bool {
+				handler.mutex.Lock()
+				defer handler.mutex.Unlock()
+				return handler.stopping
+			}).Should(BeFalse())
+			sendPacket(getPacket(2))
+			Eventually(client.getPackets).Should(HaveLen(1))
+			Expect(closed).ToNot(BeClosed())
+		})
+
+		It("doesn't notify removed client sessions when the connection is closed", func() {
+			client := &mockRawPacketHandler{}
+			Expect(handler.Add(1, client)).To(Succeed())
+			handler.Remove(1)
+			Expect(handler.CloseServer()).To(Succeed())
+			Expect(closed).To(BeClosed())
+			Consistently(client.getCloseError).Should(BeNil())
+		})
+
+		It("notifies all handlers when reading from the connection fails", func() {
+			testErr := errors.New("read failed")
+			packetConn := &mockPacketConn{readErr: testErr}
+			handler = newPacketHandlerMap(packetConn, utils.DefaultLogger)
+			c := make(chan struct{})
+			handler.onClose = func() { close(c) }
+			client := &mockRawPacketHandler{}
+			server := &mockRawPacketHandler{}
+			Expect(handler.Add(1, client)).To(Succeed())
+			Expect(handler.SetServer(server)).To(Succeed())
+			Eventually(client.getCloseError).Should(MatchError(testErr))
+			Eventually(server.getCloseError).Should(MatchError(testErr))
+			Expect(c).To(BeClosed())
+			Expect(packetConn.closed).To(BeTrue())
+		})
+	})
+})
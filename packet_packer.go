@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/seong889/quic-go/ackhandler"
 	"github.com/seong889/quic-go/internal/handshake"
@@ -26,8 +27,13 @@ type packetPacker struct {
 
 	packetNumberGenerator *packetNumberGenerator
 	streamFramer          *streamFramer
+	datagramQueue         *datagramQueue
+
+	// controlFrames are queued by the session's run loop, but also by the application,
+	// e.g. when it resets a stream or closes the session gracefully
+	controlFrameMutex sync.Mutex
+	controlFrames     []wire.Frame
 
-	controlFrames    []wire.Frame
 	stopWaiting      *wire.StopWaitingFrame
 	ackFrame         *wire.AckFrame
 	leastUnacked     protocol.PacketNumber
@@ -37,6 +43,7 @@ type packetPacker struct {
 func newPacketPacker(connectionID protocol.ConnectionID,
 	cryptoSetup handshake.CryptoSetup,
 	streamFramer *streamFramer,
+	datagramQueue *datagramQueue,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
@@ -46,6 +53,7 @@ func newPacketPacker(connectionID protocol.ConnectionID,
 		perspective:           perspective,
 		version:               version,
 		streamFramer:          streamFramer,
+		datagramQueue:         datagramQueue,
 		packetNumberGenerator: newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength),
 	}
 }
@@ -207,10 +215,12 @@ func (p *packetPacker) composeNextPacket(
 		payloadLength += l
 	}
 
+	p.controlFrameMutex.Lock()
 	for len(p.controlFrames) > 0 {
 		frame := p.controlFrames[len(p.controlFrames)-1]
 		minLength, err := frame.MinLength(p.version)
 		if err != nil {
+			p.controlFrameMutex.Unlock()
 			return nil, err
 		}
 		if payloadLength+minLength > maxFrameSize {
@@ -220,6 +230,7 @@ func (p *packetPacker) composeNextPacket(
 		payloadLength += minLength
 		p.controlFrames = p.controlFrames[:len(p.controlFrames)-1]
 	}
+	p.controlFrameMutex.Unlock()
 
 	if payloadLength > maxFrameSize {
 		return nil, fmt.Errorf("Packet Packer BUG: packet payload (%d) too large (%d)", payloadLength, maxFrameSize)
@@ -229,6 +240,22 @@ func (p *packetPacker) composeNextPacket(
 		return payloadFrames, nil
 	}
 
+	// DATAGRAM frames are sent before STREAM frames, since they are usually time-sensitive
+	// if a DATAGRAM frame doesn't fit into this packet, it is sent in the next one
+	if p.datagramQueue != nil {
+		if f := p.datagramQueue.Peek(); f != nil {
+			minLength, err := f.MinLength(p.version)
+			if err != nil {
+				return nil, err
+			}
+			if payloadLength+minLength <= maxFrameSize {
+				payloadFrames = append(payloadFrames, f)
+				payloadLength += minLength
+				p.datagramQueue.Pop()
+			}
+		}
+	}
+
 	// temporarily increase the maxFrameSize by 2 bytes
 	// this leads to a properly sized packet in all cases, since we do all the packet length calculations with StreamFrames that have the DataLen set
 	// however, for the last StreamFrame in the packet, we can omit the DataLen, thus saving 2 bytes and yielding a packet of exactly the correct size
@@ -244,9 +271,11 @@ func (p *packetPacker) composeNextPacket(
 		payloadFrames = append(payloadFrames, f)
 	}
 
+	p.controlFrameMutex.Lock()
 	for b := p.streamFramer.PopBlockedFrame(); b != nil; b = p.streamFramer.PopBlockedFrame() {
 		p.controlFrames = append(p.controlFrames, b)
 	}
+	p.controlFrameMutex.Unlock()
 
 	return payloadFrames, nil
 }
@@ -258,7 +287,9 @@ func (p *packetPacker) QueueControlFrame(frame wire.Frame) {
 	case *wire.AckFrame:
 		p.ackFrame = f
 	default:
+		p.controlFrameMutex.Lock()
 		p.controlFrames = append(p.controlFrames, f)
+		p.controlFrameMutex.Unlock()
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/seong889/quic-go/internal/flowcontrol"
 	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -25,6 +26,7 @@ var _ handshake.Sealer = &mockSealer{}
 
 type mockCryptoSetup struct {
 	handleErr          error
+	connectionState    handshake.ConnectionState
 	divNonce           []byte
 	encLevelSeal       protocol.EncryptionLevel
 	encLevelSealCrypto protocol.EncryptionLevel
@@ -51,6 +53,9 @@ func (m *mockCryptoSetup) GetSealerWithEncryptionLevel(protocol.EncryptionLevel)
 func (m *mockCryptoSetup) DiversificationNonce() []byte            { return m.divNonce }
 func (m *mockCryptoSetup) SetDiversificationNonce(divNonce []byte) { m.divNonce = divNonce }
 func (m *mockCryptoSetup) GetNextPacketType() protocol.PacketType  { return m.nextPacketType }
+func (m *mockCryptoSetup) ConnectionState() handshake.ConnectionState {
+	return m.connectionState
+}
 
 var _ = Describe("Packet packer", func() {
 	var (
@@ -62,7 +67,7 @@ var _ = Describe("Packet packer", func() {
 	)
 
 	BeforeEach(func() {
-		cryptoStream = &stream{flowController: flowcontrol.NewStreamFlowController(1, false, flowcontrol.NewConnectionFlowController(1000, 1000, nil), 1000, 1000, 1000, nil)}
+		cryptoStream = &stream{flowController: flowcontrol.NewStreamFlowController(1, false, flowcontrol.NewConnectionFlowController(1000, 1000, nil, utils.DefaultLogger), 1000, 1000, 1000, nil, utils.DefaultLogger)}
 		streamsMap := newStreamsMap(nil, protocol.PerspectiveServer, protocol.VersionWhatever)
 		streamFramer = newStreamFramer(cryptoStream, streamsMap, nil)
 
@@ -342,6 +347,25 @@ var _ = Describe("Packet packer", func() {
 		Expect(payloadFrames).To(HaveLen(10))
 	})
 
+	It("queues control frames from other goroutines while packing packets", func() {
+		const num = 100
+		go func() {
+			defer GinkgoRecover()
+			for i := 0; i < num; i++ {
+				packer.QueueControlFrame(&wire.RstStreamFrame{StreamID: protocol.StreamID(i)})
+			}
+		}()
+		var numFrames int
+		Eventually(func() int {
+			p, err := packer.PackPacket()
+			Expect(err).ToNot(HaveOccurred())
+			if p != nil {
+				numFrames += len(p.frames)
+			}
+			return numFrames
+		}).Should(Equal(num))
+	})
+
 	It("only increases the packet number when there is an actual packet to send", func() {
 		packer.packetNumberGenerator.nextToSkip = 1000
 		p, err := packer.PackPacket()
@@ -643,6 +667,54 @@ var _ = Describe("Packet packer", func() {
 		})
 	})
 
+	Context("DATAGRAM frames", func() {
+		var datagramQueue *datagramQueue
+
+		BeforeEach(func() {
+			datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
+			packer.datagramQueue = datagramQueue
+		})
+
+		It("packs a DATAGRAM frame", func() {
+			f := &wire.DatagramFrame{Data: []byte("foobar")}
+			Expect(datagramQueue.AddAndWait(f)).To(Succeed())
+			p, err := packer.PackPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.frames).To(Equal([]wire.Frame{f}))
+			Expect(datagramQueue.Peek()).To(BeNil())
+		})
+
+		It("packs DATAGRAM frames before STREAM frames", func() {
+			f := &wire.DatagramFrame{Data: []byte("foobar")}
+			Expect(datagramQueue.AddAndWait(f)).To(Succeed())
+			streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 5, Data: []byte("foobar")})
+			p, err := packer.PackPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.frames).To(HaveLen(2))
+			Expect(p.frames[0]).To(Equal(f))
+			Expect(p.frames[1]).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+		})
+
+		It("sends a DATAGRAM frame in the next packet, if it doesn't fit", func() {
+			f := &wire.DatagramFrame{Data: bytes.Repeat([]byte{'f'}, 500)}
+			Expect(datagramQueue.AddAndWait(f)).To(Succeed())
+			frames, err := packer.composeNextPacket(400, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frames).To(BeEmpty())
+			frames, err = packer.composeNextPacket(maxFrameSize, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frames).To(Equal([]wire.Frame{f}))
+		})
+
+		It("doesn't pack DATAGRAM frames if it's not allowed to send data", func() {
+			packer.cryptoSetup.(*mockCryptoSetup).encLevelSeal = protocol.EncryptionUnencrypted
+			Expect(datagramQueue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})).To(Succeed())
+			p, err := packer.PackPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(BeNil())
+		})
+	})
+
 	It("returns nil if we only have a single STOP_WAITING", func() {
 		packer.QueueControlFrame(&wire.StopWaitingFrame{})
 		p, err := packer.PackPacket()
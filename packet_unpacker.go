@@ -21,6 +21,9 @@ type quicAEAD interface {
 type packetUnpacker struct {
 	version protocol.VersionNumber
 	aead    quicAEAD
+	// the max_datagram_frame_size we sent in our transport parameters
+	// DATAGRAM frames are only accepted if it is larger than 0
+	maxDatagramFrameSize protocol.ByteCount
 }
 
 func (u *packetUnpacker) Unpack(headerBinary []byte, hdr *wire.Header, data []byte) (*unpackedPacket, error) {
@@ -112,6 +115,20 @@ func (u *packetUnpacker) Unpack(headerBinary []byte, hdr *wire.Header, data []by
 			if err != nil {
 				err = qerr.Error(qerr.InvalidBlockedData, err.Error())
 			}
+		} else if u.version.UsesStopSendingFrame() && typeByte == 0xc {
+			frame, err = wire.ParseStopSendingFrame(r, u.version)
+			if err != nil {
+				err = qerr.Error(qerr.InvalidFrameData, err.Error())
+			}
+		} else if u.maxDatagramFrameSize > 0 && typeByte == 0x30 {
+			frame, err = wire.ParseDatagramFrame(r, u.version)
+			if err != nil {
+				err = qerr.Error(qerr.InvalidFrameData, err.Error())
+			} else if encryptionLevel <= protocol.EncryptionUnencrypted {
+				err = qerr.Error(qerr.UnencryptedStreamData, "received unencrypted DATAGRAM frame")
+			} else if l, _ := frame.MinLength(u.version); l > u.maxDatagramFrameSize {
+				err = qerr.Error(qerr.InvalidFrameData, fmt.Sprintf("DATAGRAM frame too large (%d bytes, maximum %d bytes)", l, u.maxDatagramFrameSize))
+			}
 		} else {
 			err = qerr.Error(qerr.InvalidFrameData, fmt.Sprintf("unknown type byte 0x%x", typeByte))
 		}
@@ -205,11 +205,23 @@ var _ = Describe("Packet unpacker", func() {
 			Expect(packet.frames).To(Equal([]wire.Frame{f}))
 		})
 
+		It("unpacks STOP_SENDING frames", func() {
+			f := &wire.StopSendingFrame{StreamID: 0xdeadbeef, ErrorCode: 0x1337}
+			buf := &bytes.Buffer{}
+			err := f.Write(buf, versionCryptoStream0)
+			Expect(err).ToNot(HaveOccurred())
+			setData(buf.Bytes())
+			packet, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.frames).To(Equal([]wire.Frame{f}))
+		})
+
 		It("errors on invalid frames", func() {
 			for b, e := range map[byte]qerr.ErrorCode{
 				0x04: qerr.InvalidWindowUpdateData,
 				0x05: qerr.InvalidWindowUpdateData,
 				0x09: qerr.InvalidBlockedData,
+				0x0c: qerr.InvalidFrameData,
 			} {
 				setData([]byte{b})
 				_, err := unpacker.Unpack(hdrBin, hdr, data)
@@ -282,6 +294,12 @@ var _ = Describe("Packet unpacker", func() {
 				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(e))
 			}
 		})
+
+		It("doesn't accept STOP_SENDING frames", func() {
+			setData([]byte{0x0c, 0xde, 0xad, 0xbe, 0xef, 0x13, 0x37})
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError("InvalidFrameData: unknown type byte 0xc"))
+		})
 	})
 
 	It("unpacks STOP_WAITING frames", func() {
@@ -302,6 +320,45 @@ var _ = Describe("Packet unpacker", func() {
 		}))
 	})
 
+	Context("unpacking DATAGRAM frames", func() {
+		BeforeEach(func() {
+			unpacker.maxDatagramFrameSize = 100
+		})
+
+		It("unpacks DATAGRAM frames", func() {
+			unpacker.aead.(*mockAEAD).encLevelOpen = protocol.EncryptionSecure
+			setData([]byte{0x30, 0x0, 0x3, 'f', 'o', 'o'})
+			packet, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.frames).To(Equal([]wire.Frame{
+				&wire.DatagramFrame{Data: []byte("foo")},
+			}))
+		})
+
+		It("does not unpack unencrypted DATAGRAM frames", func() {
+			unpacker.aead.(*mockAEAD).encLevelOpen = protocol.EncryptionUnencrypted
+			setData([]byte{0x30, 0x0, 0x3, 'f', 'o', 'o'})
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError(qerr.Error(qerr.UnencryptedStreamData, "received unencrypted DATAGRAM frame")))
+		})
+
+		It("errors on DATAGRAM frames larger than the maximum DATAGRAM frame size", func() {
+			unpacker.aead.(*mockAEAD).encLevelOpen = protocol.EncryptionSecure
+			unpacker.maxDatagramFrameSize = 5
+			setData([]byte{0x30, 0x0, 0x3, 'f', 'o', 'o'})
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "DATAGRAM frame too large (6 bytes, maximum 5 bytes)")))
+		})
+
+		It("errors on DATAGRAM frames if we didn't allow them in our transport parameters", func() {
+			unpacker.maxDatagramFrameSize = 0
+			unpacker.aead.(*mockAEAD).encLevelOpen = protocol.EncryptionSecure
+			setData([]byte{0x30, 0x0, 0x3, 'f', 'o', 'o'})
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError("InvalidFrameData: unknown type byte 0x30"))
+		})
+	})
+
 	It("errors on invalid type", func() {
 		setData([]byte{0xf})
 		_, err := unpacker.Unpack(hdrBin, hdr, data)
@@ -316,6 +373,7 @@ var _ = Describe("Packet unpacker", func() {
 			0x02: qerr.InvalidConnectionCloseData,
 			0x03: qerr.InvalidGoawayData,
 			0x06: qerr.InvalidStopWaitingData,
+			0x30: qerr.InvalidFrameData,
 		} {
 			setData([]byte{b})
 			_, err := unpacker.Unpack(hdrBin, hdr, data)
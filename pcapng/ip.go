@@ -0,0 +1,114 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"net"
+)
+
+const (
+	protocolUDP = 17
+	ttl         = 64
+)
+
+// encapsulate prepends an IP and a UDP header to the payload.
+// If any of the addresses is an IPv6 address, an IPv6 header is used.
+func encapsulate(payload []byte, src, dst net.Addr) []byte {
+	srcIP, srcPort := ipAndPort(src)
+	dstIP, dstPort := ipAndPort(dst)
+
+	udp := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(len(udp)))
+	copy(udp[8:], payload)
+
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		binary.BigEndian.PutUint16(udp[6:8], udpChecksum(pseudoHeaderIPv4(src4, dst4, len(udp)), udp))
+		return append(ipv4Header(src4, dst4, len(udp)), udp...)
+	}
+	src6, dst6 := srcIP.To16(), dstIP.To16()
+	binary.BigEndian.PutUint16(udp[6:8], udpChecksum(pseudoHeaderIPv6(src6, dst6, len(udp)), udp))
+	return append(ipv6Header(src6, dst6, len(udp)), udp...)
+}
+
+// ipAndPort returns the IP and the port of a UDP address.
+// Other addresses are mapped to an address in 10.0.0.0/8, derived from a hash of the address.
+func ipAndPort(addr net.Addr) (net.IP, uint16) {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.IP != nil {
+		return udpAddr.IP, uint16(udpAddr.Port)
+	}
+	h := fnv.New64a()
+	if addr != nil {
+		h.Write([]byte(addr.Network()))
+		h.Write([]byte(addr.String()))
+	}
+	v := h.Sum64()
+	return net.IPv4(10, byte(v>>16), byte(v>>8), byte(v)), uint16(v >> 32)
+}
+
+func ipv4Header(src, dst net.IP, payloadLen int) []byte {
+	hdr := make([]byte, 20)
+	hdr[0] = 0x45 // version 4, header length 5 * 32 bits
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(hdr)+payloadLen))
+	binary.BigEndian.PutUint16(hdr[6:8], 0x4000) // don't fragment
+	hdr[8] = ttl
+	hdr[9] = protocolUDP
+	copy(hdr[12:16], src)
+	copy(hdr[16:20], dst)
+	binary.BigEndian.PutUint16(hdr[10:12], ^sum(0, hdr))
+	return hdr
+}
+
+func ipv6Header(src, dst net.IP, payloadLen int) []byte {
+	hdr := make([]byte, 40)
+	hdr[0] = 0x60 // version 6
+	binary.BigEndian.PutUint16(hdr[4:6], uint16(payloadLen))
+	hdr[6] = protocolUDP
+	hdr[7] = ttl
+	copy(hdr[8:24], src)
+	copy(hdr[24:40], dst)
+	return hdr
+}
+
+func pseudoHeaderIPv4(src, dst net.IP, udpLen int) []byte {
+	hdr := make([]byte, 12)
+	copy(hdr[0:4], src)
+	copy(hdr[4:8], dst)
+	hdr[9] = protocolUDP
+	binary.BigEndian.PutUint16(hdr[10:12], uint16(udpLen))
+	return hdr
+}
+
+func pseudoHeaderIPv6(src, dst net.IP, udpLen int) []byte {
+	hdr := make([]byte, 40)
+	copy(hdr[0:16], src)
+	copy(hdr[16:32], dst)
+	binary.BigEndian.PutUint32(hdr[32:36], uint32(udpLen))
+	hdr[39] = protocolUDP
+	return hdr
+}
+
+func udpChecksum(pseudoHeader, udp []byte) uint16 {
+	checksum := ^sum(sum(0, pseudoHeader), udp)
+	// a checksum of 0 means that no checksum was calculated
+	if checksum == 0 {
+		return 0xffff
+	}
+	return checksum
+}
+
+// sum calculates the ones' complement sum used by the internet checksum
+func sum(initial uint16, b []byte) uint16 {
+	s := uint32(initial)
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(binary.BigEndian.Uint16(b[i : i+2]))
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s > 0xffff {
+		s = (s >> 16) + (s & 0xffff)
+	}
+	return uint16(s)
+}
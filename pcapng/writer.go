@@ -0,0 +1,155 @@
+// Package pcapng writes packets captured by quic-go into pcapng files.
+// The files can be opened with Wireshark.
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/tracing"
+)
+
+const (
+	blockTypeSectionHeader        = 0x0a0d0d0a
+	blockTypeInterfaceDescription = 0x1
+	blockTypeEnhancedPacket       = 0x6
+
+	byteOrderMagic = 0x1a2b3c4d
+
+	// LINKTYPE_RAW: the packet begins with an IPv4 or IPv6 header
+	linkTypeRaw = 101
+
+	optionEndOfOptions = 0
+	optionComment      = 1
+)
+
+// A Writer writes packets to a pcapng file.
+// The packets are prepended with synthesized IP and UDP headers.
+// This allows capturing packets sent on any net.PacketConn, not only on UDP connections.
+// It is safe for concurrent use.
+type Writer struct {
+	mutex sync.Mutex
+
+	w             io.Writer
+	commentFrames bool
+
+	logger utils.Logger
+}
+
+var _ tracing.PacketCapture = &Writer{}
+
+// NewWriter creates a new Writer and writes the pcapng section header to w.
+// If commentFrames is set, the decrypted frames of every packet are added to the packet as a comment.
+// Errors writing captured packets are logged to the logger, or to the default logger if it is nil.
+func NewWriter(w io.Writer, commentFrames bool, logger utils.Logger) (*Writer, error) {
+	if logger == nil {
+		logger = utils.DefaultLogger
+	}
+	writer := &Writer{
+		w:             w,
+		commentFrames: commentFrames,
+		logger:        logger,
+	}
+	if err := writer.writeHeader(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) writeHeader() error {
+	shb := &bytes.Buffer{}
+	binary.Write(shb, binary.LittleEndian, uint32(byteOrderMagic))
+	binary.Write(shb, binary.LittleEndian, uint16(1)) // major version
+	binary.Write(shb, binary.LittleEndian, uint16(0)) // minor version
+	binary.Write(shb, binary.LittleEndian, int64(-1)) // section length: unspecified
+	if err := w.writeBlock(blockTypeSectionHeader, shb.Bytes()); err != nil {
+		return err
+	}
+
+	idb := &bytes.Buffer{}
+	binary.Write(idb, binary.LittleEndian, uint16(linkTypeRaw))
+	binary.Write(idb, binary.LittleEndian, uint16(0)) // reserved
+	binary.Write(idb, binary.LittleEndian, uint32(0)) // snap length: no limit
+	return w.writeBlock(blockTypeInterfaceDescription, idb.Bytes())
+}
+
+// CapturePacket writes a packet.
+// Errors are logged, since they can't be returned to the session.
+func (w *Writer) CapturePacket(t time.Time, data []byte, src, dst net.Addr, frames []tracing.Frame) {
+	var comment string
+	if w.commentFrames && frames != nil {
+		comment = describeFrames(frames)
+	}
+	if err := w.WritePacket(t, data, src, dst, comment); err != nil {
+		w.logger.Errorf("Failed to write packet to pcapng file: %s", err.Error())
+	}
+}
+
+// WritePacket writes a packet sent from src to dst.
+// If comment is not empty, it is added to the packet.
+func (w *Writer) WritePacket(t time.Time, data []byte, src, dst net.Addr, comment string) error {
+	packet := encapsulate(data, src, dst)
+
+	epb := &bytes.Buffer{}
+	timestamp := uint64(t.UnixNano() / 1000)          // the default timestamp resolution is microseconds
+	binary.Write(epb, binary.LittleEndian, uint32(0)) // interface ID
+	binary.Write(epb, binary.LittleEndian, uint32(timestamp>>32))
+	binary.Write(epb, binary.LittleEndian, uint32(timestamp))
+	binary.Write(epb, binary.LittleEndian, uint32(len(packet))) // captured length
+	binary.Write(epb, binary.LittleEndian, uint32(len(packet))) // original length
+	epb.Write(packet)
+	pad(epb)
+	if len(comment) > 0 {
+		binary.Write(epb, binary.LittleEndian, uint16(optionComment))
+		binary.Write(epb, binary.LittleEndian, uint16(len(comment)))
+		epb.WriteString(comment)
+		pad(epb)
+		binary.Write(epb, binary.LittleEndian, uint16(optionEndOfOptions))
+		binary.Write(epb, binary.LittleEndian, uint16(0))
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writeBlock(blockTypeEnhancedPacket, epb.Bytes())
+}
+
+// writeBlock writes a block. The body must be padded to 32 bits.
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	b := &bytes.Buffer{}
+	length := uint32(len(body) + 12)
+	binary.Write(b, binary.LittleEndian, blockType)
+	binary.Write(b, binary.LittleEndian, length)
+	b.Write(body)
+	binary.Write(b, binary.LittleEndian, length)
+	_, err := w.w.Write(b.Bytes())
+	return err
+}
+
+// pad pads the buffer to 32 bits
+func pad(b *bytes.Buffer) {
+	for b.Len()%4 != 0 {
+		b.WriteByte(0)
+	}
+}
+
+func describeFrames(frames []tracing.Frame) string {
+	descs := make([]string, len(frames))
+	for i, frame := range frames {
+		switch f := frame.(type) {
+		case *tracing.StreamFrame:
+			descs[i] = fmt.Sprintf("&wire.StreamFrame{StreamID: %d, FinBit: %t, Offset: 0x%x, Data length: 0x%x}", f.StreamID, f.FinBit, f.Offset, len(f.Data))
+		case *tracing.DatagramFrame:
+			descs[i] = fmt.Sprintf("&wire.DatagramFrame{Data length: 0x%x}", len(f.Data))
+		default:
+			descs[i] = fmt.Sprintf("%#v", frame)
+		}
+	}
+	return strings.Join(descs, "\n")
+}
@@ -0,0 +1,181 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/seong889/quic-go/tracing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type block struct {
+	blockType uint32
+	body      []byte
+}
+
+func readBlocks(data []byte) []block {
+	var blocks []block
+	for len(data) > 0 {
+		ExpectWithOffset(1, len(data)).To(BeNumerically(">=", 12))
+		blockType := binary.LittleEndian.Uint32(data[0:4])
+		length := binary.LittleEndian.Uint32(data[4:8])
+		ExpectWithOffset(1, length%4).To(BeZero())
+		ExpectWithOffset(1, binary.LittleEndian.Uint32(data[length-4:length])).To(Equal(length))
+		blocks = append(blocks, block{blockType: blockType, body: data[8 : length-4]})
+		data = data[length:]
+	}
+	return blocks
+}
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+var _ = Describe("Writer", func() {
+	var (
+		buf    *bytes.Buffer
+		writer *Writer
+		src    = &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1234}
+		dst    = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		var err error
+		writer, err = NewWriter(buf, true, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// getPacket returns the packet data and the options of an enhanced packet block
+	getPacket := func(b block) ([]byte, []byte) {
+		ExpectWithOffset(1, b.blockType).To(BeEquivalentTo(blockTypeEnhancedPacket))
+		capLen := binary.LittleEndian.Uint32(b.body[12:16])
+		ExpectWithOffset(1, binary.LittleEndian.Uint32(b.body[16:20])).To(Equal(capLen))
+		paddedLen := (capLen + 3) / 4 * 4
+		return b.body[20 : 20+capLen], b.body[20+paddedLen:]
+	}
+
+	It("writes the section header and the interface description", func() {
+		blocks := readBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(2))
+		Expect(blocks[0].blockType).To(BeEquivalentTo(blockTypeSectionHeader))
+		Expect(binary.LittleEndian.Uint32(blocks[0].body[0:4])).To(BeEquivalentTo(byteOrderMagic))
+		Expect(binary.LittleEndian.Uint16(blocks[0].body[4:6])).To(BeEquivalentTo(1))
+		Expect(binary.LittleEndian.Uint16(blocks[0].body[6:8])).To(BeZero())
+		Expect(blocks[1].blockType).To(BeEquivalentTo(blockTypeInterfaceDescription))
+		Expect(binary.LittleEndian.Uint16(blocks[1].body[0:2])).To(BeEquivalentTo(linkTypeRaw))
+	})
+
+	It("returns write errors", func() {
+		_, err := NewWriter(errorWriter{}, false, nil)
+		Expect(err).To(MatchError("write failed"))
+	})
+
+	It("writes a packet with an IPv4 and a UDP header", func() {
+		t := time.Unix(1234, 567000)
+		err := writer.WritePacket(t, []byte("foobar"), src, dst, "")
+		Expect(err).ToNot(HaveOccurred())
+		blocks := readBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(3))
+		b := blocks[2]
+		timestamp := uint64(binary.LittleEndian.Uint32(b.body[4:8]))<<32 | uint64(binary.LittleEndian.Uint32(b.body[8:12]))
+		Expect(timestamp).To(BeEquivalentTo(1234000567))
+		packet, options := getPacket(b)
+		Expect(options).To(BeEmpty())
+		Expect(packet).To(HaveLen(20 + 8 + 6))
+		// IPv4 header
+		ip := packet[:20]
+		Expect(ip[0]).To(BeEquivalentTo(0x45))
+		Expect(binary.BigEndian.Uint16(ip[2:4])).To(BeEquivalentTo(34))
+		Expect(ip[9]).To(BeEquivalentTo(protocolUDP))
+		Expect(net.IP(ip[12:16]).Equal(src.IP)).To(BeTrue())
+		Expect(net.IP(ip[16:20]).Equal(dst.IP)).To(BeTrue())
+		Expect(sum(0, ip)).To(BeEquivalentTo(0xffff))
+		// UDP header
+		udp := packet[20:]
+		Expect(binary.BigEndian.Uint16(udp[0:2])).To(BeEquivalentTo(1234))
+		Expect(binary.BigEndian.Uint16(udp[2:4])).To(BeEquivalentTo(4321))
+		Expect(binary.BigEndian.Uint16(udp[4:6])).To(BeEquivalentTo(14))
+		Expect(sum(sum(0, pseudoHeaderIPv4(src.IP.To4(), dst.IP.To4(), len(udp))), udp)).To(BeEquivalentTo(0xffff))
+		Expect(udp[8:]).To(Equal([]byte("foobar")))
+	})
+
+	It("writes a packet with an IPv6 header", func() {
+		src6 := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}
+		err := writer.WritePacket(time.Now(), []byte("foobar"), src6, dst, "")
+		Expect(err).ToNot(HaveOccurred())
+		packet, _ := getPacket(readBlocks(buf.Bytes())[2])
+		Expect(packet).To(HaveLen(40 + 8 + 6))
+		Expect(packet[0] >> 4).To(BeEquivalentTo(6))
+		Expect(binary.BigEndian.Uint16(packet[4:6])).To(BeEquivalentTo(14))
+		Expect(net.IP(packet[8:24]).Equal(src6.IP)).To(BeTrue())
+		Expect(net.IP(packet[24:40]).Equal(dst.IP)).To(BeTrue())
+		udp := packet[40:]
+		Expect(sum(sum(0, pseudoHeaderIPv6(src6.IP, dst.IP.To16(), len(udp))), udp)).To(BeEquivalentTo(0xffff))
+	})
+
+	It("derives stable addresses for non-UDP connections", func() {
+		ip1, port1 := ipAndPort(pipeAddr("foo"))
+		ip2, port2 := ipAndPort(pipeAddr("foo"))
+		ip3, _ := ipAndPort(pipeAddr("bar"))
+		Expect(ip1.Equal(ip2)).To(BeTrue())
+		Expect(port1).To(Equal(port2))
+		Expect(ip1.Equal(ip3)).To(BeFalse())
+		Expect(ip1.To4()[0]).To(BeEquivalentTo(10))
+		err := writer.WritePacket(time.Now(), []byte("foobar"), pipeAddr("foo"), pipeAddr("bar"), "")
+		Expect(err).ToNot(HaveOccurred())
+		packet, _ := getPacket(readBlocks(buf.Bytes())[2])
+		Expect(net.IP(packet[12:16]).Equal(ip1)).To(BeTrue())
+		Expect(net.IP(packet[16:20]).Equal(ip3)).To(BeTrue())
+	})
+
+	It("writes a comment", func() {
+		err := writer.WritePacket(time.Now(), []byte("foobar"), src, dst, "lorem ipsum")
+		Expect(err).ToNot(HaveOccurred())
+		_, options := getPacket(readBlocks(buf.Bytes())[2])
+		Expect(binary.LittleEndian.Uint16(options[0:2])).To(BeEquivalentTo(optionComment))
+		Expect(binary.LittleEndian.Uint16(options[2:4])).To(BeEquivalentTo(11))
+		Expect(options[4:15]).To(Equal([]byte("lorem ipsum")))
+		Expect(options[16:20]).To(Equal([]byte{0, 0, 0, 0})) // end of options
+	})
+
+	It("adds the frames as a comment", func() {
+		frames := []tracing.Frame{
+			&tracing.StreamFrame{StreamID: 5, Offset: 0x42, Data: []byte("foobar")},
+			&tracing.PingFrame{},
+		}
+		writer.CapturePacket(time.Now(), []byte("foobar"), src, dst, frames)
+		_, options := getPacket(readBlocks(buf.Bytes())[2])
+		commentLen := binary.LittleEndian.Uint16(options[2:4])
+		comment := string(options[4 : 4+commentLen])
+		Expect(comment).To(ContainSubstring("StreamID: 5"))
+		Expect(comment).To(ContainSubstring("Data length: 0x6"))
+		Expect(comment).ToNot(ContainSubstring("foobar"))
+		Expect(comment).To(ContainSubstring("PingFrame"))
+	})
+
+	It("doesn't add a comment for packets that couldn't be decrypted", func() {
+		writer.CapturePacket(time.Now(), []byte("foobar"), src, dst, nil)
+		_, options := getPacket(readBlocks(buf.Bytes())[2])
+		Expect(options).To(BeEmpty())
+	})
+
+	It("doesn't add the frames as a comment, if disabled", func() {
+		buf.Reset()
+		w, err := NewWriter(buf, false, nil)
+		Expect(err).ToNot(HaveOccurred())
+		w.CapturePacket(time.Now(), []byte("foobar"), src, dst, []tracing.Frame{&tracing.PingFrame{}})
+		_, options := getPacket(readBlocks(buf.Bytes())[2])
+		Expect(options).To(BeEmpty())
+	})
+})
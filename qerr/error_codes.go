@@ -89,6 +89,8 @@ const (
 	EmptyStreamFrameNoFin ErrorCode = 50
 	// We received invalid data on the headers stream.
 	InvalidHeadersStreamData ErrorCode = 56
+	// The server rejected the connection because it has too many sessions.
+	TooManySessionsOnServer ErrorCode = 96
 	// Invalid data on the headers stream received because of decompression
 	// failure.
 	HeadersStreamDataDecompressFailure ErrorCode = 97
@@ -0,0 +1,111 @@
+package qlog
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/seong889/quic-go/tracing"
+)
+
+type frame struct {
+	Frame tracing.Frame
+}
+
+var _ json.Marshaler = frame{}
+
+func (f frame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(frameToMap(f.Frame))
+}
+
+func frameToMap(f tracing.Frame) map[string]interface{} {
+	switch frame := f.(type) {
+	case *tracing.AckFrame:
+		return map[string]interface{}{
+			"frame_type":   "ack",
+			"ack_delay":    milliseconds(frame.DelayTime),
+			"acked_ranges": ackRanges(frame),
+		}
+	case *tracing.StreamFrame:
+		return map[string]interface{}{
+			"frame_type": "stream",
+			"stream_id":  frame.StreamID,
+			"offset":     frame.Offset,
+			"length":     len(frame.Data),
+			"fin":        frame.FinBit,
+		}
+	case *tracing.RstStreamFrame:
+		return map[string]interface{}{
+			"frame_type": "reset_stream",
+			"stream_id":  frame.StreamID,
+			"error_code": frame.ErrorCode,
+			"final_size": frame.ByteOffset,
+		}
+	case *tracing.StopSendingFrame:
+		return map[string]interface{}{
+			"frame_type": "stop_sending",
+			"stream_id":  frame.StreamID,
+			"error_code": frame.ErrorCode,
+		}
+	case *tracing.MaxDataFrame:
+		return map[string]interface{}{
+			"frame_type": "max_data",
+			"maximum":    frame.ByteOffset,
+		}
+	case *tracing.MaxStreamDataFrame:
+		return map[string]interface{}{
+			"frame_type": "max_stream_data",
+			"stream_id":  frame.StreamID,
+			"maximum":    frame.ByteOffset,
+		}
+	case *tracing.BlockedFrame:
+		return map[string]interface{}{"frame_type": "data_blocked"}
+	case *tracing.StreamBlockedFrame:
+		return map[string]interface{}{
+			"frame_type": "stream_data_blocked",
+			"stream_id":  frame.StreamID,
+		}
+	case *tracing.ConnectionCloseFrame:
+		return map[string]interface{}{
+			"frame_type":  "connection_close",
+			"error_space": "transport",
+			"error_code":  frame.ErrorCode,
+			"reason":      frame.ReasonPhrase,
+		}
+	case *tracing.GoawayFrame:
+		return map[string]interface{}{
+			"frame_type":       "goaway",
+			"error_code":       frame.ErrorCode,
+			"last_good_stream": frame.LastGoodStream,
+			"reason":           frame.ReasonPhrase,
+		}
+	case *tracing.PingFrame:
+		return map[string]interface{}{"frame_type": "ping"}
+	case *tracing.StopWaitingFrame:
+		return map[string]interface{}{
+			"frame_type":    "stop_waiting",
+			"least_unacked": frame.LeastUnacked,
+		}
+	case *tracing.DatagramFrame:
+		return map[string]interface{}{
+			"frame_type": "datagram",
+			"length":     len(frame.Data),
+		}
+	default:
+		return map[string]interface{}{
+			"frame_type": "unknown",
+			"raw":        fmt.Sprintf("%T", f),
+		}
+	}
+}
+
+// ackRanges returns the ACK ranges in ascending order, as [smallest, largest] pairs
+func ackRanges(f *tracing.AckFrame) [][2]tracing.PacketNumber {
+	if len(f.AckRanges) == 0 {
+		return [][2]tracing.PacketNumber{{f.LowestAcked, f.LargestAcked}}
+	}
+	ranges := make([][2]tracing.PacketNumber, len(f.AckRanges))
+	for i, r := range f.AckRanges {
+		ranges[len(f.AckRanges)-1-i] = [2]tracing.PacketNumber{r.First, r.Last}
+	}
+	return ranges
+}
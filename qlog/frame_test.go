@@ -0,0 +1,111 @@
+package qlog
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/seong889/quic-go/internal/wire"
+	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Frames", func() {
+	check := func(f tracing.Frame, expected map[string]interface{}) {
+		data, err := json.Marshal(frame{Frame: f})
+		Expect(err).ToNot(HaveOccurred())
+		var m map[string]interface{}
+		Expect(json.Unmarshal(data, &m)).To(Succeed())
+		Expect(m).To(Equal(expected))
+	}
+
+	It("marshals ACK frames with a single ACK range", func() {
+		check(&tracing.AckFrame{LowestAcked: 10, LargestAcked: 20, DelayTime: 1500 * time.Microsecond}, map[string]interface{}{
+			"frame_type":   "ack",
+			"ack_delay":    1.5,
+			"acked_ranges": []interface{}{[]interface{}{10.0, 20.0}},
+		})
+	})
+
+	It("marshals ACK frames with multiple ACK ranges", func() {
+		check(&tracing.AckFrame{
+			LowestAcked:  1,
+			LargestAcked: 20,
+			AckRanges: []wire.AckRange{
+				{First: 15, Last: 20},
+				{First: 1, Last: 10},
+			},
+		}, map[string]interface{}{
+			"frame_type":   "ack",
+			"ack_delay":    0.0,
+			"acked_ranges": []interface{}{[]interface{}{1.0, 10.0}, []interface{}{15.0, 20.0}},
+		})
+	})
+
+	It("marshals RST_STREAM frames", func() {
+		check(&tracing.RstStreamFrame{StreamID: 5, ErrorCode: 42, ByteOffset: 1337}, map[string]interface{}{
+			"frame_type": "reset_stream",
+			"stream_id":  5.0,
+			"error_code": 42.0,
+			"final_size": 1337.0,
+		})
+	})
+
+	It("marshals STOP_SENDING frames", func() {
+		check(&tracing.StopSendingFrame{StreamID: 5, ErrorCode: 42}, map[string]interface{}{
+			"frame_type": "stop_sending",
+			"stream_id":  5.0,
+			"error_code": 42.0,
+		})
+	})
+
+	It("marshals MAX_STREAM_DATA frames", func() {
+		check(&tracing.MaxStreamDataFrame{StreamID: 5, ByteOffset: 1337}, map[string]interface{}{
+			"frame_type": "max_stream_data",
+			"stream_id":  5.0,
+			"maximum":    1337.0,
+		})
+	})
+
+	It("marshals BLOCKED frames", func() {
+		check(&tracing.BlockedFrame{}, map[string]interface{}{"frame_type": "data_blocked"})
+		check(&tracing.StreamBlockedFrame{StreamID: 5}, map[string]interface{}{
+			"frame_type": "stream_data_blocked",
+			"stream_id":  5.0,
+		})
+	})
+
+	It("marshals CONNECTION_CLOSE frames", func() {
+		check(&tracing.ConnectionCloseFrame{ErrorCode: qerr.DecryptionFailure, ReasonPhrase: "foobar"}, map[string]interface{}{
+			"frame_type":  "connection_close",
+			"error_space": "transport",
+			"error_code":  float64(qerr.DecryptionFailure),
+			"reason":      "foobar",
+		})
+	})
+
+	It("marshals GOAWAY frames", func() {
+		check(&tracing.GoawayFrame{ErrorCode: qerr.PeerGoingAway, LastGoodStream: 7, ReasonPhrase: "bye"}, map[string]interface{}{
+			"frame_type":       "goaway",
+			"error_code":       float64(qerr.PeerGoingAway),
+			"last_good_stream": 7.0,
+			"reason":           "bye",
+		})
+	})
+
+	It("marshals STOP_WAITING frames", func() {
+		check(&tracing.StopWaitingFrame{LeastUnacked: 42}, map[string]interface{}{
+			"frame_type":    "stop_waiting",
+			"least_unacked": 42.0,
+		})
+	})
+
+	It("marshals DATAGRAM frames", func() {
+		check(&tracing.DatagramFrame{Data: []byte("foobar")}, map[string]interface{}{
+			"frame_type": "datagram",
+			"length":     6.0,
+		})
+	})
+})
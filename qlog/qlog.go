@@ -0,0 +1,181 @@
+// Package qlog writes qlog traces of QUIC sessions.
+// The traces can be loaded into qvis (https://qvis.edm.uhasselt.be).
+package qlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/tracing"
+)
+
+const qlogVersion = "draft-01"
+
+type tracer struct {
+	dir    string
+	logger utils.Logger
+}
+
+var _ tracing.Tracer = &tracer{}
+
+// NewTracer creates a new tracer that writes one qlog file per session into dir.
+// The files are named after the connection ID and the perspective, e.g. 1337cafe_server.qlog.
+// Errors writing the files are logged to the logger, or to the default logger if it is nil.
+// It is used by setting the Tracer in the quic.Config.
+func NewTracer(dir string, logger utils.Logger) tracing.Tracer {
+	if logger == nil {
+		logger = utils.DefaultLogger
+	}
+	return &tracer{dir: dir, logger: logger}
+}
+
+func (t *tracer) TracerForSession(p tracing.Perspective, connID tracing.ConnectionID) tracing.SessionTracer {
+	filename := filepath.Join(t.dir, fmt.Sprintf("%x_%s.qlog", uint64(connID), perspectiveString(p)))
+	f, err := os.Create(filename)
+	if err != nil {
+		t.logger.Errorf("Failed to create qlog file %s: %s", filename, err.Error())
+		return nil
+	}
+	return newSessionTracer(f, p, connID, t.logger)
+}
+
+// DroppedPacket is called for packets that can't be associated with a session.
+// Since there's no file to write these events to, they are ignored.
+func (t *tracer) DroppedPacket(net.Addr, tracing.ByteCount, tracing.PacketDropReason) {}
+
+type sessionTracer struct {
+	w             io.WriteCloser
+	buf           *bufio.Writer
+	referenceTime time.Time
+	numEvents     int
+
+	logger utils.Logger
+}
+
+var _ tracing.SessionTracer = &sessionTracer{}
+
+func newSessionTracer(w io.WriteCloser, p tracing.Perspective, connID tracing.ConnectionID, logger utils.Logger) *sessionTracer {
+	t := &sessionTracer{
+		w:             w,
+		buf:           bufio.NewWriter(w),
+		referenceTime: time.Now(),
+		logger:        logger,
+	}
+	header, err := json.Marshal(&topLevel{
+		QlogVersion: qlogVersion,
+		Title:       "quic-go qlog",
+		Traces: []trace{{
+			VantagePoint: vantagePoint{Name: "quic-go", Type: perspectiveString(p)},
+			CommonFields: commonFields{
+				ODCID:         fmt.Sprintf("%x", uint64(connID)),
+				GroupID:       fmt.Sprintf("%x", uint64(connID)),
+				ReferenceTime: float64(t.referenceTime.UnixNano()) / 1e6,
+			},
+			EventFields: eventFields,
+			Events:      []json.RawMessage{},
+		}},
+	})
+	if err != nil {
+		t.logger.Errorf("Failed to marshal qlog header: %s", err.Error())
+	}
+	// The events are streamed into the events array of the trace.
+	// The header is cut off after its opening bracket, and the brackets are closed when the session is closed.
+	t.buf.Write(header[:len(header)-len(`]}]}`)])
+	return t
+}
+
+func (t *sessionTracer) recordEvent(category, name string, data interface{}) {
+	ev, err := json.Marshal(&event{
+		RelativeTime: time.Since(t.referenceTime),
+		Category:     category,
+		Name:         name,
+		Data:         data,
+	})
+	if err != nil {
+		t.logger.Errorf("Failed to marshal qlog event %s:%s: %s", category, name, err.Error())
+		return
+	}
+	if t.numEvents > 0 {
+		t.buf.WriteByte(',')
+	}
+	t.buf.Write(ev)
+	t.numEvents++
+}
+
+func (t *sessionTracer) SentPacket(hdr *tracing.Header, size tracing.ByteCount, encLevel tracing.EncryptionLevel, frames []tracing.Frame) {
+	t.recordEvent(categoryTransport, "packet_sent", newPacketEvent(hdr, size, encLevel, frames))
+}
+
+func (t *sessionTracer) ReceivedPacket(hdr *tracing.Header, size tracing.ByteCount, encLevel tracing.EncryptionLevel, frames []tracing.Frame) {
+	t.recordEvent(categoryTransport, "packet_received", newPacketEvent(hdr, size, encLevel, frames))
+}
+
+func (t *sessionTracer) DroppedPacket(hdr *tracing.Header, size tracing.ByteCount, reason tracing.PacketDropReason) {
+	t.recordEvent(categoryTransport, "packet_dropped", &eventPacketDropped{
+		PacketType: packetTypeUnknown,
+		PacketSize: size,
+		Trigger:    dropReasonString(reason),
+	})
+}
+
+func (t *sessionTracer) LostPacket(pn tracing.PacketNumber, reason tracing.PacketLossReason) {
+	t.recordEvent(categoryRecovery, "packet_lost", &eventPacketLost{
+		PacketNumber: pn,
+		Trigger:      lossReasonString(reason),
+	})
+}
+
+func (t *sessionTracer) UpdatedRTT(rttStats *tracing.RTTStats) {
+	t.recordEvent(categoryRecovery, "metrics_updated", &eventRTTUpdated{
+		MinRTT:      milliseconds(rttStats.MinRTT()),
+		SmoothedRTT: milliseconds(rttStats.SmoothedRTT()),
+		LatestRTT:   milliseconds(rttStats.LatestRTT()),
+		RTTVariance: milliseconds(rttStats.MeanDeviation()),
+	})
+}
+
+func (t *sessionTracer) UpdatedCongestionWindow(cwnd tracing.ByteCount) {
+	t.recordEvent(categoryRecovery, "metrics_updated", &eventCongestionWindowUpdated{CongestionWindow: cwnd})
+}
+
+func (t *sessionTracer) ExitedSlowStart() {
+	t.recordEvent(categoryRecovery, "congestion_state_updated", &eventStateUpdated{New: "congestion_avoidance"})
+}
+
+func (t *sessionTracer) Blocked(streamID tracing.StreamID) {
+	if streamID == 0 {
+		t.recordEvent(categoryTransport, "data_blocked", &eventBlocked{})
+		return
+	}
+	t.recordEvent(categoryTransport, "stream_data_blocked", &eventBlocked{StreamID: &streamID})
+}
+
+func (t *sessionTracer) UpdatedEncryptionLevel(encLevel tracing.EncryptionLevel) {
+	state := "encrypted"
+	if encLevel == tracing.EncryptionForwardSecure {
+		state = "handshake_complete"
+	}
+	t.recordEvent(categoryConnectivity, "connection_state_updated", &eventStateUpdated{New: state})
+}
+
+func (t *sessionTracer) Close() {
+	t.recordEvent(categoryConnectivity, "connection_state_updated", &eventStateUpdated{New: "closed"})
+	t.buf.WriteString(`]}]}`)
+	if err := t.buf.Flush(); err != nil {
+		t.logger.Errorf("Failed to write qlog file: %s", err.Error())
+	}
+	if err := t.w.Close(); err != nil {
+		t.logger.Errorf("Failed to close qlog file: %s", err.Error())
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
@@ -0,0 +1,210 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/utils"
+	"github.com/seong889/quic-go/tracing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type nopWriteCloser struct {
+	*bytes.Buffer
+	closed bool
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+var _ = Describe("qlog", func() {
+	var (
+		buf    *nopWriteCloser
+		tracer *sessionTracer
+	)
+
+	BeforeEach(func() {
+		buf = &nopWriteCloser{Buffer: &bytes.Buffer{}}
+		tracer = newSessionTracer(buf, tracing.PerspectiveServer, 0xdeadbeef, utils.DefaultLogger)
+	})
+
+	// getEvents closes the tracer, checks the header of the trace and returns the events
+	getEvents := func() []interface{} {
+		tracer.Close()
+		Expect(buf.closed).To(BeTrue())
+		var m map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &m)).To(Succeed())
+		Expect(m).To(HaveKeyWithValue("qlog_version", "draft-01"))
+		Expect(m).To(HaveKey("traces"))
+		traces := m["traces"].([]interface{})
+		Expect(traces).To(HaveLen(1))
+		trace := traces[0].(map[string]interface{})
+		Expect(trace).To(HaveKeyWithValue("vantage_point", map[string]interface{}{"name": "quic-go", "type": "server"}))
+		Expect(trace).To(HaveKey("common_fields"))
+		Expect(trace["common_fields"]).To(HaveKeyWithValue("ODCID", "deadbeef"))
+		Expect(trace["event_fields"]).To(Equal([]interface{}{"relative_time", "category", "event", "data"}))
+		events := trace["events"].([]interface{})
+		// the last event is always the closing of the connection
+		Expect(events[len(events)-1].([]interface{})[1:]).To(Equal([]interface{}{"connectivity", "connection_state_updated", map[string]interface{}{"new": "closed"}}))
+		return events[:len(events)-1]
+	}
+
+	getData := func(ev interface{}, category, name string) map[string]interface{} {
+		e := ev.([]interface{})
+		Expect(e).To(HaveLen(4))
+		Expect(e[0]).To(BeNumerically(">=", 0))
+		Expect(e[1]).To(Equal(category))
+		Expect(e[2]).To(Equal(name))
+		return e[3].(map[string]interface{})
+	}
+
+	It("writes a valid trace without any events", func() {
+		Expect(getEvents()).To(BeEmpty())
+	})
+
+	It("records sent packets", func() {
+		tracer.SentPacket(
+			&tracing.Header{PacketNumber: 1337},
+			987,
+			tracing.EncryptionForwardSecure,
+			[]tracing.Frame{&tracing.MaxDataFrame{ByteOffset: 1000}, &tracing.PingFrame{}},
+		)
+		events := getEvents()
+		Expect(events).To(HaveLen(1))
+		data := getData(events[0], "transport", "packet_sent")
+		Expect(data).To(HaveKeyWithValue("packet_type", "1RTT"))
+		Expect(data).To(HaveKeyWithValue("header", map[string]interface{}{"packet_number": 1337.0, "packet_size": 987.0}))
+		Expect(data["frames"]).To(Equal([]interface{}{
+			map[string]interface{}{"frame_type": "max_data", "maximum": 1000.0},
+			map[string]interface{}{"frame_type": "ping"},
+		}))
+	})
+
+	It("records received packets", func() {
+		tracer.ReceivedPacket(
+			&tracing.Header{PacketNumber: 42},
+			123,
+			tracing.EncryptionUnencrypted,
+			[]tracing.Frame{&tracing.StreamFrame{StreamID: 3, Offset: 10, Data: []byte("foobar"), FinBit: true}},
+		)
+		events := getEvents()
+		Expect(events).To(HaveLen(1))
+		data := getData(events[0], "transport", "packet_received")
+		Expect(data).To(HaveKeyWithValue("packet_type", "initial"))
+		Expect(data["frames"]).To(Equal([]interface{}{
+			map[string]interface{}{"frame_type": "stream", "stream_id": 3.0, "offset": 10.0, "length": 6.0, "fin": true},
+		}))
+	})
+
+	It("records dropped packets", func() {
+		tracer.DroppedPacket(&tracing.Header{PacketNumber: 42}, 123, tracing.PacketDropUndecryptable)
+		events := getEvents()
+		Expect(events).To(HaveLen(1))
+		data := getData(events[0], "transport", "packet_dropped")
+		Expect(data).To(Equal(map[string]interface{}{
+			"packet_type": "unknown",
+			"packet_size": 123.0,
+			"trigger":     "decryption_failure",
+		}))
+	})
+
+	It("records lost packets", func() {
+		tracer.LostPacket(42, tracing.PacketLossTimeThreshold)
+		events := getEvents()
+		Expect(events).To(HaveLen(1))
+		data := getData(events[0], "recovery", "packet_lost")
+		Expect(data).To(Equal(map[string]interface{}{
+			"packet_number": 42.0,
+			"trigger":       "time_threshold",
+		}))
+	})
+
+	It("records RTT updates", func() {
+		rttStats := congestion.NewRTTStats()
+		rttStats.UpdateRTT(25*time.Millisecond, 0, time.Now())
+		tracer.UpdatedRTT(rttStats)
+		events := getEvents()
+		Expect(events).To(HaveLen(1))
+		data := getData(events[0], "recovery", "metrics_updated")
+		Expect(data).To(HaveKeyWithValue("min_rtt", 25.0))
+		Expect(data).To(HaveKeyWithValue("latest_rtt", 25.0))
+		Expect(data).To(HaveKeyWithValue("smoothed_rtt", 25.0))
+		Expect(data).To(HaveKeyWithValue("rtt_variance", 12.5))
+	})
+
+	It("records congestion window updates and slow start exits", func() {
+		tracer.UpdatedCongestionWindow(12345)
+		tracer.ExitedSlowStart()
+		events := getEvents()
+		Expect(events).To(HaveLen(2))
+		data := getData(events[0], "recovery", "metrics_updated")
+		Expect(data).To(Equal(map[string]interface{}{"congestion_window": 12345.0}))
+		data = getData(events[1], "recovery", "congestion_state_updated")
+		Expect(data).To(Equal(map[string]interface{}{"new": "congestion_avoidance"}))
+	})
+
+	It("records blocked events", func() {
+		tracer.Blocked(0)
+		tracer.Blocked(5)
+		events := getEvents()
+		Expect(events).To(HaveLen(2))
+		data := getData(events[0], "transport", "data_blocked")
+		Expect(data).To(BeEmpty())
+		data = getData(events[1], "transport", "stream_data_blocked")
+		Expect(data).To(Equal(map[string]interface{}{"stream_id": 5.0}))
+	})
+
+	It("records encryption level changes", func() {
+		tracer.UpdatedEncryptionLevel(tracing.EncryptionSecure)
+		tracer.UpdatedEncryptionLevel(tracing.EncryptionForwardSecure)
+		events := getEvents()
+		Expect(events).To(HaveLen(2))
+		data := getData(events[0], "connectivity", "connection_state_updated")
+		Expect(data).To(Equal(map[string]interface{}{"new": "encrypted"}))
+		data = getData(events[1], "connectivity", "connection_state_updated")
+		Expect(data).To(Equal(map[string]interface{}{"new": "handshake_complete"}))
+	})
+
+	Context("writing files", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "qlog")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes one file per session", func() {
+			t := NewTracer(dir, nil)
+			t.TracerForSession(tracing.PerspectiveServer, 0x1337).Close()
+			t.TracerForSession(tracing.PerspectiveClient, 0xcafe).Close()
+			files, err := filepath.Glob(filepath.Join(dir, "*.qlog"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(ConsistOf(
+				filepath.Join(dir, "1337_server.qlog"),
+				filepath.Join(dir, "cafe_client.qlog"),
+			))
+			data, err := ioutil.ReadFile(filepath.Join(dir, "1337_server.qlog"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Valid(data)).To(BeTrue())
+		})
+
+		It("doesn't trace the session if the file can't be created", func() {
+			t := NewTracer(filepath.Join(dir, "does-not-exist"), nil)
+			Expect(t.TracerForSession(tracing.PerspectiveServer, 0x1337)).To(BeNil())
+		})
+	})
+})
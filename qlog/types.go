@@ -0,0 +1,155 @@
+package qlog
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/seong889/quic-go/tracing"
+)
+
+const (
+	categoryConnectivity = "connectivity"
+	categoryTransport    = "transport"
+	categoryRecovery     = "recovery"
+)
+
+const packetTypeUnknown = "unknown"
+
+var eventFields = []string{"relative_time", "category", "event", "data"}
+
+type topLevel struct {
+	QlogVersion string  `json:"qlog_version"`
+	Title       string  `json:"title"`
+	Traces      []trace `json:"traces"` // must be the last field, see newSessionTracer
+}
+
+type trace struct {
+	VantagePoint vantagePoint      `json:"vantage_point"`
+	CommonFields commonFields      `json:"common_fields"`
+	EventFields  []string          `json:"event_fields"`
+	Events       []json.RawMessage `json:"events"` // must be the last field, see newSessionTracer
+}
+
+type vantagePoint struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type commonFields struct {
+	ODCID         string  `json:"ODCID"`
+	GroupID       string  `json:"group_id"`
+	ReferenceTime float64 `json:"reference_time"`
+}
+
+// An event is serialized as an array, in the order given by the eventFields
+type event struct {
+	RelativeTime time.Duration
+	Category     string
+	Name         string
+	Data         interface{}
+}
+
+func (e *event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{milliseconds(e.RelativeTime), e.Category, e.Name, e.Data})
+}
+
+type packetHeader struct {
+	PacketNumber tracing.PacketNumber `json:"packet_number"`
+	PacketSize   tracing.ByteCount    `json:"packet_size"`
+}
+
+type eventPacket struct {
+	PacketType string       `json:"packet_type"`
+	Header     packetHeader `json:"header"`
+	Frames     []frame      `json:"frames"`
+}
+
+func newPacketEvent(hdr *tracing.Header, size tracing.ByteCount, encLevel tracing.EncryptionLevel, frames []tracing.Frame) *eventPacket {
+	fs := make([]frame, len(frames))
+	for i, f := range frames {
+		fs[i] = frame{Frame: f}
+	}
+	return &eventPacket{
+		PacketType: packetType(encLevel),
+		Header: packetHeader{
+			PacketNumber: hdr.PacketNumber,
+			PacketSize:   size,
+		},
+		Frames: fs,
+	}
+}
+
+type eventPacketDropped struct {
+	PacketType string            `json:"packet_type"`
+	PacketSize tracing.ByteCount `json:"packet_size"`
+	Trigger    string            `json:"trigger"`
+}
+
+type eventPacketLost struct {
+	PacketNumber tracing.PacketNumber `json:"packet_number"`
+	Trigger      string               `json:"trigger"`
+}
+
+type eventRTTUpdated struct {
+	MinRTT      float64 `json:"min_rtt"`
+	SmoothedRTT float64 `json:"smoothed_rtt"`
+	LatestRTT   float64 `json:"latest_rtt"`
+	RTTVariance float64 `json:"rtt_variance"`
+}
+
+type eventCongestionWindowUpdated struct {
+	CongestionWindow tracing.ByteCount `json:"congestion_window"`
+}
+
+type eventStateUpdated struct {
+	New string `json:"new"`
+}
+
+type eventBlocked struct {
+	StreamID *tracing.StreamID `json:"stream_id,omitempty"`
+}
+
+func perspectiveString(p tracing.Perspective) string {
+	if p == tracing.PerspectiveServer {
+		return "server"
+	}
+	return "client"
+}
+
+// packetType maps the gQUIC encryption levels to the qlog packet types
+func packetType(encLevel tracing.EncryptionLevel) string {
+	switch encLevel {
+	case tracing.EncryptionUnencrypted:
+		return "initial"
+	case tracing.EncryptionSecure:
+		return "0RTT"
+	case tracing.EncryptionForwardSecure:
+		return "1RTT"
+	default:
+		return packetTypeUnknown
+	}
+}
+
+func dropReasonString(reason tracing.PacketDropReason) string {
+	switch reason {
+	case tracing.PacketDropUndecryptable:
+		return "decryption_failure"
+	case tracing.PacketDropUnknownConnectionID:
+		return "unknown_connection_id"
+	default:
+		return "unknown"
+	}
+}
+
+func lossReasonString(reason tracing.PacketLossReason) string {
+	switch reason {
+	case tracing.PacketLossTimeThreshold:
+		return "time_threshold"
+	case tracing.PacketLossRetransmissionTimeout:
+		return "retransmission_timeout"
+	case tracing.PacketLossHandshakeTimeout:
+		return "handshake_timeout"
+	default:
+		return "unknown"
+	}
+}
@@ -2,18 +2,21 @@ package quic
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/seong889/quic-go/congestion"
 	"github.com/seong889/quic-go/internal/crypto"
 	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
 	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
 )
 
 // packetHandler handles packets
@@ -29,24 +32,39 @@ type packetHandler interface {
 type server struct {
 	tlsConf *tls.Config
 	config  *Config
+	logger  utils.Logger
 
-	conn net.PacketConn
+	conn           net.PacketConn
+	sessionHandler packetHandlerManager
 
 	certChain crypto.CertChain
-	scfg      *handshake.ServerConfig
+	scfgs     *handshake.ServerConfigManager
+
+	admission *admissionController
 
 	sessions                  map[protocol.ConnectionID]packetHandler
 	sessionsMutex             sync.RWMutex
 	deleteClosedSessionsAfter time.Duration
+	// set by Shutdown. No new sessions are accepted.
+	shuttingDown bool
 
 	serverError  error
+	errorMutex   sync.Mutex
+	closed       bool
 	sessionQueue chan Session
 	errorChan    chan struct{}
+	// if set, sessions are accepted as soon as the connection is secure, before the handshake completes
+	acceptEarlySessions bool
+
+	statsMutex       sync.Mutex
+	queuedSessions   uint64
+	rejectedSessions uint64
 
-	newSession func(conn connection, v protocol.VersionNumber, connectionID protocol.ConnectionID, sCfg *handshake.ServerConfig, tlsConf *tls.Config, config *Config) (packetHandler, <-chan handshakeEvent, error)
+	newSession func(conn connection, v protocol.VersionNumber, connectionID protocol.ConnectionID, scfgs *handshake.ServerConfigManager, tlsConf *tls.Config, config *Config) (packetHandler, <-chan handshakeEvent, error)
 }
 
 var _ Listener = &server{}
+var _ rawPacketHandler = &server{}
 
 // ListenAddr creates a QUIC server listening on a given address.
 // The listener is not active until Serve() is called.
@@ -63,34 +81,64 @@ func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, err
 	return Listen(conn, tlsConf, config)
 }
 
+// ListenAddrEarly works like ListenAddr, but it returns sessions before the handshake completes.
+func ListenAddrEarly(addr string, tlsConf *tls.Config, config *Config) (Listener, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	return ListenEarly(conn, tlsConf, config)
+}
+
 // Listen listens for QUIC connections on a given net.PacketConn.
-// The listener is not active until Serve() is called.
+// The net.PacketConn can be shared with client sessions established using Dial.
+// Packets are passed to the client sessions based on their connection ID, all other packets are handled by the Listener.
 // The tls.Config must not be nil, the quic.Config may be nil.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
+	return listen(conn, tlsConf, config, false)
+}
+
+// ListenEarly works like Listen, but it returns sessions before the handshake completes.
+// A session is returned as soon as the connection is secure, i.e. after the server received a valid CHLO.
+// If the client sent a CHLO using a cached server config, data sent by the client in 0-RTT can be read right away.
+// Since the handshake is not completed yet, data read from a session might have been replayed by an attacker,
+// unless the StrikeRegister is shared by all servers. Session.HandshakeComplete can be used to wait for the handshake to complete.
+func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
+	return listen(conn, tlsConf, config, true)
+}
+
+func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly bool) (Listener, error) {
+	config = populateServerConfig(config)
 	certChain := crypto.NewCertChain(tlsConf)
-	kex, err := crypto.NewCurve25519KEX()
-	if err != nil {
-		return nil, err
-	}
-	scfg, err := handshake.NewServerConfig(kex, certChain)
+	scfgs, err := handshake.NewServerConfigManager(certChain, config.ServerConfigProvider, config.StrikeRegister)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &server{
 		conn:                      conn,
+		sessionHandler:            getMultiplexer().AddConn(conn, config.Logger),
 		tlsConf:                   tlsConf,
-		config:                    populateServerConfig(config),
+		config:                    config,
+		logger:                    config.Logger,
 		certChain:                 certChain,
-		scfg:                      scfg,
+		scfgs:                     scfgs,
+		admission:                 newAdmissionController(config),
 		sessions:                  map[protocol.ConnectionID]packetHandler{},
 		newSession:                newSession,
 		deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
-		sessionQueue:              make(chan Session, 5),
+		sessionQueue:              make(chan Session, config.AcceptQueueSize),
 		errorChan:                 make(chan struct{}),
+		acceptEarlySessions:       acceptEarly,
+	}
+	if err := s.sessionHandler.SetServer(s); err != nil {
+		return nil, err
 	}
-	go s.serve()
-	utils.Debugf("Listening for %s connections on %s", conn.LocalAddr().Network(), conn.LocalAddr().String())
+	s.logger.Debugf("Listening for %s connections on %s", conn.LocalAddr().Network(), conn.LocalAddr().String())
 	return s, nil
 }
 
@@ -101,13 +149,33 @@ var defaultAcceptCookie = func(clientAddr net.Addr, cookie *Cookie) bool {
 	if time.Now().After(cookie.SentTime.Add(protocol.CookieExpiryTime)) {
 		return false
 	}
-	var sourceAddr string
-	if udpAddr, ok := clientAddr.(*net.UDPAddr); ok {
-		sourceAddr = udpAddr.IP.String()
-	} else {
-		sourceAddr = clientAddr.String()
-	}
-	return sourceAddr == cookie.RemoteAddr
+	return sourceOf(clientAddr) == cookie.RemoteAddr
+}
+
+// NewSharedSecretServerConfigProvider returns a ServerConfigProvider that derives the server configs, including their Cookie secrets, from a secret.
+// The server configs are rotated at fixed times. Servers using the same secret must have synchronized clocks.
+// Since all private keys are derived from the secret, leaking it compromises all past and future connections.
+func NewSharedSecretServerConfigProvider(secret []byte) (ServerConfigProvider, error) {
+	return handshake.NewSharedSecretServerConfigProvider(secret)
+}
+
+// NewRotatingSecretServerConfigProvider returns a ServerConfigProvider that derives every server config from a secret and a per-rotation secret.
+// rotationSecret is called with the start of the rotation interval, and must return the same secret on all servers.
+// Deleting the secret of an interval keeps its connections confidential even if the long-lived secret leaks later.
+func NewRotatingSecretServerConfigProvider(secret []byte, rotationSecret func(intervalStart time.Time) ([]byte, error)) (ServerConfigProvider, error) {
+	return handshake.NewRotatingSecretServerConfigProvider(secret, rotationSecret)
+}
+
+// NewStrikeRegister returns a StrikeRegister that stores nonces in the StrikeRegisterStore.
+// Nonces are only accepted if their timestamp is within the window of the current time.
+func NewStrikeRegister(store StrikeRegisterStore, window time.Duration) StrikeRegister {
+	return handshake.NewStrikeRegister(store, window)
+}
+
+// NewInMemoryStrikeRegister returns a StrikeRegister that stores nonces in memory.
+// Nonces are only accepted if their timestamp is within the window of the current time.
+func NewInMemoryStrikeRegister(window time.Duration) StrikeRegister {
+	return handshake.NewInMemoryStrikeRegister(window)
 }
 
 // populateServerConfig populates fields in the quic.Config with their default values, if none are set
@@ -121,6 +189,11 @@ func populateServerConfig(config *Config) *Config {
 		versions = protocol.SupportedVersions
 	}
 
+	logger := config.Logger
+	if logger == nil {
+		logger = utils.DefaultLogger
+	}
+
 	vsa := defaultAcceptCookie
 	if config.AcceptCookie != nil {
 		vsa = config.AcceptCookie
@@ -134,6 +207,10 @@ func populateServerConfig(config *Config) *Config {
 	if config.IdleTimeout != 0 {
 		idleTimeout = config.IdleTimeout
 	}
+	acceptQueueSize := protocol.DefaultAcceptQueueSize
+	if config.AcceptQueueSize > 0 {
+		acceptQueueSize = config.AcceptQueueSize
+	}
 
 	maxReceiveStreamFlowControlWindow := config.MaxReceiveStreamFlowControlWindow
 	if maxReceiveStreamFlowControlWindow == 0 {
@@ -144,38 +221,72 @@ func populateServerConfig(config *Config) *Config {
 		maxReceiveConnectionFlowControlWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindowServer
 	}
 
+	congestionControl := config.CongestionControl
+	if congestionControl == nil {
+		congestionControl = congestion.CubicSenderFactory
+	}
+	maxCongestionWindow := config.MaxCongestionWindow
+	if maxCongestionWindow == 0 {
+		maxCongestionWindow = protocol.DefaultMaxCongestionWindow
+	}
+	initialCongestionWindow := config.InitialCongestionWindow
+	if initialCongestionWindow == 0 {
+		initialCongestionWindow = protocol.InitialCongestionWindow
+	}
+	initialCongestionWindow = utils.MinUint64(initialCongestionWindow, maxCongestionWindow)
+
 	return &Config{
 		Versions:                              versions,
 		HandshakeTimeout:                      handshakeTimeout,
 		IdleTimeout:                           idleTimeout,
 		AcceptCookie:                          vsa,
+		ServerConfigProvider:                  config.ServerConfigProvider,
+		StrikeRegister:                        config.StrikeRegister,
+		MaxIncomingHandshakes:                 config.MaxIncomingHandshakes,
+		MaxIncomingSessions:                   config.MaxIncomingSessions,
+		MaxNewSessionsPerSourcePerSecond:      config.MaxNewSessionsPerSourcePerSecond,
+		AdmitSession:                          config.AdmitSession,
+		AcceptQueueSize:                       acceptQueueSize,
 		KeepAlive:                             config.KeepAlive,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		CongestionControl:                     congestionControl,
+		InitialCongestionWindow:               initialCongestionWindow,
+		MaxCongestionWindow:                   maxCongestionWindow,
+		EnableDatagrams:                       config.EnableDatagrams,
+		Logger:                                logger,
+		Tracer:                                config.Tracer,
+		KeyLogWriter:                          config.KeyLogWriter,
+		PacketCapture:                         config.PacketCapture,
 	}
 }
 
-// serve listens on an existing PacketConn
-func (s *server) serve() {
-	for {
-		data := getPacketBuffer()
-		data = data[:protocol.MaxReceivePacketSize]
-		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
-		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, remoteAddr, err := s.conn.ReadFrom(data)
-		if err != nil {
-			s.serverError = err
-			close(s.errorChan)
-			_ = s.Close()
-			return
-		}
-		data = data[:n]
-		if err := s.handlePacket(s.conn, remoteAddr, data); err != nil {
-			utils.Errorf("error handling packet: %s", err.Error())
-		}
+func (s *server) handleRawPacket(remoteAddr net.Addr, data []byte) {
+	if err := s.handlePacket(s.conn, remoteAddr, data); err != nil {
+		s.logger.Errorf("error handling packet: %s", err.Error())
 	}
 }
 
+// closeWithError is called when reading from the connection fails
+func (s *server) closeWithError(e error) {
+	s.setCloseError(e)
+	_ = s.Close()
+}
+
+// setCloseError sets the error returned by Accept.
+// Only the first call has an effect.
+func (s *server) setCloseError(e error) {
+	s.errorMutex.Lock()
+	defer s.errorMutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.serverError = e
+	close(s.errorChan)
+}
+
 // Accept returns newly openend sessions
 func (s *server) Accept() (Session, error) {
 	var sess Session
@@ -187,6 +298,35 @@ func (s *server) Accept() (Session, error) {
 	}
 }
 
+// enqueueSession adds a session to the accept queue.
+// If the queue is full, the session is closed.
+func (s *server) enqueueSession(connID protocol.ConnectionID, sess packetHandler) {
+	select {
+	case s.sessionQueue <- sess:
+		s.statsMutex.Lock()
+		s.queuedSessions++
+		s.statsMutex.Unlock()
+	default:
+		s.logger.Infof("Accept queue full, closing connection %x", connID)
+		s.statsMutex.Lock()
+		s.rejectedSessions++
+		s.statsMutex.Unlock()
+		_ = sess.Close(qerr.Error(qerr.TooManySessionsOnServer, "accept queue full"))
+	}
+}
+
+// Stats returns statistics about the accept queue
+func (s *server) Stats() ListenerStats {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	return ListenerStats{
+		AcceptQueueLength: len(s.sessionQueue),
+		AcceptQueueSize:   cap(s.sessionQueue),
+		QueuedSessions:    s.queuedSessions,
+		RejectedSessions:  s.rejectedSessions,
+	}
+}
+
 // Close the server
 func (s *server) Close() error {
 	s.sessionsMutex.Lock()
@@ -204,10 +344,35 @@ func (s *server) Close() error {
 	s.sessionsMutex.Unlock()
 	wg.Wait()
 
-	if s.conn == nil {
-		return nil
+	s.setCloseError(errors.New("server closed"))
+	// the connection is only closed if it isn't used by any client sessions
+	return s.sessionHandler.CloseServer()
+}
+
+// Shutdown stops accepting new sessions, and gracefully closes all existing sessions
+func (s *server) Shutdown(ctx context.Context) error {
+	s.sessionsMutex.Lock()
+	s.shuttingDown = true
+	var wg sync.WaitGroup
+	for _, session := range s.sessions {
+		if session != nil {
+			wg.Add(1)
+			go func(sess packetHandler) {
+				// sess.CloseGracefully() blocks until all streams are closed, or the context is done
+				_ = sess.CloseGracefully(ctx)
+				wg.Done()
+			}(session)
+		}
 	}
-	return s.conn.Close()
+	s.sessionsMutex.Unlock()
+	wg.Wait()
+
+	s.setCloseError(errors.New("server closed"))
+	err := s.sessionHandler.CloseServer()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 // Addr returns the server's network address
@@ -226,6 +391,7 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 
 	s.sessionsMutex.RLock()
 	session, ok := s.sessions[connID]
+	shuttingDown := s.shuttingDown
 	s.sessionsMutex.RUnlock()
 
 	if ok && session == nil {
@@ -240,6 +406,9 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 
 	hdr, err := wire.ParseHeader(r, protocol.PerspectiveClient, version)
 	if err == wire.ErrPacketWithUnknownVersion {
+		if s.config.Tracer != nil {
+			s.config.Tracer.DroppedPacket(remoteAddr, protocol.ByteCount(len(packet)), tracing.PacketDropUnknownConnectionID)
+		}
 		_, err = pconn.WriteTo(wire.WritePublicReset(connID, 0, 0), remoteAddr)
 		return err
 	}
@@ -254,12 +423,12 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 			var pr *wire.PublicReset
 			pr, err = wire.ParsePublicReset(r)
 			if err != nil {
-				utils.Infof("Received a Public Reset for connection %x. An error occurred parsing the packet.")
+				s.logger.Infof("Received a Public Reset for connection %x. An error occurred parsing the packet.", hdr.ConnectionID)
 			} else {
-				utils.Infof("Received a Public Reset for connection %x, rejected packet number: 0x%x.", hdr.ConnectionID, pr.RejectedPacketNumber)
+				s.logger.Infof("Received a Public Reset for connection %x, rejected packet number: 0x%x.", hdr.ConnectionID, pr.RejectedPacketNumber)
 			}
 		} else {
-			utils.Infof("Received Public Reset for unknown connection %x.", hdr.ConnectionID)
+			s.logger.Infof("Received Public Reset for unknown connection %x.", hdr.ConnectionID)
 		}
 		return nil
 	}
@@ -278,7 +447,7 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 		if len(packet) < protocol.ClientHelloMinimumSize+len(hdr.Raw) {
 			return errors.New("dropping small packet with unknown version")
 		}
-		utils.Infof("Client offered version %s, sending VersionNegotiationPacket", hdr.Version)
+		s.logger.Infof("Client offered version %s, sending VersionNegotiationPacket", hdr.Version)
 		if _, err := pconn.WriteTo(wire.ComposeGQUICVersionNegotiation(hdr.ConnectionID, s.config.Versions), remoteAddr); err != nil {
 			return err
 		}
@@ -290,24 +459,32 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 	}
 
 	if !ok {
+		if shuttingDown {
+			return errors.New("dropping packet for new connection, server is shutting down")
+		}
 		version := hdr.Version
 		if !protocol.IsSupportedVersion(s.config.Versions, version) {
 			return errors.New("Server BUG: negotiated version not supported")
 		}
+		if decision := s.admit(hdr, packet[len(packet)-r.Len():], remoteAddr, rcvTime); decision != AdmissionAccept {
+			s.logger.Infof("Rejecting new connection %x from %s", hdr.ConnectionID, remoteAddr)
+			return s.rejectConnection(pconn, remoteAddr, hdr, decision)
+		}
 
-		utils.Infof("Serving new connection: %x, version %s from %v", hdr.ConnectionID, version, remoteAddr)
+		s.logger.Infof("Serving new connection: %x, version %s from %v", hdr.ConnectionID, version, remoteAddr)
 		var handshakeChan <-chan handshakeEvent
 		session, handshakeChan, err = s.newSession(
 			&conn{pconn: pconn, currentAddr: remoteAddr},
 			version,
 			hdr.ConnectionID,
-			s.scfg,
+			s.scfgs,
 			s.tlsConf,
 			s.config,
 		)
 		if err != nil {
 			return err
 		}
+		s.admission.SessionStarted(remoteAddr)
 		s.sessionsMutex.Lock()
 		s.sessions[connID] = session
 		s.sessionsMutex.Unlock()
@@ -319,16 +496,22 @@ func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet
 		}()
 
 		go func() {
+			defer s.admission.HandshakeFinished()
+			var enqueued bool
 			for {
 				ev := <-handshakeChan
 				if ev.err != nil {
 					return
 				}
+				// early sessions are accepted as soon as the connection is secure
+				if !enqueued && (s.acceptEarlySessions || ev.encLevel == protocol.EncryptionForwardSecure) {
+					enqueued = true
+					s.enqueueSession(connID, session)
+				}
 				if ev.encLevel == protocol.EncryptionForwardSecure {
-					break
+					return
 				}
 			}
-			s.sessionQueue <- session
 		}()
 	}
 	session.handlePacket(&receivedPacket{
@@ -344,6 +527,7 @@ func (s *server) removeConnection(id protocol.ConnectionID) {
 	s.sessionsMutex.Lock()
 	s.sessions[id] = nil
 	s.sessionsMutex.Unlock()
+	s.admission.SessionClosed()
 
 	time.AfterFunc(s.deleteClosedSessionsAfter, func() {
 		s.sessionsMutex.Lock()
@@ -351,3 +535,87 @@ func (s *server) removeConnection(id protocol.ConnectionID) {
 		s.sessionsMutex.Unlock()
 	})
 }
+
+// admit decides if a session is created for a new connection
+func (s *server) admit(hdr *wire.Header, data []byte, remoteAddr net.Addr, rcvTime time.Time) AdmissionDecision {
+	decision := s.admission.Admit(remoteAddr, rcvTime)
+	if decision == AdmissionRequireCookie && s.hasValidCookie(hdr, data, remoteAddr) {
+		return AdmissionAccept
+	}
+	return decision
+}
+
+// rejectConnection statelessly responds to the first packet of a connection that was not admitted.
+// gQUIC clients receive a stateless reject (SREJ) containing a fresh STK if a cookie is required, and a Public Reset otherwise.
+// There's no stateless response for IETF QUIC clients, so their packets are dropped.
+func (s *server) rejectConnection(pconn net.PacketConn, remoteAddr net.Addr, hdr *wire.Header, decision AdmissionDecision) error {
+	if hdr.Version.UsesTLS() {
+		return nil
+	}
+	if decision != AdmissionRequireCookie {
+		_, err := pconn.WriteTo(wire.WritePublicReset(hdr.ConnectionID, 0, 0), remoteAddr)
+		return err
+	}
+	packet, err := s.composeStatelessReject(hdr, remoteAddr)
+	if err != nil {
+		return err
+	}
+	_, err = pconn.WriteTo(packet, remoteAddr)
+	return err
+}
+
+// composeStatelessReject composes a packet containing a SREJ on the crypto stream.
+// The client starts a new connection using the connection ID designated in the SREJ, and presents the STK in its first CHLO.
+// Since the client abandons this connection, the packet number of this packet isn't used by any session.
+func (s *server) composeStatelessReject(hdr *wire.Header, remoteAddr net.Addr) ([]byte, error) {
+	connID, err := utils.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	srej, err := s.scfgs.StatelessReject(remoteAddr, connID)
+	if err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	replyHdr := &wire.Header{
+		ConnectionID:    hdr.ConnectionID,
+		PacketNumber:    1,
+		PacketNumberLen: protocol.PacketNumberLen1,
+	}
+	if err := replyHdr.Write(b, protocol.PerspectiveServer, hdr.Version); err != nil {
+		return nil, err
+	}
+	payload := &bytes.Buffer{}
+	frame := &wire.StreamFrame{StreamID: hdr.Version.CryptoStreamID(), Data: srej}
+	if err := frame.Write(payload, hdr.Version); err != nil {
+		return nil, err
+	}
+	aead, err := crypto.NewNullAEAD(protocol.PerspectiveServer, hdr.ConnectionID, hdr.Version)
+	if err != nil {
+		return nil, err
+	}
+	return append(b.Bytes(), aead.Seal(nil, payload.Bytes(), replyHdr.PacketNumber, b.Bytes())...), nil
+}
+
+// hasValidCookie checks if the CHLO in the first packet of a connection contains a valid cookie.
+// This is only possible for gQUIC, since the TLS handshake messages can only be parsed by mint.
+func (s *server) hasValidCookie(hdr *wire.Header, data []byte, remoteAddr net.Addr) bool {
+	if hdr.Version.UsesTLS() {
+		return false
+	}
+	aead, err := crypto.NewNullAEAD(protocol.PerspectiveServer, hdr.ConnectionID, hdr.Version)
+	if err != nil {
+		return false
+	}
+	decrypted, err := aead.Open(nil, data, hdr.PacketNumber, hdr.Raw)
+	if err != nil {
+		return false
+	}
+	r := bytes.NewReader(decrypted)
+	// the first packet contains a STREAM frame with the CHLO, followed by padding
+	frame, err := wire.ParseStreamFrame(r, hdr.Version)
+	if err != nil || frame.StreamID != hdr.Version.CryptoStreamID() || frame.Offset != 0 {
+		return false
+	}
+	return s.scfgs.AcceptCookie(frame.Data, remoteAddr, s.config.AcceptCookie)
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"errors"
 	"net"
 	"reflect"
 	"time"
 
+	"github.com/seong889/quic-go/congestion"
 	"github.com/seong889/quic-go/internal/crypto"
 	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/protocol"
@@ -26,6 +28,7 @@ type mockSession struct {
 	closed            bool
 	closeReason       error
 	closedRemote      bool
+	closedGracefully  bool
 	stopRunLoop       chan struct{} // run returns as soon as this channel receives a value
 	handshakeChan     chan handshakeEvent
 	handshakeComplete chan error // for WaitUntilHandshakeComplete
@@ -51,6 +54,10 @@ func (s *mockSession) Close(e error) error {
 	close(s.stopRunLoop)
 	return nil
 }
+func (s *mockSession) CloseGracefully(context.Context) error {
+	s.closedGracefully = true
+	return s.Close(nil)
+}
 func (s *mockSession) closeRemote(e error) {
 	s.closeReason = e
 	s.closed = true
@@ -60,12 +67,21 @@ func (s *mockSession) closeRemote(e error) {
 func (s *mockSession) OpenStream() (Stream, error) {
 	return &stream{streamID: 1337}, nil
 }
-func (s *mockSession) AcceptStream() (Stream, error)    { panic("not implemented") }
-func (s *mockSession) OpenStreamSync() (Stream, error)  { panic("not implemented") }
-func (s *mockSession) LocalAddr() net.Addr              { panic("not implemented") }
-func (s *mockSession) RemoteAddr() net.Addr             { panic("not implemented") }
-func (*mockSession) Context() context.Context           { panic("not implemented") }
-func (*mockSession) GetVersion() protocol.VersionNumber { return protocol.VersionWhatever }
+func (s *mockSession) AcceptStream() (Stream, error)           { panic("not implemented") }
+func (s *mockSession) OpenStreamSync() (Stream, error)         { panic("not implemented") }
+func (s *mockSession) AcceptUniStream() (ReceiveStream, error) { panic("not implemented") }
+func (s *mockSession) OpenUniStream() (SendStream, error)      { panic("not implemented") }
+func (s *mockSession) OpenUniStreamSync() (SendStream, error)  { panic("not implemented") }
+func (s *mockSession) LocalAddr() net.Addr                     { panic("not implemented") }
+func (s *mockSession) RemoteAddr() net.Addr                    { panic("not implemented") }
+func (*mockSession) Context() context.Context                  { panic("not implemented") }
+func (*mockSession) GetVersion() protocol.VersionNumber        { return protocol.VersionWhatever }
+func (*mockSession) ConnectionState() ConnectionState          { panic("not implemented") }
+func (*mockSession) Stats() ConnectionStats                    { panic("not implemented") }
+func (*mockSession) SendMessage([]byte) error                  { panic("not implemented") }
+func (*mockSession) ReceiveMessage() ([]byte, error)           { panic("not implemented") }
+func (*mockSession) HandshakeComplete() <-chan struct{}        { panic("not implemented") }
+func (*mockSession) Used0RTT() bool                            { panic("not implemented") }
 
 var _ Session = &mockSession{}
 var _ NonFWSession = &mockSession{}
@@ -74,7 +90,7 @@ func newMockSession(
 	_ connection,
 	_ protocol.VersionNumber,
 	connectionID protocol.ConnectionID,
-	_ *handshake.ServerConfig,
+	_ *handshake.ServerConfigManager,
 	_ *tls.Config,
 	_ *Config,
 ) (packetHandler, <-chan handshakeEvent, error) {
@@ -108,12 +124,15 @@ var _ = Describe("Server", func() {
 
 		BeforeEach(func() {
 			serv = &server{
-				sessions:     make(map[protocol.ConnectionID]packetHandler),
-				newSession:   newMockSession,
-				conn:         conn,
-				config:       config,
-				sessionQueue: make(chan Session, 5),
-				errorChan:    make(chan struct{}),
+				sessions:       make(map[protocol.ConnectionID]packetHandler),
+				newSession:     newMockSession,
+				conn:           conn,
+				sessionHandler: newPacketHandlerMap(conn, utils.DefaultLogger),
+				admission:      newAdmissionController(config),
+				config:         config,
+				logger:         utils.DefaultLogger,
+				sessionQueue:   make(chan Session, 5),
+				errorChan:      make(chan struct{}),
 			}
 			b := &bytes.Buffer{}
 			utils.BigEndian.WriteUint32(b, uint32(protocol.SupportedVersions[0]))
@@ -157,6 +176,75 @@ var _ = Describe("Server", func() {
 			close(done)
 		}, 0.5)
 
+		It("accepts early sessions as soon as the connection is secure", func(done Done) {
+			serv.acceptEarlySessions = true
+			var acceptedSess Session
+			go func() {
+				defer GinkgoRecover()
+				var err error
+				acceptedSess, err = serv.Accept()
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			err := serv.handlePacket(nil, nil, firstPacket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serv.sessions).To(HaveLen(1))
+			sess := serv.sessions[connID].(*mockSession)
+			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionSecure}
+			Eventually(func() Session { return acceptedSess }).Should(Equal(sess))
+			numHandshakes := func() int {
+				serv.admission.mutex.Lock()
+				defer serv.admission.mutex.Unlock()
+				return serv.admission.numHandshakes
+			}
+			Expect(numHandshakes()).To(Equal(1))
+			// the session is not queued again when the handshake completes
+			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionForwardSecure}
+			Eventually(numHandshakes).Should(BeZero())
+			Expect(serv.Stats().QueuedSessions).To(BeEquivalentTo(1))
+			close(done)
+		}, 0.5)
+
+		It("closes sessions when the accept queue is full", func() {
+			serv.sessionQueue = make(chan Session, 2)
+			completeHandshake := func(id protocol.ConnectionID) *mockSession {
+				err := serv.handlePacket(nil, nil, append([]byte{0x09, 0, 0, 0, 0, 0, 0, 0, uint8(id)}, firstPacket[9:]...))
+				Expect(err).ToNot(HaveOccurred())
+				serv.sessionsMutex.RLock()
+				sess := serv.sessions[id].(*mockSession)
+				serv.sessionsMutex.RUnlock()
+				sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionForwardSecure}
+				return sess
+			}
+			completeHandshake(1)
+			completeHandshake(2)
+			Eventually(func() int { return serv.Stats().AcceptQueueLength }).Should(Equal(2))
+			sess := completeHandshake(3)
+			Eventually(func() int { return serv.Stats().AcceptQueueLength }).Should(Equal(2))
+			// the session is removed as soon as its run loop stops
+			Eventually(func() bool {
+				serv.sessionsMutex.RLock()
+				defer serv.sessionsMutex.RUnlock()
+				return serv.sessions[3] == nil
+			}).Should(BeTrue())
+			Expect(sess.closed).To(BeTrue())
+			Expect(sess.closeReason).To(MatchError(qerr.Error(qerr.TooManySessionsOnServer, "accept queue full")))
+			Expect(serv.Stats()).To(Equal(ListenerStats{
+				AcceptQueueLength: 2,
+				AcceptQueueSize:   2,
+				QueuedSessions:    2,
+				RejectedSessions:  1,
+			}))
+			// accepting a session makes room for a new session
+			acceptedSess, err := serv.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(acceptedSess.(*mockSession).connectionID).To(Equal(protocol.ConnectionID(1)))
+			Expect(serv.Stats().AcceptQueueLength).To(Equal(1))
+			sess = completeHandshake(4)
+			Eventually(func() uint64 { return serv.Stats().QueuedSessions }).Should(BeEquivalentTo(3))
+			Expect(serv.Stats().RejectedSessions).To(BeEquivalentTo(1))
+			Expect(serv.sessions[4]).To(Equal(sess))
+		})
+
 		It("doesn't accept session that error during the handshake", func(done Done) {
 			var accepted bool
 			go func() {
@@ -198,6 +286,209 @@ var _ = Describe("Server", func() {
 			Expect(serv.sessions[connID]).To(BeNil())
 		})
 
+		Context("admission control", func() {
+			version := protocol.SupportedVersions[0]
+
+			// getFirstPacket returns the first packet of a new connection, containing a CHLO without a cookie
+			getFirstPacket := func(id protocol.ConnectionID) []byte {
+				b := &bytes.Buffer{}
+				err := (&wire.Header{
+					VersionFlag:     true,
+					Version:         version,
+					ConnectionID:    id,
+					PacketNumber:    1,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}).Write(b, protocol.PerspectiveClient, version)
+				Expect(err).ToNot(HaveOccurred())
+				chlo := &bytes.Buffer{}
+				handshake.HandshakeMessage{Tag: handshake.TagCHLO, Data: map[handshake.Tag][]byte{}}.Write(chlo)
+				payload := &bytes.Buffer{}
+				err = (&wire.StreamFrame{StreamID: version.CryptoStreamID(), Data: chlo.Bytes()}).Write(payload, version)
+				Expect(err).ToNot(HaveOccurred())
+				aead, err := crypto.NewNullAEAD(protocol.PerspectiveClient, id, version)
+				Expect(err).ToNot(HaveOccurred())
+				return append(b.Bytes(), aead.Seal(nil, payload.Bytes(), 1, b.Bytes())...)
+			}
+
+			isPublicReset := func(data []byte) bool {
+				return len(data) > 0 && data[0]&0x02 != 0
+			}
+
+			// getSREJ parses the SREJ sent by the server
+			getSREJ := func(id protocol.ConnectionID, data []byte) handshake.HandshakeMessage {
+				r := bytes.NewReader(data)
+				hdr, err := wire.ParseHeader(r, protocol.PerspectiveServer, version)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.ConnectionID).To(Equal(id))
+				hdr.Raw = data[:len(data)-r.Len()]
+				aead, err := crypto.NewNullAEAD(protocol.PerspectiveClient, id, version)
+				Expect(err).ToNot(HaveOccurred())
+				payload, err := aead.Open(nil, data[len(hdr.Raw):], hdr.PacketNumber, hdr.Raw)
+				Expect(err).ToNot(HaveOccurred())
+				frame, err := wire.ParseStreamFrame(bytes.NewReader(payload), version)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.StreamID).To(Equal(version.CryptoStreamID()))
+				Expect(frame.Offset).To(BeZero())
+				msg, err := handshake.ParseHandshakeMessage(bytes.NewReader(frame.Data))
+				Expect(err).ToNot(HaveOccurred())
+				return msg
+			}
+
+			BeforeEach(func() {
+				var err error
+				serv.scfgs, err = handshake.NewServerConfigManager(nil, nil, nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("rejects new connections when the maximum number of sessions is reached", func() {
+				config.MaxIncomingSessions = 1
+				serv.admission = newAdmissionController(config)
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+				Expect(serv.sessions).To(HaveLen(1))
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(2))).To(Succeed())
+				Expect(serv.sessions).To(HaveLen(1))
+				Expect(isPublicReset(conn.dataWritten.Bytes())).To(BeTrue())
+				Expect(conn.dataWrittenTo).To(Equal(udpAddr))
+				// close the first session
+				serv.sessions[1].(*mockSession).stopRunLoop <- struct{}{}
+				Eventually(func() bool {
+					serv.sessionsMutex.RLock()
+					defer serv.sessionsMutex.RUnlock()
+					return serv.sessions[1] == nil
+				}).Should(BeTrue())
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(3))).To(Succeed())
+				Expect(serv.sessions).To(HaveKey(protocol.ConnectionID(3)))
+			})
+
+			It("rejects new connections when the maximum number of handshakes is reached", func() {
+				config.MaxIncomingHandshakes = 1
+				serv.admission = newAdmissionController(config)
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(2))).To(Succeed())
+				Expect(serv.sessions).To(HaveLen(1))
+				Expect(isPublicReset(conn.dataWritten.Bytes())).To(BeTrue())
+				// complete the handshake of the first session
+				serv.sessions[1].(*mockSession).handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionForwardSecure}
+				Eventually(func() int {
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(3))).To(Succeed())
+					serv.sessionsMutex.RLock()
+					defer serv.sessionsMutex.RUnlock()
+					return len(serv.sessions)
+				}).Should(Equal(2))
+			})
+
+			Context("limiting the rate of new sessions per source", func() {
+				BeforeEach(func() {
+					config.MaxNewSessionsPerSourcePerSecond = 1
+				})
+
+				It("requires a cookie", func() {
+					var cookieChecked bool
+					config.AcceptCookie = func(addr net.Addr, cookie *Cookie) bool {
+						Expect(addr).To(Equal(udpAddr))
+						Expect(cookie).To(BeNil())
+						cookieChecked = true
+						return false
+					}
+					serv.admission = newAdmissionController(config)
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+					Expect(serv.sessions).To(HaveLen(1))
+					Expect(cookieChecked).To(BeFalse())
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(2))).To(Succeed())
+					Expect(cookieChecked).To(BeTrue())
+					Expect(serv.sessions).To(HaveLen(1))
+					Expect(isPublicReset(conn.dataWritten.Bytes())).To(BeFalse())
+				})
+
+				It("sends a SREJ with a cookie and a new connection ID, if the cookie is required", func() {
+					var cookie *Cookie
+					config.AcceptCookie = func(_ net.Addr, c *Cookie) bool {
+						cookie = c
+						return c != nil
+					}
+					serv.admission = newAdmissionController(config)
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(2))).To(Succeed())
+					Expect(serv.sessions).To(HaveLen(1))
+					Expect(conn.dataWrittenTo).To(Equal(udpAddr))
+					srej := getSREJ(2, conn.dataWritten.Bytes())
+					Expect(srej.Tag).To(Equal(handshake.TagSREJ))
+					Expect(srej.Data).To(HaveKey(handshake.TagSTK))
+					Expect(srej.Data[handshake.TagRCID]).To(HaveLen(8))
+					Expect(binary.LittleEndian.Uint64(srej.Data[handshake.TagRCID])).ToNot(BeEquivalentTo(2))
+					// the cookie is accepted in the next connection
+					chlo := &bytes.Buffer{}
+					handshake.HandshakeMessage{Tag: handshake.TagCHLO, Data: map[handshake.Tag][]byte{handshake.TagSTK: srej.Data[handshake.TagSTK]}}.Write(chlo)
+					Expect(serv.scfgs.AcceptCookie(chlo.Bytes(), udpAddr, config.AcceptCookie)).To(BeTrue())
+					Expect(cookie.RemoteAddr).To(Equal(udpAddr.IP.String()))
+				})
+
+				It("accepts new connections from the same source, if the cookie is accepted", func() {
+					config.AcceptCookie = func(net.Addr, *Cookie) bool { return true }
+					serv.admission = newAdmissionController(config)
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(2))).To(Succeed())
+					Expect(serv.sessions).To(HaveLen(2))
+					Expect(conn.dataWritten.Len()).To(BeZero())
+				})
+
+				It("doesn't accept packets without a valid CHLO", func() {
+					config.AcceptCookie = func(net.Addr, *Cookie) bool { return true }
+					serv.admission = newAdmissionController(config)
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+					Expect(serv.handlePacket(conn, udpAddr, firstPacket)).To(Succeed())
+					Expect(serv.sessions).To(HaveLen(1))
+					Expect(getSREJ(connID, conn.dataWritten.Bytes()).Tag).To(Equal(handshake.TagSREJ))
+				})
+
+				It("accepts new connections from different sources", func() {
+					serv.admission = newAdmissionController(config)
+					Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+					otherAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 1337}
+					Expect(serv.handlePacket(conn, otherAddr, getFirstPacket(2))).To(Succeed())
+					Expect(serv.sessions).To(HaveLen(2))
+				})
+			})
+
+			It("asks the policy callback", func() {
+				var addrs []net.Addr
+				config.AdmitSession = func(addr net.Addr) AdmissionDecision {
+					addrs = append(addrs, addr)
+					return AdmissionReject
+				}
+				serv.admission = newAdmissionController(config)
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+				Expect(serv.sessions).To(BeEmpty())
+				Expect(addrs).To(Equal([]net.Addr{udpAddr}))
+				Expect(isPublicReset(conn.dataWritten.Bytes())).To(BeTrue())
+				// packets for existing sessions don't need to be admitted
+				config.AdmitSession = nil
+				serv.admission = newAdmissionController(config)
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+				Expect(serv.handlePacket(conn, udpAddr, getFirstPacket(1))).To(Succeed())
+				Expect(serv.sessions[1].(*mockSession).packetCount).To(Equal(2))
+			})
+
+			It("doesn't send a Public Reset to IETF QUIC clients", func() {
+				config.Versions = []protocol.VersionNumber{protocol.VersionTLS}
+				config.AdmitSession = func(net.Addr) AdmissionDecision { return AdmissionReject }
+				serv.admission = newAdmissionController(config)
+				b := &bytes.Buffer{}
+				err := (&wire.Header{
+					Type:         protocol.PacketTypeInitial,
+					IsLongHeader: true,
+					ConnectionID: 0x1337,
+					PacketNumber: 1,
+					Version:      protocol.VersionTLS,
+				}).Write(b, protocol.PerspectiveClient, protocol.VersionTLS)
+				Expect(err).ToNot(HaveOccurred())
+				b.Write(bytes.Repeat([]byte{0}, protocol.ClientHelloMinimumSize))
+				Expect(serv.handlePacket(conn, udpAddr, b.Bytes())).To(Succeed())
+				Expect(serv.sessions).To(BeEmpty())
+				Expect(conn.dataWritten.Len()).To(BeZero())
+			})
+		})
+
 		It("deletes nil session entries after a wait time", func() {
 			serv.deleteClosedSessionsAfter = 25 * time.Millisecond
 			nullAEAD, err := crypto.NewNullAEAD(protocol.PerspectiveServer, connID, protocol.VersionWhatever)
@@ -225,6 +516,38 @@ var _ = Describe("Server", func() {
 			Expect(conn.closed).To(BeTrue())
 		})
 
+		It("closes sessions gracefully and closes the connection when Shutdown is called", func() {
+			session1, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+			session2, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+			serv.sessions[1] = session1
+			serv.sessions[2] = session2
+			serv.sessions[3] = nil // a closed session
+			err := serv.Shutdown(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session1.(*mockSession).closedGracefully).To(BeTrue())
+			Expect(session2.(*mockSession).closedGracefully).To(BeTrue())
+			Expect(conn.closed).To(BeTrue())
+		})
+
+		It("returns the context's error if the context is done during Shutdown", func() {
+			session, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+			serv.sessions[1] = session
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := serv.Shutdown(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(session.(*mockSession).closedGracefully).To(BeTrue())
+			Expect(conn.closed).To(BeTrue())
+		})
+
+		It("doesn't accept new sessions after Shutdown was called", func() {
+			err := serv.Shutdown(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			err = serv.handlePacket(nil, nil, firstPacket)
+			Expect(err).To(MatchError("dropping packet for new connection, server is shutting down"))
+			Expect(serv.sessions).To(BeEmpty())
+		})
+
 		It("ignores packets for closed sessions", func() {
 			serv.sessions[connID] = nil
 			err := serv.handlePacket(nil, nil, []byte{0x08, 0x4c, 0xfa, 0x9f, 0x9b, 0x66, 0x86, 0x19, 0xf6, 0x01})
@@ -248,8 +571,7 @@ var _ = Describe("Server", func() {
 			go func() {
 				defer GinkgoRecover()
 				_, err := ln.Accept()
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("use of closed network connection"))
+				Expect(err).To(MatchError("server closed"))
 				returned = true
 			}()
 			ln.Close()
@@ -259,7 +581,7 @@ var _ = Describe("Server", func() {
 		It("errors when encountering a connection error", func(done Done) {
 			testErr := errors.New("connection error")
 			conn.readErr = testErr
-			go serv.serve()
+			Expect(serv.sessionHandler.SetServer(serv)).To(Succeed())
 			_, err := serv.Accept()
 			Expect(err).To(MatchError(testErr))
 			Expect(serv.Close()).To(Succeed())
@@ -272,8 +594,7 @@ var _ = Describe("Server", func() {
 			Expect(serv.sessions[0x12345].(*mockSession).closed).To(BeFalse())
 			testErr := errors.New("connection error")
 			conn.readErr = testErr
-			go serv.serve()
-			Eventually(func() Session { return serv.sessions[connID] }).Should(BeNil())
+			Expect(serv.sessionHandler.SetServer(serv)).To(Succeed())
 			Eventually(func() bool { return session.(*mockSession).closed }).Should(BeTrue())
 			Expect(serv.Close()).To(Succeed())
 		})
@@ -346,24 +667,46 @@ var _ = Describe("Server", func() {
 	It("setups with the right values", func() {
 		supportedVersions := []protocol.VersionNumber{1, 3, 5}
 		acceptCookie := func(_ net.Addr, _ *Cookie) bool { return true }
+		logger := utils.DefaultLogger.WithField("foo", "bar")
+		scfgProvider, err := NewSharedSecretServerConfigProvider([]byte("server config secret"))
+		Expect(err).ToNot(HaveOccurred())
+		strikeRegister := NewInMemoryStrikeRegister(time.Minute)
 		config := Config{
-			Versions:         supportedVersions,
-			AcceptCookie:     acceptCookie,
-			HandshakeTimeout: 1337 * time.Hour,
-			IdleTimeout:      42 * time.Minute,
-			KeepAlive:        true,
+			Versions:                supportedVersions,
+			AcceptCookie:            acceptCookie,
+			ServerConfigProvider:    scfgProvider,
+			StrikeRegister:          strikeRegister,
+			HandshakeTimeout:        1337 * time.Hour,
+			IdleTimeout:             42 * time.Minute,
+			KeepAlive:               true,
+			CongestionControl:       congestion.BBRSenderFactory,
+			InitialCongestionWindow: 10,
+			MaxCongestionWindow:     100,
+			EnableDatagrams:         true,
+			AcceptQueueSize:         7,
+			Logger:                  logger,
 		}
 		ln, err := Listen(conn, &tls.Config{}, &config)
 		Expect(err).ToNot(HaveOccurred())
 		server := ln.(*server)
 		Expect(server.deleteClosedSessionsAfter).To(Equal(protocol.ClosedSessionDeleteTimeout))
 		Expect(server.sessions).ToNot(BeNil())
-		Expect(server.scfg).ToNot(BeNil())
+		Expect(server.scfgs).ToNot(BeNil())
 		Expect(server.config.Versions).To(Equal(supportedVersions))
 		Expect(server.config.HandshakeTimeout).To(Equal(1337 * time.Hour))
 		Expect(server.config.IdleTimeout).To(Equal(42 * time.Minute))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(acceptCookie)))
+		Expect(server.config.ServerConfigProvider).To(Equal(scfgProvider))
+		Expect(server.config.StrikeRegister).To(Equal(strikeRegister))
 		Expect(server.config.KeepAlive).To(BeTrue())
+		Expect(reflect.ValueOf(server.config.CongestionControl).Pointer()).To(Equal(reflect.ValueOf(congestion.BBRSenderFactory).Pointer()))
+		Expect(server.config.InitialCongestionWindow).To(BeEquivalentTo(10))
+		Expect(server.config.MaxCongestionWindow).To(BeEquivalentTo(100))
+		Expect(server.config.EnableDatagrams).To(BeTrue())
+		Expect(server.config.AcceptQueueSize).To(Equal(7))
+		Expect(server.Stats().AcceptQueueSize).To(Equal(7))
+		Expect(server.config.Logger).To(Equal(logger))
+		Expect(server.logger).To(Equal(logger))
 	})
 
 	It("fills in default values if options are not set in the Config", func() {
@@ -375,6 +718,36 @@ var _ = Describe("Server", func() {
 		Expect(server.config.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(defaultAcceptCookie)))
 		Expect(server.config.KeepAlive).To(BeFalse())
+		Expect(reflect.ValueOf(server.config.CongestionControl).Pointer()).To(Equal(reflect.ValueOf(congestion.CubicSenderFactory).Pointer()))
+		Expect(server.config.InitialCongestionWindow).To(BeEquivalentTo(protocol.InitialCongestionWindow))
+		Expect(server.config.MaxCongestionWindow).To(BeEquivalentTo(protocol.DefaultMaxCongestionWindow))
+		Expect(server.config.EnableDatagrams).To(BeFalse())
+		Expect(server.config.AcceptQueueSize).To(Equal(protocol.DefaultAcceptQueueSize))
+		Expect(server.config.Logger).To(Equal(utils.DefaultLogger))
+		Expect(server.config.ServerConfigProvider).To(BeNil())
+	})
+
+	It("uses the same server config on servers using the same ServerConfigProvider", func() {
+		scfgProvider, err := NewSharedSecretServerConfigProvider([]byte("server config secret"))
+		Expect(err).ToNot(HaveOccurred())
+		conf := &Config{ServerConfigProvider: scfgProvider}
+		ln1, err := Listen(conn, &tls.Config{}, conf)
+		Expect(err).ToNot(HaveOccurred())
+		ln2, err := Listen(&mockPacketConn{addr: &net.UDPAddr{}}, &tls.Config{}, conf)
+		Expect(err).ToNot(HaveOccurred())
+		scfg1, err := ln1.(*server).scfgs.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		scfg2, err := ln2.(*server).scfgs.Primary()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scfg1.ID).To(Equal(scfg2.ID))
+		Expect(scfg1.Get()).To(Equal(scfg2.Get()))
+	})
+
+	It("limits the initial congestion window to the maximum congestion window", func() {
+		ln, err := Listen(conn, &tls.Config{}, &Config{MaxCongestionWindow: 10})
+		Expect(err).ToNot(HaveOccurred())
+		server := ln.(*server)
+		Expect(server.config.InitialCongestionWindow).To(BeEquivalentTo(10))
 	})
 
 	It("listens on a given address", func() {
@@ -383,6 +756,16 @@ var _ = Describe("Server", func() {
 		Expect(err).ToNot(HaveOccurred())
 		serv := ln.(*server)
 		Expect(serv.Addr().String()).To(Equal(addr))
+		Expect(serv.acceptEarlySessions).To(BeFalse())
+	})
+
+	It("listens for early sessions on a given address", func() {
+		addr := "127.0.0.1:13580"
+		ln, err := ListenAddrEarly(addr, nil, config)
+		Expect(err).ToNot(HaveOccurred())
+		serv := ln.(*server)
+		Expect(serv.Addr().String()).To(Equal(addr))
+		Expect(serv.acceptEarlySessions).To(BeTrue())
 	})
 
 	It("errors if given an invalid address", func() {
@@ -17,6 +17,7 @@ import (
 	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
 )
 
 type unpacker interface {
@@ -33,6 +34,8 @@ type receivedPacket struct {
 var (
 	errRstStreamOnInvalidStream   = errors.New("RST_STREAM received for unknown stream")
 	errWindowUpdateOnClosedStream = errors.New("WINDOW_UPDATE received for an already closed stream")
+	errDatagramsNotEnabled        = errors.New("datagram support disabled")
+	errDatagramsNotSupported      = errors.New("peer doesn't support datagrams")
 )
 
 var (
@@ -56,6 +59,8 @@ type session struct {
 	perspective  protocol.Perspective
 	version      protocol.VersionNumber
 	config       *Config
+	logger       utils.Logger
+	tracer       tracing.SessionTracer
 
 	conn connection
 
@@ -67,8 +72,12 @@ type session struct {
 	sentPacketHandler     ackhandler.SentPacketHandler
 	receivedPacketHandler ackhandler.ReceivedPacketHandler
 	streamFramer          *streamFramer
+	datagramQueue         *datagramQueue
 
 	connFlowController flowcontrol.ConnectionFlowController
+	// refusedStreams holds the flow controllers of streams that the peer opened after we sent a GOAWAY.
+	// They are only used to account for the data received on these streams.
+	refusedStreams map[protocol.StreamID]flowcontrol.StreamFlowController
 
 	unpacker unpacker
 	packer   *packetPacker
@@ -80,6 +89,8 @@ type session struct {
 	// closeChan is used to notify the run loop that it should terminate.
 	closeChan chan closeError
 	closeOnce sync.Once
+	// goawayOnce makes sure that only a single GOAWAY is sent
+	goawayOnce sync.Once
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -98,6 +109,8 @@ type session struct {
 	// will be closed as soon as the handshake completes, and receive any error that might occur until then
 	// it is used to block WaitUntilHandshakeComplete()
 	handshakeCompleteChan chan error
+	// handshakeDone is closed as soon as the handshake completes. It is returned by HandshakeComplete()
+	handshakeDone chan struct{}
 	// handshakeChan receives handshake events and is closed as soon the handshake completes
 	// the receiving end of this channel is passed to the creator of the session
 	// it receives at most 3 handshake events: 2 when the encryption level changes, and one error
@@ -111,9 +124,19 @@ type session struct {
 	sessionCreationTime     time.Time
 	lastNetworkActivityTime time.Time
 
-	peerParams *handshake.TransportParameters
+	packetsReceived uint64
+	bytesReceived   protocol.ByteCount
+	// stats is a snapshot that is updated by the run loop, and read by Stats
+	stats      ConnectionStats
+	statsMutex sync.Mutex
+
+	// peerParams is written by the run loop, and read by ConnectionState
+	peerParams      *handshake.TransportParameters
+	peerParamsMutex sync.RWMutex
 
 	timer *utils.Timer
+	// pacingDeadline is the time when the next packet should be sent, if sending is delayed by the pacer
+	pacingDeadline time.Time
 	// keepAlivePingSent stores whether a Ping frame was sent to the peer or not
 	// it is reset as soon as we receive a packet from the peer
 	keepAlivePingSent bool
@@ -126,7 +149,7 @@ func newSession(
 	conn connection,
 	v protocol.VersionNumber,
 	connectionID protocol.ConnectionID,
-	sCfg *handshake.ServerConfig,
+	scfgs *handshake.ServerConfigManager,
 	tlsConf *tls.Config,
 	config *Config,
 ) (packetHandler, <-chan handshakeEvent, error) {
@@ -137,7 +160,7 @@ func newSession(
 		version:      v,
 		config:       config,
 	}
-	return s.setup(sCfg, "", tlsConf, v, nil)
+	return s.setup(scfgs, "", tlsConf, v, nil, nil)
 }
 
 // declare this as a variable, such that we can it mock it in the tests
@@ -150,6 +173,7 @@ var newClientSession = func(
 	config *Config,
 	initialVersion protocol.VersionNumber,
 	negotiatedVersions []protocol.VersionNumber, // needed for validation of the GQUIC version negotiaton
+	stk []byte, // the STK received in a stateless reject
 ) (packetHandler, <-chan handshakeEvent, error) {
 	s := &session{
 		conn:         conn,
@@ -158,15 +182,16 @@ var newClientSession = func(
 		version:      v,
 		config:       config,
 	}
-	return s.setup(nil, hostname, tlsConf, initialVersion, negotiatedVersions)
+	return s.setup(nil, hostname, tlsConf, initialVersion, negotiatedVersions, stk)
 }
 
 func (s *session) setup(
-	scfg *handshake.ServerConfig,
+	scfgs *handshake.ServerConfigManager,
 	hostname string,
 	tlsConf *tls.Config,
 	initialVersion protocol.VersionNumber,
 	negotiatedVersions []protocol.VersionNumber,
+	stk []byte,
 ) (packetHandler, <-chan handshakeEvent, error) {
 	aeadChanged := make(chan protocol.EncryptionLevel, 2)
 	paramsChan := make(chan handshake.TransportParameters)
@@ -175,11 +200,19 @@ func (s *session) setup(
 	handshakeChan := make(chan handshakeEvent, 3)
 	s.handshakeChan = handshakeChan
 	s.handshakeCompleteChan = make(chan error, 1)
+	s.handshakeDone = make(chan struct{})
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.logger = s.config.Logger.
+		WithField("connection", fmt.Sprintf("%x", s.connectionID)).
+		WithField("perspective", s.perspective).
+		WithField("remote", s.conn.RemoteAddr())
+	if s.config.Tracer != nil {
+		s.tracer = s.config.Tracer.TracerForSession(s.perspective, s.connectionID)
+	}
 
 	s.timer = utils.NewTimer()
 	now := time.Now()
@@ -187,22 +220,39 @@ func (s *session) setup(
 	s.sessionCreationTime = now
 
 	s.rttStats = &congestion.RTTStats{}
+	s.rttStats.SetLogger(s.logger)
 	transportParams := &handshake.TransportParameters{
 		StreamFlowControlWindow:     protocol.ReceiveStreamFlowControlWindow,
 		ConnectionFlowControlWindow: protocol.ReceiveConnectionFlowControlWindow,
 		MaxStreams:                  protocol.MaxIncomingStreams,
+		MaxUniStreams:               protocol.MaxIncomingUniStreams,
 		IdleTimeout:                 s.config.IdleTimeout,
 	}
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(s.rttStats)
+	if s.config.EnableDatagrams {
+		transportParams.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+	}
+	sendAlgorithm := s.config.CongestionControl(
+		congestion.DefaultClock{},
+		s.rttStats,
+		protocol.PacketNumber(s.config.InitialCongestionWindow),
+		protocol.PacketNumber(s.config.MaxCongestionWindow),
+	)
+	if tracingSendAlgorithm, ok := sendAlgorithm.(congestion.TracingSendAlgorithm); ok && s.tracer != nil {
+		tracingSendAlgorithm.SetTracer(s.tracer)
+	}
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(s.rttStats, sendAlgorithm, s.tracer, s.logger)
 	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.version)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ReceiveConnectionFlowControlWindow,
 		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
 		s.rttStats,
+		s.logger,
 	)
+	s.refusedStreams = make(map[protocol.StreamID]flowcontrol.StreamFlowController)
 	s.streamsMap = newStreamsMap(s.newStream, s.perspective, s.version)
 	s.cryptoStream = s.newStream(s.version.CryptoStreamID())
 	s.streamFramer = newStreamFramer(s.cryptoStream, s.streamsMap, s.connFlowController)
+	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
 
 	var err error
 	if s.perspective == protocol.PerspectiveServer {
@@ -221,6 +271,8 @@ func (s *session) setup(
 				verifySourceAddr,
 				s.config.Versions,
 				s.version,
+				s.config.KeyLogWriter,
+				s.logger,
 			)
 		} else {
 			s.cryptoSetup, err = newCryptoSetup(
@@ -228,12 +280,14 @@ func (s *session) setup(
 				s.connectionID,
 				s.conn.RemoteAddr(),
 				s.version,
-				scfg,
+				scfgs,
 				transportParams,
 				s.config.Versions,
 				verifySourceAddr,
 				paramsChan,
 				aeadChanged,
+				s.config.KeyLogWriter,
+				s.logger,
 			)
 		}
 	} else {
@@ -250,6 +304,7 @@ func (s *session) setup(
 				initialVersion,
 				s.config.Versions,
 				s.version,
+				s.config.KeyLogWriter,
 			)
 		} else {
 			s.cryptoSetup, err = newCryptoSetupClient(
@@ -263,6 +318,10 @@ func (s *session) setup(
 				aeadChanged,
 				initialVersion,
 				negotiatedVersions,
+				stk,
+				s.config.KeyLogWriter,
+				s.config.ClientSessionCache,
+				s.logger,
 			)
 		}
 	}
@@ -273,10 +332,15 @@ func (s *session) setup(
 	s.packer = newPacketPacker(s.connectionID,
 		s.cryptoSetup,
 		s.streamFramer,
+		s.datagramQueue,
 		s.perspective,
 		s.version,
 	)
-	s.unpacker = &packetUnpacker{aead: s.cryptoSetup, version: s.version}
+	s.unpacker = &packetUnpacker{
+		aead:                 s.cryptoSetup,
+		version:              s.version,
+		maxDatagramFrameSize: transportParams.MaxDatagramFrameSize,
+	}
 
 	return s, handshakeChan, nil
 }
@@ -293,6 +357,7 @@ func (s *session) run() error {
 
 	var closeErr closeError
 	aeadChanged := s.aeadChanged
+	s.updateStats()
 
 runLoop:
 	for {
@@ -337,7 +402,11 @@ runLoop:
 				s.sentPacketHandler.SetHandshakeComplete()
 				close(s.handshakeChan)
 				close(s.handshakeCompleteChan)
+				close(s.handshakeDone)
 			} else {
+				if s.tracer != nil {
+					s.tracer.UpdatedEncryptionLevel(l)
+				}
 				s.tryDecryptingQueuedPackets()
 				s.handshakeChan <- handshakeEvent{encLevel: l}
 			}
@@ -372,6 +441,7 @@ runLoop:
 		if err := s.streamsMap.DeleteClosedStreams(); err != nil {
 			s.closeLocal(err)
 		}
+		s.updateStats()
 	}
 
 	// only send the error the handshakeChan when the handshake is not completed yet
@@ -381,6 +451,10 @@ runLoop:
 		s.handshakeChan <- handshakeEvent{err: closeErr.err}
 	}
 	s.handleCloseError(closeErr)
+	s.updateStats()
+	if s.tracer != nil {
+		s.tracer.Close()
+	}
 	return closeErr.err
 }
 
@@ -388,6 +462,34 @@ func (s *session) Context() context.Context {
 	return s.ctx
 }
 
+func (s *session) updateStats() {
+	sentStats := s.sentPacketHandler.GetStatistics()
+	s.statsMutex.Lock()
+	s.stats = ConnectionStats{
+		SmoothedRTT:      s.rttStats.SmoothedRTT(),
+		MinRTT:           s.rttStats.MinRTT(),
+		LatestRTT:        s.rttStats.LatestRTT(),
+		MeanDeviation:    s.rttStats.MeanDeviation(),
+		CongestionWindow: uint64(sentStats.CongestionWindow),
+		BytesInFlight:    uint64(sentStats.BytesInFlight),
+		PacketsSent:      sentStats.PacketsSent,
+		BytesSent:        uint64(sentStats.BytesSent),
+		PacketsReceived:  s.packetsReceived,
+		BytesReceived:    uint64(s.bytesReceived),
+		PacketsLost:      sentStats.PacketsLost,
+		RTOCount:         sentStats.RTOCount,
+		Retransmissions:  sentStats.Retransmissions,
+		SendWindow:       uint64(s.connFlowController.SendWindowSize()),
+	}
+	s.statsMutex.Unlock()
+}
+
+func (s *session) Stats() ConnectionStats {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	return s.stats
+}
+
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if s.config.KeepAlive && s.handshakeComplete && !s.keepAlivePingSent {
@@ -409,6 +511,9 @@ func (s *session) maybeResetTimer() {
 	if !s.receivedTooManyUndecrytablePacketsTime.IsZero() {
 		deadline = utils.MinTime(deadline, s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout))
 	}
+	if !s.pacingDeadline.IsZero() {
+		deadline = utils.MinTime(deadline, s.pacingDeadline)
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -439,13 +544,13 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	)
 
 	packet, err := s.unpacker.Unpack(hdr.Raw, hdr, data)
-	if utils.Debug() {
+	if s.logger.Debug() {
 		if err != nil {
-			utils.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID)
+			s.logger.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID)
 		} else {
-			utils.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x, %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, packet.encryptionLevel)
+			s.logger.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x, %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, packet.encryptionLevel)
 		}
-		hdr.Log()
+		hdr.Log(s.logger)
 	}
 	// if the decryption failed, this might be a packet sent by an attacker
 	// don't update the remote address
@@ -459,7 +564,15 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	if err != nil {
 		return err
 	}
+	s.captureReceivedPacket(p, packet.frames)
 
+	size := protocol.ByteCount(len(data) + len(hdr.Raw))
+	if s.tracer != nil {
+		s.tracer.ReceivedPacket(hdr, size, packet.encryptionLevel, packet.frames)
+	}
+
+	s.packetsReceived++
+	s.bytesReceived += size
 	s.lastRcvdPacketNumber = hdr.PacketNumber
 	// Only do this after decrypting, so we are sure the packet is not attacker-controlled
 	s.largestRcvdPacketNumber = utils.MaxPacketNumber(s.largestRcvdPacketNumber, hdr.PacketNumber)
@@ -475,22 +588,24 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 func (s *session) handleFrames(fs []wire.Frame) error {
 	for _, ff := range fs {
 		var err error
-		wire.LogFrame(ff, false)
+		wire.LogFrame(s.logger, ff, false)
 		switch frame := ff.(type) {
 		case *wire.StreamFrame:
 			err = s.handleStreamFrame(frame)
 		case *wire.AckFrame:
 			err = s.handleAckFrame(frame)
 		case *wire.ConnectionCloseFrame:
-			s.closeRemote(qerr.Error(frame.ErrorCode, frame.ReasonPhrase))
+			s.closeRemote(parseCloseError(frame.ErrorCode, frame.ReasonPhrase))
 		case *wire.GoawayFrame:
-			err = errors.New("unimplemented: handling GOAWAY frames")
+			s.streamsMap.RegisterGoaway(frame.LastGoodStream)
 		case *wire.StopWaitingFrame:
 			// LeastUnacked is guaranteed to have LeastUnacked > 0
 			// therefore this will never underflow
 			s.receivedPacketHandler.SetLowerLimit(frame.LeastUnacked - 1)
 		case *wire.RstStreamFrame:
 			err = s.handleRstStreamFrame(frame)
+		case *wire.StopSendingFrame:
+			err = s.handleStopSendingFrame(frame)
 		case *wire.MaxDataFrame:
 			s.handleMaxDataFrame(frame)
 		case *wire.MaxStreamDataFrame:
@@ -498,6 +613,8 @@ func (s *session) handleFrames(fs []wire.Frame) error {
 		case *wire.BlockedFrame:
 		case *wire.StreamBlockedFrame:
 		case *wire.PingFrame:
+		case *wire.DatagramFrame:
+			err = s.handleDatagramFrame(frame)
 		default:
 			return errors.New("Session BUG: unexpected frame type")
 		}
@@ -508,9 +625,11 @@ func (s *session) handleFrames(fs []wire.Frame) error {
 				// Can happen e.g. when packets thought missing arrive late
 			case errRstStreamOnInvalidStream:
 				// Can happen when RST_STREAMs arrive early or late (?)
-				utils.Errorf("Ignoring error in session: %s", err.Error())
+				s.logger.Errorf("Ignoring error in session: %s", err.Error())
 			case errWindowUpdateOnClosedStream:
 				// Can happen when we already sent the last StreamFrame with the FinBit, but the client already sent a WindowUpdate for this Stream
+			case errStreamRefused:
+				// Can happen when the peer sends frames for a new stream after we sent a GOAWAY
 			default:
 				return err
 			}
@@ -533,7 +652,13 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame) error {
 	if frame.StreamID == s.version.CryptoStreamID() {
 		return s.cryptoStream.AddStreamFrame(frame)
 	}
+	if frame.StreamID.IsUnidirectional() && frame.StreamID.InitiatedBy() == s.perspective {
+		return qerr.Error(qerr.InvalidStreamData, fmt.Sprintf("received STREAM frame for send-only stream %d", frame.StreamID))
+	}
 	str, err := s.streamsMap.GetOrOpenStream(frame.StreamID)
+	if err == errStreamRefused {
+		return s.handleRefusedStreamData(frame.StreamID, frame.Offset+frame.DataLen(), frame.FinBit)
+	}
 	if err != nil {
 		return err
 	}
@@ -561,15 +686,45 @@ func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error
 	return nil
 }
 
+func (s *session) handleDatagramFrame(frame *wire.DatagramFrame) error {
+	if !s.config.EnableDatagrams {
+		return qerr.Error(qerr.InvalidFrameData, "received DATAGRAM frame, but datagrams are not enabled")
+	}
+	s.datagramQueue.HandleDatagramFrame(frame)
+	return nil
+}
+
 func (s *session) handleRstStreamFrame(frame *wire.RstStreamFrame) error {
 	str, err := s.streamsMap.GetOrOpenStream(frame.StreamID)
+	if err == errStreamRefused {
+		return s.handleRefusedStreamData(frame.StreamID, frame.ByteOffset, true)
+	}
 	if err != nil {
 		return err
 	}
 	if str == nil {
 		return errRstStreamOnInvalidStream
 	}
-	return str.RegisterRemoteError(fmt.Errorf("RST_STREAM received with code %d", frame.ErrorCode), frame.ByteOffset)
+	return str.RegisterRemoteError(parseRstStreamError(frame.StreamID, frame.ErrorCode), frame.ByteOffset)
+}
+
+func (s *session) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
+	if frame.StreamID == s.version.CryptoStreamID() {
+		return qerr.Error(qerr.InvalidStreamID, "received STOP_SENDING frame for the crypto stream")
+	}
+	if frame.StreamID.IsUnidirectional() && frame.StreamID.InitiatedBy() != s.perspective {
+		return qerr.Error(qerr.InvalidStreamID, fmt.Sprintf("received STOP_SENDING frame for receive-only stream %d", frame.StreamID))
+	}
+	str, err := s.streamsMap.GetOrOpenStream(frame.StreamID)
+	if err != nil {
+		return err
+	}
+	if str == nil {
+		// Stream is closed and already garbage collected
+		return nil
+	}
+	str.HandleStopSendingFrame(frame)
+	return nil
 }
 
 func (s *session) handleAckFrame(frame *wire.AckFrame) error {
@@ -601,24 +756,30 @@ func (s *session) handleCloseError(closeErr closeError) error {
 		closeErr.err = qerr.PeerGoingAway
 	}
 
-	var quicErr *qerr.QuicError
-	var ok bool
-	if quicErr, ok = closeErr.err.(*qerr.QuicError); !ok {
-		quicErr = qerr.ToQuicError(closeErr.err)
-	}
-	// Don't log 'normal' reasons
-	if quicErr.ErrorCode == qerr.PeerGoingAway || quicErr.ErrorCode == qerr.NetworkIdleTimeout {
-		utils.Infof("Closing connection %x", s.connectionID)
+	quicErr := toQuicError(closeErr.err)
+	// application errors are passed to the streams as they are
+	var streamErr error = quicErr
+	if _, ok := closeErr.err.(*ApplicationError); ok {
+		streamErr = closeErr.err
+		s.logger.Infof("Closing connection %x with %s", s.connectionID, closeErr.err.Error())
+	} else if quicErr.ErrorCode == qerr.PeerGoingAway || quicErr.ErrorCode == qerr.NetworkIdleTimeout {
+		// Don't log 'normal' reasons
+		s.logger.Infof("Closing connection %x", s.connectionID)
 	} else {
-		utils.Errorf("Closing session with error: %s", closeErr.err.Error())
+		s.logger.Errorf("Closing session with error: %s", closeErr.err.Error())
 	}
 
-	s.cryptoStream.Cancel(quicErr)
-	s.streamsMap.CloseWithError(quicErr)
+	s.cryptoStream.Cancel(streamErr)
+	s.streamsMap.CloseWithError(streamErr)
+	s.datagramQueue.CloseWithError(streamErr)
 
 	if closeErr.err == errCloseSessionForNewVersion {
 		return nil
 	}
+	// the server didn't keep any state for a connection it rejected statelessly
+	if _, ok := closeErr.err.(*handshake.StatelessRejectError); ok {
+		return nil
+	}
 
 	// If this is a remote close we're done here
 	if closeErr.remote {
@@ -634,8 +795,11 @@ func (s *session) handleCloseError(closeErr closeError) error {
 }
 
 func (s *session) processTransportParameters(params *handshake.TransportParameters) {
+	s.peerParamsMutex.Lock()
 	s.peerParams = params
+	s.peerParamsMutex.Unlock()
 	s.streamsMap.UpdateMaxStreamLimit(params.MaxStreams)
+	s.streamsMap.UpdateMaxUniStreamLimit(params.MaxUniStreams)
 	if params.OmitConnectionID {
 		s.packer.SetOmitConnectionID()
 	}
@@ -662,11 +826,20 @@ func (s *session) sendPacket() error {
 
 	// Repeatedly try sending until we don't have any more data, or run out of the congestion window
 	for {
-		if !s.sentPacketHandler.SendingAllowed() {
+		sendingAllowed := s.sentPacketHandler.SendingAllowed()
+		// If the congestion controller allows sending, the pacer might still delay the next packet.
+		// The timer will fire when the pacer allows sending the next packet.
+		s.pacingDeadline = time.Time{}
+		if sendingAllowed {
+			s.pacingDeadline = s.sentPacketHandler.TimeUntilSend()
+			sendingAllowed = s.pacingDeadline.IsZero()
+		}
+		if !sendingAllowed {
 			if ack == nil {
 				return nil
 			}
-			// If we aren't allowed to send, at least try sending an ACK frame
+			// If we aren't allowed to send, at least try sending an ACK frame.
+			// ACK-only packets are neither congestion controlled nor paced.
 			swf := s.sentPacketHandler.GetStopWaitingFrame(false)
 			if swf != nil {
 				s.packer.QueueControlFrame(swf)
@@ -690,7 +863,7 @@ func (s *session) sendPacket() error {
 					// Don't retransmit handshake packets when the handshake is complete
 					continue
 				}
-				utils.Debugf("\tDequeueing handshake retransmission for packet 0x%x", retransmitPacket.PacketNumber)
+				s.logger.Debugf("\tDequeueing handshake retransmission for packet 0x%x", retransmitPacket.PacketNumber)
 				s.packer.QueueControlFrame(s.sentPacketHandler.GetStopWaitingFrame(true))
 				packet, err := s.packer.PackHandshakeRetransmission(retransmitPacket)
 				if err != nil {
@@ -700,7 +873,7 @@ func (s *session) sendPacket() error {
 					return err
 				}
 			} else {
-				utils.Debugf("\tDequeueing retransmission for packet 0x%x", retransmitPacket.PacketNumber)
+				s.logger.Debugf("\tDequeueing retransmission for packet 0x%x", retransmitPacket.PacketNumber)
 				// resend the frames that were in the packet
 				for _, frame := range retransmitPacket.GetFramesForRetransmission() {
 					// TODO: only retransmit WINDOW_UPDATEs if they actually enlarge the window
@@ -754,6 +927,8 @@ func (s *session) sendPackedPacket(packet *packedPacket) error {
 		return err
 	}
 	s.logPacket(packet)
+	s.tracePacket(packet)
+	s.captureSentPacket(packet)
 	return s.conn.Write(packet.raw)
 }
 
@@ -767,21 +942,57 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) error {
 		return err
 	}
 	s.logPacket(packet)
+	s.tracePacket(packet)
+	s.captureSentPacket(packet)
 	return s.conn.Write(packet.raw)
 }
 
 func (s *session) logPacket(packet *packedPacket) {
-	if !utils.Debug() {
+	if !s.logger.Debug() {
 		// We don't need to allocate the slices for calling the format functions
 		return
 	}
-	utils.Debugf("-> Sending packet 0x%x (%d bytes) for connection %x, %s", packet.header.PacketNumber, len(packet.raw), s.connectionID, packet.encryptionLevel)
-	packet.header.Log()
+	s.logger.Debugf("-> Sending packet 0x%x (%d bytes) for connection %x, %s", packet.header.PacketNumber, len(packet.raw), s.connectionID, packet.encryptionLevel)
+	packet.header.Log(s.logger)
+	for _, frame := range packet.frames {
+		wire.LogFrame(s.logger, frame, true)
+	}
+}
+
+func (s *session) tracePacket(packet *packedPacket) {
+	if s.tracer == nil {
+		return
+	}
+	s.tracer.SentPacket(packet.header, protocol.ByteCount(len(packet.raw)), packet.encryptionLevel, packet.frames)
 	for _, frame := range packet.frames {
-		wire.LogFrame(frame, true)
+		switch f := frame.(type) {
+		case *wire.BlockedFrame:
+			s.tracer.Blocked(0)
+		case *wire.StreamBlockedFrame:
+			s.tracer.Blocked(f.StreamID)
+		}
 	}
 }
 
+func (s *session) captureSentPacket(packet *packedPacket) {
+	if s.config.PacketCapture == nil {
+		return
+	}
+	s.config.PacketCapture.CapturePacket(time.Now(), packet.raw, s.conn.LocalAddr(), s.conn.RemoteAddr(), packet.frames)
+}
+
+// captureReceivedPacket captures a received packet.
+// The frames are nil if the packet couldn't be decrypted.
+func (s *session) captureReceivedPacket(p *receivedPacket, frames []wire.Frame) {
+	if s.config.PacketCapture == nil {
+		return
+	}
+	data := make([]byte, 0, len(p.header.Raw)+len(p.data))
+	data = append(data, p.header.Raw...)
+	data = append(data, p.data...)
+	s.config.PacketCapture.CapturePacket(p.rcvTime, data, p.remoteAddr, s.conn.LocalAddr(), frames)
+}
+
 // GetOrOpenStream either returns an existing stream, a newly opened stream, or nil if a stream with the provided ID is already closed.
 // Newly opened streams should only originate from the client. To open a stream from the server, OpenStream should be used.
 func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
@@ -793,7 +1004,7 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 	return nil, err
 }
 
-// AcceptStream returns the next stream openend by the peer
+// AcceptStream returns the next stream opened by the peer
 func (s *session) AcceptStream() (Stream, error) {
 	return s.streamsMap.AcceptStream()
 }
@@ -807,24 +1018,134 @@ func (s *session) OpenStreamSync() (Stream, error) {
 	return s.streamsMap.OpenStreamSync()
 }
 
+// AcceptUniStream returns the next unidirectional stream opened by the peer
+func (s *session) AcceptUniStream() (ReceiveStream, error) {
+	str, err := s.streamsMap.AcceptUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// OpenUniStream opens a unidirectional stream
+func (s *session) OpenUniStream() (SendStream, error) {
+	str, err := s.streamsMap.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+func (s *session) OpenUniStreamSync() (SendStream, error) {
+	str, err := s.streamsMap.OpenUniStreamSync()
+	if err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// CloseGracefully sends a GOAWAY frame, and closes the session as soon as all open streams are closed.
+// New streams opened by the peer are refused.
+// If the context is cancelled before all streams are closed, the session is closed immediately.
+func (s *session) CloseGracefully(ctx context.Context) error {
+	lastGoodStream, allStreamsClosed := s.streamsMap.StopAcceptingStreams()
+	s.goawayOnce.Do(func() {
+		s.queueControlFrame(&wire.GoawayFrame{
+			ErrorCode:      qerr.PeerGoingAway,
+			LastGoodStream: lastGoodStream,
+		})
+	})
+
+	select {
+	case <-allStreamsClosed:
+		return s.Close(nil)
+	case <-s.ctx.Done():
+		return nil
+	case <-ctx.Done():
+		s.Close(ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// SendMessage sends an unreliable datagram
+func (s *session) SendMessage(p []byte) error {
+	if !s.config.EnableDatagrams {
+		return errDatagramsNotEnabled
+	}
+	s.peerParamsMutex.RLock()
+	var maxSize protocol.ByteCount
+	if s.peerParams != nil {
+		maxSize = s.peerParams.MaxDatagramFrameSize
+	}
+	s.peerParamsMutex.RUnlock()
+	if maxSize == 0 {
+		return errDatagramsNotSupported
+	}
+	maxSize = utils.MinByteCount(maxSize, protocol.MaxDatagramFrameSize)
+	f := &wire.DatagramFrame{Data: make([]byte, len(p))}
+	copy(f.Data, p)
+	if l, _ := f.MinLength(s.version); l > maxSize {
+		return fmt.Errorf("DATAGRAM frame too large (%d bytes, maximum %d bytes)", l, maxSize)
+	}
+	return s.datagramQueue.AddAndWait(f)
+}
+
+// ReceiveMessage returns the next datagram received from the peer
+func (s *session) ReceiveMessage() ([]byte, error) {
+	if !s.config.EnableDatagrams {
+		return nil, errDatagramsNotEnabled
+	}
+	return s.datagramQueue.Receive()
+}
+
 func (s *session) WaitUntilHandshakeComplete() error {
 	return <-s.handshakeCompleteChan
 }
 
+func (s *session) HandshakeComplete() <-chan struct{} {
+	return s.handshakeDone
+}
+
+func (s *session) Used0RTT() bool {
+	return s.cryptoSetup.ConnectionState().Used0RTT
+}
+
+func (s *session) queueControlFrame(f wire.Frame) {
+	s.packer.QueueControlFrame(f)
+	s.scheduleSending()
+}
+
 func (s *session) queueResetStreamFrame(id protocol.StreamID, offset protocol.ByteCount) {
-	s.packer.QueueControlFrame(&wire.RstStreamFrame{
+	s.queueControlFrame(&wire.RstStreamFrame{
 		StreamID:   id,
 		ByteOffset: offset,
 	})
-	s.scheduleSending()
 }
 
-func (s *session) newStream(id protocol.StreamID) streamI {
+// handleRefusedStreamData handles data for a stream that the peer opened after we sent a GOAWAY.
+// The data counts against connection-level flow control, but it is considered consumed right away.
+// The first frame received for the stream makes us reset the stream.
+func (s *session) handleRefusedStreamData(id protocol.StreamID, offset protocol.ByteCount, final bool) error {
+	flowController, ok := s.refusedStreams[id]
+	if !ok {
+		flowController = s.newFlowController(id)
+		flowController.Abandon()
+		s.refusedStreams[id] = flowController
+		// tell the peer that this stream won't be processed
+		s.queueControlFrame(&wire.RstStreamFrame{
+			StreamID:  id,
+			ErrorCode: refusedStreamErrorCode,
+		})
+	}
+	return flowController.UpdateHighestReceived(offset, final)
+}
+
+func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
 	var initialSendWindow protocol.ByteCount
 	if s.peerParams != nil {
 		initialSendWindow = s.peerParams.StreamFlowControlWindow
 	}
-	flowController := flowcontrol.NewStreamFlowController(
+	return flowcontrol.NewStreamFlowController(
 		id,
 		s.version.StreamContributesToConnectionFlowControl(id),
 		s.connFlowController,
@@ -832,12 +1153,23 @@ func (s *session) newStream(id protocol.StreamID) streamI {
 		protocol.ByteCount(s.config.MaxReceiveStreamFlowControlWindow),
 		initialSendWindow,
 		s.rttStats,
+		s.logger,
 	)
-	return newStream(id, s.scheduleSending, s.queueResetStreamFrame, flowController, s.version)
+}
+
+func (s *session) newStream(id protocol.StreamID) streamI {
+	flowController := s.newFlowController(id)
+	if id.IsUnidirectional() {
+		if id.InitiatedBy() == s.perspective {
+			return newSendStream(id, s.scheduleSending, s.queueControlFrame, s.streamsMap.UpdatePriority, flowController, s.version)
+		}
+		return newReceiveStream(id, s.scheduleSending, s.queueControlFrame, s.streamsMap.UpdatePriority, flowController, s.version)
+	}
+	return newStream(id, s.scheduleSending, s.queueControlFrame, s.streamsMap.UpdatePriority, flowController, s.version)
 }
 
 func (s *session) sendPublicReset(rejectedPacketNumber protocol.PacketNumber) error {
-	utils.Infof("Sending public reset for connection %x, packet number %d", s.connectionID, rejectedPacketNumber)
+	s.logger.Infof("Sending public reset for connection %x, packet number %d", s.connectionID, rejectedPacketNumber)
 	return s.conn.Write(wire.WritePublicReset(s.connectionID, rejectedPacketNumber, 0))
 }
 
@@ -851,7 +1183,9 @@ func (s *session) scheduleSending() {
 
 func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket) {
 	if s.handshakeComplete {
-		utils.Debugf("Received undecryptable packet from %s after the handshake: %#v, %d bytes data", p.remoteAddr.String(), p.header, len(p.data))
+		s.logger.Debugf("Received undecryptable packet from %s after the handshake: %#v, %d bytes data", p.remoteAddr.String(), p.header, len(p.data))
+		s.traceDroppedUndecryptablePacket(p)
+		s.captureReceivedPacket(p, nil)
 		return
 	}
 	if len(s.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
@@ -860,13 +1194,21 @@ func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket) {
 			s.receivedTooManyUndecrytablePacketsTime = time.Now()
 			s.maybeResetTimer()
 		}
-		utils.Infof("Dropping undecrytable packet 0x%x (undecryptable packet queue full)", p.header.PacketNumber)
+		s.logger.Infof("Dropping undecrytable packet 0x%x (undecryptable packet queue full)", p.header.PacketNumber)
+		s.traceDroppedUndecryptablePacket(p)
+		s.captureReceivedPacket(p, nil)
 		return
 	}
-	utils.Infof("Queueing packet 0x%x for later decryption", p.header.PacketNumber)
+	s.logger.Infof("Queueing packet 0x%x for later decryption", p.header.PacketNumber)
 	s.undecryptablePackets = append(s.undecryptablePackets, p)
 }
 
+func (s *session) traceDroppedUndecryptablePacket(p *receivedPacket) {
+	if s.tracer != nil {
+		s.tracer.DroppedPacket(p.header, protocol.ByteCount(len(p.header.Raw)+len(p.data)), tracing.PacketDropUndecryptable)
+	}
+}
+
 func (s *session) tryDecryptingQueuedPackets() {
 	for _, p := range s.undecryptablePackets {
 		s.handlePacket(p)
@@ -904,3 +1246,21 @@ func (s *session) RemoteAddr() net.Addr {
 func (s *session) GetVersion() protocol.VersionNumber {
 	return s.version
 }
+
+func (s *session) ConnectionState() ConnectionState {
+	cs := s.cryptoSetup.ConnectionState()
+	s.peerParamsMutex.RLock()
+	peerParams := s.peerParams
+	s.peerParamsMutex.RUnlock()
+	return ConnectionState{
+		Version:                 s.version,
+		HandshakeComplete:       cs.HandshakeComplete,
+		Used0RTT:                cs.Used0RTT,
+		ServerName:              cs.ServerName,
+		CipherSuite:             cs.CipherSuite,
+		KeyExchange:             cs.KeyExchange,
+		NegotiatedProtocol:      cs.NegotiatedProtocol,
+		PeerCertificates:        cs.PeerCertificates,
+		PeerTransportParameters: peerParams,
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"log"
 	"net"
+	"os"
 	"runtime/pprof"
 	"strings"
 	"time"
@@ -16,13 +18,16 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/seong889/quic-go/ackhandler"
+	"github.com/seong889/quic-go/congestion"
 	"github.com/seong889/quic-go/internal/crypto"
 	"github.com/seong889/quic-go/internal/handshake"
 	"github.com/seong889/quic-go/internal/mocks"
 	"github.com/seong889/quic-go/internal/protocol"
 	"github.com/seong889/quic-go/internal/testdata"
+	"github.com/seong889/quic-go/internal/utils"
 	"github.com/seong889/quic-go/internal/wire"
 	"github.com/seong889/quic-go/qerr"
+	"github.com/seong889/quic-go/tracing"
 )
 
 type mockConnection struct {
@@ -70,10 +75,45 @@ func (m *mockUnpacker) Unpack(headerBinary []byte, hdr *wire.Header, data []byte
 	}, nil
 }
 
+type mockTracer struct {
+	sessionTracer tracing.SessionTracer
+	perspectives  []protocol.Perspective
+	connectionIDs []protocol.ConnectionID
+}
+
+func (t *mockTracer) TracerForSession(p protocol.Perspective, connID protocol.ConnectionID) tracing.SessionTracer {
+	t.perspectives = append(t.perspectives, p)
+	t.connectionIDs = append(t.connectionIDs, connID)
+	return t.sessionTracer
+}
+func (t *mockTracer) DroppedPacket(net.Addr, protocol.ByteCount, tracing.PacketDropReason) {
+	panic("not implemented")
+}
+
+var _ tracing.Tracer = &mockTracer{}
+
+type capturedPacket struct {
+	time     time.Time
+	data     []byte
+	src, dst net.Addr
+	frames   []wire.Frame
+}
+
+type mockPacketCapture struct {
+	packets []capturedPacket
+}
+
+func (c *mockPacketCapture) CapturePacket(t time.Time, data []byte, src, dst net.Addr, frames []tracing.Frame) {
+	c.packets = append(c.packets, capturedPacket{time: t, data: data, src: src, dst: dst, frames: frames})
+}
+
+var _ tracing.PacketCapture = &mockPacketCapture{}
+
 type mockSentPacketHandler struct {
 	retransmissionQueue             []*ackhandler.Packet
 	sentPackets                     []*ackhandler.Packet
 	congestionLimited               bool
+	pacingDeadline                  time.Time
 	requestedStopWaiting            bool
 	shouldSendRetransmittablePacket bool
 }
@@ -87,9 +127,18 @@ func (h *mockSentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketN
 }
 func (h *mockSentPacketHandler) SetHandshakeComplete()                  {}
 func (h *mockSentPacketHandler) GetLeastUnacked() protocol.PacketNumber { return 1 }
-func (h *mockSentPacketHandler) GetAlarmTimeout() time.Time             { panic("not implemented") }
+func (h *mockSentPacketHandler) GetAlarmTimeout() time.Time             { return time.Time{} }
 func (h *mockSentPacketHandler) OnAlarm()                               { panic("not implemented") }
 func (h *mockSentPacketHandler) SendingAllowed() bool                   { return !h.congestionLimited }
+func (h *mockSentPacketHandler) TimeUntilSend() time.Time {
+	if h.pacingDeadline.After(time.Now()) {
+		return h.pacingDeadline
+	}
+	return time.Time{}
+}
+func (h *mockSentPacketHandler) GetStatistics() ackhandler.Statistics {
+	return ackhandler.Statistics{PacketsSent: uint64(len(h.sentPackets))}
+}
 func (h *mockSentPacketHandler) ShouldSendRetransmittablePacket() bool {
 	b := h.shouldSendRetransmittablePacket
 	h.shouldSendRetransmittablePacket = false
@@ -145,7 +194,7 @@ func areSessionsRunning() bool {
 var _ = Describe("Session", func() {
 	var (
 		sess          *session
-		scfg          *handshake.ServerConfig
+		scfgs         *handshake.ServerConfigManager
 		mconn         *mockConnection
 		cryptoSetup   *mockCryptoSetup
 		handshakeChan <-chan handshakeEvent
@@ -161,12 +210,14 @@ var _ = Describe("Session", func() {
 			_ protocol.ConnectionID,
 			_ net.Addr,
 			_ protocol.VersionNumber,
-			_ *handshake.ServerConfig,
+			_ *handshake.ServerConfigManager,
 			_ *handshake.TransportParameters,
 			_ []protocol.VersionNumber,
 			_ func(net.Addr, *Cookie) bool,
 			_ chan<- handshake.TransportParameters,
 			aeadChangedP chan<- protocol.EncryptionLevel,
+			_ io.Writer,
+			_ utils.Logger,
 		) (handshake.CryptoSetup, error) {
 			aeadChanged = aeadChangedP
 			return cryptoSetup, nil
@@ -174,16 +225,15 @@ var _ = Describe("Session", func() {
 
 		mconn = newMockConnection()
 		certChain := crypto.NewCertChain(testdata.GetTLSConfig())
-		kex, err := crypto.NewCurve25519KEX()
-		Expect(err).NotTo(HaveOccurred())
-		scfg, err = handshake.NewServerConfig(kex, certChain)
+		var err error
+		scfgs, err = handshake.NewServerConfigManager(certChain, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		var pSess Session
 		pSess, handshakeChan, err = newSession(
 			mconn,
 			protocol.Version39,
 			0,
-			scfg,
+			scfgs,
 			nil,
 			populateServerConfig(&Config{}),
 		)
@@ -211,12 +261,14 @@ var _ = Describe("Session", func() {
 				_ protocol.ConnectionID,
 				_ net.Addr,
 				_ protocol.VersionNumber,
-				_ *handshake.ServerConfig,
+				_ *handshake.ServerConfigManager,
 				_ *handshake.TransportParameters,
 				_ []protocol.VersionNumber,
 				cookieFunc func(net.Addr, *Cookie) bool,
 				_ chan<- handshake.TransportParameters,
 				_ chan<- protocol.EncryptionLevel,
+				_ io.Writer,
+				_ utils.Logger,
 			) (handshake.CryptoSetup, error) {
 				cookieVerify = cookieFunc
 				return cryptoSetup, nil
@@ -232,7 +284,7 @@ var _ = Describe("Session", func() {
 				mconn,
 				protocol.Version39,
 				0,
-				scfg,
+				scfgs,
 				nil,
 				conf,
 			)
@@ -257,11 +309,39 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	It("creates the congestion controller from the config", func() {
+		var initialWindow, maxWindow protocol.PacketNumber
+		conf := populateServerConfig(&Config{InitialCongestionWindow: 10, MaxCongestionWindow: 100})
+		conf.CongestionControl = func(clock congestion.Clock, rttStats *congestion.RTTStats, initial, max congestion.PacketNumber) congestion.SendAlgorithm {
+			initialWindow = initial
+			maxWindow = max
+			return congestion.BBRSenderFactory(clock, rttStats, initial, max)
+		}
+		_, _, err := newSession(mconn, protocol.Version39, 0, scfgs, nil, conf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(initialWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(maxWindow).To(Equal(protocol.PacketNumber(100)))
+	})
+
+	It("logs using a logger tagged with the connection ID, the perspective and the remote address", func() {
+		buf := &bytes.Buffer{}
+		log.SetOutput(buf)
+		defer log.SetOutput(os.Stderr)
+		utils.SetLogLevel(utils.LogLevelDebug)
+		defer utils.SetLogLevel(utils.LogLevelNothing)
+		mconn.remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1000}
+		pSess, _, err := newSession(mconn, protocol.Version39, 0x1337, scfgs, nil, populateServerConfig(&Config{}))
+		Expect(err).ToNot(HaveOccurred())
+		pSess.(*session).logger.Debugf("foobar")
+		Expect(buf.String()).To(ContainSubstring("[connection=1337 perspective=Server remote=192.168.13.37:1000] foobar"))
+	})
+
 	Context("frame handling", func() {
 		BeforeEach(func() {
 			sess.streamsMap.newStream = func(id protocol.StreamID) streamI {
 				str := mocks.NewMockStreamI(mockCtrl)
 				str.EXPECT().StreamID().Return(id).AnyTimes()
+				str.EXPECT().Priority().Return(protocol.DefaultStreamUrgency, true).AnyTimes()
 				if id == 1 {
 					str.EXPECT().Finished().AnyTimes()
 				}
@@ -330,6 +410,73 @@ var _ = Describe("Session", func() {
 				})
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			Context("refusing new streams after sending a GOAWAY", func() {
+				BeforeEach(func() {
+					sess.streamsMap.StopAcceptingStreams()
+				})
+
+				It("resets new streams", func() {
+					err := sess.handleStreamFrame(&wire.StreamFrame{
+						StreamID: 5,
+						Data:     []byte("foobar"),
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.packer.controlFrames).To(Equal([]wire.Frame{
+						&wire.RstStreamFrame{StreamID: 5, ErrorCode: refusedStreamErrorCode},
+					}))
+					Expect(sess.streamsMap.streams).ToNot(HaveKey(protocol.StreamID(5)))
+				})
+
+				It("resets every stream only once", func() {
+					for i := 0; i < 3; i++ {
+						err := sess.handleStreamFrame(&wire.StreamFrame{
+							StreamID: 5,
+							Offset:   protocol.ByteCount(6 * i),
+							Data:     []byte("foobar"),
+						})
+						Expect(err).ToNot(HaveOccurred())
+					}
+					err := sess.handleRstStreamFrame(&wire.RstStreamFrame{StreamID: 5, ByteOffset: 18})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.packer.controlFrames).To(HaveLen(1))
+				})
+
+				It("counts the data against connection-level flow control, and considers it consumed", func() {
+					err := sess.handleStreamFrame(&wire.StreamFrame{
+						StreamID: 5,
+						Data:     make([]byte, protocol.ReceiveStreamFlowControlWindow),
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.connFlowController.GetWindowUpdate()).ToNot(BeZero())
+				})
+
+				It("counts the final offset of a RST_STREAM against connection-level flow control", func() {
+					err := sess.handleRstStreamFrame(&wire.RstStreamFrame{
+						StreamID:   5,
+						ByteOffset: protocol.ReceiveStreamFlowControlWindow,
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.connFlowController.GetWindowUpdate()).ToNot(BeZero())
+				})
+
+				It("errors if the peer violates flow control on a refused stream", func() {
+					err := sess.handleStreamFrame(&wire.StreamFrame{
+						StreamID: 5,
+						Data:     make([]byte, protocol.ReceiveStreamFlowControlWindow+1),
+					})
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.FlowControlReceivedTooMuchData))
+				})
+			})
+
+			It("errors on STREAM frames for unidirectional streams opened by us", func() {
+				err := sess.handleStreamFrame(&wire.StreamFrame{
+					StreamID: 0x80000002,
+					Data:     []byte("foobar"),
+				})
+				Expect(err).To(MatchError(qerr.Error(qerr.InvalidStreamData, "received STREAM frame for send-only stream 2147483650")))
+			})
 		})
 
 		Context("handling RST_STREAM frames", func() {
@@ -337,12 +484,42 @@ var _ = Describe("Session", func() {
 				str, err := sess.GetOrOpenStream(5)
 				Expect(err).ToNot(HaveOccurred())
 				str.(*mocks.MockStreamI).EXPECT().RegisterRemoteError(
-					errors.New("RST_STREAM received with code 42"),
+					&StreamError{StreamID: 5, ErrorCode: 42},
+					protocol.ByteCount(0x1337),
+				)
+				err = sess.handleRstStreamFrame(&wire.RstStreamFrame{
+					StreamID:   5,
+					ErrorCode:  0x10000 + 42,
+					ByteOffset: 0x1337,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("reports transport error codes as a StreamTransportError", func() {
+				str, err := sess.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*mocks.MockStreamI).EXPECT().RegisterRemoteError(
+					&StreamTransportError{StreamID: 5, ErrorCode: 6},
+					protocol.ByteCount(0x1337),
+				)
+				err = sess.handleRstStreamFrame(&wire.RstStreamFrame{
+					StreamID:   5,
+					ErrorCode:  6, // QUIC_STREAM_CANCELLED
+					ByteOffset: 0x1337,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("doesn't truncate error codes above 0xffff", func() {
+				str, err := sess.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*mocks.MockStreamI).EXPECT().RegisterRemoteError(
+					&StreamTransportError{StreamID: 5, ErrorCode: 0x20000 + 42},
 					protocol.ByteCount(0x1337),
 				)
 				err = sess.handleRstStreamFrame(&wire.RstStreamFrame{
 					StreamID:   5,
-					ErrorCode:  42,
+					ErrorCode:  0x20000 + 42,
 					ByteOffset: 0x1337,
 				})
 				Expect(err).ToNot(HaveOccurred())
@@ -385,6 +562,45 @@ var _ = Describe("Session", func() {
 			})
 		})
 
+		Context("handling STOP_SENDING frames", func() {
+			It("passes the frame to the stream", func() {
+				frame := &wire.StopSendingFrame{
+					StreamID:  5,
+					ErrorCode: 42,
+				}
+				str, err := sess.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*mocks.MockStreamI).EXPECT().HandleStopSendingFrame(frame)
+				err = sess.handleFrames([]wire.Frame{frame})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("ignores the frame when the stream is already closed", func() {
+				str, err := sess.GetOrOpenStream(3)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*mocks.MockStreamI).EXPECT().Finished().Return(true)
+				sess.streamsMap.DeleteClosedStreams()
+				err = sess.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: 3})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("errors for the crypto stream", func() {
+				err := sess.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: sess.version.CryptoStreamID()})
+				Expect(err).To(MatchError("InvalidStreamID: received STOP_SENDING frame for the crypto stream"))
+			})
+
+			It("errors for unidirectional streams opened by the peer", func() {
+				err := sess.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: 0x80000001})
+				Expect(err).To(MatchError("InvalidStreamID: received STOP_SENDING frame for receive-only stream 2147483649"))
+			})
+
+			It("queues control frames for streams", func() {
+				frame := &wire.StopSendingFrame{StreamID: 5, ErrorCode: 42}
+				sess.queueControlFrame(frame)
+				Expect(sess.packer.controlFrames).To(Equal([]wire.Frame{frame}))
+			})
+		})
+
 		Context("handling MAX_DATA and MAX_STREAM_DATA frames", func() {
 			var connFC *mocks.MockConnectionFlowController
 
@@ -452,14 +668,37 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("handles DATAGRAM frames", func() {
+			sess.config.EnableDatagrams = true
+			err := sess.handleFrames([]wire.Frame{&wire.DatagramFrame{Data: []byte("foobar")}})
+			Expect(err).NotTo(HaveOccurred())
+			data, err := sess.ReceiveMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("errors on DATAGRAM frames if datagrams are not enabled", func() {
+			err := sess.handleFrames([]wire.Frame{&wire.DatagramFrame{Data: []byte("foobar")}})
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "received DATAGRAM frame, but datagrams are not enabled")))
+		})
+
 		It("handles BLOCKED frames", func() {
 			err := sess.handleFrames([]wire.Frame{&wire.BlockedFrame{}})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("errors on GOAWAY frames", func() {
-			err := sess.handleFrames([]wire.Frame{&wire.GoawayFrame{}})
-			Expect(err).To(MatchError("unimplemented: handling GOAWAY frames"))
+		It("handles GOAWAY frames", func() {
+			sess.streamsMap.UpdateMaxStreamLimit(100)
+			_, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(4)))
+			str.(*mocks.MockStreamI).EXPECT().Cancel(errGoawayReceived)
+			err = sess.handleFrames([]wire.Frame{&wire.GoawayFrame{LastGoodStream: 2}})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sess.OpenStream()
+			Expect(err).To(MatchError(errGoawayReceived))
 		})
 
 		It("handles STOP_WAITING frames", func() {
@@ -488,6 +727,29 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 			Eventually(done).Should(BeClosed())
 		})
+
+		It("handles CONNECTION_CLOSE frames with application error codes", func() {
+			appErr := &ApplicationError{ErrorCode: 42, ReasonPhrase: "foobar"}
+			cryptoStream := mocks.NewMockStreamI(mockCtrl)
+			cryptoStream.EXPECT().Cancel(appErr)
+			sess.cryptoStream = cryptoStream
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := sess.run()
+				Expect(err).To(Equal(appErr))
+				close(done)
+			}()
+			_, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			sess.streamsMap.Range(func(s streamI) {
+				s.(*mocks.MockStreamI).EXPECT().Cancel(appErr)
+			})
+			err = sess.handleFrames([]wire.Frame{&wire.ConnectionCloseFrame{ErrorCode: 0x10000 + 42, ReasonPhrase: "foobar"}})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			Eventually(done).Should(BeClosed())
+		})
 	})
 
 	It("tells its versions", func() {
@@ -495,6 +757,59 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("tells if 0-RTT was used", func() {
+		Expect(sess.Used0RTT()).To(BeFalse())
+		cryptoSetup.connectionState = handshake.ConnectionState{Used0RTT: true}
+		Expect(sess.Used0RTT()).To(BeTrue())
+	})
+
+	It("returns the connection state", func() {
+		sess.version = 4242
+		cryptoSetup.connectionState = handshake.ConnectionState{
+			HandshakeComplete: true,
+			ServerName:        "quic.clemente.io",
+			CipherSuite:       "AESG",
+			KeyExchange:       "C255",
+		}
+		params := &handshake.TransportParameters{IdleTimeout: 42 * time.Second}
+		sess.processTransportParameters(params)
+		state := sess.ConnectionState()
+		Expect(state.Version).To(Equal(protocol.VersionNumber(4242)))
+		Expect(state.HandshakeComplete).To(BeTrue())
+		Expect(state.ServerName).To(Equal("quic.clemente.io"))
+		Expect(state.CipherSuite).To(Equal("AESG"))
+		Expect(state.KeyExchange).To(Equal("C255"))
+		Expect(state.PeerTransportParameters).To(Equal(params))
+	})
+
+	It("returns the stats", func() {
+		sess.sentPacketHandler = newMockSentPacketHandler()
+		sess.sentPacketHandler.(*mockSentPacketHandler).sentPackets = make([]*ackhandler.Packet, 3)
+		sess.rttStats.UpdateRTT(time.Second, 0, time.Now())
+		sess.packetsReceived = 2
+		sess.updateStats()
+		stats := sess.Stats()
+		Expect(stats.PacketsSent).To(BeEquivalentTo(3))
+		Expect(stats.PacketsReceived).To(BeEquivalentTo(2))
+		Expect(stats.SmoothedRTT).To(Equal(time.Second))
+		Expect(stats.MinRTT).To(Equal(time.Second))
+		Expect(stats.LatestRTT).To(Equal(time.Second))
+		Expect(stats.SendWindow).To(BeZero())
+	})
+
+	It("updates the stats when the run loop processes an event", func() {
+		sess.unpacker = &mockUnpacker{}
+		go sess.run()
+		defer sess.Close(nil)
+		Consistently(func() uint64 { return sess.Stats().PacketsReceived }).Should(BeZero())
+		sess.handlePacket(&receivedPacket{header: &wire.Header{
+			PacketNumber:    5,
+			PacketNumberLen: protocol.PacketNumberLen6,
+			Raw:             getPacketBuffer(),
+		}})
+		Eventually(func() uint64 { return sess.Stats().PacketsReceived }).Should(BeEquivalentTo(1))
+	})
+
 	Context("waiting until the handshake completes", func() {
 		It("waits until the handshake is complete", func() {
 			go func() {
@@ -578,6 +893,23 @@ var _ = Describe("Session", func() {
 			Expect(str.StreamID()).To(Equal(protocol.StreamID(5)))
 		})
 
+		It("accepts unidirectional streams", func() {
+			strChan := make(chan ReceiveStream)
+			go func() {
+				defer GinkgoRecover()
+				str, err := sess.AcceptUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				strChan <- str
+			}()
+			Consistently(strChan).ShouldNot(Receive())
+			_, err := sess.GetOrOpenStream(0x80000001)
+			Expect(err).ToNot(HaveOccurred())
+			var str ReceiveStream
+			Eventually(strChan).Should(Receive(&str))
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(0x80000001)))
+			Expect(str.(*stream).receiveOnly).To(BeTrue())
+		})
+
 		It("stops accepting when the session is closed", func() {
 			testErr := errors.New("testErr")
 			done := make(chan struct{})
@@ -651,12 +983,32 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("sends application error codes", func() {
+			appErr := &ApplicationError{ErrorCode: 42, ReasonPhrase: "foobar"}
+			s, err := sess.GetOrOpenStream(5)
+			Expect(err).NotTo(HaveOccurred())
+			sess.Close(appErr)
+			Eventually(areSessionsRunning).Should(BeFalse())
+			buf := &bytes.Buffer{}
+			err = (&wire.ConnectionCloseFrame{ErrorCode: 0x10000 + 42, ReasonPhrase: "foobar"}).Write(buf, sess.version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(Receive(ContainSubstring(string(buf.Bytes()))))
+			_, err = s.Read([]byte{0})
+			Expect(err).To(Equal(appErr))
+		})
+
 		It("closes the session in order to replace it with another QUIC version", func() {
 			sess.Close(errCloseSessionForNewVersion)
 			Eventually(areSessionsRunning).Should(BeFalse())
 			Expect(mconn.written).To(BeEmpty()) // no CONNECTION_CLOSE or PUBLIC_RESET sent
 		})
 
+		It("closes the session without sending a CONNECTION_CLOSE after a stateless reject", func() {
+			sess.Close(&handshake.StatelessRejectError{ConnectionID: 0xdecafbad, STK: []byte("foobar")})
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(mconn.written).To(BeEmpty())
+		})
+
 		It("sends a Public Reset if the client is initiating the head-of-line blocking experiment", func() {
 			sess.Close(handshake.ErrHOLExperiment)
 			Expect(mconn.written).To(HaveLen(1))
@@ -729,6 +1081,15 @@ var _ = Describe("Session", func() {
 			Expect(sess.largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
 		})
 
+		It("counts received packets", func() {
+			hdr.PacketNumber = 5
+			hdr.Raw = []byte("header")
+			err := sess.handlePacketImpl(&receivedPacket{header: hdr, data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.packetsReceived).To(BeEquivalentTo(1))
+			Expect(sess.bytesReceived).To(Equal(protocol.ByteCount(12)))
+		})
+
 		It("handles duplicate packets", func() {
 			hdr.PacketNumber = 5
 			err := sess.handlePacketImpl(&receivedPacket{header: hdr})
@@ -804,6 +1165,28 @@ var _ = Describe("Session", func() {
 			Expect(mconn.written).To(Receive(ContainSubstring(string([]byte{0x03, 0x5e}))))
 		})
 
+		It("sends ACK frames when paced", func() {
+			sess.sentPacketHandler = &mockSentPacketHandler{pacingDeadline: time.Now().Add(time.Hour)}
+			sess.packer.packetNumberGenerator.next = 0x1338
+			packetNumber := protocol.PacketNumber(0x035e)
+			sess.receivedPacketHandler.ReceivedPacket(packetNumber, true)
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written).To(Receive(ContainSubstring(string([]byte{0x03, 0x5e}))))
+		})
+
+		It("doesn't send retransmittable packets when paced", func() {
+			pacingDeadline := time.Now().Add(time.Hour)
+			sess.sentPacketHandler = &mockSentPacketHandler{pacingDeadline: pacingDeadline}
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			sess.packer.QueueControlFrame(&wire.PingFrame{})
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(BeEmpty())
+			Expect(sess.pacingDeadline).To(Equal(pacingDeadline))
+		})
+
 		It("sends a retransmittable packet when required by the SentPacketHandler", func() {
 			sess.sentPacketHandler = &mockSentPacketHandler{shouldSendRetransmittablePacket: true}
 			err := sess.sendPacket()
@@ -973,6 +1356,19 @@ var _ = Describe("Session", func() {
 				Expect(packet).To(ContainSubstring("loremipsum"))
 			})
 
+			It("doesn't retransmit DATAGRAM frames", func() {
+				p := ackhandler.Packet{
+					PacketNumber:    0x1337,
+					Frames:          []wire.Frame{&wire.DatagramFrame{Data: []byte("foobar")}},
+					EncryptionLevel: protocol.EncryptionForwardSecure,
+				}
+				sph.retransmissionQueue = []*ackhandler.Packet{&p}
+
+				err := sess.sendPacket()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mconn.written).To(BeEmpty())
+			})
+
 			It("always attaches a StopWaiting to a packet that contains a retransmission", func() {
 				f := &wire.StreamFrame{
 					StreamID: 0x5,
@@ -1052,6 +1448,17 @@ var _ = Describe("Session", func() {
 			Expect(mconn.written).To(Receive(ContainSubstring(string([]byte{0x13, 0x37}))))
 		})
 
+		It("sets the timer to the pacing deadline", func() {
+			sess.sentPacketHandler = &mockSentPacketHandler{pacingDeadline: time.Now().Add(100 * time.Millisecond)}
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			sess.packer.QueueControlFrame(&wire.PingFrame{})
+			go sess.run()
+			defer sess.Close(nil)
+			sess.scheduleSending()
+			Consistently(func() int { return len(mconn.written) }, 50*time.Millisecond).Should(BeZero())
+			Eventually(func() int { return len(mconn.written) }).ShouldNot(BeZero())
+		})
+
 		Context("bundling of small packets", func() {
 			It("bundles two small frames of different streams into one packet", func() {
 				s1, err := sess.GetOrOpenStream(5)
@@ -1245,6 +1652,17 @@ var _ = Describe("Session", func() {
 		close(done)
 	})
 
+	It("closes the HandshakeComplete channel when the handshake completes", func(done Done) {
+		go sess.run()
+		aeadChanged <- protocol.EncryptionSecure
+		Eventually(handshakeChan).Should(Receive())
+		Consistently(sess.HandshakeComplete()).ShouldNot(BeClosed())
+		close(aeadChanged)
+		Eventually(sess.HandshakeComplete()).Should(BeClosed())
+		Expect(sess.Close(nil)).To(Succeed())
+		close(done)
+	})
+
 	It("passes errors to the handshakeChan", func(done Done) {
 		testErr := errors.New("handshake error")
 		go sess.run()
@@ -1271,6 +1689,7 @@ var _ = Describe("Session", func() {
 		go sess.run()
 		params := handshake.TransportParameters{
 			MaxStreams:                  123,
+			MaxUniStreams:               42,
 			IdleTimeout:                 90 * time.Second,
 			StreamFlowControlWindow:     0x5000,
 			ConnectionFlowControlWindow: 0x5000,
@@ -1279,6 +1698,7 @@ var _ = Describe("Session", func() {
 		paramsChan <- params
 		Eventually(func() *handshake.TransportParameters { return sess.peerParams }).Should(Equal(&params))
 		Eventually(func() uint32 { return sess.streamsMap.maxOutgoingStreams }).Should(Equal(uint32(123)))
+		Eventually(func() uint32 { return sess.streamsMap.maxOutgoingUniStreams }).Should(Equal(uint32(42)))
 		// Eventually(func() (protocol.ByteCount, error) { return sess.flowControlManager.SendWindowSize(5) }).Should(Equal(protocol.ByteCount(0x5000)))
 		Eventually(func() bool { return sess.packer.omitConnectionID }).Should(BeTrue())
 		Expect(sess.Close(nil)).To(Succeed())
@@ -1383,11 +1803,314 @@ var _ = Describe("Session", func() {
 		close(done)
 	}, 0.5)
 
+	Context("closing gracefully", func() {
+		BeforeEach(func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+		})
+
+		It("sends a GOAWAY and closes as soon as all streams are closed", func() {
+			go sess.run()
+			str, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := sess.CloseGracefully(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			goaway := []byte{0x03,
+				0x0, 0x0, 0x0, byte(qerr.PeerGoingAway), // error code
+				0x0, 0x0, 0x0, 0x3, // last good stream
+				0x0, 0x0, // reason phrase length
+			}
+			Eventually(mconn.written).Should(Receive(ContainSubstring(string(goaway))))
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(sess.Context().Done()).ToNot(BeClosed())
+			// opening stream 3 also opened stream 1
+			str1, err := sess.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			str1.(streamI).Cancel(errors.New("test done"))
+			str.(streamI).Cancel(errors.New("test done"))
+			sess.scheduleSending()
+			Eventually(done).Should(BeClosed())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
+		It("closes immediately when the context is done", func() {
+			go sess.run()
+			_, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := sess.CloseGracefully(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
+		It("returns when the session is closed", func() {
+			go sess.run()
+			_, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := sess.CloseGracefully(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			sess.Close(nil)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't open new streams", func() {
+			go sess.run()
+			Expect(sess.CloseGracefully(context.Background())).To(Succeed())
+			_, err := sess.OpenStream()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("datagrams", func() {
+		BeforeEach(func() {
+			sess.config.EnableDatagrams = true
+			sess.peerParams = &handshake.TransportParameters{MaxDatagramFrameSize: 100}
+		})
+
+		It("sends datagrams", func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			err := sess.SendMessage([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written).To(Receive(ContainSubstring("foobar")))
+		})
+
+		It("copies the data", func() {
+			b := []byte("foobar")
+			err := sess.SendMessage(b)
+			Expect(err).ToNot(HaveOccurred())
+			b[0] = 'l'
+			Expect(sess.datagramQueue.Peek().Data).To(Equal([]byte("foobar")))
+		})
+
+		It("errors if datagrams are not enabled", func() {
+			sess.config.EnableDatagrams = false
+			err := sess.SendMessage([]byte("foobar"))
+			Expect(err).To(MatchError(errDatagramsNotEnabled))
+			_, err = sess.ReceiveMessage()
+			Expect(err).To(MatchError(errDatagramsNotEnabled))
+		})
+
+		It("errors if the peer doesn't support datagrams", func() {
+			sess.peerParams = &handshake.TransportParameters{}
+			err := sess.SendMessage([]byte("foobar"))
+			Expect(err).To(MatchError(errDatagramsNotSupported))
+		})
+
+		It("errors if the transport parameters were not received yet", func() {
+			sess.peerParams = nil
+			err := sess.SendMessage([]byte("foobar"))
+			Expect(err).To(MatchError(errDatagramsNotSupported))
+		})
+
+		It("errors if the message is too large for the peer", func() {
+			err := sess.SendMessage(make([]byte, 97))
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.SendMessage(make([]byte, 98))
+			Expect(err).To(MatchError("DATAGRAM frame too large (101 bytes, maximum 100 bytes)"))
+		})
+
+		It("errors if the message is larger than the maximum DATAGRAM frame size", func() {
+			sess.peerParams = &handshake.TransportParameters{MaxDatagramFrameSize: 0xffff}
+			err := sess.SendMessage(make([]byte, protocol.MaxDatagramFrameSize))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("unblocks ReceiveMessage when the session is closed", func() {
+			testErr := errors.New("test error")
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := sess.ReceiveMessage()
+				Expect(err).To(MatchError(qerr.ToQuicError(testErr)))
+				close(done)
+			}()
+			go sess.run()
+			Consistently(done).ShouldNot(BeClosed())
+			sess.Close(testErr)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("tracing", func() {
+		var tracer *mocks.MockSessionTracer
+
+		BeforeEach(func() {
+			tracer = mocks.NewMockSessionTracer(mockCtrl)
+			sess.tracer = tracer
+			sess.unpacker = &mockUnpacker{}
+		})
+
+		It("creates a tracer for every session", func() {
+			tr := &mockTracer{sessionTracer: tracer}
+			s, _, err := newSession(
+				mconn,
+				protocol.Version39,
+				0x1337,
+				scfgs,
+				nil,
+				populateServerConfig(&Config{Tracer: tr}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tr.perspectives).To(Equal([]protocol.Perspective{protocol.PerspectiveServer}))
+			Expect(tr.connectionIDs).To(Equal([]protocol.ConnectionID{0x1337}))
+			Expect(s.(*session).tracer).To(Equal(tracer))
+		})
+
+		It("traces received packets", func() {
+			hdr := &wire.Header{PacketNumber: 5, PacketNumberLen: protocol.PacketNumberLen6, Raw: []byte("header")}
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(12), gomock.Any(), gomock.Any())
+			err := sess.handlePacketImpl(&receivedPacket{header: hdr, data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("traces duplicate packets", func() {
+			hdr := &wire.Header{PacketNumber: 5, PacketNumberLen: protocol.PacketNumberLen6, Raw: []byte("header")}
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(12), gomock.Any(), gomock.Any()).Times(2)
+			err := sess.handlePacketImpl(&receivedPacket{header: hdr, data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.handlePacketImpl(&receivedPacket{header: hdr, data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.packetsReceived).To(BeEquivalentTo(2))
+		})
+
+		It("traces undecryptable packets that are dropped after the handshake", func() {
+			sess.handshakeComplete = true
+			hdr := &wire.Header{PacketNumber: 5, Raw: []byte("header")}
+			tracer.EXPECT().DroppedPacket(hdr, protocol.ByteCount(12), tracing.PacketDropUndecryptable)
+			sess.tryQueueingUndecryptablePacket(&receivedPacket{
+				header:     hdr,
+				remoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+				data:       []byte("foobar"),
+			})
+		})
+
+		It("traces sent packets and BLOCKED frames", func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			sess.packer.QueueControlFrame(&wire.BlockedFrame{})
+			sess.packer.QueueControlFrame(&wire.StreamBlockedFrame{StreamID: 5})
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), protocol.EncryptionForwardSecure, gomock.Any()).Do(func(_ *wire.Header, _ protocol.ByteCount, _ protocol.EncryptionLevel, frames []wire.Frame) {
+				Expect(frames).To(ContainElement(&wire.BlockedFrame{}))
+				Expect(frames).To(ContainElement(&wire.StreamBlockedFrame{StreamID: 5}))
+			})
+			tracer.EXPECT().Blocked(protocol.StreamID(0))
+			tracer.EXPECT().Blocked(protocol.StreamID(5))
+			err := sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+		})
+
+		It("traces encryption level changes, and closing", func() {
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedEncryptionLevel(protocol.EncryptionSecure)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				sess.run()
+				close(done)
+			}()
+			aeadChanged <- protocol.EncryptionSecure
+			Eventually(handshakeChan).Should(Receive(&handshakeEvent{encLevel: protocol.EncryptionSecure}))
+			tracer.EXPECT().Close()
+			Expect(sess.Close(nil)).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("capturing packets", func() {
+		var (
+			capture    *mockPacketCapture
+			localAddr  = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4433}
+			remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		)
+
+		BeforeEach(func() {
+			capture = &mockPacketCapture{}
+			sess.config.PacketCapture = capture
+			mconn.localAddr = localAddr
+			mconn.remoteAddr = remoteAddr
+		})
+
+		It("captures sent packets", func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			sess.packer.QueueControlFrame(&wire.BlockedFrame{})
+			err := sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(capture.packets).To(HaveLen(1))
+			p := capture.packets[0]
+			Expect(p.data).To(Equal(<-mconn.written))
+			Expect(p.src).To(Equal(localAddr))
+			Expect(p.dst).To(Equal(remoteAddr))
+			Expect(p.frames).To(ContainElement(&wire.BlockedFrame{}))
+		})
+
+		It("captures received packets", func() {
+			sess.unpacker = &mockUnpacker{}
+			rcvTime := time.Now().Add(-time.Second)
+			err := sess.handlePacketImpl(&receivedPacket{
+				remoteAddr: remoteAddr,
+				header:     &wire.Header{PacketNumber: 5, PacketNumberLen: protocol.PacketNumberLen6, Raw: []byte("header")},
+				data:       []byte("foobar"),
+				rcvTime:    rcvTime,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(capture.packets).To(HaveLen(1))
+			p := capture.packets[0]
+			Expect(p.time).To(Equal(rcvTime))
+			Expect(p.data).To(Equal([]byte("headerfoobar")))
+			Expect(p.src).To(Equal(remoteAddr))
+			Expect(p.dst).To(Equal(localAddr))
+		})
+
+		It("captures undecryptable packets that are dropped, without frames", func() {
+			sess.handshakeComplete = true
+			sess.tryQueueingUndecryptablePacket(&receivedPacket{
+				remoteAddr: remoteAddr,
+				header:     &wire.Header{PacketNumber: 5, Raw: []byte("header")},
+				data:       []byte("foobar"),
+			})
+			Expect(capture.packets).To(HaveLen(1))
+			Expect(capture.packets[0].data).To(Equal([]byte("headerfoobar")))
+			Expect(capture.packets[0].frames).To(BeNil())
+		})
+	})
+
 	Context("getting streams", func() {
 		BeforeEach(func() {
 			sess.processTransportParameters(&handshake.TransportParameters{MaxStreams: 1000})
 		})
 
+		It("opens unidirectional streams", func() {
+			sess.processTransportParameters(&handshake.TransportParameters{MaxUniStreams: 1})
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(0x80000002)))
+			Expect(str.(*stream).sendOnly).To(BeTrue())
+			_, err = sess.OpenUniStream()
+			Expect(err).To(MatchError(qerr.TooManyOpenStreams))
+		})
+
 		It("returns a new stream", func() {
 			str, err := sess.GetOrOpenStream(11)
 			Expect(err).ToNot(HaveOccurred())
@@ -1523,6 +2246,10 @@ var _ = Describe("Client Session", func() {
 			aeadChangedP chan<- protocol.EncryptionLevel,
 			_ protocol.VersionNumber,
 			_ []protocol.VersionNumber,
+			_ []byte,
+			_ io.Writer,
+			_ handshake.ClientSessionCache,
+			_ utils.Logger,
 		) (handshake.CryptoSetup, error) {
 			aeadChanged = aeadChangedP
 			return cryptoSetup, nil
@@ -1538,6 +2265,7 @@ var _ = Describe("Client Session", func() {
 			populateClientConfig(&Config{}),
 			protocol.VersionWhatever,
 			nil,
+			nil,
 		)
 		sess = sessP.(*session)
 		Expect(err).ToNot(HaveOccurred())
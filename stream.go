@@ -24,8 +24,10 @@ type streamI interface {
 	GetWriteOffset() protocol.ByteCount
 	Finished() bool
 	Cancel(error)
+	HandleStopSendingFrame(*wire.StopSendingFrame)
 	ShouldSendFin() bool
 	SentFin()
+	Priority() (protocol.StreamUrgency, bool)
 	// methods needed for flow control
 	GetWindowUpdate() protocol.ByteCount
 	UpdateSendWindow(protocol.ByteCount)
@@ -43,8 +45,10 @@ type stream struct {
 
 	streamID protocol.StreamID
 	onData   func()
-	// onReset is a callback that should send a RST_STREAM
-	onReset func(protocol.StreamID, protocol.ByteCount)
+	// queueControlFrame is a callback that should send a RST_STREAM or a STOP_SENDING
+	queueControlFrame func(wire.Frame)
+	// onPriorityChanged is called when SetPriority is called, such that the stream can be rescheduled
+	onPriorityChanged func(protocol.StreamID)
 
 	readPosInFrame int
 	writeOffset    protocol.ByteCount
@@ -57,12 +61,20 @@ type stream struct {
 	cancelled utils.AtomicBool
 	// finishedReading is set once we read a frame with a FinBit
 	finishedReading utils.AtomicBool
+	// finReceived is set once we receive a frame with a FinBit, even if the data is never read
+	finReceived utils.AtomicBool
 	// finisedWriting is set once Close() is called
 	finishedWriting utils.AtomicBool
 	// resetLocally is set if Reset() is called
 	resetLocally utils.AtomicBool
 	// resetRemotely is set if RegisterRemoteError() is called
 	resetRemotely utils.AtomicBool
+	// readCancelled is set when CancelRead() is called
+	readCancelled utils.AtomicBool
+	cancelReadErr error
+	// writeCancelled is set when CancelWrite() is called, or when the peer sends a STOP_SENDING
+	writeCancelled utils.AtomicBool
+	cancelWriteErr error
 
 	frameQueue   *streamFrameSorter
 	readChan     chan struct{}
@@ -74,11 +86,20 @@ type stream struct {
 	writeChan      chan struct{}
 	writeDeadline  time.Time
 
+	urgency     protocol.StreamUrgency
+	incremental bool
+
 	flowController flowcontrol.StreamFlowController
 	version        protocol.VersionNumber
+
+	// for unidirectional streams, one direction is closed when the stream is created
+	sendOnly    bool
+	receiveOnly bool
 }
 
 var _ Stream = &stream{}
+var _ SendStream = &stream{}
+var _ ReceiveStream = &stream{}
 var _ streamI = &stream{}
 
 type deadlineError struct{}
@@ -92,32 +113,73 @@ var errDeadline net.Error = &deadlineError{}
 // newStream creates a new Stream
 func newStream(StreamID protocol.StreamID,
 	onData func(),
-	onReset func(protocol.StreamID, protocol.ByteCount),
+	queueControlFrame func(wire.Frame),
+	onPriorityChanged func(protocol.StreamID),
 	flowController flowcontrol.StreamFlowController,
 	version protocol.VersionNumber,
 ) *stream {
 	s := &stream{
-		onData:         onData,
-		onReset:        onReset,
-		streamID:       StreamID,
-		flowController: flowController,
-		frameQueue:     newStreamFrameSorter(),
-		readChan:       make(chan struct{}, 1),
-		writeChan:      make(chan struct{}, 1),
-		version:        version,
+		onData:            onData,
+		queueControlFrame: queueControlFrame,
+		onPriorityChanged: onPriorityChanged,
+		streamID:          StreamID,
+		flowController:    flowController,
+		frameQueue:        newStreamFrameSorter(),
+		readChan:          make(chan struct{}, 1),
+		writeChan:         make(chan struct{}, 1),
+		version:           version,
+		urgency:           protocol.DefaultStreamUrgency,
+		incremental:       true,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	return s
 }
 
+// newSendStream creates a stream that can only be written to.
+// It is used for unidirectional streams opened by us.
+func newSendStream(StreamID protocol.StreamID,
+	onData func(),
+	queueControlFrame func(wire.Frame),
+	onPriorityChanged func(protocol.StreamID),
+	flowController flowcontrol.StreamFlowController,
+	version protocol.VersionNumber,
+) *stream {
+	s := newStream(StreamID, onData, queueControlFrame, onPriorityChanged, flowController, version)
+	s.sendOnly = true
+	s.finishedReading.Set(true)
+	return s
+}
+
+// newReceiveStream creates a stream that can only be read from.
+// It is used for unidirectional streams opened by the peer.
+func newReceiveStream(StreamID protocol.StreamID,
+	onData func(),
+	queueControlFrame func(wire.Frame),
+	onPriorityChanged func(protocol.StreamID),
+	flowController flowcontrol.StreamFlowController,
+	version protocol.VersionNumber,
+) *stream {
+	s := newStream(StreamID, onData, queueControlFrame, onPriorityChanged, flowController, version)
+	s.receiveOnly = true
+	// we will never send any data on this stream, so don't send a FIN either
+	s.finishedWriting.Set(true)
+	s.finSent.Set(true)
+	s.ctxCancel()
+	return s
+}
+
 // Read implements io.Reader. It is not thread safe!
 func (s *stream) Read(p []byte) (int, error) {
 	s.mutex.Lock()
 	err := s.err
+	cancelReadErr := s.cancelReadErr
 	s.mutex.Unlock()
 	if s.cancelled.Get() || s.resetLocally.Get() {
 		return 0, err
 	}
+	if s.readCancelled.Get() {
+		return 0, cancelReadErr
+	}
 	if s.finishedReading.Get() {
 		return 0, io.EOF
 	}
@@ -139,6 +201,10 @@ func (s *stream) Read(p []byte) (int, error) {
 				err = s.err
 				break
 			}
+			if s.readCancelled.Get() {
+				err = s.cancelReadErr
+				break
+			}
 
 			deadline := s.readDeadline
 			if !deadline.IsZero() && !time.Now().Before(deadline) {
@@ -211,6 +277,9 @@ func (s *stream) Write(p []byte) (int, error) {
 	if s.resetLocally.Get() || s.err != nil {
 		return 0, s.err
 	}
+	if s.writeCancelled.Get() {
+		return 0, s.cancelWriteErr
+	}
 	if s.finishedWriting.Get() {
 		return 0, fmt.Errorf("write on closed stream %d", s.streamID)
 	}
@@ -229,7 +298,7 @@ func (s *stream) Write(p []byte) (int, error) {
 			err = errDeadline
 			break
 		}
-		if s.dataForWriting == nil || s.err != nil {
+		if s.dataForWriting == nil || s.err != nil || s.writeCancelled.Get() {
 			break
 		}
 
@@ -251,6 +320,11 @@ func (s *stream) Write(p []byte) (int, error) {
 	if s.err != nil {
 		return len(p) - len(s.dataForWriting), s.err
 	}
+	if s.writeCancelled.Get() {
+		n := len(p) - len(s.dataForWriting)
+		s.dataForWriting = nil
+		return n, s.cancelWriteErr
+	}
 	return len(p), nil
 }
 
@@ -261,7 +335,7 @@ func (s *stream) GetWriteOffset() protocol.ByteCount {
 func (s *stream) LenOfDataForWriting() protocol.ByteCount {
 	s.mutex.Lock()
 	var l protocol.ByteCount
-	if s.err == nil {
+	if s.err == nil && !s.writeCancelled.Get() {
 		l = protocol.ByteCount(len(s.dataForWriting))
 	}
 	s.mutex.Unlock()
@@ -272,7 +346,7 @@ func (s *stream) GetDataForWriting(maxBytes protocol.ByteCount) []byte {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if s.err != nil || s.dataForWriting == nil {
+	if s.err != nil || s.writeCancelled.Get() || s.dataForWriting == nil {
 		return nil
 	}
 
@@ -310,12 +384,16 @@ func (s *stream) shouldSendReset() bool {
 	if s.rstSent.Get() {
 		return false
 	}
+	// a receive stream sends a RST_STREAM to ask the peer to stop sending
+	if s.receiveOnly {
+		return s.resetLocally.Get()
+	}
 	return (s.resetLocally.Get() || s.resetRemotely.Get()) && !s.finishedWriteAndSentFin()
 }
 
 func (s *stream) ShouldSendFin() bool {
 	s.mutex.Lock()
-	res := s.finishedWriting.Get() && !s.finSent.Get() && s.err == nil && s.dataForWriting == nil
+	res := s.finishedWriting.Get() && !s.finSent.Get() && !s.writeCancelled.Get() && s.err == nil && s.dataForWriting == nil
 	s.mutex.Unlock()
 	return res
 }
@@ -330,9 +408,16 @@ func (s *stream) AddStreamFrame(frame *wire.StreamFrame) error {
 	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.FinBit); err != nil {
 		return err
 	}
+	if frame.FinBit {
+		s.finReceived.Set(true)
+	}
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.readCancelled.Get() {
+		// the application is not interested in this data any more
+		return nil
+	}
 	if err := s.frameQueue.Push(frame); err != nil && err != errDuplicateStreamData {
 		return err
 	}
@@ -420,12 +505,84 @@ func (s *stream) Reset(err error) {
 		s.signalWrite()
 	}
 	if s.shouldSendReset() {
-		s.onReset(s.streamID, s.writeOffset)
+		s.queueControlFrame(&wire.RstStreamFrame{
+			StreamID:   s.streamID,
+			ByteOffset: s.writeOffset,
+			ErrorCode:  rstStreamErrorCode(err),
+		})
 		s.rstSent.Set(true)
 	}
 	s.mutex.Unlock()
 }
 
+// CancelRead aborts receiving on this stream.
+// Buffered data is released, and the peer is asked to stop sending (if the version supports it).
+func (s *stream) CancelRead(errorCode protocol.ApplicationErrorCode) error {
+	if s.sendOnly {
+		return fmt.Errorf("CancelRead for send-only stream %d", s.streamID)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.readCancelled.Get() {
+		return nil
+	}
+	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.readCancelled.Set(true)
+	s.frameQueue.Release()
+	s.flowController.Abandon()
+	s.signalRead()
+	// no need to ask the peer to stop sending if it already finished or reset the stream
+	if s.finishedReading.Get() || s.resetRemotely.Get() || !s.version.UsesStopSendingFrame() {
+		return nil
+	}
+	s.queueControlFrame(&wire.StopSendingFrame{
+		StreamID:  s.streamID,
+		ErrorCode: errorCode,
+	})
+	return nil
+}
+
+// CancelWrite aborts sending on this stream.
+// Data that wasn't sent yet is discarded, and a RST_STREAM is sent.
+func (s *stream) CancelWrite(errorCode protocol.ApplicationErrorCode) error {
+	if s.receiveOnly {
+		return fmt.Errorf("CancelWrite for receive-only stream %d", s.streamID)
+	}
+	s.mutex.Lock()
+	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.mutex.Unlock()
+	return nil
+}
+
+// HandleStopSendingFrame is called when the peer sends a STOP_SENDING.
+func (s *stream) HandleStopSendingFrame(frame *wire.StopSendingFrame) {
+	s.mutex.Lock()
+	s.cancelWriteImpl(frame.ErrorCode, &StreamError{StreamID: s.streamID, ErrorCode: frame.ErrorCode})
+	s.mutex.Unlock()
+}
+
+// must be called with the mutex locked
+func (s *stream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, writeErr error) {
+	if s.writeCancelled.Get() {
+		return
+	}
+	s.cancelWriteErr = writeErr
+	s.writeCancelled.Set(true)
+	s.ctxCancel()
+	s.signalWrite()
+	// if we already sent a FIN (or a RST_STREAM), the peer already knows the final offset
+	if s.finishedWriteAndSentFin() || s.rstSent.Get() {
+		return
+	}
+	s.queueControlFrame(&wire.RstStreamFrame{
+		StreamID:   s.streamID,
+		ByteOffset: s.writeOffset,
+		ErrorCode:  applicationRstStreamErrorCode(errorCode),
+	})
+	s.rstSent.Set(true)
+}
+
 // resets the stream remotely
 func (s *stream) RegisterRemoteError(err error, offset protocol.ByteCount) error {
 	if s.resetRemotely.Get() {
@@ -443,20 +600,56 @@ func (s *stream) RegisterRemoteError(err error, offset protocol.ByteCount) error
 		return err
 	}
 	if s.shouldSendReset() {
-		s.onReset(s.streamID, s.writeOffset)
+		s.queueControlFrame(&wire.RstStreamFrame{
+			StreamID:   s.streamID,
+			ByteOffset: s.writeOffset,
+		})
 		s.rstSent.Set(true)
 	}
 	s.mutex.Unlock()
 	return nil
 }
 
+// SetPriority sets the priority used for scheduling the stream's data
+func (s *stream) SetPriority(urgency protocol.StreamUrgency, incremental bool) {
+	if urgency > protocol.MaxStreamUrgency {
+		urgency = protocol.MaxStreamUrgency
+	}
+	s.mutex.Lock()
+	s.urgency = urgency
+	s.incremental = incremental
+	s.mutex.Unlock()
+	// must be called without holding the mutex, since the streams map acquires it to read the priority
+	s.onPriorityChanged(s.streamID)
+}
+
+func (s *stream) Priority() (protocol.StreamUrgency, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.urgency, s.incremental
+}
+
 func (s *stream) finishedWriteAndSentFin() bool {
 	return s.finishedWriting.Get() && s.finSent.Get()
 }
 
+// readCancelledAndFinalOffsetKnown says if reading was canceled, and the peer already sent a FIN.
+// Until then, the stream is kept, such that data received for it is still accounted for by connection-level flow control.
+// If the peer resets the stream, the final offset is known as well, but that's covered by resetRemotely.
+func (s *stream) readCancelledAndFinalOffsetKnown() bool {
+	return s.readCancelled.Get() && s.finReceived.Get()
+}
+
 func (s *stream) Finished() bool {
+	if s.sendOnly {
+		return s.cancelled.Get() || s.finishedWriteAndSentFin() || s.rstSent.Get()
+	}
+	if s.receiveOnly {
+		return s.cancelled.Get() || s.finishedReading.Get() || s.resetRemotely.Get() || s.rstSent.Get() || s.readCancelledAndFinalOffsetKnown()
+	}
 	return s.cancelled.Get() ||
 		(s.finishedReading.Get() && s.finishedWriteAndSentFin()) ||
+		(s.readCancelledAndFinalOffsetKnown() && (s.finishedWriteAndSentFin() || s.rstSent.Get())) ||
 		(s.resetRemotely.Get() && s.rstSent.Get()) ||
 		(s.finishedReading.Get() && s.rstSent.Get()) ||
 		(s.finishedWriteAndSentFin() && s.resetRemotely.Get())
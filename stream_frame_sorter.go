@@ -152,6 +152,12 @@ func (s *streamFrameSorter) Pop() *wire.StreamFrame {
 	return frame
 }
 
+// Release drops all queued frames.
+// It is used when the application isn't interested in reading the data any more.
+func (s *streamFrameSorter) Release() {
+	s.queuedFrames = make(map[protocol.ByteCount]*wire.StreamFrame)
+}
+
 func (s *streamFrameSorter) Head() *wire.StreamFrame {
 	frame, ok := s.queuedFrames[s.readPosition]
 	if ok {
@@ -30,6 +30,16 @@ var _ = Describe("StreamFrame sorter", func() {
 		Expect(s.Head()).To(BeNil())
 	})
 
+	It("releases all queued frames", func() {
+		err := s.Push(&wire.StreamFrame{Offset: 0, Data: []byte("foo")})
+		Expect(err).ToNot(HaveOccurred())
+		err = s.Push(&wire.StreamFrame{Offset: 10, Data: []byte("bar")})
+		Expect(err).ToNot(HaveOccurred())
+		s.Release()
+		Expect(s.queuedFrames).To(BeEmpty())
+		Expect(s.Head()).To(BeNil())
+	})
+
 	Context("Push", func() {
 		It("inserts and pops a single frame", func() {
 			f := &wire.StreamFrame{
@@ -149,7 +149,7 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		return true, nil
 	}
 
-	f.streamsMap.RoundRobinIterate(fn)
+	f.streamsMap.PriorityIterate(fn)
 	return
 }
 
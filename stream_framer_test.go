@@ -38,8 +38,10 @@ var _ = Describe("Stream Framer", func() {
 
 		stream1 = mocks.NewMockStreamI(mockCtrl)
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
+		stream1.EXPECT().Priority().Return(protocol.DefaultStreamUrgency, true).AnyTimes()
 		stream2 = mocks.NewMockStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
+		stream2.EXPECT().Priority().Return(protocol.DefaultStreamUrgency, true).AnyTimes()
 
 		streamsMap = newStreamsMap(nil, protocol.PerspectiveServer, protocol.VersionWhatever)
 		streamsMap.putStream(stream1)
@@ -186,6 +188,24 @@ var _ = Describe("Stream Framer", func() {
 			Expect(fs[0].StreamID).ToNot(Equal(firstStreamID))
 		})
 
+		It("sends data of streams with a lower urgency first", func() {
+			stream3 := mocks.NewMockStreamI(mockCtrl)
+			stream3.EXPECT().StreamID().Return(protocol.StreamID(7)).AnyTimes()
+			stream3.EXPECT().Priority().Return(protocol.StreamUrgency(0), false).AnyTimes()
+			stream3.EXPECT().IsFlowControlBlocked().Return(false).AnyTimes()
+			Expect(streamsMap.putStream(stream3)).To(Succeed())
+			streamFrameHeaderLen := protocol.ByteCount(4)
+			stream3.EXPECT().GetDataForWriting(10 - streamFrameHeaderLen).Return(bytes.Repeat([]byte("f"), int(10-streamFrameHeaderLen))).Times(2)
+			stream3.EXPECT().LenOfDataForWriting().Return(protocol.ByteCount(100)).Times(2)
+			stream3.EXPECT().GetWriteOffset().Times(2)
+			stream3.EXPECT().ShouldSendFin().Times(2)
+			for i := 0; i < 2; i++ {
+				fs := framer.PopStreamFrames(10)
+				Expect(fs).To(HaveLen(1))
+				Expect(fs[0].StreamID).To(Equal(protocol.StreamID(7)))
+			}
+		})
+
 		Context("splitting of frames", func() {
 			It("splits off nothing", func() {
 				f := &wire.StreamFrame{
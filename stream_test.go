@@ -29,6 +29,9 @@ var _ = Describe("Stream", func() {
 		resetCalled          bool
 		resetCalledForStream protocol.StreamID
 		resetCalledAtOffset  protocol.ByteCount
+		queuedControlFrames  []wire.Frame
+
+		priorityChangedForStreams []protocol.StreamID
 
 		mockFC *mocks.MockStreamFlowController
 	)
@@ -49,17 +52,26 @@ var _ = Describe("Stream", func() {
 		onDataCalled = true
 	}
 
-	onReset := func(id protocol.StreamID, offset protocol.ByteCount) {
-		resetCalled = true
-		resetCalledForStream = id
-		resetCalledAtOffset = offset
+	queueControlFrame := func(f wire.Frame) {
+		queuedControlFrames = append(queuedControlFrames, f)
+		if rst, ok := f.(*wire.RstStreamFrame); ok {
+			resetCalled = true
+			resetCalledForStream = rst.StreamID
+			resetCalledAtOffset = rst.ByteOffset
+		}
+	}
+
+	onPriorityChanged := func(id protocol.StreamID) {
+		priorityChangedForStreams = append(priorityChangedForStreams, id)
 	}
 
 	BeforeEach(func() {
 		onDataCalled = false
 		resetCalled = false
+		queuedControlFrames = nil
+		priorityChangedForStreams = nil
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newStream(streamID, onData, onReset, mockFC, protocol.VersionWhatever)
+		str = newStream(streamID, onData, queueControlFrame, onPriorityChanged, mockFC, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = struct {
@@ -616,7 +628,7 @@ var _ = Describe("Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
-			It("calls onReset when receiving a remote error", func() {
+			It("queues a RST_STREAM when receiving a remote error", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
 				done := make(chan struct{})
 				str.writeOffset = 0x1000
@@ -631,7 +643,7 @@ var _ = Describe("Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
-			It("doesn't call onReset if it already sent a FIN", func() {
+			It("doesn't queue a RST_STREAM if it already sent a FIN", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
 				str.Close()
 				str.SentFin()
@@ -639,7 +651,7 @@ var _ = Describe("Stream", func() {
 				Expect(resetCalled).To(BeFalse())
 			})
 
-			It("doesn't call onReset if the stream was reset locally before", func() {
+			It("doesn't queue a RST_STREAM if the stream was reset locally before", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
 				str.Reset(testErr)
 				Expect(resetCalled).To(BeTrue())
@@ -648,7 +660,7 @@ var _ = Describe("Stream", func() {
 				Expect(resetCalled).To(BeFalse())
 			})
 
-			It("doesn't call onReset twice, when it gets two remote errors", func() {
+			It("doesn't queue a RST_STREAM twice, when it gets two remote errors", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
 				str.RegisterRemoteError(testErr, 0)
 				Expect(resetCalled).To(BeTrue())
@@ -709,7 +721,7 @@ var _ = Describe("Stream", func() {
 				Expect(err).To(MatchError(testErr))
 			})
 
-			It("calls onReset", func() {
+			It("queues a RST_STREAM", func() {
 				str.writeOffset = 0x1000
 				str.Reset(testErr)
 				Expect(resetCalled).To(BeTrue())
@@ -717,14 +729,21 @@ var _ = Describe("Stream", func() {
 				Expect(resetCalledAtOffset).To(Equal(protocol.ByteCount(0x1000)))
 			})
 
-			It("doesn't call onReset if it already sent a FIN", func() {
+			It("sends the error code of application errors", func() {
+				str.Reset(&ApplicationError{ErrorCode: 42})
+				Expect(queuedControlFrames).To(Equal([]wire.Frame{
+					&wire.RstStreamFrame{StreamID: streamID, ErrorCode: 0x10000 + 42},
+				}))
+			})
+
+			It("doesn't queue a RST_STREAM if it already sent a FIN", func() {
 				str.Close()
 				str.SentFin()
 				str.Reset(testErr)
 				Expect(resetCalled).To(BeFalse())
 			})
 
-			It("doesn't call onReset if the stream was reset remotely before", func() {
+			It("doesn't queue a RST_STREAM if the stream was reset remotely before", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
 				str.RegisterRemoteError(testErr, 0)
 				Expect(resetCalled).To(BeTrue())
@@ -733,7 +752,7 @@ var _ = Describe("Stream", func() {
 				Expect(resetCalled).To(BeFalse())
 			})
 
-			It("doesn't call onReset twice", func() {
+			It("doesn't queue a RST_STREAM twice", func() {
 				str.Reset(testErr)
 				Expect(resetCalled).To(BeTrue())
 				resetCalled = false
@@ -1051,6 +1070,411 @@ var _ = Describe("Stream", func() {
 		})
 	})
 
+	Context("canceling reading", func() {
+		It("unblocks Read", func() {
+			mockFC.EXPECT().Abandon()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Read(make([]byte, 10))
+				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't allow further calls to Read", func() {
+			mockFC.EXPECT().Abandon()
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = strWithTimeout.Read(make([]byte, 10))
+			Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+		})
+
+		It("releases buffered data, and ignores data received afterwards", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(12), false)
+			mockFC.EXPECT().Abandon()
+			err := str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			err = str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.frameQueue.Head()).To(BeNil())
+			err = str.AddStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.frameQueue.Head()).To(BeNil())
+		})
+
+		It("queues a STOP_SENDING frame", func() {
+			mockFC.EXPECT().Abandon()
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(Equal([]wire.Frame{
+				&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234},
+			}))
+		})
+
+		It("doesn't queue a STOP_SENDING frame for versions that don't support it", func() {
+			str.version = protocol.Version39
+			mockFC.EXPECT().Abandon()
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(BeEmpty())
+		})
+
+		It("doesn't queue a STOP_SENDING frame after the stream was reset by the peer", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+			mockFC.EXPECT().Abandon()
+			err := str.RegisterRemoteError(errors.New("reset"), 0)
+			Expect(err).ToNot(HaveOccurred())
+			queuedControlFrames = nil
+			err = str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(BeEmpty())
+		})
+
+		It("only cancels once", func() {
+			mockFC.EXPECT().Abandon()
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			err = str.CancelRead(4321)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(HaveLen(1))
+			_, err = strWithTimeout.Read(make([]byte, 10))
+			Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+		})
+
+		It("doesn't affect the write side", func() {
+			mockFC.EXPECT().Abandon()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Context().Done()).ToNot(BeClosed())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+				close(done)
+			}()
+			Eventually(func() []byte { return str.GetDataForWriting(1000) }).Should(Equal([]byte("foobar")))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("is finished after canceling reading, receiving a FIN and sending a FIN", func() {
+			mockFC.EXPECT().Abandon()
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeFalse())
+			str.Close()
+			str.SentFin()
+			// the final offset is not known yet
+			Expect(str.Finished()).To(BeFalse())
+			err = str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), FinBit: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeTrue())
+		})
+
+		It("is finished after canceling reading, receiving a RST and sending a FIN", func() {
+			mockFC.EXPECT().Abandon()
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+			err := str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			str.Close()
+			str.SentFin()
+			Expect(str.Finished()).To(BeFalse())
+			err = str.RegisterRemoteError(errors.New("reset"), 42)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeTrue())
+		})
+
+		It("is finished right away when canceling reading after receiving a FIN", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			mockFC.EXPECT().Abandon()
+			err := str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), FinBit: true})
+			Expect(err).ToNot(HaveOccurred())
+			str.Close()
+			str.SentFin()
+			Expect(str.Finished()).To(BeFalse())
+			err = str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeTrue())
+		})
+	})
+
+	Context("canceling writing", func() {
+		It("unblocks Write", func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(2))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+				Expect(n).To(Equal(2))
+				close(done)
+			}()
+			Eventually(func() []byte { return str.GetDataForWriting(2) }).ShouldNot(BeEmpty())
+			Consistently(done).ShouldNot(BeClosed())
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("discards data that wasn't sent yet", func() {
+			go func() {
+				defer GinkgoRecover()
+				_, _ = strWithTimeout.Write([]byte("foobar"))
+			}()
+			Eventually(func() protocol.ByteCount { return str.LenOfDataForWriting() }).ShouldNot(BeZero())
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.LenOfDataForWriting()).To(BeZero())
+			Expect(str.GetDataForWriting(1000)).To(BeNil())
+		})
+
+		It("doesn't allow further calls to Write", func() {
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = strWithTimeout.Write([]byte("foobar"))
+			Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+		})
+
+		It("queues a RST_STREAM with the error code", func() {
+			str.writeOffset = 0x1000
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(Equal([]wire.Frame{
+				&wire.RstStreamFrame{
+					StreamID:   streamID,
+					ByteOffset: 0x1000,
+					ErrorCode:  0x10000 + 1234,
+				},
+			}))
+		})
+
+		It("doesn't queue a RST_STREAM if the FIN was already sent", func() {
+			str.Close()
+			str.SentFin()
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queuedControlFrames).To(BeEmpty())
+		})
+
+		It("doesn't send a FIN after canceling", func() {
+			str.Close()
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.ShouldSendFin()).To(BeFalse())
+		})
+
+		It("cancels the context", func() {
+			Expect(str.Context().Done()).ToNot(BeClosed())
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Context().Done()).To(BeClosed())
+		})
+
+		It("doesn't affect the read side", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			err = str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 6)
+			n, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("is finished after canceling writing and reading all data", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(0))
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeFalse())
+			err = str.AddStreamFrame(&wire.StreamFrame{FinBit: true})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = strWithTimeout.Read(make([]byte, 10))
+			Expect(err).To(MatchError(io.EOF))
+			Expect(str.Finished()).To(BeTrue())
+		})
+
+		It("is finished after canceling both sides, once the final offset is known", func() {
+			mockFC.EXPECT().Abandon()
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			err := str.CancelWrite(1234)
+			Expect(err).ToNot(HaveOccurred())
+			err = str.CancelRead(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeFalse())
+			err = str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), FinBit: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Finished()).To(BeTrue())
+		})
+
+		Context("receiving a STOP_SENDING", func() {
+			It("stops writing, and sends a RST_STREAM", func() {
+				str.writeOffset = 0x42
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := strWithTimeout.Write([]byte("foobar"))
+					Expect(err).To(MatchError("Stream 1337 was reset with error code 1234"))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				str.HandleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				Eventually(done).Should(BeClosed())
+				Expect(queuedControlFrames).To(Equal([]wire.Frame{
+					&wire.RstStreamFrame{
+						StreamID:   streamID,
+						ByteOffset: 0x42,
+						ErrorCode:  0x10000 + 1234,
+					},
+				}))
+			})
+
+			It("only sends one RST_STREAM", func() {
+				str.HandleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				str.HandleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				err := str.CancelWrite(4321)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(queuedControlFrames).To(HaveLen(1))
+			})
+		})
+	})
+
+	Context("priorities", func() {
+		It("has the default priority", func() {
+			urgency, incremental := str.Priority()
+			Expect(urgency).To(Equal(protocol.DefaultStreamUrgency))
+			Expect(incremental).To(BeTrue())
+		})
+
+		It("sets the priority", func() {
+			str.SetPriority(1, false)
+			urgency, incremental := str.Priority()
+			Expect(urgency).To(Equal(protocol.StreamUrgency(1)))
+			Expect(incremental).To(BeFalse())
+			Expect(priorityChangedForStreams).To(Equal([]protocol.StreamID{streamID}))
+		})
+
+		It("limits the urgency", func() {
+			str.SetPriority(100, true)
+			urgency, _ := str.Priority()
+			Expect(urgency).To(Equal(protocol.MaxStreamUrgency))
+		})
+	})
+
+	Context("unidirectional streams", func() {
+		testErr := errors.New("testErr")
+
+		Context("send streams", func() {
+			BeforeEach(func() {
+				str = newSendStream(streamID, onData, queueControlFrame, onPriorityChanged, mockFC, protocol.VersionWhatever)
+			})
+
+			It("is finished after it is closed and the FIN was sent", func() {
+				Expect(str.Finished()).To(BeFalse())
+				str.Close()
+				Expect(str.Finished()).To(BeFalse())
+				str.SentFin()
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("is finished after being reset locally", func() {
+				str.Reset(testErr)
+				Expect(resetCalled).To(BeTrue())
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("is finished after receiving a RST", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+				err := str.RegisterRemoteError(testErr, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resetCalled).To(BeTrue())
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("is finished after canceling writing", func() {
+				err := str.CancelWrite(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("doesn't allow canceling reading", func() {
+				err := str.CancelRead(1234)
+				Expect(err).To(MatchError("CancelRead for send-only stream 1337"))
+			})
+		})
+
+		Context("receive streams", func() {
+			BeforeEach(func() {
+				str = newReceiveStream(streamID, onData, queueControlFrame, onPriorityChanged, mockFC, protocol.VersionWhatever)
+			})
+
+			It("doesn't send any data", func() {
+				Expect(str.LenOfDataForWriting()).To(BeZero())
+				Expect(str.ShouldSendFin()).To(BeFalse())
+				Expect(str.Context().Done()).To(BeClosed())
+			})
+
+			It("is finished after reading all data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(0))
+				err := str.AddStreamFrame(&wire.StreamFrame{FinBit: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Finished()).To(BeFalse())
+				_, err = str.Read(make([]byte, 10))
+				Expect(err).To(MatchError(io.EOF))
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("is finished after receiving a RST, without sending a RST", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				err := str.RegisterRemoteError(testErr, 42)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resetCalled).To(BeFalse())
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("sends a RST when it is reset locally", func() {
+				str.Reset(testErr)
+				Expect(resetCalled).To(BeTrue())
+				Expect(resetCalledForStream).To(Equal(streamID))
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("is finished after canceling reading, once the final offset is known", func() {
+				mockFC.EXPECT().Abandon()
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				err := str.CancelRead(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(queuedControlFrames).To(Equal([]wire.Frame{
+					&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234},
+				}))
+				Expect(str.Finished()).To(BeFalse())
+				err = str.AddStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), FinBit: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Finished()).To(BeTrue())
+			})
+
+			It("doesn't allow canceling writing", func() {
+				err := str.CancelWrite(1234)
+				Expect(err).To(MatchError("CancelWrite for receive-only stream 1337"))
+			})
+		})
+	})
+
 	Context("flow control", func() {
 		It("says when it's flow control blocked", func() {
 			mockFC.EXPECT().IsBlocked().Return(false)
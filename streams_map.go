@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/seong889/quic-go/internal/protocol"
@@ -16,9 +17,17 @@ type streamsMap struct {
 	perspective protocol.Perspective
 
 	streams map[protocol.StreamID]streamI
-	// needed for round-robin scheduling
-	openStreams     []protocol.StreamID
-	roundRobinIndex int
+	// needed for the scheduling of streams
+	openStreams []protocol.StreamID
+	// For every urgency level, the non-incremental and the incremental streams, in the order they were opened.
+	// The buckets are updated when a stream is opened or closed, or when its priority changes.
+	priorities         map[protocol.StreamID]streamPriority
+	sequentialStreams  [protocol.MaxStreamUrgency + 1][]protocol.StreamID
+	incrementalStreams [protocol.MaxStreamUrgency + 1][]protocol.StreamID
+	// the number of streams opened so far, used to keep the buckets in the order the streams were opened
+	numOpenedStreams uint64
+	// for every urgency level, the index into incrementalStreams of the stream that will be considered first
+	roundRobinIndex [protocol.MaxStreamUrgency + 1]int
 
 	nextStream                protocol.StreamID // StreamID of the next Stream that will be returned by OpenStream()
 	highestStreamOpenedByPeer protocol.StreamID
@@ -34,12 +43,43 @@ type streamsMap struct {
 	numIncomingStreams uint32
 	maxIncomingStreams uint32
 	maxOutgoingStreams uint32
+
+	// unidirectional streams use a separate stream ID space, and have separate limits
+	nextUniStream                protocol.StreamID // StreamID of the next unidirectional Stream that will be returned by OpenUniStream()
+	highestUniStreamOpenedByPeer protocol.StreamID
+	nextUniStreamToAccept        protocol.StreamID
+	openUniStreamOrErrCond       sync.Cond
+
+	numOutgoingUniStreams uint32
+	numIncomingUniStreams uint32
+	maxIncomingUniStreams uint32
+	maxOutgoingUniStreams uint32
+
+	// set when we sent a GOAWAY. New streams opened by the peer are refused.
+	goawaySent bool
+	// closed as soon as all streams are closed after we sent a GOAWAY
+	allStreamsClosed chan struct{}
+	// set when the peer sent a GOAWAY. We're not allowed to open new streams.
+	goawayReceived bool
+}
+
+type streamPriority struct {
+	urgency     protocol.StreamUrgency
+	incremental bool
+	// the position of the stream in the order the streams were opened
+	position uint64
 }
 
 type streamLambda func(streamI) (bool, error)
 type newStreamLambda func(protocol.StreamID) streamI
 
-var errMapAccess = errors.New("streamsMap: Error accessing the streams map")
+var (
+	errMapAccess = errors.New("streamsMap: Error accessing the streams map")
+	// errStreamRefused is returned by GetOrOpenStream when the peer opens a new stream after we sent a GOAWAY
+	errStreamRefused  = errors.New("streamsMap: refusing new stream after sending GOAWAY")
+	errGoawaySent     = errors.New("session is shutting down")
+	errGoawayReceived = errors.New("peer sent a GOAWAY")
+)
 
 func newStreamsMap(newStream newStreamLambda, pers protocol.Perspective, ver protocol.VersionNumber) *streamsMap {
 	// add some tolerance to the maximum incoming streams value
@@ -48,15 +88,25 @@ func newStreamsMap(newStream newStreamLambda, pers protocol.Perspective, ver pro
 		maxStreams+protocol.MaxStreamsMinimumIncrement,
 		uint32(float64(maxStreams)*float64(protocol.MaxStreamsMultiplier)),
 	)
+	maxUniStreams := uint32(protocol.MaxIncomingUniStreams)
+	maxIncomingUniStreams := utils.MaxUint32(
+		maxUniStreams+protocol.MaxStreamsMinimumIncrement,
+		uint32(float64(maxUniStreams)*float64(protocol.MaxStreamsMultiplier)),
+	)
 	sm := streamsMap{
-		perspective:        pers,
-		streams:            make(map[protocol.StreamID]streamI),
-		openStreams:        make([]protocol.StreamID, 0),
-		newStream:          newStream,
-		maxIncomingStreams: maxIncomingStreams,
+		perspective:           pers,
+		streams:               make(map[protocol.StreamID]streamI),
+		openStreams:           make([]protocol.StreamID, 0),
+		priorities:            make(map[protocol.StreamID]streamPriority),
+		newStream:             newStream,
+		maxIncomingStreams:    maxIncomingStreams,
+		maxIncomingUniStreams: maxIncomingUniStreams,
+		nextUniStream:         protocol.FirstUnidirectionalStream(pers),
+		nextUniStreamToAccept: protocol.FirstUnidirectionalStream(pers.Opposite()),
 	}
 	sm.nextStreamOrErrCond.L = &sm.mutex
 	sm.openStreamOrErrCond.L = &sm.mutex
+	sm.openUniStreamOrErrCond.L = &sm.mutex
 
 	nextOddStream := protocol.StreamID(1)
 	if ver.CryptoStreamID() == protocol.StreamID(1) {
@@ -92,6 +142,10 @@ func (m *streamsMap) GetOrOpenStream(id protocol.StreamID) (streamI, error) {
 		return s, nil
 	}
 
+	if id.IsUnidirectional() {
+		return m.getOrOpenRemoteUniStream(id)
+	}
+
 	if m.perspective == protocol.PerspectiveServer {
 		if id%2 == 0 {
 			if id <= m.nextStream { // this is a server-side stream that we already opened. Must have been closed already
@@ -115,6 +169,10 @@ func (m *streamsMap) GetOrOpenStream(id protocol.StreamID) (streamI, error) {
 		}
 	}
 
+	if m.goawaySent {
+		return nil, errStreamRefused
+	}
+
 	// sid is the next stream that will be opened
 	sid := m.highestStreamOpenedByPeer + 2
 	// if there is no stream opened yet, and this is the server, stream 1 should be openend
@@ -156,7 +214,43 @@ func (m *streamsMap) openRemoteStream(id protocol.StreamID) (streamI, error) {
 	return s, nil
 }
 
+// getOrOpenRemoteUniStream opens unidirectional streams opened by the peer.
+// It must be called with the mutex locked.
+func (m *streamsMap) getOrOpenRemoteUniStream(id protocol.StreamID) (streamI, error) {
+	if id.InitiatedBy() == m.perspective {
+		if id < m.nextUniStream { // this is a unidirectional stream that we already opened. Must have been closed already
+			return nil, nil
+		}
+		return nil, qerr.Error(qerr.InvalidStreamID, fmt.Sprintf("peer attempted to open unidirectional stream %d", id))
+	}
+	if id <= m.highestUniStreamOpenedByPeer { // this is a stream that doesn't exist anymore. Must have been closed already
+		return nil, nil
+	}
+	if m.goawaySent {
+		return nil, errStreamRefused
+	}
+
+	sid := m.highestUniStreamOpenedByPeer + 2
+	if m.highestUniStreamOpenedByPeer == 0 {
+		sid = protocol.FirstUnidirectionalStream(m.perspective.Opposite())
+	}
+	for ; sid <= id; sid += 2 {
+		if m.numIncomingUniStreams >= m.maxIncomingUniStreams {
+			return nil, qerr.TooManyOpenStreams
+		}
+		m.numIncomingUniStreams++
+		m.highestUniStreamOpenedByPeer = sid
+		m.putStream(m.newStream(sid))
+	}
+
+	m.nextStreamOrErrCond.Broadcast()
+	return m.streams[id], nil
+}
+
 func (m *streamsMap) openStreamImpl() (streamI, error) {
+	if err := m.goawayError(); err != nil {
+		return nil, err
+	}
 	id := m.nextStream
 	if m.numOutgoingStreams >= m.maxOutgoingStreams {
 		return nil, qerr.TooManyOpenStreams
@@ -204,6 +298,71 @@ func (m *streamsMap) OpenStreamSync() (streamI, error) {
 	}
 }
 
+func (m *streamsMap) openUniStreamImpl() (streamI, error) {
+	if err := m.goawayError(); err != nil {
+		return nil, err
+	}
+	if m.numOutgoingUniStreams >= m.maxOutgoingUniStreams {
+		return nil, qerr.TooManyOpenStreams
+	}
+	m.numOutgoingUniStreams++
+	s := m.newStream(m.nextUniStream)
+	m.nextUniStream += 2
+	m.putStream(s)
+	return s, nil
+}
+
+// OpenUniStream opens the next available unidirectional stream
+func (m *streamsMap) OpenUniStream() (streamI, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closeErr != nil {
+		return nil, m.closeErr
+	}
+	return m.openUniStreamImpl()
+}
+
+func (m *streamsMap) OpenUniStreamSync() (streamI, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for {
+		if m.closeErr != nil {
+			return nil, m.closeErr
+		}
+		str, err := m.openUniStreamImpl()
+		if err == nil {
+			return str, nil
+		}
+		if err != qerr.TooManyOpenStreams {
+			return nil, err
+		}
+		m.openUniStreamOrErrCond.Wait()
+	}
+}
+
+// AcceptUniStream returns the next unidirectional stream opened by the peer
+// it blocks until a new stream is opened
+func (m *streamsMap) AcceptUniStream() (streamI, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var str streamI
+	for {
+		var ok bool
+		if m.closeErr != nil {
+			return nil, m.closeErr
+		}
+		str, ok = m.streams[m.nextUniStreamToAccept]
+		if ok {
+			break
+		}
+		m.nextStreamOrErrCond.Wait()
+	}
+	m.nextUniStreamToAccept += 2
+	return str, nil
+}
+
 // AcceptStream returns the next stream opened by the peer
 // it blocks until a new stream is opened
 func (m *streamsMap) AcceptStream() (streamI, error) {
@@ -241,7 +400,15 @@ func (m *streamsMap) DeleteClosedStreams() error {
 		}
 		numDeletedStreams++
 		m.openStreams[i] = 0
-		if streamID%2 == 0 {
+		m.removeFromBucket(streamID, m.priorities[streamID])
+		delete(m.priorities, streamID)
+		if streamID.IsUnidirectional() {
+			if streamID.InitiatedBy() == m.perspective {
+				m.numOutgoingUniStreams--
+			} else {
+				m.numIncomingUniStreams--
+			}
+		} else if streamID%2 == 0 {
 			m.numOutgoingStreams--
 		} else {
 			m.numIncomingStreams--
@@ -254,7 +421,6 @@ func (m *streamsMap) DeleteClosedStreams() error {
 	}
 
 	// remove all 0s (representing closed streams) from the openStreams slice
-	// and adjust the roundRobinIndex
 	var j int
 	for i, id := range m.openStreams {
 		if i != j {
@@ -262,39 +428,176 @@ func (m *streamsMap) DeleteClosedStreams() error {
 		}
 		if id != 0 {
 			j++
-		} else if j < m.roundRobinIndex {
-			m.roundRobinIndex--
 		}
 	}
 	m.openStreams = m.openStreams[:len(m.openStreams)-numDeletedStreams]
 	m.openStreamOrErrCond.Signal()
+	m.openUniStreamOrErrCond.Signal()
+	m.maybeSignalAllStreamsClosed()
 	return nil
 }
 
-// RoundRobinIterate executes the streamLambda for every open stream, until the streamLambda returns false
-// It uses a round-robin-like scheduling to ensure that every stream is considered fairly
-// It prioritizes the the header-stream (StreamID 3)
-func (m *streamsMap) RoundRobinIterate(fn streamLambda) error {
+// StopAcceptingStreams is called when we send a GOAWAY.
+// It returns the highest stream opened by the peer, which is the last stream that will be processed,
+// and a channel that is closed as soon as all streams are closed.
+func (m *streamsMap) StopAcceptingStreams() (protocol.StreamID, <-chan struct{}) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	numStreams := len(m.streams)
-	startIndex := m.roundRobinIndex
+	if !m.goawaySent {
+		m.goawaySent = true
+		m.allStreamsClosed = make(chan struct{})
+		m.maybeSignalAllStreamsClosed()
+		// wake up calls to OpenStreamSync, they will return an error now
+		m.openStreamOrErrCond.Broadcast()
+		m.openUniStreamOrErrCond.Broadcast()
+	}
+	return m.highestStreamOpenedByPeer, m.allStreamsClosed
+}
 
-	for i := 0; i < numStreams; i++ {
-		streamID := m.openStreams[(i+startIndex)%numStreams]
-		cont, err := m.iterateFunc(streamID, fn)
-		if err != nil {
-			return err
+// must be called with the mutex locked
+func (m *streamsMap) maybeSignalAllStreamsClosed() {
+	if !m.goawaySent || len(m.openStreams) > 0 {
+		return
+	}
+	select {
+	case <-m.allStreamsClosed:
+	default:
+		close(m.allStreamsClosed)
+	}
+}
+
+// RegisterGoaway is called when the peer sends a GOAWAY.
+// Streams opened by us that won't be processed by the peer are cancelled.
+func (m *streamsMap) RegisterGoaway(lastGoodStream protocol.StreamID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.goawayReceived = true
+	m.openStreamOrErrCond.Broadcast()
+	m.openUniStreamOrErrCond.Broadcast()
+	for _, id := range m.openStreams {
+		if id.IsUnidirectional() || id.InitiatedBy() != m.perspective {
+			continue
 		}
-		m.roundRobinIndex = (m.roundRobinIndex + 1) % numStreams
-		if !cont {
-			break
+		if id > lastGoodStream {
+			m.streams[id].Cancel(errGoawayReceived)
 		}
 	}
+}
+
+// must be called with the mutex locked
+func (m *streamsMap) goawayError() error {
+	if m.goawaySent {
+		return errGoawaySent
+	}
+	if m.goawayReceived {
+		return errGoawayReceived
+	}
 	return nil
 }
 
+// PriorityIterate executes the streamLambda for every open stream, until the streamLambda returns false.
+// Streams with a lower urgency are considered first.
+// Within one urgency level, the non-incremental streams are considered in the order they were opened.
+// After that, the incremental streams are considered, using a round-robin-like scheduling to ensure that every stream is considered fairly.
+func (m *streamsMap) PriorityIterate(fn streamLambda) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for urgency := range m.sequentialStreams {
+		for _, id := range m.sequentialStreams[urgency] {
+			cont, err := m.iterateFunc(id, fn)
+			if err != nil || !cont {
+				return err
+			}
+		}
+		ids := m.incrementalStreams[urgency]
+		numStreams := len(ids)
+		if numStreams == 0 {
+			continue
+		}
+		// continue with the stream after the one that was last considered on this urgency level
+		startIndex := m.roundRobinIndex[urgency] % numStreams
+		for j := 0; j < numStreams; j++ {
+			i := (j + startIndex) % numStreams
+			cont, err := m.iterateFunc(ids[i], fn)
+			if err != nil {
+				return err
+			}
+			m.roundRobinIndex[urgency] = (i + 1) % numStreams
+			if !cont {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// UpdatePriority is called when the priority of a stream changes.
+// It moves the stream to the bucket of its new priority.
+func (m *streamsMap) UpdatePriority(id protocol.StreamID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	str, ok := m.streams[id]
+	if !ok || str == nil {
+		return
+	}
+	oldPriority, ok := m.priorities[id]
+	if !ok {
+		return
+	}
+	urgency, incremental := str.Priority()
+	if urgency == oldPriority.urgency && incremental == oldPriority.incremental {
+		return
+	}
+	m.removeFromBucket(id, oldPriority)
+	p := streamPriority{urgency: urgency, incremental: incremental, position: oldPriority.position}
+	m.priorities[id] = p
+	m.addToBucket(id, p)
+}
+
+// addToBucket inserts a stream into the bucket for its priority, keeping the order the streams were opened in.
+// It must be called with the mutex locked.
+func (m *streamsMap) addToBucket(id protocol.StreamID, p streamPriority) {
+	bucket := &m.sequentialStreams[p.urgency]
+	if p.incremental {
+		bucket = &m.incrementalStreams[p.urgency]
+	}
+	ids := *bucket
+	i := sort.Search(len(ids), func(i int) bool { return m.priorities[ids[i]].position > p.position })
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	*bucket = ids
+	// keep pointing to the same stream
+	if p.incremental && i < m.roundRobinIndex[p.urgency] {
+		m.roundRobinIndex[p.urgency]++
+	}
+}
+
+// removeFromBucket removes a stream from the bucket for its priority.
+// It must be called with the mutex locked.
+func (m *streamsMap) removeFromBucket(id protocol.StreamID, p streamPriority) {
+	bucket := &m.sequentialStreams[p.urgency]
+	if p.incremental {
+		bucket = &m.incrementalStreams[p.urgency]
+	}
+	ids := *bucket
+	for i, sid := range ids {
+		if sid != id {
+			continue
+		}
+		*bucket = append(ids[:i], ids[i+1:]...)
+		// keep pointing to the same stream
+		if p.incremental && i < m.roundRobinIndex[p.urgency] {
+			m.roundRobinIndex[p.urgency]--
+		}
+		return
+	}
+}
+
 // Range executes a callback for all streams, in pseudo-random order
 func (m *streamsMap) Range(cb func(s streamI)) {
 	m.mutex.RLock()
@@ -323,6 +626,11 @@ func (m *streamsMap) putStream(s streamI) error {
 
 	m.streams[id] = s
 	m.openStreams = append(m.openStreams, id)
+	urgency, incremental := s.Priority()
+	p := streamPriority{urgency: urgency, incremental: incremental, position: m.numOpenedStreams}
+	m.numOpenedStreams++
+	m.priorities[id] = p
+	m.addToBucket(id, p)
 	return nil
 }
 
@@ -332,6 +640,7 @@ func (m *streamsMap) CloseWithError(err error) {
 	m.closeErr = err
 	m.nextStreamOrErrCond.Broadcast()
 	m.openStreamOrErrCond.Broadcast()
+	m.openUniStreamOrErrCond.Broadcast()
 	for _, s := range m.openStreams {
 		m.streams[s].Cancel(err)
 	}
@@ -342,3 +651,10 @@ func (m *streamsMap) UpdateMaxStreamLimit(limit uint32) {
 	defer m.mutex.Unlock()
 	m.maxOutgoingStreams = limit
 }
+
+func (m *streamsMap) UpdateMaxUniStreamLimit(limit uint32) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxOutgoingUniStreams = limit
+	m.openUniStreamOrErrCond.Broadcast()
+}
@@ -22,6 +22,7 @@ var _ = Describe("Streams Map", func() {
 	newStream := func(id protocol.StreamID) streamI {
 		str := mocks.NewMockStreamI(mockCtrl)
 		str.EXPECT().StreamID().Return(id).AnyTimes()
+		str.EXPECT().Priority().Return(protocol.DefaultStreamUrgency, true).AnyTimes()
 		c := str.EXPECT().Finished().Return(false).AnyTimes()
 		finishedStreams[id] = c
 		return str
@@ -517,6 +518,325 @@ var _ = Describe("Streams Map", func() {
 		})
 	})
 
+	Context("unidirectional streams", func() {
+		const firstClientUniStream = protocol.StreamID(0x80000001)
+		const firstServerUniStream = protocol.StreamID(0x80000002)
+
+		BeforeEach(func() {
+			setNewStreamsMap(protocol.PerspectiveServer, versionCryptoStream1)
+		})
+
+		Context("opening", func() {
+			It("doesn't open streams if the peer didn't allow it", func() {
+				_, err := m.OpenUniStream()
+				Expect(err).To(MatchError(qerr.TooManyOpenStreams))
+			})
+
+			It("opens streams in the unidirectional stream ID space", func() {
+				m.UpdateMaxUniStreamLimit(100)
+				s, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstServerUniStream))
+				s, err = m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstServerUniStream + 2))
+				Expect(m.numOutgoingUniStreams).To(BeEquivalentTo(2))
+				Expect(m.numOutgoingStreams).To(BeZero())
+			})
+
+			It("doesn't affect the bidirectional stream limit", func() {
+				m.UpdateMaxUniStreamLimit(1)
+				_, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenUniStream()
+				Expect(err).To(MatchError(qerr.TooManyOpenStreams))
+				m.UpdateMaxStreamLimit(1)
+				_, err = m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("decreases the counter when a stream is deleted", func() {
+				m.UpdateMaxUniStreamLimit(1)
+				_, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				deleteStream(firstServerUniStream)
+				Expect(m.numOutgoingUniStreams).To(BeZero())
+				s, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstServerUniStream + 2))
+			})
+
+			It("waits until the peer allows opening a stream", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					s, err := m.OpenUniStreamSync()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s.StreamID()).To(Equal(firstServerUniStream))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				m.UpdateMaxUniStreamLimit(1)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("waits until another stream is closed", func() {
+				m.UpdateMaxUniStreamLimit(1)
+				_, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					s, err := m.OpenUniStreamSync()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s.StreamID()).To(Equal(firstServerUniStream + 2))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				deleteStream(firstServerUniStream)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("stops waiting when an error is registered", func() {
+				testErr := errors.New("test error")
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := m.OpenUniStreamSync()
+					Expect(err).To(MatchError(testErr))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				m.CloseWithError(testErr)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
+		Context("accepting", func() {
+			It("gets streams opened by the peer", func() {
+				s, err := m.GetOrOpenStream(firstClientUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstClientUniStream))
+				Expect(m.numIncomingUniStreams).To(BeEquivalentTo(1))
+				Expect(m.numIncomingStreams).To(BeZero())
+			})
+
+			It("opens skipped streams", func() {
+				_, err := m.GetOrOpenStream(firstClientUniStream + 4)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.streams).To(HaveKey(firstClientUniStream))
+				Expect(m.streams).To(HaveKey(firstClientUniStream + 2))
+				Expect(m.streams).To(HaveKey(firstClientUniStream + 4))
+				Expect(m.numIncomingUniStreams).To(BeEquivalentTo(3))
+			})
+
+			It("rejects streams in our stream ID space that we didn't open yet", func() {
+				_, err := m.GetOrOpenStream(firstServerUniStream)
+				Expect(err).To(MatchError(qerr.Error(qerr.InvalidStreamID, "peer attempted to open unidirectional stream 2147483650")))
+			})
+
+			It("returns nil for closed streams", func() {
+				_, err := m.GetOrOpenStream(firstClientUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				deleteStream(firstClientUniStream)
+				Expect(m.numIncomingUniStreams).To(BeZero())
+				s, err := m.GetOrOpenStream(firstClientUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).To(BeNil())
+			})
+
+			It("returns nil for closed streams opened by us", func() {
+				m.UpdateMaxUniStreamLimit(1)
+				_, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				deleteStream(firstServerUniStream)
+				s, err := m.GetOrOpenStream(firstServerUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).To(BeNil())
+			})
+
+			It("errors when too many streams are opened by the peer", func() {
+				for i := uint32(0); i < m.maxIncomingUniStreams; i++ {
+					_, err := m.GetOrOpenStream(firstClientUniStream + protocol.StreamID(2*i))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err := m.GetOrOpenStream(firstClientUniStream + protocol.StreamID(2*m.maxIncomingUniStreams))
+				Expect(err).To(MatchError(qerr.TooManyOpenStreams))
+			})
+
+			It("accepts streams in order", func() {
+				_, err := m.GetOrOpenStream(firstClientUniStream + 2)
+				Expect(err).ToNot(HaveOccurred())
+				s, err := m.AcceptUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstClientUniStream))
+				s, err = m.AcceptUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.StreamID()).To(Equal(firstClientUniStream + 2))
+			})
+
+			It("doesn't accept bidirectional streams", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					s, err := m.AcceptUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s.StreamID()).To(Equal(firstClientUniStream))
+					close(done)
+				}()
+				_, err := m.GetOrOpenStream(3)
+				Expect(err).ToNot(HaveOccurred())
+				Consistently(done).ShouldNot(BeClosed())
+				_, err = m.GetOrOpenStream(firstClientUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("stops accepting when an error is registered", func() {
+				testErr := errors.New("test error")
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := m.AcceptUniStream()
+					Expect(err).To(MatchError(testErr))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				m.CloseWithError(testErr)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+	})
+
+	Context("GOAWAY", func() {
+		BeforeEach(func() {
+			setNewStreamsMap(protocol.PerspectiveServer, versionCryptoStream1)
+			m.UpdateMaxStreamLimit(100)
+			m.UpdateMaxUniStreamLimit(100)
+		})
+
+		Context("sending", func() {
+			It("returns the highest stream opened by the peer", func() {
+				_, err := m.GetOrOpenStream(7)
+				Expect(err).ToNot(HaveOccurred())
+				lastGoodStream, _ := m.StopAcceptingStreams()
+				Expect(lastGoodStream).To(Equal(protocol.StreamID(7)))
+			})
+
+			It("refuses new streams opened by the peer", func() {
+				_, err := m.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				m.StopAcceptingStreams()
+				str, err := m.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str).ToNot(BeNil())
+				_, err = m.GetOrOpenStream(7)
+				Expect(err).To(MatchError(errStreamRefused))
+				_, err = m.GetOrOpenStream(0x80000001)
+				Expect(err).To(MatchError(errStreamRefused))
+			})
+
+			It("still returns nil for closed streams", func() {
+				_, err := m.GetOrOpenStream(1)
+				Expect(err).ToNot(HaveOccurred())
+				deleteStream(1)
+				m.StopAcceptingStreams()
+				str, err := m.GetOrOpenStream(1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str).To(BeNil())
+			})
+
+			It("doesn't open new streams", func() {
+				m.StopAcceptingStreams()
+				_, err := m.OpenStream()
+				Expect(err).To(MatchError(errGoawaySent))
+				_, err = m.OpenUniStream()
+				Expect(err).To(MatchError(errGoawaySent))
+			})
+
+			It("unblocks OpenStreamSync", func() {
+				m.UpdateMaxStreamLimit(0)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := m.OpenStreamSync()
+					Expect(err).To(MatchError(errGoawaySent))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				m.StopAcceptingStreams()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("signals when all streams are closed", func() {
+				_, err := m.GetOrOpenStream(1)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, allStreamsClosed := m.StopAcceptingStreams()
+				Expect(allStreamsClosed).ToNot(BeClosed())
+				deleteStream(1)
+				Expect(allStreamsClosed).ToNot(BeClosed())
+				deleteStream(2)
+				Expect(allStreamsClosed).To(BeClosed())
+			})
+
+			It("signals immediately if no stream is open", func() {
+				_, allStreamsClosed := m.StopAcceptingStreams()
+				Expect(allStreamsClosed).To(BeClosed())
+			})
+
+			It("returns the same channel when called multiple times", func() {
+				_, err := m.GetOrOpenStream(3)
+				Expect(err).ToNot(HaveOccurred())
+				_, c1 := m.StopAcceptingStreams()
+				_, c2 := m.StopAcceptingStreams()
+				Expect(c1).To(Equal(c2))
+			})
+		})
+
+		Context("receiving", func() {
+			It("doesn't open new streams", func() {
+				m.RegisterGoaway(0)
+				_, err := m.OpenStream()
+				Expect(err).To(MatchError(errGoawayReceived))
+				_, err = m.OpenUniStream()
+				Expect(err).To(MatchError(errGoawayReceived))
+			})
+
+			It("still accepts streams opened by the peer", func() {
+				m.RegisterGoaway(0)
+				_, err := m.GetOrOpenStream(3)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("cancels streams that won't be processed by the peer", func() {
+				for i := 0; i < 3; i++ {
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err := m.GetOrOpenStream(7)
+				Expect(err).ToNot(HaveOccurred())
+				m.streams[6].(*mocks.MockStreamI).EXPECT().Cancel(errGoawayReceived)
+				m.RegisterGoaway(4)
+			})
+
+			It("unblocks OpenStreamSync", func() {
+				m.UpdateMaxStreamLimit(0)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := m.OpenStreamSync()
+					Expect(err).To(MatchError(errGoawayReceived))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				m.RegisterGoaway(0)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+	})
+
 	Context("DoS mitigation, iterating and deleting", func() {
 		BeforeEach(func() {
 			setNewStreamsMap(protocol.PerspectiveServer, versionCryptoStream1)
@@ -606,7 +926,7 @@ var _ = Describe("Streams Map", func() {
 			})
 		})
 
-		Context("RoundRobinIterate", func() {
+		Context("PriorityIterate", func() {
 			// create 5 streams, ids 4 to 8
 			var lambdaCalledForStream []protocol.StreamID
 			var numIterations int
@@ -626,11 +946,11 @@ var _ = Describe("Streams Map", func() {
 					numIterations++
 					return true, nil
 				}
-				err := m.RoundRobinIterate(fn)
+				err := m.PriorityIterate(fn)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(numIterations).To(Equal(5))
 				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 6, 7, 8}))
-				Expect(m.roundRobinIndex).To(BeZero())
+				Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeZero())
 			})
 
 			It("goes around once when starting in the middle", func() {
@@ -639,12 +959,12 @@ var _ = Describe("Streams Map", func() {
 					numIterations++
 					return true, nil
 				}
-				m.roundRobinIndex = 3 // pointing to stream 7
-				err := m.RoundRobinIterate(fn)
+				m.roundRobinIndex[protocol.DefaultStreamUrgency] = 3 // pointing to stream 7
+				err := m.PriorityIterate(fn)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(numIterations).To(Equal(5))
 				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{7, 8, 4, 5, 6}))
-				Expect(m.roundRobinIndex).To(BeEquivalentTo(3))
+				Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(3))
 			})
 
 			It("picks up at the index+1 where it last stopped", func() {
@@ -656,11 +976,11 @@ var _ = Describe("Streams Map", func() {
 					}
 					return true, nil
 				}
-				err := m.RoundRobinIterate(fn)
+				err := m.PriorityIterate(fn)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(numIterations).To(Equal(2))
 				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5}))
-				Expect(m.roundRobinIndex).To(BeEquivalentTo(2))
+				Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(2))
 				numIterations = 0
 				lambdaCalledForStream = lambdaCalledForStream[:0]
 				fn2 := func(str streamI) (bool, error) {
@@ -671,7 +991,7 @@ var _ = Describe("Streams Map", func() {
 					}
 					return true, nil
 				}
-				err = m.RoundRobinIterate(fn2)
+				err = m.PriorityIterate(fn2)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(numIterations).To(Equal(2))
 				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 7}))
@@ -684,31 +1004,148 @@ var _ = Describe("Streams Map", func() {
 				*/
 
 				It("adjusts when deleting an element in front", func() {
-					m.roundRobinIndex = 3 // stream 7
+					m.roundRobinIndex[protocol.DefaultStreamUrgency] = 3 // stream 7
 					deleteStream(5)
-					Expect(m.roundRobinIndex).To(BeEquivalentTo(2))
+					Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(2))
 				})
 
 				It("doesn't adjust when deleting an element at the back", func() {
-					m.roundRobinIndex = 1 // stream 5
+					m.roundRobinIndex[protocol.DefaultStreamUrgency] = 1 // stream 5
 					deleteStream(7)
-					Expect(m.roundRobinIndex).To(BeEquivalentTo(1))
+					Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(1))
 				})
 
 				It("doesn't adjust when deleting the element it is pointing to", func() {
-					m.roundRobinIndex = 3 // stream 7
+					m.roundRobinIndex[protocol.DefaultStreamUrgency] = 3 // stream 7
 					deleteStream(7)
-					Expect(m.roundRobinIndex).To(BeEquivalentTo(3))
+					Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(3))
 				})
 
 				It("adjusts when deleting multiple elements", func() {
-					m.roundRobinIndex = 3 // stream 7
+					m.roundRobinIndex[protocol.DefaultStreamUrgency] = 3 // stream 7
 					closeStream(5)
 					closeStream(6)
 					closeStream(8)
 					err := m.DeleteClosedStreams()
 					Expect(err).ToNot(HaveOccurred())
-					Expect(m.roundRobinIndex).To(BeEquivalentTo(1))
+					Expect(m.roundRobinIndex[protocol.DefaultStreamUrgency]).To(BeEquivalentTo(1))
+				})
+			})
+		})
+
+		Context("prioritizing streams", func() {
+			var lambdaCalledForStream []protocol.StreamID
+
+			putStreamWithPriority := func(id protocol.StreamID, urgency protocol.StreamUrgency, incremental bool) {
+				str := mocks.NewMockStreamI(mockCtrl)
+				str.EXPECT().StreamID().Return(id).AnyTimes()
+				str.EXPECT().Priority().Return(urgency, incremental).AnyTimes()
+				finishedStreams[id] = str.EXPECT().Finished().Return(false).AnyTimes()
+				err := m.putStream(str)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			iterateUntil := func(stopAt protocol.StreamID) {
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				fn := func(str streamI) (bool, error) {
+					lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+					return str.StreamID() != stopAt, nil
+				}
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			It("considers streams with a lower urgency first", func() {
+				putStreamWithPriority(4, 5, true)
+				putStreamWithPriority(5, 1, true)
+				putStreamWithPriority(6, 3, true)
+				iterateUntil(0)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5, 6, 4}))
+			})
+
+			It("considers non-incremental streams in the order they were opened, before the incremental streams", func() {
+				putStreamWithPriority(4, 3, true)
+				putStreamWithPriority(5, 3, false)
+				putStreamWithPriority(6, 3, false)
+				putStreamWithPriority(7, 3, true)
+				iterateUntil(4)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5, 6, 4}))
+				iterateUntil(0)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5, 6, 7, 4}))
+			})
+
+			It("doesn't consider streams with a higher urgency if the lambda returns false", func() {
+				putStreamWithPriority(4, 1, false)
+				putStreamWithPriority(5, 0, false)
+				iterateUntil(5)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5}))
+			})
+
+			It("uses a separate round-robin index for every urgency level", func() {
+				putStreamWithPriority(4, 1, true)
+				putStreamWithPriority(5, 1, true)
+				putStreamWithPriority(6, 2, true)
+				putStreamWithPriority(7, 2, true)
+				iterateUntil(6)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 6}))
+				iterateUntil(7)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 7}))
+				iterateUntil(7)
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 6, 7}))
+			})
+
+			Context("changing priorities", func() {
+				var streams map[protocol.StreamID]*stream
+
+				BeforeEach(func() {
+					streams = make(map[protocol.StreamID]*stream)
+					for id := protocol.StreamID(4); id <= 7; id++ {
+						str := &stream{
+							streamID:          id,
+							onPriorityChanged: m.UpdatePriority,
+							urgency:           protocol.DefaultStreamUrgency,
+							incremental:       true,
+						}
+						Expect(m.putStream(str)).To(Succeed())
+						streams[id] = str
+					}
+				})
+
+				It("reschedules a stream when its priority changes", func() {
+					iterateUntil(0)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 6, 7}))
+					streams[6].SetPriority(1, false)
+					iterateUntil(0)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 4, 5, 7}))
+					// when moving back, the stream is scheduled in the order it was opened
+					streams[6].SetPriority(protocol.DefaultStreamUrgency, true)
+					iterateUntil(0)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5, 6, 7}))
+				})
+
+				It("keeps the round-robin position when a stream is added in front of it", func() {
+					streams[5].SetPriority(1, true)
+					iterateUntil(6)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5, 4, 6}))
+					// the next iteration continues with stream 7
+					streams[5].SetPriority(protocol.DefaultStreamUrgency, true)
+					iterateUntil(0)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{7, 4, 5, 6}))
+				})
+
+				It("doesn't reschedule a stream if its priority didn't change", func() {
+					streams[5].SetPriority(protocol.DefaultStreamUrgency, true)
+					Expect(m.incrementalStreams[protocol.DefaultStreamUrgency]).To(Equal([]protocol.StreamID{4, 5, 6, 7}))
+				})
+
+				It("removes closed streams from the buckets", func() {
+					streams[5].SetPriority(1, false)
+					streams[5].cancelled.Set(true)
+					Expect(m.DeleteClosedStreams()).To(Succeed())
+					Expect(m.sequentialStreams[1]).To(BeEmpty())
+					Expect(m.priorities).ToNot(HaveKey(protocol.StreamID(5)))
+					iterateUntil(0)
+					Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 6, 7}))
 				})
 			})
 		})
@@ -0,0 +1,124 @@
+// Package tracing defines the interface for tracing QUIC sessions.
+// The types passed to the callbacks are aliases for the types used internally by quic-go.
+package tracing
+
+import (
+	"net"
+	"time"
+
+	"github.com/seong889/quic-go/congestion"
+	"github.com/seong889/quic-go/internal/protocol"
+	"github.com/seong889/quic-go/internal/wire"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// A ConnectionID is a QUIC connection ID.
+	ConnectionID = protocol.ConnectionID
+	// The EncryptionLevel is the encryption level of a packet.
+	EncryptionLevel = protocol.EncryptionLevel
+	// A PacketNumber is a QUIC packet number.
+	PacketNumber = protocol.PacketNumber
+	// The Perspective determines if we're acting as a server or a client.
+	Perspective = protocol.Perspective
+	// A StreamID is the ID of a QUIC stream.
+	StreamID = protocol.StreamID
+	// A VersionNumber is a QUIC version number.
+	VersionNumber = protocol.VersionNumber
+	// RTTStats are the round-trip statistics of a session.
+	RTTStats = congestion.RTTStats
+
+	// The Header is the header of a QUIC packet.
+	Header = wire.Header
+	// A Frame is a QUIC frame.
+	Frame = wire.Frame
+
+	// An AckFrame is an ACK frame.
+	AckFrame = wire.AckFrame
+	// A BlockedFrame is a connection-level BLOCKED frame.
+	BlockedFrame = wire.BlockedFrame
+	// A ConnectionCloseFrame is a CONNECTION_CLOSE frame.
+	ConnectionCloseFrame = wire.ConnectionCloseFrame
+	// A DatagramFrame is a DATAGRAM frame.
+	DatagramFrame = wire.DatagramFrame
+	// A GoawayFrame is a GOAWAY frame.
+	GoawayFrame = wire.GoawayFrame
+	// A MaxDataFrame is a MAX_DATA frame.
+	MaxDataFrame = wire.MaxDataFrame
+	// A MaxStreamDataFrame is a MAX_STREAM_DATA frame.
+	MaxStreamDataFrame = wire.MaxStreamDataFrame
+	// A PingFrame is a PING frame.
+	PingFrame = wire.PingFrame
+	// A RstStreamFrame is a RST_STREAM frame.
+	RstStreamFrame = wire.RstStreamFrame
+	// A StopSendingFrame is a STOP_SENDING frame.
+	StopSendingFrame = wire.StopSendingFrame
+	// A StopWaitingFrame is a STOP_WAITING frame.
+	StopWaitingFrame = wire.StopWaitingFrame
+	// A StreamBlockedFrame is a stream-level BLOCKED frame.
+	StreamBlockedFrame = wire.StreamBlockedFrame
+	// A StreamFrame is a STREAM frame.
+	StreamFrame = wire.StreamFrame
+)
+
+const (
+	// PerspectiveServer is used for a QUIC server
+	PerspectiveServer = protocol.PerspectiveServer
+	// PerspectiveClient is used for a QUIC client
+	PerspectiveClient = protocol.PerspectiveClient
+)
+
+const (
+	// EncryptionUnencrypted is not encrypted
+	EncryptionUnencrypted = protocol.EncryptionUnencrypted
+	// EncryptionSecure is encrypted, but not forward secure
+	EncryptionSecure = protocol.EncryptionSecure
+	// EncryptionForwardSecure is forward secure
+	EncryptionForwardSecure = protocol.EncryptionForwardSecure
+)
+
+// A Tracer creates a SessionTracer for every new session.
+// It is also notified about packets that can't be associated with a session.
+type Tracer interface {
+	// TracerForSession is called when a new session is created.
+	// If it returns nil, the session is not traced.
+	TracerForSession(p Perspective, connectionID ConnectionID) SessionTracer
+	// DroppedPacket is called when a packet is dropped before it was passed to a session.
+	DroppedPacket(remoteAddr net.Addr, size ByteCount, reason PacketDropReason)
+}
+
+// A SessionTracer receives the events of a single session.
+// All methods are called from the session's run loop.
+type SessionTracer interface {
+	// SentPacket is called when a packet is sent.
+	SentPacket(hdr *Header, size ByteCount, encLevel EncryptionLevel, frames []Frame)
+	// ReceivedPacket is called when a packet was received and successfully decrypted.
+	ReceivedPacket(hdr *Header, size ByteCount, encLevel EncryptionLevel, frames []Frame)
+	// DroppedPacket is called when a packet is dropped.
+	DroppedPacket(hdr *Header, size ByteCount, reason PacketDropReason)
+	// LostPacket is called when a packet is declared lost, and queued for retransmission.
+	LostPacket(packetNumber PacketNumber, reason PacketLossReason)
+	// UpdatedRTT is called when a new RTT sample was taken.
+	UpdatedRTT(rttStats *RTTStats)
+	// UpdatedCongestionWindow is called when the congestion window changes.
+	UpdatedCongestionWindow(congestionWindow ByteCount)
+	// ExitedSlowStart is called when the congestion controller leaves slow start.
+	ExitedSlowStart()
+	// Blocked is called when sending data is blocked by flow control.
+	// For connection-level flow control, the stream ID is 0.
+	Blocked(streamID StreamID)
+	// UpdatedEncryptionLevel is called when the encryption level of the session changes.
+	UpdatedEncryptionLevel(encLevel EncryptionLevel)
+	// Close is called when the session is closed.
+	Close()
+}
+
+// A PacketCapture receives the raw bytes of the packets sent and received by a session.
+// It is used by multiple sessions concurrently.
+type PacketCapture interface {
+	// CapturePacket is called for every packet that is sent or received.
+	// The data must not be retained after CapturePacket returns.
+	// The frames are the decrypted frames contained in the packet. They are nil if the packet couldn't be decrypted.
+	CapturePacket(t time.Time, data []byte, src, dst net.Addr, frames []Frame)
+}
@@ -0,0 +1,47 @@
+package tracing
+
+// A PacketDropReason is the reason why a packet was dropped
+type PacketDropReason uint8
+
+const (
+	// PacketDropUndecryptable is used when the packet couldn't be decrypted
+	PacketDropUndecryptable PacketDropReason = iota + 1
+	// PacketDropUnknownConnectionID is used when the packet belongs to an unknown connection
+	PacketDropUnknownConnectionID
+)
+
+func (r PacketDropReason) String() string {
+	switch r {
+	case PacketDropUndecryptable:
+		return "undecryptable"
+	case PacketDropUnknownConnectionID:
+		return "unknown connection ID"
+	default:
+		return "unknown reason"
+	}
+}
+
+// A PacketLossReason is the reason why a packet was declared lost
+type PacketLossReason uint8
+
+const (
+	// PacketLossTimeThreshold is used when the packet was detected lost by time-based loss detection
+	PacketLossTimeThreshold PacketLossReason = iota + 1
+	// PacketLossRetransmissionTimeout is used when the packet was retransmitted because the RTO fired
+	PacketLossRetransmissionTimeout
+	// PacketLossHandshakeTimeout is used for handshake packets that were retransmitted because the handshake timer fired
+	PacketLossHandshakeTimeout
+)
+
+func (r PacketLossReason) String() string {
+	switch r {
+	case PacketLossTimeThreshold:
+		return "time threshold"
+	case PacketLossRetransmissionTimeout:
+		return "retransmission timeout"
+	case PacketLossHandshakeTimeout:
+		return "handshake timeout"
+	default:
+		return "unknown reason"
+	}
+}
@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Types", func() {
+	It("has a string representation for the packet drop reason", func() {
+		Expect(PacketDropUndecryptable.String()).To(Equal("undecryptable"))
+		Expect(PacketDropUnknownConnectionID.String()).To(Equal("unknown connection ID"))
+		Expect(PacketDropReason(0).String()).To(Equal("unknown reason"))
+	})
+
+	It("has a string representation for the packet loss reason", func() {
+		Expect(PacketLossTimeThreshold.String()).To(Equal("time threshold"))
+		Expect(PacketLossRetransmissionTimeout.String()).To(Equal("retransmission timeout"))
+		Expect(PacketLossHandshakeTimeout.String()).To(Equal("handshake timeout"))
+		Expect(PacketLossReason(0).String()).To(Equal("unknown reason"))
+	})
+})